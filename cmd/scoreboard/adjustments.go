@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseAdjustmentRows parses the adjustments tab, with the date, player,
+// amount and reason in the first four columns. The first row holds the labels.
+// Adjustments are returned in date order.
+func parseAdjustmentRows(values [][]interface{}) ([]*Adjustment, error) {
+	var adjustments []*Adjustment
+	for idx, row := range values {
+		if idx == 0 || len(row) < 3 {
+			continue
+		}
+
+		adj := &Adjustment{
+			Date:   strings.TrimSpace(fmt.Sprintf("%s", row[0])),
+			Player: strings.TrimSpace(fmt.Sprintf("%s", row[1])),
+		}
+		if len(row) > 3 {
+			adj.Reason = strings.TrimSpace(fmt.Sprintf("%s", row[3]))
+		}
+
+		ts, err := parseAdjustmentDate(adj.Date)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid date %q", idx+1, adj.Date)
+		}
+		adj.Timestamp = ts
+
+		amount, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(fmt.Sprintf("%s", row[2])), "+"))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid amount %q", idx+1, row[2])
+		}
+		adj.Amount = amount
+
+		if adj.Player == "" {
+			return nil, fmt.Errorf("row %d: missing player", idx+1)
+		}
+		adjustments = append(adjustments, adj)
+	}
+
+	// keep the sheet order for adjustments on the same date
+	for i := 1; i < len(adjustments); i++ {
+		for j := i; j > 0 && adjustments[j].Timestamp.Before(adjustments[j-1].Timestamp); j-- {
+			adjustments[j], adjustments[j-1] = adjustments[j-1], adjustments[j]
+		}
+	}
+	return adjustments, nil
+}
+
+// parseAdjustmentDate parses an adjustment date in any of the game log's date
+// formats.
+func parseAdjustmentDate(date string) (time.Time, error) {
+	return parseGameDate(date)
+}
+
+// adjustmentsInSeason returns copies of the adjustments made during the season.
+// Seeds are left out, since season standings start everyone afresh.
+func adjustmentsInSeason(adjustments []*Adjustment, s Season) []*Adjustment {
+	var season []*Adjustment
+	for _, adj := range adjustments {
+		if !adj.Seed && s.Contains(adj.Timestamp) {
+			c := *adj
+			season = append(season, &c)
+		}
+	}
+	return season
+}
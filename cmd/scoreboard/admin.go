@@ -0,0 +1,26 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// requireAdmin only lets requests through that carry the admin token as a
+// bearer token. If no admin token is configured the admin endpoints are
+// disabled and respond with 404.
+func requireAdmin(token string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			notFoundRes(w, r)
+			return
+		}
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scoreboard admin"`)
+			unauthorizedRes(w, r, "The admin endpoints need the admin token as a bearer token.")
+			return
+		}
+		next(w, r)
+	})
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// StatsQuery filters the games aggregated by the stats API.
+type StatsQuery struct {
+	Player   string    // only aggregate this player, empty for everyone.
+	From     time.Time // only games on or after this date, zero for no limit.
+	To       time.Time // only games before the end of this date, zero for no limit.
+	PodSizes []int     // only games with these numbers of players, empty for any.
+	Tag      string    // only games with this tag, empty for any.
+}
+
+// PlayerStats are a player's aggregate results over the queried games.
+type PlayerStats struct {
+	Player       string  `json:"player"`
+	Name         string  `json:"name"`
+	Games        int     `json:"games"`
+	Wins         int     `json:"wins"`
+	WinRate      float64 `json:"winRate"`
+	WinRateLow   float64 `json:"winRateLow"`   // the low end of the win rate's 95% confidence interval.
+	WinRateHigh  float64 `json:"winRateHigh"`  // the high end of the win rate's 95% confidence interval.
+	AvgPlacement float64 `json:"avgPlacement"` // the average finishing place in games that weren't draws.
+	AvgDelta     float64 `json:"avgDelta"`     // the average rating change per game.
+
+	placed     int
+	placements int
+}
+
+// parseStatsQuery parses the stats API's query parameters: player, dateRange
+// as YYYY-MM-DD..YYYY-MM-DD with either end optional, podSize as a comma
+// separated list of pod sizes, and tag.
+func parseStatsQuery(q url.Values) (StatsQuery, error) {
+	sq := StatsQuery{Player: strings.TrimSpace(q.Get("player")), Tag: strings.TrimSpace(q.Get("tag"))}
+
+	if v := q.Get("dateRange"); v != "" {
+		parts := strings.Split(v, "..")
+		if len(parts) != 2 {
+			return sq, fmt.Errorf("dateRange must look like 2023-01-01..2023-03-31")
+		}
+		var err error
+		if parts[0] != "" {
+			if sq.From, err = time.Parse("2006-01-02", parts[0]); err != nil {
+				return sq, fmt.Errorf("invalid dateRange start %q", parts[0])
+			}
+		}
+		if parts[1] != "" {
+			if sq.To, err = time.Parse("2006-01-02", parts[1]); err != nil {
+				return sq, fmt.Errorf("invalid dateRange end %q", parts[1])
+			}
+			// the end date is inclusive
+			sq.To = sq.To.AddDate(0, 0, 1)
+		}
+	}
+
+	if v := q.Get("podSize"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || n < 2 {
+				return sq, fmt.Errorf("invalid podSize %q", s)
+			}
+			sq.PodSizes = append(sq.PodSizes, n)
+		}
+	}
+	return sq, nil
+}
+
+// matches reports whether the scored game is included by the query.
+func (sq StatsQuery) matches(g *Game) bool {
+	if !sq.From.IsZero() && g.Timestamp.Before(sq.From) {
+		return false
+	}
+	if !sq.To.IsZero() && !g.Timestamp.Before(sq.To) {
+		return false
+	}
+	if len(sq.PodSizes) > 0 {
+		found := false
+		for _, n := range sq.PodSizes {
+			if len(g.Results) == n {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if sq.Tag != "" && !hasTag(g, sq.Tag) {
+		return false
+	}
+	return true
+}
+
+// aggregateStats aggregates each player's results in the scored games that
+// match the query, ordered by games played.
+func aggregateStats(games []*Game, sq StatsQuery) []PlayerStats {
+	players := map[string]*PlayerStats{}
+	for _, g := range games {
+		if !sq.matches(g) {
+			continue
+		}
+		for _, res := range g.Results {
+			if sq.Player != "" && res.Player != sq.Player {
+				continue
+			}
+			s, ok := players[res.Player]
+			if !ok {
+				s = &PlayerStats{Player: res.Player}
+				players[res.Player] = s
+			}
+			s.Games++
+			s.AvgDelta += float64(res.Delta)
+			if g.IsDraw() {
+				continue
+			}
+			if res.Place == 1 {
+				s.Wins++
+			}
+			s.placed++
+			s.placements += res.Place
+		}
+	}
+
+	stats := make([]PlayerStats, 0, len(players))
+	for _, s := range players {
+		s.WinRate = float64(s.Wins) / float64(s.Games)
+		s.WinRateLow, s.WinRateHigh = wilsonInterval(s.Wins, s.Games)
+		s.AvgDelta /= float64(s.Games)
+		if s.placed > 0 {
+			s.AvgPlacement = float64(s.placements) / float64(s.placed)
+		}
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Games != stats[j].Games {
+			return stats[i].Games > stats[j].Games
+		}
+		return stats[i].Player < stats[j].Player
+	})
+	return stats
+}
+
+// statsHandler returns the handler for the stats API at /api/stats, which
+// returns filtered aggregate stats per player and deck archetype as JSON, or
+// the players' as CSV with format=csv. Games are scored over the whole log before filtering, so rating
+// changes match the rest of the site.
+func statsHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sq, err := parseStatsQuery(r.URL.Query())
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			web.WriteJSON(w, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "json" && format != "csv" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			web.WriteJSON(w, map[string]interface{}{"error": "format must be json or csv"})
+			return
+		}
+
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		if sq.Player != "" {
+			sq.Player = ds.playerID(sq.Player)
+		}
+		calculateScores(ds.Games, ds.Adjustments...)
+
+		stats := aggregateStats(ds.Games, sq)
+		for idx := range stats {
+			stats[idx].Name = ds.Names.Of(stats[idx].Player)
+		}
+
+		if format == "csv" {
+			writeStatsCSV(w, stats)
+			return
+		}
+		web.WriteJSON(w, map[string]interface{}{
+			"version":    version,
+			"stats":      stats,
+			"archetypes": archetypeStats(ds.Games, sq),
+		})
+	}
+}
+
+// writeStatsCSV writes the stats as CSV with a header row.
+func writeStatsCSV(w http.ResponseWriter, stats []PlayerStats) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"player", "name", "games", "wins", "winRate", "winRateLow", "winRateHigh", "avgPlacement", "avgDelta"})
+	for _, s := range stats {
+		cw.Write([]string{
+			s.Player,
+			s.Name,
+			strconv.Itoa(s.Games),
+			strconv.Itoa(s.Wins),
+			strconv.FormatFloat(s.WinRate, 'f', 3, 64),
+			strconv.FormatFloat(s.WinRateLow, 'f', 3, 64),
+			strconv.FormatFloat(s.WinRateHigh, 'f', 3, 64),
+			strconv.FormatFloat(s.AvgPlacement, 'f', 2, 64),
+			strconv.FormatFloat(s.AvgDelta, 'f', 1, 64),
+		})
+	}
+	cw.Flush()
+}
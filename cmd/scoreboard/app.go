@@ -0,0 +1,898 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/web"
+	"github.com/fly-apps/go-example/scoring"
+)
+
+// second version of the algorithm, patch version 2
+var version = "0.2.3"
+
+// Player binds a calculated score to a player
+type Player struct {
+	ID          string         `json:"id"`   // the player's stable ID, which games are scored by.
+	Name        string         `json:"name"` // the name shown for the player.
+	Score       int            `json:"score"`
+	Wins        int            `json:"wins"`        // the number of games the player won outright.
+	Games       int            `json:"games"`       // the number of games the player was ranked in.
+	HeadToHead  map[string]int `json:"headToHead"`  // the number of games the player finished above each opponent.
+	Trend       int            `json:"trend"`       // the player's net rating change over the trend window.
+	Performance int            `json:"performance"` // the player's strength of schedule adjusted performance rating over their recent games.
+
+	// FieldPlacement is the player's expected placement in a pod of
+	// fieldPodSize players rated at the league's average.
+	FieldPlacement float64 `json:"fieldPlacement"`
+
+	// AvgSurvival is the average number of minutes the player survived in
+	// games with recorded elimination times.
+	AvgSurvival float64 `json:"avgSurvivalMinutes,omitempty"`
+	// FirstOutRate is the share of the player's games where they were the
+	// first player eliminated.
+	FirstOutRate float64 `json:"firstOutRate"`
+
+	survivalGames int
+	firstOuts     int
+}
+
+// ByID implements the sort.Interface for sorting games by ID, numerically
+// when the IDs are numbers.
+type ByID []*Game
+
+// ByScore implements the sort.Interface for sorting players by Score.
+type ByScore []Player
+
+var (
+	// NOTE: spreadsheetId for the game tracker, configured with
+	// SCOREBOARD_SHEET_ID.
+	spreadsheetID = "1-qr-ejHx07Hrr35OymMcGRH00-Jzb-k8S8-xS9P5vqk"
+
+	// readRange is the range of the game log tab that holds the game data,
+	// configured with SCOREBOARD_GAMES_RANGE.
+	readRange = "Ranked game log!A:AA"
+)
+
+// apiCORS is the CORS policy of the /api routes. No origins are allowed
+// unless configured with SCOREBOARD_CORS_ORIGINS.
+var apiCORS = web.CORSPolicy{Methods: "GET, OPTIONS", Headers: "Content-Type"}
+
+//go:embed templates/*
+var resources embed.FS
+var t = template.Must(template.New("").Funcs(templateFuncs).ParseFS(resources, "templates/*"))
+
+// templateFuncs are the helper functions available to all templates.
+var templateFuncs = template.FuncMap{
+	"signed":            signed,
+	"trend":             trendArrow,
+	"replacementRating": func() int { return replacementRating },
+	"shortDate":         shortDate,
+	"percent":           func(f float64) float64 { return f * 100 },
+	"winRate":           winRate,
+	"placing":           placing,
+	"money":             func(cents int) string { return finances.format(cents) },
+}
+
+func main() {
+	haveConfig, err := loadConfigFile(configFile())
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+	if err := configureSheetFromEnv(); err != nil {
+		log.Fatalf("%s", err)
+	}
+	if err := configureScoringFromEnv(); err != nil {
+		log.Fatalf("%s", err)
+	}
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+	checkOnly := flag.Bool("check", false, "validate the configuration and exit")
+	flag.Usage = usage
+	flag.Parse()
+
+	certs := autocertFromEnv()
+	port := os.Getenv("SCOREBOARD_PORT")
+	if port == "" {
+		port = "8080"
+		if certs != nil {
+			port = "443"
+		}
+	}
+
+	// a new install without any configuration is walked through writing one
+	if needsSetup(haveConfig) && !*checkOnly {
+		if err := runSetupWizard(web.ListenAddrs(port)[0], configFile()); err != nil {
+			log.Fatalf("setup failed: %s", err)
+		}
+		if _, err := loadConfigFile(configFile()); err != nil {
+			log.Fatalf("%s", err)
+		}
+		if err := configureSheetFromEnv(); err != nil {
+			log.Fatalf("%s", err)
+		}
+		if err := configureScoringFromEnv(); err != nil {
+			log.Fatalf("%s", err)
+		}
+	}
+
+	if v := os.Getenv("SCOREBOARD_VERBOSE"); v != "" {
+		setVerbose(isMarked(v))
+	}
+	watchVerboseSignal()
+
+	if v := os.Getenv("SCOREBOARD_REQUEST_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("invalid SCOREBOARD_REQUEST_TIMEOUT: %q", v)
+		}
+		requestTimeout = d
+	}
+	if v := os.Getenv("SCOREBOARD_MAX_RECOMPUTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			log.Fatalf("invalid SCOREBOARD_MAX_RECOMPUTES: %q", v)
+		}
+		recomputes = make(chan struct{}, n)
+	}
+	if v := os.Getenv("SCOREBOARD_TRUSTED_PROXIES"); v != "" {
+		proxies, err := web.ParseTrustedProxies(v)
+		if err != nil {
+			log.Fatalf("invalid SCOREBOARD_TRUSTED_PROXIES: %s", err)
+		}
+		web.TrustedProxies = proxies
+	}
+
+	if v := os.Getenv("SCOREBOARD_CORS_ORIGINS"); v != "" {
+		apiCORS.Origins = web.ParseCORSOrigins(v)
+	}
+	if v := os.Getenv("SCOREBOARD_CORS_METHODS"); v != "" {
+		apiCORS.Methods = v
+	}
+	if v := os.Getenv("SCOREBOARD_CORS_HEADERS"); v != "" {
+		apiCORS.Headers = v
+	}
+
+	opts, err := sheetsOptionFromEnv()
+	if err != nil {
+		log.Fatalf("invalid Sheets API credentials: %s", err)
+	}
+
+	adminToken, err := secretEnv("SCOREBOARD_ADMIN_TOKEN")
+	if err != nil {
+		log.Fatalf("invalid SCOREBOARD_ADMIN_TOKEN: %s", err)
+	}
+	http.Handle("/admin/verbose", requireAdmin(adminToken, verboseHandler))
+	backgroundJobs = newJobQueue()
+	http.Handle("/admin/jobs", requireAdmin(adminToken, jobsHandler(backgroundJobs)))
+
+	var l *league
+	var n *notifier
+	if isMarked(os.Getenv("SCOREBOARD_HOSTED")) {
+		// hosted mode serves a league per registered tenant
+		hosted, err := newHostedServerFromEnv(opts)
+		if err != nil {
+			log.Fatalf("invalid hosted mode configuration: %s", err)
+		}
+		http.Handle("/", hosted)
+	} else {
+		l = newLeague(spreadsheetID, readRange, opts)
+		l.configureRangesFromEnv()
+		l.profilesPath = os.Getenv("SCOREBOARD_PROFILES")
+		pickem := isMarked(os.Getenv("SCOREBOARD_PICKEM"))
+		comments := isMarked(os.Getenv("SCOREBOARD_COMMENTS"))
+		l.live = isMarked(os.Getenv("SCOREBOARD_LIVE"))
+		scheduling := isMarked(os.Getenv("SCOREBOARD_SCHEDULING"))
+		reports := isMarked(os.Getenv("SCOREBOARD_REPORTS"))
+		if pickem || comments || l.live || scheduling || reports {
+			secret, err := secretEnv("SCOREBOARD_SESSION_SECRET")
+			if err != nil {
+				log.Fatalf("invalid SCOREBOARD_SESSION_SECRET: %s", err)
+			}
+			if len(secret) < 32 {
+				log.Fatalf("SCOREBOARD_SESSION_SECRET of at least 32 characters is required for pick-em, comments, live game entry, scheduling and game reports")
+			}
+			l.sessions = &sessions{key: []byte(secret)}
+		}
+		if l.live && !hasSecretEnv("SCOREBOARD_CREDENTIALS") {
+			log.Fatalf("SCOREBOARD_CREDENTIALS with write access to the sheet is required for live game entry")
+		}
+		dsn, err := secretEnv("SCOREBOARD_DATABASE")
+		if err != nil {
+			log.Fatalf("invalid SCOREBOARD_DATABASE: %s", err)
+		}
+		verify := isMarked(os.Getenv("SCOREBOARD_VERIFY_GAMES"))
+		if verify && !l.live {
+			log.Fatalf("SCOREBOARD_VERIFY_GAMES confirms games recorded live, so it requires SCOREBOARD_LIVE")
+		}
+		if dsn == "" && (pickem || comments || verify || scheduling || reports) {
+			log.Fatalf("SCOREBOARD_DATABASE is required for pick-em, comments, confirming games, scheduling and game reports")
+		}
+		if dsn != "" {
+			st, err := openStore(dsn)
+			if err != nil {
+				log.Fatalf("failed to open database: %s", err)
+			}
+			l.snapshots = st
+			l.archive = st
+			http.Handle("/admin/sql", requireAdmin(adminToken, consoleHandler(st)))
+			if verify {
+				l.pending = st
+			}
+			if pickem {
+				l.picks = st
+			}
+			if scheduling {
+				l.polls = st
+			}
+			if reports {
+				l.photos, err = photoStoreFromEnv()
+				if err != nil {
+					log.Fatalf("invalid game report photo storage: %s", err)
+				}
+				l.reports = st
+			}
+			if comments {
+				l.comments = st
+				http.Handle("/admin/comments", requireAdmin(adminToken, commentsAdminHandler(st)))
+			}
+		}
+		http.Handle("/admin/rename", requireAdmin(adminToken, renameHandler(l, l.snapshots)))
+		http.Handle("/admin/forget", requireAdmin(adminToken, forgetHandler(l, l.snapshots)))
+		http.Handle("/admin/schema", requireAdmin(adminToken, schemaHandler(l)))
+		http.Handle("/admin/fetch", requireAdmin(adminToken, fetchProgressHandler(l)))
+		http.Handle("/admin/quota", requireAdmin(adminToken, quotaStatusHandler(l)))
+		http.Handle("/admin/maintenance", requireAdmin(adminToken, maintenanceHandler(l)))
+		http.Handle("/", l.routes())
+
+		n, err = newNotifierFromEnv(l)
+		if err != nil {
+			log.Fatalf("invalid email configuration: %s", err)
+		}
+	}
+
+	// claim the ports before checking anything else so a port conflict is
+	// reported up front
+	var listeners []net.Listener
+	for _, addr := range web.ListenAddrs(port) {
+		ln, err := web.Listen(addr)
+		if err != nil {
+			log.Fatalf("%s is not available, stop whatever is using it or set SCOREBOARD_PORT and SCOREBOARD_BIND, or SCOREBOARD_LISTEN: %s", addr, err)
+		}
+		listeners = append(listeners, ln)
+	}
+	if problems := checkStartup(context.Background(), l); len(problems) > 0 {
+		for _, p := range problems {
+			log.Printf("startup check failed: %s", p)
+		}
+		log.Fatalf("%d startup checks failed", len(problems))
+	}
+	if *checkOnly {
+		for _, ln := range listeners {
+			ln.Close()
+		}
+		fmt.Println("configuration ok")
+		return
+	}
+
+	go backgroundJobs.run(context.Background(), 2)
+	if l != nil && l.archive != nil {
+		go runArchiver(context.Background(), l)
+	}
+	if n != nil {
+		go n.run(context.Background())
+	}
+	srv := &http.Server{Handler: web.Compress(withDeadline(http.DefaultServeMux, requestTimeout))}
+	if certs != nil {
+		// serving TLS also negotiates HTTP/2 with clients that support it
+		srv.TLSConfig = certs.TLSConfig()
+		go serveACMEChallenges(certs)
+	}
+	errs := make(chan error, len(listeners))
+	for _, ln := range listeners {
+		go func(ln net.Listener) {
+			if certs != nil {
+				log.Println("listening with TLS on", ln.Addr())
+				errs <- srv.ServeTLS(ln, "", "")
+				return
+			}
+			log.Println("listening on", ln.Addr())
+			errs <- srv.Serve(ln)
+		}(ln)
+	}
+	log.Fatal(<-errs)
+}
+
+// indexHandler returns the leaderboard handler for the league.
+func indexHandler(l *league) http.HandlerFunc {
+	cache := l.pages
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// the leaderboard is the catch-all route, so anything else is a 404
+		if r.URL.Path != "/" && r.URL.Path != "" {
+			notFoundRes(w, r)
+			return
+		}
+		cacheKey := basePath(r) + "?" + r.URL.RawQuery
+		prefs := requestPreferences(r)
+		if saved := prefs.encode(); saved != "" {
+			// the board opens the way each visitor prefers
+			cacheKey += "#" + saved
+		}
+		w.Header().Add("Vary", "Cookie")
+		view := r.URL.Query().Get("view")
+		if view == "hot" || view == "recency" {
+			// the hot and recency boards move with the date, not just the data
+			cacheKey += "@" + l.clock.Now().Format("2006-01-02")
+		}
+		if maintenanceBanner(r) != "" {
+			// the banner isn't part of the data, so it's keyed separately
+			cacheKey += "#maintenance"
+		}
+
+		// a busy server sheds the request before it spends a Sheets fetch
+		done, ok := tryRecompute()
+		if !ok {
+			shed(w, r, cache.stale(cacheKey))
+			return
+		}
+		defer done()
+
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		games := ds.Games
+
+		w.Header().Add("X-PoweredBy", "stamina_crü") // 💪
+
+		// a league without any games yet gets an explanation of how to set
+		// up the sheet instead of an empty leaderboard
+		if len(games) == 0 {
+			onboardingRes(w, r)
+			return
+		}
+
+		// serve the cached page if the data hasn't changed since it was rendered
+		dataVersion := datasetVersion(ds)
+		w.Header().Set("X-Dataset-Version", dataVersion)
+		if page := cache.get(dataVersion, cacheKey); page != nil {
+			page.serve(w, r)
+			return
+		}
+
+		// a tag's leaderboard rates only the games with the tag, from scratch
+		tags := leagueTags(games)
+		tag := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("tag")))
+		adjustments := ds.Adjustments
+		if tag != "" {
+			games = gamesWithTag(games, tag)
+			adjustments = nil
+		}
+
+		// so does a season's or a pod size's, which open on the visitor's
+		// preferred ones unless the link picks its own
+		q := r.URL.Query()
+		seasonName := prefs.Season
+		if _, ok := q["season"]; ok {
+			seasonName = strings.TrimSpace(q.Get("season"))
+		}
+		var season Season
+		if seasonName != "" {
+			season, err = parseEra(seasonName, leagueSeasons(ds.Games, ds.Seasons))
+			switch {
+			case err == nil:
+				games = gamesInSeason(games, season)
+				adjustments = adjustmentsInSeason(adjustments, season)
+			case q.Get("season") != "":
+				badRequestRes(w, r, err.Error())
+				return
+			}
+			// a preferred season that's since been renamed opens on all time
+		}
+		podSize := prefs.PodSize
+		if _, ok := q["podSize"]; ok {
+			podSize = 0
+			if v := q.Get("podSize"); v != "" {
+				if podSize, err = strconv.Atoi(v); err != nil || podSize < 2 || podSize > maxPlayers {
+					badRequestRes(w, r, fmt.Sprintf("invalid podSize %q", v))
+					return
+				}
+			}
+		}
+		if podSize != 0 {
+			games = gamesWithPodSize(games, podSize)
+			adjustments = nil
+		}
+
+		// calculate and render scores
+		scores := calculateScores(games, adjustments...)
+
+		// collect and sort players into rankings
+		rankings := rankPlayers(games, scores, l.clock.Now())
+		ds.Names.apply(rankings)
+
+		// the performance view orders players by recent strength of schedule
+		// adjusted results instead of rating, the hot board rates only recent
+		// games, and the recency board counts older games less
+		switch view {
+		case "performance":
+			sort.Stable(ByPerformance(rankings))
+		case "hot":
+			rankings = hotRankings(games, l.clock.Now())
+			ds.Names.apply(rankings)
+		case "recency":
+			rankings = recencyRankings(games, adjustments, l.clock.Now())
+			ds.Names.apply(rankings)
+		default:
+			view = "rating"
+		}
+
+		// create and format a response object
+		data := IndexView{
+			PageView: newPageView(r, "Scoreboard",
+				fmt.Sprintf("The league leaderboard: %d players over %d games.", len(rankings), len(games))),
+			View:             view,
+			PerformanceGames: performanceGames,
+			HotDays:          hotDays,
+			FieldPodSize:     fieldPodSize,
+			HalfLifeDays:     int(recencyHalfLife.Hours() / 24),
+			TrendWindow:      trendWindow(),
+			Games:            games,
+			Scores:           scores,
+			Rankings:         rankings,
+			Total:            len(games),
+			Tag:              tag,
+			Tags:             tags,
+			Season:           season.Name,
+			PodSize:          podSize,
+			Favorite:         prefs.Favorite,
+			Pickem:           l.picks != nil,
+			Live:             l.live,
+			Archive:          l.archive != nil,
+			Rules:            l.ranges.Rules != "",
+			Refresh:          l.sessions != nil,
+		}
+		if finances.enabled() && data.View == "rating" && tag == "" {
+			data.PrizePool = finances.prizePool(games, ds.Seasons, ds.Adjustments, ds.Names, l.clock.Now())
+		}
+		if trialEngine != "" && data.View == "rating" {
+			data.Trial = trialEngine
+			data.TrialScores = trialScores(games, adjustments)
+			data.TrialRanks = standingRanks(data.TrialScores)
+		}
+		if data.View == "rating" && tag == "" && season.Name == "" && podSize == 0 {
+			if recap, ok := nightRecap(games, ds.Names); ok {
+				data.Recap = &recap
+			}
+		}
+		if len(ds.Houses) > 0 && data.View == "rating" {
+			data.Houses = houseStandings(ds.Houses, rankings, houseScoring)
+			data.HouseScoring = houseScoring
+		}
+		if isVerbose() {
+			log.Printf("%+v", data)
+		}
+
+		page, err := cache.render(dataVersion, cacheKey, "index.html.tmpl", data)
+		if err != nil {
+			log.Printf("error rendering leaderboard: %+v", err)
+			errorRes(w, r, err)
+			return
+		}
+		page.serve(w, r)
+	}
+}
+
+// rankingsHandler returns the JSON rankings API handler. Rankings are ordered
+// by score, with ties broken by the configured tie-breakers. With asOf set to
+// a date, the rankings are as they stood at the end of that day.
+func rankingsHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var asOf time.Time
+		if v := r.URL.Query().Get("asOf"); v != "" {
+			var err error
+			if asOf, err = time.Parse("2006-01-02", v); err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				web.WriteJSON(w, map[string]interface{}{"error": fmt.Sprintf("invalid asOf date %q, it must look like 2024-01-01", v)})
+				return
+			}
+		}
+
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+
+		var rankings []Player
+		var total int
+		if asOf.IsZero() {
+			games := ds.Games
+			scores := calculateScores(games, ds.Adjustments...)
+			rankings = rankPlayers(games, scores, l.clock.Now())
+			ds.Names.apply(rankings)
+			total = len(games)
+		} else {
+			rankings, total = rankingsAsOf(r.Context(), l, ds, asOf)
+		}
+
+		names := make([]string, 0, len(tieBreakers))
+		for _, tb := range tieBreakers {
+			names = append(names, tb.Name)
+		}
+
+		res := map[string]interface{}{
+			"version":     version,
+			"tiebreakers": names,
+			"trendWindow": trendWindow(),
+			"rankings":    rankings,
+			"total":       total,
+		}
+		if !asOf.IsZero() {
+			res["asOf"] = asOf.Format("2006-01-02")
+		}
+		web.WriteJSON(w, res)
+	}
+}
+
+// loadDataset fetches the league's data, sorts the games by ID, and applies
+// the request's date filters. If the filters are invalid or fetching fails
+// the error response has already been written when loadDataset returns.
+func loadDataset(w http.ResponseWriter, r *http.Request, l *league) (*Dataset, error) {
+	// check the filters before spending a fetch on the request
+	start, err := dateParam(r, "start")
+	if err != nil {
+		badRequestRes(w, r, err.Error())
+		return nil, err
+	}
+	end, err := dateParam(r, "end")
+	if err != nil {
+		badRequestRes(w, r, err.Error())
+		return nil, err
+	}
+
+	// fetch games
+	ds, err := l.fetch(r.Context())
+	if err != nil {
+		log.Printf("error fetching game data: %+v", err)
+		errorRes(w, r, err)
+		return nil, err
+	}
+
+	l.migrateStoredNames(ds)
+
+	// sort by ID to ensure order
+	sort.Sort(ByID(ds.Games))
+
+	ds.Games = filterByDate(ds.Games, start, end)
+
+	return ds, nil
+}
+
+// dateParam parses the RFC 1123 date in the named query parameter, which is
+// the zero time when the parameter isn't set.
+func dateParam(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	d, err := time.Parse(time.RFC1123, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s date %q, it must look like %s", name, v, time.RFC1123)
+	}
+	return d, nil
+}
+
+// filterByDate returns the games played between start and end, either of
+// which may be zero to leave that side open.
+func filterByDate(games []*Game, start, end time.Time) []*Game {
+	if start.IsZero() && end.IsZero() {
+		return games
+	}
+	var filtered []*Game
+	for _, game := range games {
+		if !start.IsZero() && game.Timestamp.Before(start) {
+			continue
+		}
+		if !end.IsZero() && game.Timestamp.After(end) {
+			continue
+		}
+		filtered = append(filtered, game)
+	}
+	return filtered
+}
+
+// onboardingRes renders the page explaining how to fill in the game log.
+func onboardingRes(w http.ResponseWriter, r *http.Request) {
+	t.ExecuteTemplate(w, "onboarding.html.tmpl", OnboardingView{
+		PageView:    barePageView(r),
+		ReadRange:   readRange,
+		DateFormat:  dateLayouts[0],
+		DateExample: time.Date(2023, 1, 2, 19, 0, 0, 0, time.UTC).Format(dateLayouts[0]),
+		TemplateURL: os.Getenv("SCOREBOARD_TEMPLATE_SHEET_URL"),
+	})
+}
+
+// parseGame is responsible for parsing the raw game data that we get from
+// Google Sheets.
+func parseGameData(values [][]interface{}) ([]*Game, error) {
+	games, _, err := parseGameLog(values)
+	return games, err
+}
+
+// parseGameLog parses the game log into the games that are scored and the
+// two-headed giant games, which aren't scored yet but are kept with their
+// teams so they still show up in the log.
+func parseGameLog(values [][]interface{}) ([]*Game, []*Game, error) {
+	games, unscored := parseGameLogRows(values, 0)
+	return games, unscored, nil
+}
+
+// parseGameLogRows parses a run of the game log's rows, the first of which is
+// at index first in the game log, so a long game log can be parsed a chunk
+// at a time.
+func parseGameLogRows(values [][]interface{}, first int) ([]*Game, []*Game) {
+	var games, unscored []*Game
+	for i, row := range values {
+		idx := first + i
+		if len(row) < 4 {
+			log.Printf("encountered malformed row %+v at %+v", row, idx)
+			continue
+		}
+		if idx == 0 {
+			// skip the first row, it contains the game sheet labels
+			continue
+		}
+
+		// This function assumes a CSV sheet with the following schema.
+		// * Each row is a game in the data.
+		// * Columns C through H map to in-order player rankings for a given game.
+		// * This schema supports up to 6 players, because we only have calculated
+		// reward curves for up to 6 players, and there is a drastic drop off in
+		// quantity of games after 4 players, which is the overwhelming average
+		// pod size. The column schema then looks like below.
+		// * column schema: |    A	 | 	 B 	|  C  |   D  |   E   |     F	   | ... |      L	      |
+		// 					| gameID | date | zap | draw | notes | player 1 | ... | eliminated 1 |
+		// * Columns L through Q optionally hold the time each player in
+		// columns F through K was eliminated, columns R through W the
+		// archetypes of the decks they played, and column X the game's tags.
+
+		gameID := fmt.Sprintf("%s", row[0])
+		date := fmt.Sprintf("%s", row[1])
+		zap := fmt.Sprintf("%s", row[2])
+		draw := fmt.Sprintf("%s", row[3])
+
+		var notes string
+		if len(row) > 4 {
+			notes = strings.TrimSpace(fmt.Sprintf("%s", row[4]))
+		}
+
+		ts, err := parseGameDate(date)
+		if err != nil {
+			log.Printf("failed to parse date for game %s: %+v", gameID, err)
+		}
+
+		g := &Game{
+			ID:        gameID,
+			Date:      date,
+			Timestamp: ts,
+			Rankings:  []string{},
+			TableZap:  zap,
+			DrawGame:  draw,
+			Notes:     notes,
+			Row:       idx + 1,
+		}
+
+		if len(row) > tagsColumn {
+			g.Tags = parseTags(fmt.Sprintf("%s", row[tagsColumn]))
+		}
+		if len(row) > firstBloodColumn {
+			parseHonors(g, row)
+		}
+
+		var players []interface{}
+		if len(row) > playerColumn {
+			players = row[playerColumn:minInt(len(row), eliminationColumn)]
+		}
+
+		var cells []string
+		var eliminated []time.Time
+		placements := map[string]int{}
+		for idx, player := range players {
+			name := fmt.Sprintf("%s", player)
+			name = strings.Trim(name, " ")
+			if name == "" {
+				// pods smaller than 6 leave player columns blank before
+				// the elimination times
+				continue
+			}
+			name, placement := parsePlacementAnnotation(name)
+			cells = append(cells, name)
+			if strings.Contains(name, "/") {
+				g.TwoHeadedGiant = true
+				continue
+			}
+			g.Rankings = append(g.Rankings, name)
+			if placement > 0 {
+				placements[name] = placement
+			}
+
+			var at time.Time
+			if col := eliminationColumn + idx; col < len(row) {
+				at, err = parseEliminationTime(fmt.Sprintf("%s", row[col]), ts)
+				if err != nil {
+					log.Printf("failed to parse elimination time for %s in game %s: %+v", name, gameID, err)
+				}
+			}
+			eliminated = append(eliminated, at)
+
+			if col := deckColumn + idx; col < len(row) {
+				if tags := parseArchetypes(fmt.Sprintf("%s", row[col])); len(tags) > 0 {
+					if g.Decks == nil {
+						g.Decks = map[string][]string{}
+					}
+					g.Decks[name] = tags
+				}
+			}
+		}
+
+		if g.TwoHeadedGiant {
+			if solo, team, ok := parseArchenemy(cells); ok {
+				g.TwoHeadedGiant = false
+				g.Archenemy = solo
+				if cells[0] == solo {
+					g.Rankings = append([]string{solo}, team...)
+				} else {
+					g.Rankings = append(team, solo)
+				}
+				games = append(games, g)
+				continue
+			}
+			// TODO: Handle two headed giant scoring in the future.
+			g.Teams = parseTeams(cells)
+			g.Rankings = []string{}
+			for _, team := range g.Teams {
+				g.Rankings = append(g.Rankings, team...)
+			}
+			unscored = append(unscored, g)
+			continue
+		}
+		applyEliminations(g, eliminated)
+		if err := applyPlacements(g, placements); err != nil {
+			log.Printf("ignoring the placements in game %s: %+v", gameID, err)
+		}
+		games = append(games, g)
+	}
+
+	return games, unscored
+}
+
+// calculateScores takes a slice of games and calculates their scores with the
+// configured rating engine. Adjustments, which must be in date order, are
+// applied in between the games they were made between. Seeds among them set
+// the rating players start at instead.
+func calculateScores(games []*Game, adjustments ...*Adjustment) map[string]int {
+	engine, err := newRatingEngine(ratingEngine)
+	if err != nil {
+		// the engine name is validated at startup
+		panic(err)
+	}
+
+	scores, errs := scoring.Score(engine, games, adjustments...)
+	for _, err := range errs {
+		log.Printf("%+v", err)
+	}
+
+	if isVerbose() {
+		for _, game := range games {
+			log.Printf("scored game: %+v\n", game)
+		}
+		log.Printf("calculated scores: %+v", scores)
+	}
+	return scores
+}
+
+// rankPlayers collects the scored players into rankings along with the win,
+// game and head-to-head records used to break ties, sorted by score, and
+// their trends as of now.
+func rankPlayers(games []*Game, scores map[string]int, now time.Time) []Player {
+	players := map[string]*Player{}
+	for name, score := range scores {
+		players[name] = &Player{
+			ID:         name,
+			Name:       name,
+			Score:      score,
+			HeadToHead: map[string]int{},
+		}
+	}
+
+	for _, game := range games {
+		firstOut := game.FirstOut()
+		for idx, name := range game.Rankings {
+			p, ok := players[name]
+			if !ok {
+				continue
+			}
+			p.Games++
+			if minutes, ok := game.Survival(name); ok {
+				p.AvgSurvival += minutes
+				p.survivalGames++
+			}
+			if name == firstOut {
+				p.firstOuts++
+			}
+			if game.IsDraw() {
+				continue
+			}
+			if game.Place(idx) == 1 {
+				p.Wins++
+			}
+			for _, opponent := range game.Beat(idx) {
+				p.HeadToHead[opponent]++
+			}
+		}
+	}
+
+	trends := playerTrends(games, now)
+	performances := performanceRatings(games)
+	field := fieldRatings(scores)
+
+	rankings := make([]Player, 0, len(players))
+	for _, p := range players {
+		p.Trend = trends[p.ID]
+		p.Performance = performances[p.ID]
+		p.FieldPlacement = expectedPlacement(p.Score, field)
+		if p.survivalGames > 0 {
+			p.AvgSurvival /= float64(p.survivalGames)
+		}
+		if p.Games > 0 {
+			p.FirstOutRate = float64(p.firstOuts) / float64(p.Games)
+		}
+		rankings = append(rankings, *p)
+	}
+
+	// sort by score to determine rankings
+	sort.Sort(ByScore(rankings))
+
+	return rankings
+}
+
+// isMarked reports whether a checkbox-style sheet cell is set.
+func isMarked(cell string) bool {
+	switch strings.ToLower(strings.TrimSpace(cell)) {
+	case "", "false", "no", "n", "0":
+		return false
+	}
+	return true
+}
+
+func (g ByID) Len() int           { return len(g) }
+func (g ByID) Less(i, j int) bool { return lessGameID(g[i].ID, g[j].ID) }
+func (g ByID) Swap(i, j int)      { g[i], g[j] = g[j], g[i] }
+
+func (g ByScore) Len() int      { return len(g) }
+func (g ByScore) Swap(i, j int) { g[i], g[j] = g[j], g[i] }
+func (g ByScore) Less(i, j int) bool {
+	if g[i].Score != g[j].Score {
+		return g[i].Score > g[j].Score
+	}
+	for _, tb := range tieBreakers {
+		if c := tb.Compare(g[i], g[j]); c != 0 {
+			return c > 0
+		}
+	}
+	return lessGameID(g[i].ID, g[j].ID)
+}
@@ -0,0 +1,4271 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"image/png"
+	"io/ioutil"
+	"log"
+	"math"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/sheets"
+	"github.com/fly-apps/go-example/internal/web"
+	"google.golang.org/api/googleapi"
+)
+
+// gameLog is a small game log in the sheet's row format, header included.
+var gameLog = [][]interface{}{
+	{"Game", "Date", "Zap", "Draw", "Notes", "1st", "2nd", "3rd", "4th"},
+	{"1", "Mon, 02 Jan 2023 19:00:00 UTC", "", "", "", "alice", "bob"},
+	{"2", "Mon, 09 Jan 2023 19:00:00 UTC", "", "", "", "alice", "carol", "bob"},
+	{"3", "Mon, 16 Jan 2023 19:00:00 UTC", "", "", "", "dave/erin", "frank/gus"},
+	{"4"},
+}
+
+// get runs a request against the leaderboard handler wired to the fake.
+func get(t *testing.T, f *fakeSheets, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	indexHandler(f.league())(rec, req)
+	return rec
+}
+
+func TestPipelineRendersRankings(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+
+	rec := get(t, f, "/")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if f.requestCount() != 1 {
+		t.Fatalf("expected 1 sheets request, got %d", f.requestCount())
+	}
+
+	body := rec.Body.String()
+	alice := strings.Index(body, ">alice</a>")
+	carol := strings.Index(body, ">carol</a>")
+	bob := strings.Index(body, ">bob</a>")
+	if alice < 0 || carol < 0 || bob < 0 {
+		t.Fatalf("expected alice, carol and bob in rankings, got:\n%s", body)
+	}
+	if !(alice < carol && carol < bob) {
+		t.Fatalf("expected rankings alice > carol > bob, got:\n%s", body)
+	}
+	if strings.Contains(body, "dave") {
+		t.Fatalf("expected two-headed giant game to be skipped, got:\n%s", body)
+	}
+}
+
+func TestIndexServesCachedPage(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	handler := indexHandler(f.league())
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip encoded response")
+	}
+	etag := rec.Header().Get("ETag")
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected status 304 for unchanged data, got %d", rec.Code)
+	}
+
+	f.serveRows(append(gameLog, []interface{}{"5", "Mon, 23 Jan 2023 19:00:00 UTC", "", "", "", "bob", "alice"}))
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("expected a fresh page after the data changed, got status %d", rec.Code)
+	}
+}
+
+func TestGamePage(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+
+	rec := httptest.NewRecorder()
+	gameHandler(f.league())(rec, httptest.NewRequest(http.MethodGet, "/game/2", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"Game 2", `href="/player/carol"`, "<td>1531</td>"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected game page to contain %q, got:\n%s", want, body)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	gameHandler(f.league())(rec, httptest.NewRequest(http.MethodGet, "/game/2/explain", nil))
+	body = rec.Body.String()
+	for _, want := range []string{"rank average, the average of everyone's rating before the game: 1500", "K = 32", "<td>0.5</td>"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected explain page to contain %q, got:\n%s", want, body)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	gameHandler(f.league())(rec, httptest.NewRequest(http.MethodGet, "/game/99", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for unknown game, got %d", rec.Code)
+	}
+}
+
+func TestPipelineUpstreamFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(f *fakeSheets)
+		status int
+	}{
+		{
+			name: "quota exceeded",
+			setup: func(f *fakeSheets) {
+				f.serveError(http.StatusTooManyRequests, "RESOURCE_EXHAUSTED", "Quota exceeded for quota metric 'Read requests'")
+			},
+			status: http.StatusTooManyRequests,
+		},
+		{
+			name: "server error",
+			setup: func(f *fakeSheets) {
+				f.serveError(http.StatusServiceUnavailable, "UNAVAILABLE", "The service is currently unavailable.")
+			},
+		},
+		{
+			name:  "malformed payload",
+			setup: func(f *fakeSheets) { f.serveRaw(`{"values": [[`) },
+		},
+		{
+			name:  "wrong payload shape",
+			setup: func(f *fakeSheets) { f.serveRaw(`{"values": "not rows"}`) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeSheets(t, gameLog)
+			tt.setup(f)
+
+			rec := get(t, f, "/")
+			status := tt.status
+			if status == 0 {
+				status = http.StatusInternalServerError
+			}
+			if rec.Code != status {
+				t.Fatalf("expected status %d, got %d", status, rec.Code)
+			}
+			// the error page doesn't show the upstream error
+			body := rec.Body.String()
+			for _, leaked := range []string{"Quota exceeded", "currently unavailable", "googleapi", "json"} {
+				if strings.Contains(body, leaked) {
+					t.Fatalf("expected a generic error page, got:\n%s", body)
+				}
+			}
+			if !strings.Contains(body, "Try again") {
+				t.Fatalf("expected the error page to say to try again, got:\n%s", body)
+			}
+		})
+	}
+}
+
+func TestStartupChecksExplainSheetErrors(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	f.serveError(http.StatusForbidden, "PERMISSION_DENIED", "The caller does not have permission")
+
+	_, err := f.league().fetch(context.Background())
+	if got := sheetProblem(f.league(), err); !strings.Contains(got, "share it so anyone with the link can view it") {
+		t.Fatalf("expected advice to share the sheet, got %q", got)
+	}
+}
+
+func TestEmptySheetOnboarding(t *testing.T) {
+	for _, rows := range [][][]interface{}{nil, gameLog[:1]} {
+		f := newFakeSheets(t, rows)
+
+		rec := get(t, f, "/")
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "No games have been recorded yet") {
+			t.Fatalf("expected onboarding page, got %d:\n%s", rec.Code, rec.Body.String())
+		}
+
+		rec = httptest.NewRecorder()
+		healthHandler(f.league())(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"empty"`) {
+			t.Fatalf("expected empty health status, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestLeagueFetchQuotaError(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	f.serveError(http.StatusTooManyRequests, "RESOURCE_EXHAUSTED", "Quota exceeded")
+
+	_, err := f.league().fetch(context.Background())
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		t.Fatalf("expected a wrapped googleapi.Error, got %v", err)
+	}
+	if gerr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected code 429, got %d", gerr.Code)
+	}
+}
+
+func TestQuotaBackoff(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	l := f.league()
+	if _, err := l.fetch(context.Background()); err != nil {
+		t.Fatalf("failed to fetch: %v", err)
+	}
+
+	f.serveError(http.StatusTooManyRequests, "RESOURCE_EXHAUSTED", "Quota exceeded")
+	ds, err := l.fetch(context.Background())
+	if err != nil || len(ds.Games) != 2 {
+		t.Fatalf("expected the last dataset to be served on a quota error, got %v", err)
+	}
+	requests := f.requestCount()
+	if _, err := l.fetch(context.Background()); err != nil || f.requestCount() != requests {
+		t.Fatalf("expected no fetches while backing off, got %d more: %v", f.requestCount()-requests, err)
+	}
+	if s := l.backoff.status(time.Now()); !s.BackingOff || s.Interval != "1m0s" || s.Rejections != 1 {
+		t.Fatalf("expected to back off for a minute, got %+v", s)
+	}
+
+	// a second rejection once the interval is up backs off further
+	l.backoff.next = time.Now()
+	l.fetch(context.Background())
+	if s := l.backoff.status(time.Now()); s.Interval != "2m0s" || s.Rejections != 2 {
+		t.Fatalf("expected to back off for two minutes, got %+v", s)
+	}
+
+	// fetches that get through ease off until every request fetches again
+	f.serveRows(gameLog)
+	for _, want := range []string{"1m0s", "0s"} {
+		l.backoff.next = time.Now()
+		if _, err := l.fetch(context.Background()); err != nil {
+			t.Fatalf("failed to fetch: %v", err)
+		}
+		if s := l.backoff.status(time.Now()); s.Interval != want {
+			t.Fatalf("expected the interval to ease off to %s, got %+v", want, s)
+		}
+	}
+	requests = f.requestCount()
+	l.fetch(context.Background())
+	if f.requestCount() != requests+1 {
+		t.Fatalf("expected every request to fetch once the back off is over")
+	}
+}
+
+func TestLeagueFetchSkipsMalformedRows(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+
+	ds, err := f.league().fetch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ds.Games) != 2 {
+		t.Fatalf("expected 2 games, got %d", len(ds.Games))
+	}
+	if ds.Games[1].Timestamp.IsZero() {
+		t.Fatalf("expected game timestamp to be parsed")
+	}
+}
+
+func TestLeagueFetchBatchesAuxiliaryTabs(t *testing.T) {
+	f := newFakeSheets(t, nil)
+	f.serveRows(gameLog, map[string][][]interface{}{
+		"Players!A:A": {{"Name"}, {"Alice"}, {"Bob"}, {"Carol"}},
+		"Aliases!A:B": {{"Alias", "Name"}, {"ALICE", "Alice"}, {"bob", "Bob"}, {"carol", "Carol"}},
+		"Seasons!A:B": {{"Season", "Start"}, {"Winter", "2023-01-01"}, {"Spring", "2023-01-08"}},
+	})
+	l := f.league()
+	l.ranges.Players = "Players!A:A"
+	l.ranges.Aliases = "Aliases!A:B"
+	l.ranges.Seasons = "Seasons!A:B"
+
+	ds, err := l.fetch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.requestCount() != 1 {
+		t.Fatalf("expected a single batch request, got %d", f.requestCount())
+	}
+	if len(ds.Players) != 3 {
+		t.Fatalf("expected 3 players, got %v", ds.Players)
+	}
+	if got := strings.Join(ds.Games[1].Rankings, ","); got != "alice,carol,bob" || ds.Names.Of("alice") != "Alice" {
+		t.Fatalf("expected aliases to be applied, got %s", got)
+	}
+	if len(ds.Seasons) != 2 || ds.Seasons[0].End != ds.Seasons[1].Start {
+		t.Fatalf("expected 2 consecutive seasons, got %+v", ds.Seasons)
+	}
+}
+
+func TestPlayerIDsSurviveRenames(t *testing.T) {
+	f := newFakeSheets(t, nil)
+	f.serveRows(gameLog, map[string][][]interface{}{
+		"Players!A:C": {{"ID", "Name", "Other names"}, {"p1", "Alicia", "alice"}},
+	})
+	l := f.league()
+	l.ranges.Players = "Players!A:C"
+
+	ds, err := l.fetch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(ds.Games[1].Rankings, ","); got != "p1,carol,bob" {
+		t.Fatalf("expected games to be keyed by player ID, got %s", got)
+	}
+
+	rec := httptest.NewRecorder()
+	l.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/player/alice", nil))
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/player/p1" {
+		t.Fatalf("expected a redirect to the player's ID, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = httptest.NewRecorder()
+	l.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/player/p1", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<h1>Alicia</h1>") {
+		t.Fatalf("expected the player page to show the player's name, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPickemStandings(t *testing.T) {
+	games, err := parseGameData(gameLog)
+	if err != nil {
+		t.Fatalf("failed to parse game data: %v", err)
+	}
+	if got := nextGameID(games); got != "3" {
+		t.Fatalf("expected the next game to be 3, got %s", got)
+	}
+
+	before := games[1].Timestamp.Add(-time.Hour)
+	standings := pickemStandings(games, []*Prediction{
+		{Member: "zoe", GameID: "1", Pick: "alice", CreatedAt: games[0].Timestamp},
+		{Member: "zoe", GameID: "2", Pick: "carol", CreatedAt: before},
+		{Member: "yan", GameID: "2", Pick: "alice", CreatedAt: before},
+		{Member: "xia", GameID: "2", Pick: "alice", CreatedAt: games[1].Timestamp.Add(time.Hour)},
+		{Member: "xia", GameID: "3", Pick: "bob", CreatedAt: before},
+	})
+	if len(standings) != 2 {
+		t.Fatalf("expected late and unplayed picks not to count, got %+v", standings)
+	}
+	if standings[0].Member != "yan" || standings[0].Accuracy() != 1 || standings[1].Picks != 2 {
+		t.Fatalf("expected yan to lead on accuracy, got %+v", standings)
+	}
+}
+
+func TestHotRankingsUseRecentGamesOnly(t *testing.T) {
+	games, err := parseGameData(gameLog)
+	if err != nil {
+		t.Fatalf("failed to parse game data: %v", err)
+	}
+	calculateScores(games)
+
+	rankings := hotRankings(games, games[0].Timestamp.AddDate(0, 0, hotDays).Add(time.Hour))
+	if len(rankings) != 3 {
+		t.Fatalf("expected the players from game 2, got %+v", rankings)
+	}
+	for _, p := range rankings {
+		if p.Games != 1 {
+			t.Fatalf("expected only game 2 to count, got %+v", p)
+		}
+	}
+	if rankings[0].ID != "alice" || rankings[0].Score <= replacementRating {
+		t.Fatalf("expected alice to lead from a fresh baseline, got %+v", rankings[0])
+	}
+	if games[1].Results[0].Before == replacementRating {
+		t.Fatalf("expected the hot board not to rescore the original games")
+	}
+}
+
+func TestByScoreTieBreakers(t *testing.T) {
+	rankings := []Player{
+		{Name: "carol", Score: 1500},
+		{Name: "bob", Score: 1500, Wins: 1, HeadToHead: map[string]int{"alice": 1}},
+		{Name: "alice", Score: 1500, Wins: 1, HeadToHead: map[string]int{"bob": 2}},
+		{Name: "dave", Score: 1510},
+	}
+	sort.Sort(ByScore(rankings))
+
+	var got []string
+	for _, p := range rankings {
+		got = append(got, p.Name)
+	}
+	if want := "dave,alice,bob,carol"; strings.Join(got, ",") != want {
+		t.Fatalf("expected %s, got %s", want, strings.Join(got, ","))
+	}
+}
+
+func TestHostedTenantRouting(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+
+	h := &hostedServer{
+		store:        st,
+		domain:       "scoreboard.test",
+		opts:         f.options(),
+		defaultQuota: 2,
+		leagues:      map[string]*hostedLeague{},
+	}
+
+	form := url.Values{"name": {"Stamina Crew"}, "slug": {"stamina"}, "spreadsheetID": {"abcdefghijklmnop"}}
+	req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected registration to succeed, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/t/stamina/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `href="/t/stamina/player/alice"`) {
+		t.Fatalf("expected tenant leaderboard under path prefix, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "stamina.scoreboard.test"
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `href="/player/alice"`) {
+		t.Fatalf("expected tenant leaderboard on subdomain, got %d", rec.Code)
+	}
+
+	// the quota of two fetches per hour has been used up
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/t/stamina/", nil))
+	if rec.Code == http.StatusOK {
+		t.Fatalf("expected tenant quota to be enforced")
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/t/unknown/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown tenant, got %d", rec.Code)
+	}
+}
+
+func TestCrossLeagueComparison(t *testing.T) {
+	first := &Dataset{Games: []*Game{
+		{ID: "1", Timestamp: time.Date(2023, 1, 2, 19, 0, 0, 0, time.UTC), Rankings: []string{"alice", "bob"}},
+		{ID: "2", Timestamp: time.Date(2023, 1, 9, 19, 0, 0, 0, time.UTC), Rankings: []string{"alice", "bob"}},
+		{ID: "3", Timestamp: time.Date(2023, 1, 16, 19, 0, 0, 0, time.UTC), Rankings: []string{"dave", "alice"}},
+	}}
+	second := &Dataset{Games: []*Game{
+		{ID: "1", Timestamp: time.Date(2023, 1, 3, 19, 0, 0, 0, time.UTC), Rankings: []string{"carol", "dave"}},
+		{ID: "2", Timestamp: time.Date(2023, 1, 10, 19, 0, 0, 0, time.UTC), Rankings: []string{"carol", "dave"}},
+		{ID: "3", Timestamp: time.Date(2023, 1, 16, 19, 0, 0, 0, time.UTC), Rankings: []string{"dave", "alice"}},
+	}}
+	first.resolvePlayerIDs()
+	second.resolvePlayerIDs()
+
+	c := crossLeague([]ComparedLeague{{Slug: "first"}, {Slug: "second"}}, []*Dataset{first, second})
+	if c.Leagues[0].Players != 3 || c.Leagues[1].Players != 3 || c.Leagues[0].StdDev == 0 {
+		t.Fatalf("expected each league's ratings to be summarized, got %+v", c.Leagues)
+	}
+	for _, e := range c.Entries {
+		if e.League == "first" && e.Player == "alice" && e.Z <= 0 {
+			t.Fatalf("expected alice to be above the first league's average, got %+v", e)
+		}
+	}
+
+	// dave plays mostly in the second league, and the game both leagues
+	// logged is only counted once
+	if len(c.Games) != 1 || len(c.Games[0].Winners) != 1 || c.Games[0].Winners[0] != "second" || c.Leagues[1].Wins != 1 || c.Leagues[0].Wins != 0 {
+		t.Fatalf("expected dave's win over alice to be one inter-league game for the second league, got %+v", c.Games)
+	}
+	if p := c.Games[0].Players; len(p) != 2 || p[0].League != "second" || p[1].League != "first" {
+		t.Fatalf("expected each player's league, got %+v", p)
+	}
+
+	f := newFakeSheets(t, gameLog)
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+	for _, slug := range []string{"first", "second"} {
+		if err := st.createTenant(context.Background(), &Tenant{Slug: slug, Name: slug + " pod", SpreadsheetID: spreadsheetID, ReadRange: readRange}); err != nil {
+			t.Fatalf("failed to create tenant: %v", err)
+		}
+	}
+	h := &hostedServer{store: st, opts: f.options(), defaultQuota: 10, leagues: map[string]*hostedLeague{}}
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	if rec := serve("/compare?leagues=first,second"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `href="/t/second/player/alice"`) {
+		t.Fatalf("expected the comparison page, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+	if rec := serve("/compare?leagues=first,unknown"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected an unknown league to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestCommentsRequireLogin(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+
+	profiles := filepath.Join(t.TempDir(), "profiles.json")
+	if err := os.WriteFile(profiles, []byte(`[{"name": "alice", "loginToken": "s3cret"}]`), 0600); err != nil {
+		t.Fatalf("failed to write profiles: %v", err)
+	}
+	l := f.league()
+	l.profilesPath = profiles
+	l.comments = st
+	l.sessions = &sessions{key: []byte("0123456789abcdef0123456789abcdef")}
+	mux := l.routes()
+
+	post := func(target string, form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	comment := url.Values{"kind": {"game"}, "target": {"1"}, "body": {"what a comeback"}}
+
+	if rec := post("/comments", comment); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous comments to be rejected, got %d", rec.Code)
+	}
+	if rec := post("/login", url.Values{"player": {"alice"}, "token": {"wrong"}}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a wrong token to be rejected, got %d", rec.Code)
+	}
+	rec := post("/login", url.Values{"player": {"alice"}, "token": {"s3cret"}, "next": {"/game/1"}})
+	if rec.Code != http.StatusSeeOther || len(rec.Result().Cookies()) != 1 {
+		t.Fatalf("expected login to set a session cookie, got %d", rec.Code)
+	}
+	session := rec.Result().Cookies()[0]
+
+	if rec := post("/comments", comment, session); rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected the comment to be posted, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/game/1", nil))
+	if !strings.Contains(rec.Body.String(), "what a comeback") {
+		t.Fatalf("expected the game page to show the comment, got:\n%s", rec.Body.String())
+	}
+
+	if err := st.moderateComment(context.Background(), 1, "hide"); err != nil {
+		t.Fatalf("failed to hide comment: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/game/1", nil))
+	if strings.Contains(rec.Body.String(), "what a comeback") {
+		t.Fatalf("expected hidden comments not to be shown")
+	}
+}
+
+func TestGameReports(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+
+	profiles := filepath.Join(t.TempDir(), "profiles.json")
+	if err := os.WriteFile(profiles, []byte(`[{"name": "alice", "loginToken": "s3cret"}]`), 0600); err != nil {
+		t.Fatalf("failed to write profiles: %v", err)
+	}
+	l := f.league()
+	l.profilesPath = profiles
+	l.reports = st
+	l.photos = diskPhotos(t.TempDir())
+	l.sessions = &sessions{key: []byte("0123456789abcdef0123456789abcdef")}
+	mux := l.routes()
+
+	login := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(url.Values{"player": {"alice"}, "token": {"s3cret"}}.Encode()))
+	login.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, login)
+	if len(rec.Result().Cookies()) != 1 {
+		t.Fatalf("expected login to set a session cookie, got %d", rec.Code)
+	}
+	session := rec.Result().Cookies()[0]
+
+	post := func(photo []byte, writeup string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("game", "1")
+		mw.WriteField("writeup", writeup)
+		if photo != nil {
+			fw, _ := mw.CreateFormFile("photo", "board.png")
+			fw.Write(photo)
+		}
+		mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/reports", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	if rec := post(png, "the board state"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous reports to be rejected, got %d", rec.Code)
+	}
+	if rec := post([]byte("not an image"), "", session); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a photo that isn't an image to be rejected, got %d", rec.Code)
+	}
+	rec = post(png, "the board state before the combo", session)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/game/1#report-1" {
+		t.Fatalf("expected the report to redirect to the game, got %d %q: %s", rec.Code, rec.Header().Get("Location"), rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/photos/1", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" || !bytes.Equal(rec.Body.Bytes(), png) {
+		t.Fatalf("expected the photo to be served, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/game/1", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "the board state before the combo") || !strings.Contains(body, "/photos/1") {
+		t.Fatalf("expected the game page to show the report, got:\n%s", body)
+	}
+
+	ctx := context.Background()
+	photos, err := l.reportPhotos(ctx, []string{"alice"})
+	if err != nil || len(photos) != 1 {
+		t.Fatalf("expected alice's report's photo, got %v: %v", photos, err)
+	}
+	if n, err := st.forgetPlayer(ctx, l.snapshotKey(), []string{"alice"}, false); err != nil || n["game_reports"] != 1 {
+		t.Fatalf("expected alice's report to be deleted, got %v: %v", n, err)
+	}
+	if err := l.deletePhotos(ctx, photos); err != nil {
+		t.Fatalf("failed to delete photos: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/photos/1", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected the deleted report's photo to be gone, got %d", rec.Code)
+	}
+}
+
+func TestValidateGameData(t *testing.T) {
+	rows := append([][]interface{}{}, gameLog...)
+	rows = append(rows,
+		[]interface{}{"2", "Mon, 23 Jan 2023 19:00:00 UTC", "", "", "", "alice", "bob"},
+		[]interface{}{"6", "sometime in January", "", "", "", "alice", "bob"},
+		[]interface{}{"7", "Mon, 06 Feb 2023 19:00:00 UTC", "", "", "", "alice"},
+		[]interface{}{"8", "Mon, 13 Feb 2023 19:00:00 UTC", "", "", "", "alice", "bob", "Alice"},
+	)
+
+	var report strings.Builder
+	if !printValidationReport(&report, rows) {
+		t.Fatalf("expected validation to find errors")
+	}
+	for _, want := range []string{
+		"row 4 (game 3): warning: two-headed giant game is not scored",
+		"row 5: error: malformed row",
+		"row 6 (game 2): error: duplicate game ID, first used on row 3",
+		`row 7 (game 6): error: bad date "sometime in January"`,
+		"row 8 (game 7): error: only one player",
+		`row 9 (game 8): error: "alice" is listed twice`,
+	} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, report.String())
+		}
+	}
+}
+
+func TestFlexibleGameDates(t *testing.T) {
+	games, err := parseGameData([][]interface{}{
+		{"id", "date", "zap", "draw", "notes", "p1", "p2"},
+		{"1", "Mon, 02 Jan 2023 19:00:00 UTC", "", "", "", "alice", "bob"},
+		{"2", "1/2/2023", "", "", "", "alice", "bob"},
+		{"3", "2023-01-02", "", "", "", "alice", "bob"},
+		{"4", "Jan 2 2023", "", "", "", "alice", "bob"},
+		{"5", " Jan 2, 2023 ", "", "", "", "alice", "bob"},
+	})
+	if err != nil {
+		t.Fatalf("failed to parse game data: %v", err)
+	}
+	for _, g := range games {
+		if y, m, d := g.Timestamp.Date(); y != 2023 || m != time.January || d != 2 {
+			t.Errorf("expected game %s on %q to be on Jan 2, 2023, got %s", g.ID, g.Date, g.Timestamp)
+		}
+	}
+
+	defer configureDateFormat("")
+	if ts, _ := parseGameDate("3/1/2023"); ts.Month() != time.March {
+		t.Fatalf("expected month first dates by default, got %s", ts)
+	}
+	if err := configureDateFormat("eu"); err != nil {
+		t.Fatalf("failed to configure date format: %v", err)
+	}
+	if ts, _ := parseGameDate("3/1/2023"); ts.Month() != time.January || ts.Day() != 3 {
+		t.Fatalf("expected day first dates, got %s", ts)
+	}
+	if ts, _ := parseGameDate("13/1/2023"); ts.Month() != time.January || ts.Day() != 13 {
+		t.Fatalf("expected day first dates, got %s", ts)
+	}
+	if err := configureDateFormat("02.01.2006"); err != nil {
+		t.Fatalf("failed to configure a Go layout: %v", err)
+	}
+	if ts, err := parseGameDate("03.01.2023"); err != nil || ts.Day() != 3 {
+		t.Fatalf("expected the configured layout to be tried first, got %s: %v", ts, err)
+	}
+	if err := configureDateFormat("whenever"); err == nil {
+		t.Fatalf("expected an invalid date format to be rejected")
+	}
+}
+
+func TestEliminationTimesOrderPlacements(t *testing.T) {
+	games, err := parseGameData([][]interface{}{
+		{"id", "date", "zap", "draw", "notes", "p1", "p2", "p3", "p4", "p5", "p6", "e1", "e2", "e3"},
+		{"1", "Mon, 16 Jan 2023 23:00:00 UTC", "", "", "", "alice", "bob", "carol", "", "", "", "", "23:45", "00:30"},
+	})
+	if err != nil {
+		t.Fatalf("failed to parse game data: %v", err)
+	}
+	if got := strings.Join(games[0].Rankings, ","); got != "alice,carol,bob" {
+		t.Fatalf("expected the later elimination to place higher, got %s", got)
+	}
+
+	players := rankPlayers(games, calculateScores(games), time.Now())
+	for _, p := range players {
+		switch p.Name {
+		case "alice":
+			if p.AvgSurvival != 90 {
+				t.Errorf("expected the winner to survive until the last elimination, got %v minutes", p.AvgSurvival)
+			}
+		case "bob":
+			if p.FirstOutRate != 1 {
+				t.Errorf("expected bob to be first out, got %v", p.FirstOutRate)
+			}
+		}
+	}
+}
+
+func TestUpsetMultiplier(t *testing.T) {
+	curve, err := parseUpsetCurve("200:1.5, 100:1.25")
+	if err != nil {
+		t.Fatalf("failed to parse upset curve: %v", err)
+	}
+	defer func(prev []upsetStep) { upsetCurve = prev }(upsetCurve)
+	upsetCurve = curve
+
+	// the underdog won, the favorite finished last
+	ratings := []int{1000, 1050, 1250}
+	if m := upsetMultiplier(ratings, 0, 20); m != 1.5 {
+		t.Errorf("expected the underdog's gain to be multiplied by 1.5, got %v", m)
+	}
+	if m := upsetMultiplier(ratings, 2, -20); m != 1.5 {
+		t.Errorf("expected the favorite's loss to be multiplied by 1.5, got %v", m)
+	}
+	if m := upsetMultiplier(ratings, 1, -5); m != 1 {
+		t.Errorf("expected no multiplier for a loss to a gap under 100, got %v", m)
+	}
+
+	if _, err := parseUpsetCurve("200:0.5"); err == nil {
+		t.Error("expected a multiplier under 1 to be rejected")
+	}
+}
+
+func TestGamesAPIPagesWithCursor(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	h := gamesHandler(f.league())
+
+	var ids []string
+	target := "/api/games?limit=1"
+	for page := 0; page < 5; page++ {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body)
+		}
+		var res struct {
+			Games      []GameRecord `json:"games"`
+			NextCursor string       `json:"nextCursor"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		for _, g := range res.Games {
+			ids = append(ids, g.ID)
+		}
+		if res.NextCursor == "" {
+			break
+		}
+		target = "/api/games?limit=1&cursor=" + res.NextCursor
+	}
+	if got := strings.Join(ids, ","); got != "1,2,3" {
+		t.Fatalf("expected games 1,2,3 across the pages, got %s", got)
+	}
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/api/games?player=carol", nil))
+	if body := rec.Body.String(); !strings.Contains(body, `"id":"2"`) || strings.Contains(body, `"id":"1"`) {
+		t.Fatalf("expected only carol's game, got %s", body)
+	}
+}
+
+func TestArchenemyGamesAreScored(t *testing.T) {
+	games, err := parseGameData([][]interface{}{
+		{"Game", "Date", "Zap", "Draw", "Notes", "1st", "2nd"},
+		{"1", "Mon, 02 Jan 2023 19:00:00 UTC", "", "", "", "bob/carol/dave", "alice"},
+		{"2", "Mon, 09 Jan 2023 19:00:00 UTC", "", "", "", "dave/erin", "frank/gus"},
+	})
+	if err != nil {
+		t.Fatalf("failed to parse game data: %v", err)
+	}
+	if len(games) != 1 || games[0].Archenemy != "alice" {
+		t.Fatalf("expected only the archenemy game to be kept, got %+v", games)
+	}
+
+	scores := calculateScores(games)
+	for _, res := range games[0].Results {
+		switch res.Player {
+		case "alice":
+			if res.Place != 2 || res.Delta != -32 {
+				t.Errorf("expected the archenemy to lose double, got %+v", res)
+			}
+		default:
+			if res.Place != 1 || res.Delta != 16 {
+				t.Errorf("expected the team to share first place, got %+v", res)
+			}
+		}
+	}
+	players := rankPlayers(games, scores, time.Now())
+	for _, p := range players {
+		if p.ID != "alice" && (p.Wins != 1 || p.HeadToHead["alice"] != 1 || len(p.HeadToHead) != 1) {
+			t.Errorf("expected %s to win against alice only, got %+v", p.ID, p)
+		}
+	}
+}
+
+func TestLiveGameRowParses(t *testing.T) {
+	start := time.Date(2023, 1, 16, 23, 0, 0, 0, time.UTC)
+	ms := func(d time.Duration) string {
+		return strconv.FormatInt(start.Add(d).UnixNano()/int64(time.Millisecond), 10)
+	}
+	g, err := parseLiveGame(url.Values{
+		"start":      {ms(0)},
+		"player":     {"alice", "carol", "bob"},
+		"eliminated": {"", ms(90 * time.Minute), ms(45 * time.Minute)},
+	})
+	if err != nil {
+		t.Fatalf("failed to parse live game: %v", err)
+	}
+
+	games, err := parseGameData([][]interface{}{gameLog[0], g.row("7")})
+	if err != nil || len(games) != 1 {
+		t.Fatalf("failed to parse the recorded row: %v", err)
+	}
+	if got := strings.Join(games[0].Rankings, ","); got != "alice,carol,bob" {
+		t.Fatalf("expected the recorded finishing order, got %s", got)
+	}
+	if minutes, _ := games[0].Survival("bob"); minutes != 45 {
+		t.Errorf("expected bob to survive 45 minutes, got %v", minutes)
+	}
+
+	if _, err := parseLiveGame(url.Values{"start": {ms(0)}, "player": {"alice", "alice"}, "eliminated": {"", ms(time.Minute)}}); err == nil {
+		t.Error("expected a player in the pod twice to be rejected")
+	}
+}
+
+func TestRankingsAsOf(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	l := f.league()
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	l.snapshots = st
+
+	// the second request is served from the stored snapshot
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		rankingsHandler(l)(rec, httptest.NewRequest(http.MethodGet, "/api/rankings?asOf=2023-01-05", nil))
+		var res struct {
+			Rankings []Player `json:"rankings"`
+			Total    int      `json:"total"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if res.Total != 1 || len(res.Rankings) != 2 || res.Rankings[0].ID != "alice" || res.Rankings[0].Score != 1516 {
+			t.Fatalf("expected the leaderboard after game 1, got %+v", res)
+		}
+	}
+
+	// tenants sharing a spreadsheet keep their snapshots apart
+	hosted := f.league()
+	hosted.tenant = "acme"
+	hosted.snapshots = st
+	rankingsHandler(hosted)(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/rankings?asOf=2023-01-05", nil))
+	for _, key := range []string{spreadsheetID, "acme"} {
+		var n int
+		if err := st.db.QueryRow(`SELECT COUNT(*) FROM rating_snapshots WHERE league = ?`, key).Scan(&n); err != nil || n != 1 {
+			t.Fatalf("expected a snapshot stored under %q, got %d (%v)", key, n, err)
+		}
+	}
+}
+
+func TestSitemapAndLinkPreviews(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	mux := f.league().routes()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://scores.example.com/sitemap.xml", nil))
+	body := rec.Body.String()
+	for _, loc := range []string{"<loc>http://scores.example.com/game/2</loc>", "<loc>http://scores.example.com/player/carol</loc>"} {
+		if !strings.Contains(body, loc) {
+			t.Errorf("expected %s in the sitemap, got:\n%s", loc, body)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://scores.example.com/?view=hot", nil))
+	body = rec.Body.String()
+	if !strings.Contains(body, `<link rel="canonical" href="http://scores.example.com/">`) ||
+		!strings.Contains(body, `content="http://scores.example.com/snapshot.png?top=3"`) {
+		t.Errorf("expected a canonical link and preview image, got:\n%s", body)
+	}
+}
+
+func TestLiveGamesWaitForConfirmation(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+
+	l := f.league()
+	l.live = true
+	l.pending = st
+	l.sessions = &sessions{key: []byte("0123456789abcdef0123456789abcdef")}
+	mux := l.routes()
+
+	post := func(target, player string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: sessionCookie, Value: l.sessions.sign(player, time.Now().Add(time.Hour))})
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	appended := func() bool {
+		for _, path := range f.requestPaths() {
+			if strings.HasSuffix(path, ":append") {
+				return true
+			}
+		}
+		return false
+	}
+
+	start := strconv.FormatInt(time.Now().Add(-time.Hour).UnixNano()/int64(time.Millisecond), 10)
+	out := strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+	rec := post("/live", "alice", url.Values{"start": {start}, "player": {"alice", "bob"}, "eliminated": {"", out}})
+	if rec.Code != http.StatusSeeOther || appended() {
+		t.Fatalf("expected the game to be held as pending, got %d", rec.Code)
+	}
+
+	confirm := url.Values{"id": {"1"}, "action": {"confirm"}}
+	if rec := post("/live/verify", "alice", confirm); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected the recorder not to be able to confirm their own game, got %d", rec.Code)
+	}
+	if rec := post("/live/verify", "carol", confirm); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a player outside the pod not to be able to confirm, got %d", rec.Code)
+	}
+	if rec := post("/live/verify", "bob", confirm); rec.Code != http.StatusSeeOther || !appended() {
+		t.Fatalf("expected bob's confirmation to add the game to the log, got %d", rec.Code)
+	}
+	if pending, _ := st.pendingGames(context.Background(), l.spreadsheetID); len(pending) != 0 {
+		t.Fatalf("expected no pending games after confirmation, got %d", len(pending))
+	}
+}
+
+func TestSchedulingPollPredictsPods(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+
+	l := f.league()
+	l.polls = st
+	l.sessions = &sessions{key: []byte("0123456789abcdef0123456789abcdef")}
+	mux := l.routes()
+
+	post := func(target, player string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: sessionCookie, Value: l.sessions.sign(player, time.Now().Add(time.Hour))})
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("/schedule", "alice", url.Values{"title": {"March league night"}, "dates": {"2024-03-21\n2024-03-14"}})
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/schedule/1" {
+		t.Fatalf("expected a redirect to the new poll, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	for _, player := range []string{"alice", "bob", "carol"} {
+		if rec := post("/schedule/1", player, url.Values{"date": {"2024-03-21"}}); rec.Code != http.StatusSeeOther {
+			t.Fatalf("expected %s's availability to be saved, got %d", player, rec.Code)
+		}
+	}
+	post("/schedule/1", "dave", url.Values{"date": {"2024-03-14", "2024-03-21"}})
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/schedule/1", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "with 4 available") {
+		t.Fatalf("expected 2024-03-21 to be the best date, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	pods := predictPods([]string{"a", "b", "c", "d", "e", "f", "g", "h"}, map[string]int{"a": 1800, "b": 1700, "c": 1600, "d": 1550, "e": 1500, "f": 1450, "g": 1400, "h": 1300})
+	if len(pods) != 2 || strings.Join(pods[0], ",") != "a,d,e,h" || strings.Join(pods[1], ",") != "b,c,f,g" {
+		t.Fatalf("expected two balanced pods, got %v", pods)
+	}
+	if pods := predictPods([]string{"a", "b", "c", "d", "e"}, nil); len(pods) != 1 {
+		t.Fatalf("expected 5 players to make one pod rather than a pod of 2, got %v", pods)
+	}
+}
+
+func TestRenamePlayerRewritesHistory(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	var updated map[string]interface{}
+	serve := f.handler
+	f.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/values:batchUpdate") {
+			serve(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&updated)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+	l := f.league()
+	if err := st.addComment(context.Background(), &Comment{League: spreadsheetID, Kind: "player", Target: "bob", Author: "Bob", Body: "gg", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("failed to add comment: %v", err)
+	}
+
+	rename := func(form url.Values) *RenameResult {
+		req := httptest.NewRequest(http.MethodPost, "/admin/rename", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		renameHandler(l, st)(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected the rename to succeed, got %d: %s", rec.Code, rec.Body.String())
+		}
+		res := &RenameResult{}
+		if err := json.NewDecoder(rec.Body).Decode(res); err != nil {
+			t.Fatalf("failed to decode rename: %v", err)
+		}
+		return res
+	}
+
+	res := rename(url.Values{"from": {"bob"}, "to": {"robert"}})
+	if !res.DryRun || updated != nil || len(res.Cells) != 2 || res.Cells[0].Cell != "Ranked game log!G2" || res.Cells[1].Cell != "Ranked game log!H3" {
+		t.Fatalf("expected a preview of bob's two cells without writing them, got %+v", res)
+	}
+	if res.After != res.Before["bob"] || res.Records["comments"] != 2 {
+		t.Fatalf("expected robert to keep bob's rating and comments, got %+v", res)
+	}
+	if comments, _ := st.comments(context.Background(), spreadsheetID, "player", "bob"); len(comments) != 1 {
+		t.Fatalf("expected the dry run to leave the comments alone, got %d", len(comments))
+	}
+
+	res = rename(url.Values{"from": {"bob"}, "to": {"robert"}, "apply": {"true"}})
+	if res.DryRun || updated == nil || len(updated["data"].([]interface{})) != 2 {
+		t.Fatalf("expected the cells to be written, got %+v", updated)
+	}
+	if comments, _ := st.comments(context.Background(), spreadsheetID, "player", "robert"); len(comments) != 1 || comments[0].Author != "robert" {
+		t.Fatalf("expected the comments to follow the rename, got %+v", comments)
+	}
+}
+
+func TestRenamePlayerRewritesRosterTabs(t *testing.T) {
+	f := newFakeSheets(t, nil)
+	f.serveRows(gameLog, map[string][][]interface{}{
+		"Players!A:C": {{"Player", "Name", "Other names"}, {"bob", "Bobby Tables"}},
+		"Aliases!A:B": {{"Alias", "Name"}, {"Bobby", "bob"}},
+		"Seeds!A:B":   {{"Player", "Rating"}, {"bob", "1600"}},
+	})
+	l := f.league()
+	l.ranges.Players = "Players!A:C"
+	l.ranges.Aliases = "Aliases!A:B"
+	l.ranges.Seeds = "Seeds!A:B"
+
+	res, err := l.renamePlayer(context.Background(), nil, "bob", "robert", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cells := map[string]string{}
+	for _, c := range res.Cells {
+		cells[c.Cell] = c.New
+	}
+	for _, cell := range []string{"Ranked game log!G2", "Ranked game log!H3", "Players!A2", "Aliases!B2", "Seeds!A2"} {
+		if cells[cell] != "robert" {
+			t.Fatalf("expected %s to be renamed, got %+v", cell, res.Cells)
+		}
+	}
+	if len(cells) != 5 {
+		t.Fatalf("expected only the cells naming bob to be renamed, got %+v", res.Cells)
+	}
+}
+
+func TestSensitivityDiffsStandings(t *testing.T) {
+	ds := &Dataset{Games: []*Game{
+		{ID: "1", Rankings: []string{"alice", "bob", "carol", "dave"}},
+		{ID: "2", Rankings: []string{"bob", "alice", "dave", "carol"}},
+		{ID: "3", Rankings: []string{"carol", "dave", "alice", "bob"}},
+	}}
+	ds.resolvePlayerIDs()
+
+	variants, err := sensitivityVariants("64", "", "winner", "")
+	if err != nil {
+		t.Fatalf("failed to build variants: %v", err)
+	}
+	results := sensitivity(ds, variants, time.Now())
+	if len(results) != 2 || kFactor != 32 || rewardCurveShape != "default" {
+		t.Fatalf("expected two results with the current parameters restored, got %d", len(results))
+	}
+	for _, c := range results[0].Changes {
+		if c.Rating != 1500 && c.VariantRating == c.Rating {
+			t.Fatalf("expected a larger K to change %s's rating, got %+v", c.Name, c)
+		}
+	}
+	for _, g := range ds.Games {
+		if g.Results != nil {
+			t.Fatalf("expected the dataset to be left unscored, got %+v", g)
+		}
+	}
+	for idx, v := range variants {
+		ratings := map[string]int{}
+		for _, c := range results[idx].Changes {
+			ratings[c.Name] = c.VariantRating
+		}
+		for _, p := range finalStandings(ds, v.config(), time.Now()) {
+			if ratings[p.Name] != p.Score {
+				t.Fatalf("expected %s under the %s to match scoring it alone, got %d and %d", p.Name, v.Name, ratings[p.Name], p.Score)
+			}
+		}
+	}
+
+	var report strings.Builder
+	printSensitivityReport(&report, results)
+	if !strings.Contains(report.String(), "K = 64: ") || !strings.Contains(report.String(), "winner reward curve: ") {
+		t.Fatalf("unexpected report:\n%s", report.String())
+	}
+}
+
+func TestResponsesAreCompressed(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	h := web.Compress(f.league().routes())
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip;q=0.8")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected the leaderboard to be gzipped, got %q", rec.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("invalid gzip stream: %v", err)
+	}
+	body, err := ioutil.ReadAll(zr)
+	if err != nil || !strings.Contains(string(body), "alice") {
+		t.Fatalf("expected the leaderboard in the gzipped body, got %v: %s", err, body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "" || !strings.Contains(rec.Body.String(), "alice") {
+		t.Fatalf("expected an uncompressed response when gzip is refused, got %q", rec.Header().Get("Content-Encoding"))
+	}
+
+	// brotli isn't offered, so a client that only accepts it gets the page
+	// uncompressed rather than labelled br
+	for _, accept := range []string{"br", "*;q=1, gzip;q=0"} {
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", accept)
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Header().Get("Content-Encoding") != "" || !strings.Contains(rec.Body.String(), "alice") {
+			t.Fatalf("expected an uncompressed response for %q, got %q", accept, rec.Header().Get("Content-Encoding"))
+		}
+	}
+}
+
+func TestAPIAllowsConfiguredOrigins(t *testing.T) {
+	defer func(prev web.CORSPolicy) { apiCORS = prev }(apiCORS)
+	apiCORS.Origins = web.ParseCORSOrigins("https://dash.example.com/, http://localhost:3000")
+	mux := newFakeSheets(t, gameLog).league().routes()
+
+	request := func(method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/rankings", nil)
+		req.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", "GET")
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request(http.MethodOptions, "https://dash.example.com")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://dash.example.com" ||
+		rec.Header().Get("Access-Control-Allow-Methods") != "GET, OPTIONS" {
+		t.Fatalf("expected the preflight to be allowed, got %d %v", rec.Code, rec.Header())
+	}
+	rec = request(http.MethodGet, "http://localhost:3000")
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "http://localhost:3000" {
+		t.Fatalf("expected the rankings to be readable from the dashboard, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := request(http.MethodGet, "https://evil.example.com"); rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("expected other origins to be refused, got %v", rec.Header())
+	}
+}
+
+func TestNemesisAndVictim(t *testing.T) {
+	games := []*Game{
+		{ID: "1", Rankings: []string{"bob", "alice", "carol"}},
+		{ID: "2", Rankings: []string{"bob", "carol", "alice"}},
+		{ID: "3", Rankings: []string{"alice", "carol", "bob"}},
+		{ID: "4", Rankings: []string{"alice", "carol"}, DrawGame: "TRUE"},
+	}
+	nemesis, victim := nemesisAndVictim(games, "alice")
+	if nemesis == nil || nemesis.ID != "bob" || nemesis.Losses != 2 || nemesis.Games != 3 {
+		t.Fatalf("expected bob to be alice's nemesis with 2 of 3, got %+v", nemesis)
+	}
+	if victim == nil || victim.ID != "carol" || victim.Wins != 2 || victim.Games != 3 {
+		t.Fatalf("expected carol to be alice's victim with 2 of 3, got %+v", victim)
+	}
+
+	f := newFakeSheets(t, gameLog)
+	rec := httptest.NewRecorder()
+	f.league().routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/player/bob", nil))
+	if !strings.Contains(rec.Body.String(), "Nemesis: <a href=\"/player/alice\">alice</a>, finished above bob in 2 of 2 games (100% [34–100%])") {
+		t.Fatalf("expected alice as the nemesis on bob's page, got %s", rec.Body.String())
+	}
+}
+
+func TestErasCompareSideBySide(t *testing.T) {
+	f := newFakeSheets(t, [][]interface{}{
+		gameLog[0],
+		{"1", "Mon, 02 Jan 2023 19:00:00 UTC", "TRUE", "", "", "alice", "bob", "carol", "dave"},
+		{"2", "Mon, 06 Feb 2023 19:00:00 UTC", "", "", "", "alice", "bob"},
+		{"3", "Mon, 08 Jan 2024 19:00:00 UTC", "", "", "", "carol", "bob", "alice"},
+	})
+	mux := f.league().routes()
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	rec := get("/eras?a=2023&b=2024-01-01..2024-12-31")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the eras page, got %d: %s", rec.Code, body)
+	}
+	for _, want := range []string{"<td>3.0 <svg", "<td>50%</td><td>0%</td>", `alice</a> won 2 of 2 (100% [34–100%])`, `carol</a> won 1 of 1 (100% [21–100%])`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in the eras page:\n%s", want, body)
+		}
+	}
+	if rec := get("/eras?a=last+year"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected an unknown era to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestJobsAreRetried(t *testing.T) {
+	q := newJobQueue()
+	q.backoff = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.run(ctx, 2)
+
+	attempts := 0
+	job := q.enqueue("webhook", 3, func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	failing := q.enqueue("email", 2, func(ctx context.Context) error { return errors.New("relay down") })
+	for _, j := range []*Job{job, failing} {
+		select {
+		case <-j.done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the %s job", j.Kind)
+		}
+	}
+
+	jobs := q.snapshot()
+	if len(jobs) != 2 || jobs[1].Status != jobDone || jobs[1].Attempts != 3 {
+		t.Fatalf("expected the webhook to succeed on its third attempt, got %+v", jobs)
+	}
+	if jobs[0].Status != jobFailed || jobs[0].Attempts != 2 || jobs[0].LastError != "relay down" {
+		t.Fatalf("expected the email to fail after 2 attempts, got %+v", jobs[0])
+	}
+}
+
+func TestSeededRatingsApplyOnFirstGame(t *testing.T) {
+	f := newFakeSheets(t, nil)
+	f.serveRows(gameLog, map[string][][]interface{}{
+		"Seeds!A:B": {{"Player", "Rating"}, {"alice", "1700"}, {"zoe", "1600"}},
+	})
+	l := f.league()
+	l.ranges.Seeds = "Seeds!A:B"
+
+	ds, err := l.fetch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	scores := calculateScores(ds.Games[:1], ds.Adjustments...)
+	if scores["alice"] <= 1700 {
+		t.Errorf("expected alice to start at their seed of 1700 and win, got %d", scores["alice"])
+	}
+	if scores["bob"] >= startingRating {
+		t.Errorf("expected bob to start at %d and lose, got %d", startingRating, scores["bob"])
+	}
+	if _, ok := scores["zoe"]; ok {
+		t.Errorf("expected a seeded player who hasn't played to be unrated, got %d", scores["zoe"])
+	}
+	if adjs := adjustmentsInSeason(ds.Adjustments, Season{}); len(adjs) != 0 {
+		t.Errorf("expected seeds not to count as adjustments, got %+v", adjs)
+	}
+}
+
+func TestArchetypeWinRates(t *testing.T) {
+	row := func(id, first, second, firstDeck, secondDeck string) []interface{} {
+		r := make([]interface{}, deckColumn+2)
+		for idx := range r {
+			r[idx] = ""
+		}
+		r[0], r[1] = id, "Mon, 02 Jan 2023 19:00:00 UTC"
+		r[playerColumn], r[playerColumn+1] = first, second
+		r[deckColumn], r[deckColumn+1] = firstDeck, secondDeck
+		return r
+	}
+	games, err := parseGameData([][]interface{}{
+		gameLog[0],
+		row("1", "alice", "bob", "combo, bu", "stax"),
+		row("2", "bob", "alice", "Aggro", "UB"),
+		row("3", "alice", "bob", "", "stax"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(games[0].Decks["alice"], ","); got != "combo,UB" {
+		t.Fatalf("expected alice's deck to be tagged combo,UB, got %s", got)
+	}
+	calculateScores(games)
+
+	stats := map[string]ArchetypeStats{}
+	for _, s := range archetypeStats(games, StatsQuery{}) {
+		stats[s.Archetype] = s
+	}
+	if s := stats["UB"]; s.Games != 2 || s.Wins != 1 {
+		t.Errorf("expected UB to win 1 of 2 games, got %+v", s)
+	}
+	if s := stats["stax"]; s.Games != 2 || s.Wins != 0 {
+		t.Errorf("expected stax to win none of 2 games, got %+v", s)
+	}
+	if s := stats["aggro"]; s.Games != 1 || s.WinRate != 1 || s.WinRateHigh != 1 || math.Round(s.WinRateLow*100) != 21 {
+		t.Errorf("expected aggro to win its only game with a wide confidence interval, got %+v", s)
+	}
+	if _, ok := stats["BU"]; ok {
+		t.Error("expected color identities to be written in WUBRG order")
+	}
+}
+
+func TestRefreshDropsRenderedPages(t *testing.T) {
+	f := newFakeSheets(t, nil)
+	f.serveRows(gameLog, map[string][][]interface{}{
+		"Adjustments!A:D": {{"Date", "Player", "Amount", "Reason"}},
+	})
+	l := f.league()
+	l.ranges.Adjustments = "Adjustments!A:D"
+	l.sessions = &sessions{key: []byte("0123456789abcdef0123456789abcdef")}
+	mux := l.routes()
+
+	leaderboard := func() string {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Body.String()
+	}
+	refresh := func(cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/refresh", nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	before := leaderboard()
+	// an adjustment changes the dataset version, so the page is rendered
+	// afresh without a refresh
+	f.serveRows(gameLog, map[string][][]interface{}{
+		"Adjustments!A:D": {{"Date", "Player", "Amount", "Reason"}, {"2023-01-20", "alice", "-100", "slow play"}},
+	})
+	if leaderboard() == before {
+		t.Fatal("expected the adjustment to render the leaderboard afresh")
+	}
+
+	if rec := refresh(); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous refreshes to be rejected, got %d", rec.Code)
+	}
+	session := &http.Cookie{Name: sessionCookie, Value: l.sessions.sign("alice", time.Now().Add(time.Hour))}
+	rec := refresh(session)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the refresh to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var res struct {
+		DatasetVersion string `json:"datasetVersion"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || res.DatasetVersion == "" {
+		t.Fatalf("expected the new dataset version, got %s", rec.Body.String())
+	}
+	if l.pages.get(res.DatasetVersion, "?") != nil {
+		t.Fatal("expected the rendered leaderboard to be dropped by the refresh")
+	}
+}
+
+func TestDatasetVersionCoversEveryTab(t *testing.T) {
+	ds := &Dataset{
+		Games:       []*Game{{ID: "1", Rankings: []string{"alice", "bob"}}},
+		Adjustments: []*Adjustment{{Date: "2023-01-20", Player: "alice", Amount: -100}},
+	}
+	version := datasetVersion(ds)
+	edits := map[string]func(){
+		"adjustment": func() { ds.Adjustments[0].Amount = -50 },
+		"seed":       func() { ds.Adjustments = append(ds.Adjustments, &Adjustment{Player: "bob", Amount: 1600, Seed: true}) },
+		"season":     func() { ds.Seasons = []Season{{Name: "Spring"}} },
+		"house":      func() { ds.Houses = []House{{Name: "North", Members: []string{"alice"}}} },
+		"tag":        func() { ds.Games[0].Tags = []string{"cedh"} },
+		"deck":       func() { ds.Games[0].Decks = map[string][]string{"alice": {"stax"}} },
+		"elimination": func() {
+			ds.Games[0].Eliminations = map[string]time.Time{"bob": time.Date(2023, 1, 2, 20, 0, 0, 0, time.UTC)}
+		},
+		"unscored game": func() { ds.Unscored = []*Game{{ID: "2", Teams: [][]string{{"dave", "erin"}, {"frank", "gus"}}}} },
+	}
+	for _, edit := range []string{"adjustment", "seed", "season", "house", "tag", "deck", "elimination", "unscored game"} {
+		edits[edit]()
+		if next := datasetVersion(ds); next == version {
+			t.Fatalf("expected changing the %s to change the dataset version", edit)
+		} else {
+			version = next
+		}
+	}
+	if datasetVersion(ds) != version {
+		t.Fatal("expected the same data to keep its version")
+	}
+}
+
+func TestMilestonesTimeline(t *testing.T) {
+	games := []*Game{
+		{ID: "1", Rankings: []string{"alice", "bob"}},
+		{ID: "2", Rankings: []string{"bob", "alice"}, TableZap: "TRUE"},
+		{ID: "3", Rankings: []string{"bob", "alice"}},
+	}
+	calculateScores(games)
+
+	var got []string
+	for _, m := range playerMilestones(leagueMilestones(games), "bob") {
+		got = append(got, m.Game.ID+" "+m.Title)
+	}
+	want := []string{"1 First game", "2 First win", "2 First table zap", "3 Highest rating, " + strconv.Itoa(games[2].Results[0].After)}
+	if strings.Join(got, "; ") != strings.Join(want, "; ") {
+		t.Errorf("expected bob's milestones to be %q, got %q", want, got)
+	}
+
+	if ordinal(50) != "50th" || ordinal(101) != "101st" || ordinal(112) != "112th" {
+		t.Errorf("unexpected ordinals %s, %s, %s", ordinal(50), ordinal(101), ordinal(112))
+	}
+
+	f := newFakeSheets(t, gameLog)
+	rec := httptest.NewRecorder()
+	f.league().routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/timeline", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "First win") {
+		t.Fatalf("expected the timeline to list milestones, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRecencyRankingsDecayOldGames(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	old := now.AddDate(-2, 0, 0)
+	games := []*Game{
+		{ID: "1", Timestamp: old, Rankings: []string{"alice", "bob"}},
+		{ID: "2", Timestamp: old, Rankings: []string{"alice", "bob"}},
+		{ID: "3", Timestamp: old, Rankings: []string{"alice", "bob"}},
+		{ID: "4", Timestamp: now.AddDate(0, 0, -1), Rankings: []string{"bob", "alice"}},
+	}
+	if rankings := rankPlayers(games, calculateScores(games), time.Now()); rankings[0].ID != "alice" {
+		t.Fatalf("expected alice to lead on rating, got %+v", rankings)
+	}
+	delta := games[0].Results[0].Delta
+
+	rankings := recencyRankings(games, nil, now)
+	if rankings[0].ID != "bob" {
+		t.Fatalf("expected bob's recent win to outweigh alice's old ones, got %+v", rankings)
+	}
+	if games[0].Results[0].Delta != delta {
+		t.Fatalf("expected the recency board not to rescore the original games")
+	}
+}
+
+func TestForgetPlayerKeepsAggregates(t *testing.T) {
+	f := newFakeSheets(t, nil)
+	tabs := map[string][][]interface{}{
+		"Aliases!A:B": {{"Alias", "Name"}, {"Bobby", "bob"}},
+	}
+	f.serveRows(gameLog, tabs)
+	var updated map[string]interface{}
+	serve := f.handler
+	f.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/values:batchUpdate") {
+			serve(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&updated)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+	profiles := filepath.Join(t.TempDir(), "profiles.json")
+	if err := os.WriteFile(profiles, []byte(`[{"name": "bob", "email": "bob@example.com"}, {"name": "alice"}]`), 0600); err != nil {
+		t.Fatalf("failed to write profiles: %v", err)
+	}
+	l := f.league()
+	l.ranges.Aliases = "Aliases!A:B"
+	l.profilesPath = profiles
+	ctx := context.Background()
+	st.addComment(ctx, &Comment{League: spreadsheetID, Kind: "game", Target: "1", Author: "bob", Body: "gg", CreatedAt: time.Now()})
+	st.addComment(ctx, &Comment{League: spreadsheetID, Kind: "player", Target: "bob", Author: "alice", Body: "wp", CreatedAt: time.Now()})
+
+	res, err := l.forgetPlayer(ctx, st, "Bobby", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Player != "bob" || res.Anonymous != "Former player 1" || res.After != res.Before {
+		t.Fatalf("expected bob's rating to be kept under an anonymous name, got %+v", res)
+	}
+	// bob's two game log cells and the alias row
+	if len(res.Cells) != 4 || updated == nil || len(updated["data"].([]interface{})) != 4 {
+		t.Fatalf("expected bob's cells to be anonymized, got %+v", res.Cells)
+	}
+	if comments, _ := st.comments(ctx, spreadsheetID, "game", "1"); len(comments) != 0 {
+		t.Fatalf("expected bob's comments to be deleted, got %+v", comments)
+	}
+	if comments, _ := st.comments(ctx, spreadsheetID, "player", slugify(res.Anonymous)); len(comments) != 1 {
+		t.Fatalf("expected comments on bob's page to follow the anonymous name, got %+v", comments)
+	}
+	left, _ := loadProfiles(profiles, &Dataset{})
+	if _, ok := left["bob"]; ok || !res.Profile || len(left) != 1 {
+		t.Fatalf("expected bob's profile to be deleted, got %+v", left)
+	}
+}
+
+func TestForwardedHeadersOnlyFromTrustedProxies(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Forwarded-Proto", "https")
+	r.Header.Set("X-Forwarded-Host", "scores.example.com, internal:8080")
+	if got := siteURL(r); got != "http://example.com" {
+		t.Fatalf("expected forwarded headers from an untrusted client to be ignored, got %q", got)
+	}
+
+	defer func(old []*net.IPNet) { web.TrustedProxies = old }(web.TrustedProxies)
+	proxies, err := web.ParseTrustedProxies("10.0.0.0/8, 192.0.2.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	web.TrustedProxies = proxies
+	if got := siteURL(r); got != "https://scores.example.com" {
+		t.Fatalf("expected the forwarded scheme and host, got %q", got)
+	}
+	if _, err := web.ParseTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Fatal("expected an invalid range to be rejected")
+	}
+}
+
+func TestLoadSheddingServesStalePage(t *testing.T) {
+	f := newFakeSheets(t, nil)
+	f.serveRows(gameLog)
+	l := f.league()
+	mux := l.routes()
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	fresh := get("/")
+	if fresh.Code != http.StatusOK {
+		t.Fatalf("expected the leaderboard to render, got %d", fresh.Code)
+	}
+
+	// every recompute slot is taken and the rendered pages are out of date
+	defer func(old chan struct{}) { recomputes = old }(recomputes)
+	recomputes = make(chan struct{}, 1)
+	recomputes <- struct{}{}
+	l.pages.clear()
+
+	requests := f.requestCount()
+	rec := get("/")
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != fresh.Body.String() || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected the stale leaderboard with 503, got %d", rec.Code)
+	}
+	if f.requestCount() != requests {
+		t.Fatalf("expected a shed request not to fetch the sheet, got %d fetches", f.requestCount()-requests)
+	}
+	if rec := get("/?view=performance"); rec.Code != http.StatusServiceUnavailable || strings.Contains(rec.Body.String(), "<table") {
+		t.Fatalf("expected 503 without a page that was never rendered, got %d", rec.Code)
+	}
+
+	<-recomputes
+	if rec := get("/?view=performance"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the leaderboard to render once a slot is free, got %d", rec.Code)
+	}
+}
+
+func TestStreamingRoutesHaveNoDeadline(t *testing.T) {
+	deadlines := map[string]bool{}
+	h := withDeadline(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := r.Context().Deadline()
+		deadlines[r.URL.Path] = ok
+	}), time.Millisecond)
+	for _, path := range []string{"/", "/api/rankings", "/api/export", "/acme/api/export"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	if !deadlines["/"] || !deadlines["/api/rankings"] {
+		t.Fatalf("expected pages to have a deadline, got %v", deadlines)
+	}
+	if deadlines["/api/export"] || deadlines["/acme/api/export"] {
+		t.Fatalf("expected the export to stream without a deadline, got %v", deadlines)
+	}
+}
+
+func TestTwoHeadedGiantGamesListTeams(t *testing.T) {
+	f := newFakeSheets(t, nil)
+	f.serveRows(gameLog, map[string][][]interface{}{
+		"Aliases!A:B": {{"Alias", "Name"}, {"Dave", "dave"}},
+	})
+	l := f.league()
+	l.ranges.Aliases = "Aliases!A:B"
+	ds, err := l.fetch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ds.Games) != 2 || len(ds.Unscored) != 1 {
+		t.Fatalf("expected the two-headed giant game to be kept out of scoring, got %d scored and %d unscored", len(ds.Games), len(ds.Unscored))
+	}
+	if teams := ds.Unscored[0].Teams; len(teams) != 2 || strings.Join(teams[0], ",") != "dave,erin" || strings.Join(teams[1], ",") != "frank,gus" {
+		t.Fatalf("expected dave and erin to beat frank and gus, got %+v", teams)
+	}
+
+	mux := l.routes()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/games?player=gus", nil))
+	if body := rec.Body.String(); !strings.Contains(body, `"teams":[["dave","erin"],["frank","gus"]]`) {
+		t.Fatalf("expected the game's teams in the game log, got %s", body)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/game/3", nil))
+	if body := rec.Body.String(); rec.Code != http.StatusOK || !strings.Contains(body, `>dave</a> and <a href="/player/erin">erin</a></li>`) {
+		t.Fatalf("expected the game page to list the teams, got %d: %s", rec.Code, body)
+	}
+}
+
+func TestPrizePoolPaysOutSeasonStandings(t *testing.T) {
+	games, err := parseGameData(gameLog)
+	if err != nil {
+		t.Fatalf("failed to parse game data: %v", err)
+	}
+	calculateScores(games)
+
+	f := Finances{GameFee: 500, SeasonFee: 1000, Payouts: []int{70, 30}, Currency: "$"}
+	pool := f.prizePool(games, nil, nil, playerNames{}, time.Now())
+	// 5 game entries by 3 players
+	if pool == nil || pool.Season != "2023" || pool.Total != 5500 {
+		t.Fatalf("expected a $55 pool for 2023, got %+v", pool)
+	}
+	if len(pool.Payouts) != 2 || pool.Payouts[0].Player.ID != "alice" || pool.Payouts[0].Amount != 3850 || pool.Payouts[1].Amount != 1650 {
+		t.Fatalf("expected alice to take 70%%, got %+v", pool.Payouts)
+	}
+	if got := f.format(1650); got != "$16.50" {
+		t.Fatalf("expected $16.50, got %s", got)
+	}
+
+	if _, err := parsePayouts("60,50"); err == nil {
+		t.Error("expected payouts over 100% to be rejected")
+	}
+	if cents, err := parseMoney("2.5"); err != nil || cents != 250 {
+		t.Errorf("expected 250 cents, got %d, %v", cents, err)
+	}
+}
+
+func TestOvertakesReportWhoPassedWhom(t *testing.T) {
+	before := map[string]int{"alice": 1520, "bob": 1510, "carol": 1500}
+	after := map[string]int{"alice": 1505, "bob": 1512, "carol": 1508, "dave": 1530}
+	passed := overtakes(before, after)
+	// dave is new, so they didn't pass anyone, and bob staying ahead of carol
+	// isn't a pass
+	if len(passed) != 2 || passed[0].By != "bob" || passed[1].By != "carol" || passed[0].Player != "alice" {
+		t.Fatalf("expected bob and carol to pass alice, got %+v", passed)
+	}
+	if m := passed[0].Margin(); m != 7 {
+		t.Fatalf("expected bob to be 7 ahead, got %d", m)
+	}
+	body := overtakenBody([]Overtake{{Player: "alice", Name: "Alice", ByName: "Bob", Rating: 1505, ByRating: 1512}})
+	if !strings.Contains(body, "Bob passed you on the leaderboard and is now 7 points ahead, 1512 to your 1505.") {
+		t.Fatalf("unexpected email body:\n%s", body)
+	}
+}
+
+func TestSQLConsoleIsReadOnly(t *testing.T) {
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+	for _, body := range []string{"gg", "wp", "again"} {
+		st.addComment(ctx, &Comment{League: spreadsheetID, Kind: "game", Target: "1", Author: "bob", Body: body, CreatedAt: time.Now()})
+	}
+	h := requireAdmin("secret", consoleHandler(st))
+	query := func(q, limit string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin/sql?"+url.Values{"q": {q}, "limit": {limit}}.Encode(), nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := query("SELECT author, body FROM comments ORDER BY id", "2")
+	var res QueryResult
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("expected rows, got %d: %v", rec.Code, err)
+	}
+	if len(res.Rows) != 2 || !res.Truncated || res.Columns[1] != "body" || res.Rows[0][1] != "gg" {
+		t.Fatalf("expected the first 2 comments, got %+v", res)
+	}
+
+	for _, q := range []string{"DELETE FROM comments", "SELECT 1; DELETE FROM comments", "WITH c AS (SELECT 1) DELETE FROM comments"} {
+		if rec := query(q, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected %q to be rejected, got %d", q, rec.Code)
+		}
+	}
+	if comments, _ := st.comments(ctx, spreadsheetID, "game", "1"); len(comments) != 3 {
+		t.Fatalf("expected the comments to be untouched, got %d", len(comments))
+	}
+	// the connection is writable again after a console query
+	if err := st.addComment(ctx, &Comment{League: spreadsheetID, Kind: "game", Target: "1", Author: "bob", Body: "gl", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSeasonProjections(t *testing.T) {
+	games, err := parseGameData([][]interface{}{
+		gameLog[0],
+		{"1", "Mon, 02 Jan 2023 19:00:00 UTC", "", "", "", "alice", "bob"},
+		{"2", "Mon, 09 Jan 2023 19:00:00 UTC", "", "", "", "alice", "carol", "bob"},
+		{"3", "Mon, 16 Jan 2023 19:00:00 UTC", "", "", "", "bob", "carol"},
+	})
+	if err != nil {
+		t.Fatalf("failed to parse game data: %v", err)
+	}
+	now := time.Date(2023, time.January, 29, 0, 0, 0, 0, time.UTC)
+
+	season, projections, ok := seasonProjections(games, nil, nil, now)
+	if !ok || season.Name != "2023" || len(projections) != 3 {
+		t.Fatalf("expected projections for 2023, got %v %+v", ok, projections)
+	}
+	leader := projections[0]
+	// alice won both of their 2 games in 4 weeks, so they're expected to keep
+	// playing every other week and keep climbing
+	if leader.Player.ID != "alice" || leader.WinsToFirst != 0 || leader.GamesPerWeek != 0.5 || leader.Projected <= leader.Player.Score {
+		t.Fatalf("expected alice to be projected to stay on top, got %+v", leader)
+	}
+	for _, p := range projections[1:] {
+		switch p.Player.ID {
+		case "bob":
+			if p.WinsToFirst < 1 {
+				t.Errorf("expected bob to need wins to catch alice, got %+v", p)
+			}
+		case "carol":
+			if p.WinsToFirst != -1 {
+				t.Errorf("expected carol, who hasn't won, to have no estimate, got %+v", p)
+			}
+		}
+	}
+
+	if _, _, ok := seasonProjections(games, nil, nil, time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)); ok {
+		t.Fatal("expected no projections once the season is over")
+	}
+}
+
+func TestPrintStandings(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	mux := f.league().routes()
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := get("/print")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "<td>alice</td>") || !strings.Contains(body, "Game 2: alice won against carol, bob") {
+		t.Fatalf("expected the printable standings, got %d: %s", rec.Code, body)
+	}
+
+	rec = get("/print?format=pdf")
+	pdf := rec.Body.String()
+	if rec.Header().Get("Content-Type") != "application/pdf" || !strings.HasPrefix(pdf, "%PDF-1.4") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Fatalf("expected a pdf, got %q", rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(pdf, "/Count 2") || !strings.Contains(pdf, "(1     alice") {
+		t.Fatalf("expected a standings page and a recent results page, got:\n%s", pdf)
+	}
+	if got := pdfString("Zoë (\\o/)"); got != `Zo\353 \(\\o/\)` {
+		t.Fatalf("unexpected escaping %q", got)
+	}
+}
+
+func TestTagLeaderboards(t *testing.T) {
+	row := func(id, first, second, tags string) []interface{} {
+		r := make([]interface{}, tagsColumn+1)
+		for idx := range r {
+			r[idx] = ""
+		}
+		r[0], r[1] = id, "Mon, 02 Jan 2023 19:00:00 UTC"
+		r[playerColumn], r[playerColumn+1] = first, second
+		r[tagsColumn] = tags
+		return r
+	}
+	f := newFakeSheets(t, [][]interface{}{
+		gameLog[0],
+		row("1", "alice", "bob", "cEDH, budget-night"),
+		row("2", "bob", "alice", "cedh"),
+		row("3", "bob", "carol", "CEDH"),
+		row("4", "carol", "alice", ""),
+	})
+	mux := f.league().routes()
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Body.String()
+	}
+
+	body := get("/?tag=cEDH")
+	if !strings.Contains(body, "over the 3 games tagged cedh") || !strings.Contains(body, `<a href="/?tag=budget-night">budget-night</a> <small>(1)</small>`) {
+		t.Fatalf("expected the cedh leaderboard with the other tags linked, got:\n%s", body)
+	}
+	if bob, alice := strings.Index(body, ">bob</a>"), strings.Index(body, ">alice</a>"); bob < 0 || alice < 0 || bob > alice {
+		t.Fatalf("expected bob to lead the cedh games, having won two of them, got:\n%s", body)
+	}
+
+	if body := get("/api/games?tag=budget-night"); !strings.Contains(body, `"tags":["cedh","budget-night"]`) || strings.Contains(body, `"id":"2"`) {
+		t.Fatalf("expected only the budget night game, got %s", body)
+	}
+}
+
+func TestFieldPlacement(t *testing.T) {
+	rankings := rankPlayers(nil, map[string]int{"alice": 1700, "bob": 1500, "carol": 1300}, time.Now())
+	// an average player finishes in the middle of a 4 player pod
+	if got := placing(expectedPlacement(1500, fieldRatings(map[string]int{"a": 1400, "b": 1600}))); got != "2.5th" {
+		t.Fatalf("expected an average player to finish 2.5th, got %s", got)
+	}
+	if rankings[0].ID != "alice" || rankings[0].FieldPlacement >= 2 || rankings[2].FieldPlacement <= 3 {
+		t.Fatalf("expected alice to typically finish above 2nd and carol below 3rd, got %+v", rankings)
+	}
+	for place, want := range map[float64]string{1.96: "2nd", 2.3: "2.3rd", 3.14: "3.1st"} {
+		if got := placing(place); got != want {
+			t.Errorf("expected %v to be %s, got %s", place, want, got)
+		}
+	}
+}
+
+func TestCurveReport(t *testing.T) {
+	games := []*Game{
+		{ID: "1", Timestamp: time.Date(2023, 1, 2, 19, 0, 0, 0, time.UTC), Rankings: []string{"alice", "bob"}},
+		{ID: "2", Timestamp: time.Date(2023, 1, 9, 19, 0, 0, 0, time.UTC), Rankings: []string{"alice", "bob", "carol", "dave"}},
+		{ID: "3", Timestamp: time.Date(2023, 2, 6, 19, 0, 0, 0, time.UTC), Rankings: []string{"bob", "alice", "dave", "carol"}},
+		{ID: "4", Timestamp: time.Date(2023, 2, 13, 19, 0, 0, 0, time.UTC), Rankings: []string{"carol", "dave"}, DrawGame: "TRUE"},
+	}
+	calculateScores(games)
+
+	sizes := curveReport(games)
+	if len(sizes) != 2 || sizes[0].Size != 2 || sizes[0].Games != 1 || sizes[1].Size != 4 || sizes[1].Games != 2 {
+		t.Fatalf("expected a 2 player game and two 4 player games without the draw, got %+v", sizes)
+	}
+	four := sizes[1]
+	if math.Abs(four.Share-2.0/3) > 1e-9 || len(four.Places) != 4 || four.Places[1].Reward != 0.5 {
+		t.Fatalf("expected the 4 player curve, got %+v", four)
+	}
+	if four.Places[0].MeanDelta <= 0 || four.Places[3].MeanDelta >= 0 || four.Places[0].Expected == 0 {
+		t.Fatalf("expected winners to gain and last places to lose, got %+v", four.Places)
+	}
+	if len(four.Drift) != 2 || four.Drift[0].Month != "2023-01" || four.Drift[0].Games != 1 || four.Chart == "" {
+		t.Fatalf("expected a month of drift for each month played, got %+v", four.Drift)
+	}
+
+	f := newFakeSheets(t, gameLog)
+	rec := httptest.NewRecorder()
+	f.league().routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/curves?season=2023", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "3 players") {
+		t.Fatalf("expected the curve report, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAuditFindsLeakingGames(t *testing.T) {
+	ds := &Dataset{Games: []*Game{
+		{ID: "1", Rankings: []string{"alice", "bob", "carol", "dave"}},
+		{ID: "2", Rankings: []string{"bob", "alice"}},
+	}}
+	ds.resolvePlayerIDs()
+
+	c := ds.copy()
+	calculateScores(c.Games)
+	leaks, total := auditZeroSum(c.Games, 2)
+	if len(leaks) != 1 || leaks[0].Game.ID != "1" || leaks[0].Net != -8 || total != -8 {
+		t.Fatalf("expected the 4 player game to leak 8 points, got %+v and %d", leaks, total)
+	}
+	var report strings.Builder
+	printAuditReport(&report, c.Games, leaks, total, c.Names)
+	if !strings.Contains(report.String(), "audited 2 games: 1 leak rating points, -8 points net") {
+		t.Fatalf("unexpected report:\n%s", report.String())
+	}
+
+	zeroSum = true
+	defer func() { zeroSum = false }()
+	c = ds.copy()
+	scores := calculateScores(c.Games)
+	if leaks, total := auditZeroSum(c.Games, 0); len(leaks) != 0 || total != 0 {
+		t.Fatalf("expected renormalized games to be zero-sum, got %+v and %d", leaks, total)
+	}
+	if sum := scores["alice"] + scores["bob"] + scores["carol"] + scores["dave"]; sum != 4*startingRating {
+		t.Fatalf("expected the ratings to still sum to %d, got %d", 4*startingRating, sum)
+	}
+}
+
+func TestMigrateSheetToSQLite(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	players := map[string][][]interface{}{"Players!A:C": {{"ID", "Name"}, {"alice", "Alice"}}}
+	f.serveRows(gameLog, players)
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+	l := f.league()
+	l.ranges.Players = "Players!A:C"
+
+	results, games, err := migrateSheet(context.Background(), l, st)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if len(results) != 2 || results[0].Added != len(gameLog) || results[1].Added != 2 || !results[0].Verified || !results[1].Verified || games != 3 {
+		t.Fatalf("expected both tabs to be imported and verified, got %+v with %d games", results, games)
+	}
+	var report strings.Builder
+	if !printMigrateReport(&report, results, games) || !strings.Contains(report.String(), "imported 2 tabs with 3 games") {
+		t.Fatalf("unexpected report:\n%s", report.String())
+	}
+
+	// importing again changes nothing
+	results, _, err = migrateSheet(context.Background(), l, st)
+	if err != nil || results[0].Added+results[0].Changed+results[0].Removed != 0 || !results[0].Verified {
+		t.Fatalf("expected a second import to change nothing, got %+v: %v", results, err)
+	}
+
+	// and syncs the rows that changed since
+	rows := append([][]interface{}{}, gameLog[:2]...)
+	rows = append(rows, []interface{}{"2", "Mon, 09 Jan 2023 19:00:00 UTC", "", "", "", "carol", "alice"})
+	f.serveRows(rows, players)
+	results, games, err = migrateSheet(context.Background(), l, st)
+	if err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	if r := results[0]; r.Rows != 3 || r.Added != 0 || r.Changed != 1 || r.Removed != len(gameLog)-3 || !r.Verified || games != 2 {
+		t.Fatalf("expected the changed and removed rows to be synced, got %+v with %d games", r, games)
+	}
+}
+
+func TestArchivesWeeklyStandings(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+	l := f.league()
+	l.archive = st
+	mux := l.routes()
+
+	// the week of Jan 2, 2023 ended before Wednesday the 11th, with 1 game played
+	now := time.Date(2023, 1, 11, 12, 0, 0, 0, time.UTC)
+	if err := archiveWeek(context.Background(), l, now); err != nil {
+		t.Fatalf("failed to archive the week: %v", err)
+	}
+	if err := archiveWeek(context.Background(), l, now.Add(time.Hour)); err != nil {
+		t.Fatalf("failed to check the archived week: %v", err)
+	}
+	if weeks, err := st.archivedWeeks(context.Background(), l.spreadsheetID); err != nil || len(weeks) != 1 || weeks[0] != "2023-W01" {
+		t.Fatalf("expected week 2023-W01 to be archived once, got %v: %v", weeks, err)
+	}
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	if rec := serve("/archive"); !strings.Contains(rec.Body.String(), `href="/archive/2023-W01"`) {
+		t.Fatalf("expected the archive to list the week, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec := serve("/archive/2023-W01")
+	if body := rec.Body.String(); !strings.Contains(body, "1 games as of Jan 8, 2023") || !strings.Contains(body, "alice") || strings.Contains(body, "carol") {
+		t.Fatalf("expected the standings after the first game, got %d: %s", rec.Code, body)
+	}
+	if rec := serve("/archive/2023-W01.png"); rec.Header().Get("Content-Type") != "image/png" || rec.Body.Len() == 0 {
+		t.Fatalf("expected the week's image, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec := serve("/archive/2023-W02"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a week that isn't archived, got %d", rec.Code)
+	}
+}
+
+func TestGamePageLinksToSheetRow(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	l := f.league()
+	l.gameLogGID = "1234"
+
+	rec := httptest.NewRecorder()
+	l.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/game/2", nil))
+	want := "https://docs.google.com/spreadsheets/d/" + spreadsheetID + "/edit#gid=1234&amp;range=3:3"
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Fatalf("expected a link to game 2's row, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	for rng, want := range map[string]int{"Log!A:X": 1, "Log!A5:X": 5, "'Game log'!$A$12:$X$400": 12, "A2:X": 2} {
+		if _, _, got := sheets.RangeStart(rng); got != want {
+			t.Fatalf("expected %q to start at row %d, got %d", rng, want, got)
+		}
+	}
+}
+
+func TestPublicProfilesAreOptIn(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	profiles := filepath.Join(t.TempDir(), "profiles.json")
+	err := os.WriteFile(profiles, []byte(`[
+		{"name": "alice", "publicProfile": true, "publicGames": true},
+		{"name": "bob", "publicProfile": true},
+		{"name": "carol", "email": "carol@example.com"}
+	]`), 0600)
+	if err != nil {
+		t.Fatalf("failed to write profiles: %v", err)
+	}
+	l := f.league()
+	l.profilesPath = profiles
+	mux := l.routes()
+
+	profile := func(name string) (*httptest.ResponseRecorder, PublicProfile) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/players/"+name, nil))
+		var pp PublicProfile
+		json.Unmarshal(rec.Body.Bytes(), &pp)
+		return rec, pp
+	}
+
+	rec, alice := profile("alice")
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("expected alice's profile to be embeddable, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if alice.Rank != 1 || len(alice.History) != 2 || alice.History[1].Rating != alice.Rating || len(alice.Recent) != 2 || alice.Recent[0].ID != "2" {
+		t.Fatalf("unexpected profile for alice: %+v", alice)
+	}
+	if rec, bob := profile("bob"); rec.Code != http.StatusOK || len(bob.History) != 2 || bob.Recent != nil {
+		t.Fatalf("expected bob's profile without their games, got %d: %s", rec.Code, rec.Body.String())
+	}
+	for _, name := range []string{"carol", "dave", "nobody"} {
+		if rec, _ := profile(name); rec.Code != http.StatusNotFound {
+			t.Fatalf("expected no public profile for %s, got %d", name, rec.Code)
+		}
+	}
+}
+
+func TestHouseLeaderboard(t *testing.T) {
+	f := newFakeSheets(t, nil)
+	f.serveRows(gameLog, map[string][][]interface{}{
+		"Houses!A:B": {{"House", "Player"}, {"North", "alice"}, {"South", "bob"}, {"North", "carol"}, {"South", "zoe"}, {"West", "yolanda"}},
+	})
+	l := f.league()
+	l.ranges.Houses = "Houses!A:B"
+
+	ds, err := l.fetch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	scores := calculateScores(ds.Games, ds.Adjustments...)
+	rankings := rankPlayers(ds.Games, scores, time.Now())
+
+	standings := houseStandings(ds.Houses, rankings, "average")
+	if len(standings) != 2 || standings[0].Name != "North" || standings[0].Score != (scores["alice"]+scores["carol"])/2 {
+		t.Fatalf("expected North ranked on alice and carol's average, got %+v", standings)
+	}
+	if south := standings[1]; len(south.Members) != 1 || south.Score != scores["bob"] {
+		t.Fatalf("expected South to count only bob, who has played, got %+v", south)
+	}
+	if best := houseStandings(ds.Houses, rankings, "best-1"); best[0].Score != scores["alice"] || best[0].Counted != 1 {
+		t.Fatalf("expected North rated on its best member, got %+v", best[0])
+	}
+	if err := parseHouseScoring("best-0"); err == nil {
+		t.Fatalf("expected best-0 to be rejected")
+	}
+
+	rec := httptest.NewRecorder()
+	l.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "<h2>Houses</h2>") || !strings.Contains(body, "North") {
+		t.Fatalf("expected the house leaderboard on the index, got %s", body)
+	}
+}
+
+func TestSchemaDiffFlagsShiftedColumns(t *testing.T) {
+	if mismatches := schemaDiff(gameLog); len(mismatches) != 0 {
+		t.Fatalf("expected the usual layout to match, got %v", mismatches)
+	}
+
+	// a week column inserted before the date shifts everything after it
+	shifted := [][]interface{}{
+		{"Game", "Week", "Date", "Zap", "Draw", "Notes", "1st", "2nd"},
+		{"1", "1", "Mon, 02 Jan 2023 19:00:00 UTC", "", "", "", "alice", "bob"},
+		{"2", "2", "Mon, 09 Jan 2023 19:00:00 UTC", "", "", "", "carol", "alice"},
+	}
+	f := newFakeSheets(t, shifted)
+	rec := httptest.NewRecorder()
+	schemaHandler(f.league())(rec, httptest.NewRequest(http.MethodGet, "/admin/schema", nil))
+	var res struct {
+		OK         bool
+		Mismatches []ColumnMismatch
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || res.OK || len(res.Mismatches) == 0 {
+		t.Fatalf("expected the shifted layout to be flagged, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if m := res.Mismatches[0]; m.Column != "B" || m.Expected != "date" || m.Inferred != "id" {
+		t.Fatalf("expected the week column to be flagged first, got %+v", m)
+	}
+
+	var report strings.Builder
+	printValidationReport(&report, shifted)
+	if want := `row 1: warning: column F ("Notes") is read as player but looks like notes`; !strings.Contains(report.String(), want) {
+		t.Fatalf("expected report to contain %q, got:\n%s", want, report.String())
+	}
+}
+
+func TestFetchInChunks(t *testing.T) {
+	defer func(n int) { fetchChunkRows = n }(fetchChunkRows)
+	fetchChunkRows = 2
+
+	f := newFakeSheets(t, gameLog)
+	l := f.league()
+	ds, err := l.fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rows 1-2, 3-4 and 5, which comes back short
+	if f.requestCount() != 3 {
+		t.Fatalf("expected 3 sheets requests, got %d", f.requestCount())
+	}
+	if len(ds.Games) != 2 || len(ds.Unscored) != 1 {
+		t.Fatalf("expected 2 scored and 1 unscored game, got %d and %d", len(ds.Games), len(ds.Unscored))
+	}
+	if g := ds.Games[1]; g.ID != "2" || g.Row != 3 {
+		t.Fatalf("expected game 2 on row 3, got game %s on row %d", g.ID, g.Row)
+	}
+
+	rec := httptest.NewRecorder()
+	fetchProgressHandler(l)(rec, httptest.NewRequest(http.MethodGet, "/admin/fetch", nil))
+	var res struct {
+		Progress FetchProgress
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if p := res.Progress; p.Running || p.Chunks != 3 || p.Rows != len(gameLog) || p.Games != 3 {
+		t.Fatalf("expected a finished fetch of 3 chunks, got %+v", p)
+	}
+}
+
+func TestErrorPages(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+
+	rec := get(t, f, "/nowhere")
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "<h1>Not Found</h1>") {
+		t.Fatalf("expected the 404 page for an unknown path, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = get(t, f, "/?start=yesterday")
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid start date") {
+		t.Fatalf("expected a 400 for a bad start date, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), ">alice</a>") {
+		t.Fatal("expected only the error page after a bad start date, got the leaderboard too")
+	}
+	if f.requestCount() != 0 {
+		t.Fatalf("expected a bad request not to fetch the sheet, got %d requests", f.requestCount())
+	}
+
+	rec = get(t, f, "/?start="+url.QueryEscape("Sun, 08 Jan 2023 00:00:00 UTC"))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), ">carol</a>") {
+		t.Fatalf("expected the leaderboard for a valid start date, got %d", rec.Code)
+	}
+
+	f.serveError(http.StatusServiceUnavailable, "UNAVAILABLE", "The service is currently unavailable.")
+	rec = get(t, f, "/")
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "<h1>Internal Server Error</h1>") {
+		t.Fatalf("expected the 500 page for an upstream failure, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	l := f.league()
+	l.sessions = &sessions{key: []byte("0123456789abcdef0123456789abcdef")}
+	mux := l.routes()
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/refresh", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "POST" || !strings.Contains(rec.Body.String(), "<h1>Method Not Allowed</h1>") {
+		t.Fatalf("expected the 405 page for the wrong method, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/refresh", nil))
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "log in to refresh") {
+		t.Fatalf("expected the 401 page for an anonymous refresh, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRulesPage(t *testing.T) {
+	f := newFakeSheets(t, nil)
+	f.serveRows(gameLog, map[string][][]interface{}{
+		"Houses!A:B": {{"House", "Player"}, {"North", "bob"}},
+		"Rules!A:A": {
+			{"# House rules"},
+			{"Games are **best of one**, see [the banlist](https://example.com/bans)."},
+			{},
+			{"- No proxies"},
+			{"- <script>alert(1)</script>"},
+			{"1. Roll for turn order"},
+		},
+	})
+	l := f.league()
+	l.ranges.Houses = "Houses!A:B"
+	l.ranges.Rules = "Rules!A:A"
+
+	rec := httptest.NewRecorder()
+	rulesHandler(l)(rec, httptest.NewRequest(http.MethodGet, "/rules", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"<h2>House rules</h2>",
+		`<strong>best of one</strong>, see <a href="https://example.com/bans">the banlist</a>.`,
+		"<li>No proxies</li>\n  <li>&lt;script&gt;",
+		"<ol>\n  <li>Roll for turn order</li>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected rules page to contain %q, got:\n%s", want, body)
+		}
+	}
+
+	// the rules tab is fetched last, after the houses tab rename rewrites
+	res, err := l.renamePlayer(context.Background(), nil, "bob", "robert", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	houses := 0
+	for _, c := range res.Cells {
+		if c.Tab == "houses" {
+			houses++
+		}
+	}
+	if houses != 1 {
+		t.Fatalf("expected bob renamed in the houses tab, got %+v", res.Cells)
+	}
+}
+
+func TestDubiousHonors(t *testing.T) {
+	honors := func(firstBlood, firstOut, kingmaker string) []interface{} {
+		row := make([]interface{}, kingmakerColumn+1)
+		for i := range row {
+			row[i] = ""
+		}
+		row[firstBloodColumn], row[firstOutColumn], row[kingmakerColumn] = firstBlood, firstOut, kingmaker
+		return row
+	}
+	rows := [][]interface{}{gameLog[0]}
+	for _, h := range []struct {
+		players                         []string
+		firstBlood, firstOut, kingmaker string
+	}{
+		{[]string{"alice", "bob", "carol"}, "bob", "", "carol"},
+		{[]string{"alice", "carol", "bob"}, "bob", "alice", ""},
+		{[]string{"carol", "alice", "bob"}, "Carol", "", ""},
+	} {
+		row := honors(h.firstBlood, h.firstOut, h.kingmaker)
+		row[0], row[1] = strconv.Itoa(len(rows)), "Mon, 02 Jan 2023 19:00:00 UTC"
+		for i, p := range h.players {
+			row[playerColumn+i] = p
+		}
+		rows = append(rows, row)
+	}
+	f := newFakeSheets(t, nil)
+	f.serveRows(rows, map[string][][]interface{}{
+		"Players!A:C": {{"ID", "Name", "Other names"}, {"carol", "Carol"}},
+	})
+	l := f.league()
+	l.ranges.Players = "Players!A:C"
+
+	ds, err := l.fetch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := dubiousHonors(ds.Games)
+	if fb := got.FirstBlood; len(fb) != 2 || fb[0].Player != "bob" || fb[0].Count != 2 || fb[1].Player != "carol" {
+		t.Fatalf("expected bob to lead first bloods ahead of carol, got %+v", fb)
+	}
+	// game 2 records alice as first out even though bob finished last
+	if fo := got.FirstOut; len(fo) != 3 || fo[0].Player != "alice" || fo[0].Count != 1 || fo[0].Rate != 1.0/3 {
+		t.Fatalf("expected one first out each, got %+v", fo)
+	}
+	if km := got.Kingmaker; len(km) != 1 || km[0].Player != "carol" {
+		t.Fatalf("expected carol as the only kingmaker, got %+v", km)
+	}
+
+	res, err := l.renamePlayer(context.Background(), nil, "bob", "robert", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := len(res.Cells); n != 5 {
+		t.Fatalf("expected bob renamed in 3 player and 2 first blood cells, got %+v", res.Cells)
+	}
+}
+
+func TestPodStrength(t *testing.T) {
+	var games []*Game
+	for _, avg := range []int{1600, 1400, 1800, 1500, 1700} {
+		games = append(games, &Game{RankAverage: avg, Results: []Result{{Player: "alice"}}})
+	}
+	scale := newPodScale(append(games, &Game{ID: "unscored"}))
+	for idx, want := range []string{"typical pod", "casual pod", "high-stakes pod", "typical pod", "strong pod"} {
+		if got := scale.strength(games[idx]); got == nil || got.Label != want {
+			t.Fatalf("expected a rank average of %d to be a %s, got %+v", games[idx].RankAverage, want, got)
+		}
+	}
+	if got := scale.strength(&Game{ID: "unscored"}); got != nil {
+		t.Fatalf("expected no strength for an unscored game, got %+v", got)
+	}
+
+	f := newFakeSheets(t, gameLog)
+	rec := httptest.NewRecorder()
+	gamesHandler(f.league())(rec, httptest.NewRequest(http.MethodGet, "/api/games", nil))
+	var res struct {
+		Games []GameRecord
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if g := res.Games[0]; g.RankAverage != 1500 || g.RankTotal != 3000 || g.Strength == nil {
+		t.Fatalf("expected game 1's pod of two unrated players in the game log, got %+v", g)
+	}
+}
+
+func TestReplayFrames(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	l := f.league()
+	ds, err := l.fetch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sort.Sort(ByID(ds.Games))
+
+	frames := replayFrames(context.Background(), l, ds)
+	if len(frames) != 2 || frames[0].Day != "2023-01-02" || frames[1].Total != 2 {
+		t.Fatalf("expected a frame for each of the two game nights, got %+v", frames)
+	}
+	var carol, alice ReplayEntry
+	for _, e := range frames[1].Top {
+		switch e.ID {
+		case "carol":
+			carol = e
+		case "alice":
+			alice = e
+		}
+	}
+	if !carol.New || alice.New || alice.Change <= 0 {
+		t.Fatalf("expected carol new and alice gaining on the second night, got %+v", frames[1].Top)
+	}
+
+	rec := httptest.NewRecorder()
+	replayHandler(l)(rec, httptest.NewRequest(http.MethodGet, "/replay", nil))
+	if body := rec.Body.String(); !strings.Contains(body, `"day":"2023-01-09"`) {
+		t.Fatalf("expected the frames in the replay page, got:\n%s", body)
+	}
+}
+
+func TestMaintenanceMode(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	l := f.league()
+	mux := l.routes()
+	toggle := func(form url.Values) MaintenanceStatus {
+		req := httptest.NewRequest(http.MethodPost, "/admin/maintenance", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		maintenanceHandler(l)(rec, req)
+		var status MaintenanceStatus
+		if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+			t.Fatalf("failed to decode the maintenance status: %v", err)
+		}
+		return status
+	}
+
+	if s := toggle(url.Values{"enabled": {"true"}}); !s.Enabled || s.Message != defaultMaintenanceMessage {
+		t.Fatalf("expected maintenance mode on with the default message, got %+v", s)
+	}
+
+	// the frozen data is served without going back to the sheet
+	requests := f.requestCount()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), defaultMaintenanceMessage) {
+		t.Fatalf("expected the leaderboard with the banner, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+	if f.requestCount() != requests {
+		t.Fatalf("expected no fetches in maintenance mode, got %d", f.requestCount()-requests)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/comments", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected writes to be rejected in maintenance mode, got %d", rec.Code)
+	}
+
+	if s := toggle(url.Values{"enabled": {"false"}}); s.Enabled {
+		t.Fatalf("expected maintenance mode off, got %+v", s)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if strings.Contains(rec.Body.String(), defaultMaintenanceMessage) || f.requestCount() == requests {
+		t.Fatalf("expected live data without the banner once maintenance mode is off")
+	}
+}
+
+func TestSetupWizard(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	path := filepath.Join(t.TempDir(), "scoreboard.env")
+	wizard := newSetupWizard(path, "s3cret", f.options()...)
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		if _, ok := form["token"]; !ok {
+			form.Set("token", "s3cret")
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		wizard.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// only someone with the token from the log can use the wizard
+	requests := f.requestCount()
+	for _, token := range []string{"", "guess"} {
+		if rec := post(url.Values{"token": {token}, "step": {"check"}, "sheetID": {spreadsheetID}, "apiKey": {"key"}}); rec.Code != http.StatusForbidden {
+			t.Fatalf("expected the wizard to refuse a request with token %q, got %d", token, rec.Code)
+		}
+	}
+	if f.requestCount() != requests {
+		t.Fatalf("expected the sheet not to be read without the token")
+	}
+	rec := httptest.NewRecorder()
+	wizard.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?token=s3cret", nil))
+	if body := rec.Body.String(); rec.Code != http.StatusOK || !strings.Contains(body, `name="token" value="s3cret"`) {
+		t.Fatalf("expected the wizard's form to carry the token, got %d:\n%s", rec.Code, body)
+	}
+
+	if rec := post(url.Values{"step": {"check"}, "sheetID": {"not a sheet"}, "apiKey": {"key"}}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected an invalid sheet ID to be rejected, got %d", rec.Code)
+	}
+	sheet := url.Values{"sheetID": {"https://docs.google.com/spreadsheets/d/" + spreadsheetID + "/edit#gid=0"}, "apiKey": {"key"}}
+	check := url.Values{"step": {"check"}}
+	for k, v := range sheet {
+		check[k] = v
+	}
+	rec = post(check)
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "has 3 games") || !strings.Contains(body, `value="`+spreadsheetID+`"`) {
+		t.Fatalf("expected the sheet to be checked, got %d:\n%s", rec.Code, body)
+	}
+	if !strings.Contains(body, "<option selected>rfc1123</option>") {
+		t.Fatalf("expected the date format to be detected, got:\n%s", body)
+	}
+
+	save := url.Values{"step": {"save"}, "sheetID": {spreadsheetID}, "apiKey": {"key"}, "kFactor": {"24"}, "dateFormat": {"rfc1123"}}
+	if rec := post(save); rec.Code != http.StatusOK {
+		t.Fatalf("expected the config to be saved, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+	select {
+	case <-wizard.saved:
+	default:
+		t.Fatalf("expected the wizard to finish once the config is saved")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("expected the config file to be written: %v", err)
+	}
+	defer file.Close()
+	vars, err := parseConfigFile(file)
+	if err != nil {
+		t.Fatalf("failed to parse the config file: %v", err)
+	}
+	if vars["SCOREBOARD_SHEET_ID"] != spreadsheetID || vars["SCOREBOARD_K_FACTOR"] != "24" || vars["SCOREBOARD_GAMES_RANGE"] != readRange || vars["SCOREBOARD_ENGINE"] != "elo" {
+		t.Fatalf("unexpected config %v", vars)
+	}
+	if rec := post(save); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected an existing config file not to be overwritten, got %d", rec.Code)
+	}
+}
+
+func TestDraftEvents(t *testing.T) {
+	f := newFakeSheets(t, nil)
+	f.serveRows(gameLog, map[string][][]interface{}{
+		"Events!A:H": {
+			{"Event", "Date", "Pod", "Round", "Player", "Opponent", "Result"},
+			{"MOM draft", "2023-05-06", "Pod 1", "", "alice", "bob", "carol", "dave"},
+			{"MOM draft", "2023-05-06", "Pod 1", "1", "alice", "bob", "2-0"},
+			{"MOM draft", "2023-05-06", "Pod 1", "1", "carol", "dave", "2-1"},
+			{"MOM draft", "2023-05-06", "Pod 1", "2", "alice", "carol", "2-1"},
+			{"MOM draft", "2023-05-06", "Pod 1", "2", "bob", "dave", "1-1-1"},
+			{"MOM draft", "2023-05-06", "Pod 2", "1", "erin", "", ""},
+		},
+	})
+	l := f.league()
+	l.ranges.Events = "Events!A:H"
+
+	ds, err := l.fetch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ds.Events) != 1 || len(ds.Events[0].Pods) != 2 || len(ds.Events[0].Pods[0].Seats) != 4 {
+		t.Fatalf("expected one event with two pods, got %+v", ds.Events)
+	}
+
+	standings := podStandings(ds.Events[0].Pods[0], ds.Names)
+	var order []string
+	for _, s := range standings {
+		order = append(order, s.Player+":"+strconv.Itoa(s.Points))
+	}
+	// bob and dave drew for a point each, bob's opponents won more
+	if got := strings.Join(order, " "); got != "alice:6 carol:3 bob:1 dave:1" {
+		t.Fatalf("unexpected standings %s", got)
+	}
+	if bye := podStandings(ds.Events[0].Pods[1], ds.Names); len(bye) != 1 || bye[0].Points != 3 {
+		t.Fatalf("expected a bye to count as a win, got %+v", bye)
+	}
+
+	defer func(enabled bool) { limitedElo = enabled }(limitedElo)
+	limitedElo = true
+	rankings := limitedRankings(ds.Events, ds.Names)
+	if len(rankings) != 4 || rankings[0].ID != "alice" || rankings[0].Games != 2 || rankings[0].Score <= startingRating {
+		t.Fatalf("expected alice atop the limited pool, got %+v", rankings)
+	}
+
+	mux := l.routes()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if body := rec.Body.String(); !strings.Contains(body, `/events/1">MOM draft</a>`) || !strings.Contains(body, "Limited ratings") {
+		t.Fatalf("expected the events and limited ratings, got:\n%s", body)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events/1", nil))
+	if body := rec.Body.String(); rec.Code != http.StatusOK || !strings.Contains(body, "Round 2: bob 1-1-1 dave") {
+		t.Fatalf("expected the event's matches, got %d:\n%s", rec.Code, body)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events/2", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected an unknown event to be a 404, got %d", rec.Code)
+	}
+}
+
+func TestAttendance(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 1, d, 19, 0, 0, 0, time.UTC) }
+	games := []*Game{
+		{ID: "1", Timestamp: day(2), Rankings: []string{"alice", "bob"}},
+		{ID: "2", Timestamp: day(2), Rankings: []string{"alice", "carol"}},
+		{ID: "3", Timestamp: day(9), Rankings: []string{"bob", "carol"}},
+		{ID: "4", Timestamp: day(30), Rankings: []string{"carol", "bob"}},
+		{ID: "5", Rankings: []string{"carol", "bob"}},
+	}
+	players, activity := attendance(games, playerNames{})
+	if activity.Nights != 3 || activity.MostPlayers != 3 || len(activity.Months) != 1 || activity.Months[0].Games != 4 {
+		t.Fatalf("unexpected league activity %+v", activity)
+	}
+	byID := map[string]PlayerAttendance{}
+	for _, p := range players {
+		byID[p.ID] = p
+	}
+	if alice := byID["alice"]; alice.Nights != 1 || alice.Participation != 1.0/3 || alice.LongestAbsence != 28 || alice.Games != 2 {
+		t.Fatalf("expected alice to have missed the last two nights, got %+v", alice)
+	}
+	if carol := byID["carol"]; players[2].ID != "alice" || carol.Participation != 1 || carol.LongestAbsence != 21 {
+		t.Fatalf("expected carol to have played every night, got %+v", carol)
+	}
+
+	f := newFakeSheets(t, gameLog)
+	rec := httptest.NewRecorder()
+	f.league().routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/attendance", nil))
+	if body := rec.Body.String(); rec.Code != http.StatusOK || !strings.Contains(body, "3 league nights") {
+		t.Fatalf("expected the attendance page, got %d:\n%s", rec.Code, body)
+	}
+}
+
+// pageTemplates are the page templates, which tests' *testing.T shadows.
+var pageTemplates = t
+
+func TestTypedViewsRender(t *testing.T) {
+	games, _, err := parseGameLog(gameLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := &Player{ID: "alice", Name: "Alice", Score: 1531, Games: 2, Wins: 2}
+	comments := &CommentsView{Names: playerNames{}, Kind: "player", Target: "alice", User: "bob", Path: "/player/alice",
+		Comments: []*Comment{{Author: "bob", Body: "gg"}}}
+	season := Season{Name: "2023", Start: games[0].Timestamp}
+
+	// templates given structs fail on a field that doesn't exist instead of
+	// rendering it empty, like they would with a map
+	views := map[string]interface{}{
+		"index.html.tmpl":  IndexView{View: "rating", Games: games, Rankings: []Player{*alice}, Total: len(games)},
+		"player.html.tmpl": PlayerView{Player: alice, Rank: 1, Names: playerNames{}, Comments: comments},
+		"eras.html.tmpl":   StatsView{Seasons: []Season{season}, Names: playerNames{}, Eras: []EraStats{eraStats(games, season, time.Now()), eraStats(games, season, time.Now())}},
+
+		"error.html.tmpl":       ErrorView{Status: http.StatusNotFound, Title: "Not found", Message: "no such page"},
+		"login.html.tmpl":       LoginView{Next: "/", Errors: "unknown player"},
+		"game.html.tmpl":        GameView{Game: games[0], Names: playerNames{}, Rivalries: [][2]string{{"alice", "bob"}}},
+		"honors.html.tmpl":      HonorsView{Honors: dubiousHonors(games), Names: playerNames{}},
+		"timeline.html.tmpl":    TimelineView{Milestones: leagueMilestones(games), Names: playerNames{}},
+		"pods.html.tmpl":        PodsView{Players: "alice\nbob", Errors: "not enough players"},
+		"preferences.html.tmpl": PreferencesView{Seasons: []Season{season}, PodSizes: []int{3, 4}, Players: []Player{*alice}},
+		"projections.html.tmpl": ProjectionsView{Season: season, OK: true, Projections: []Projection{{Player: *alice}}},
+		"replay.html.tmpl":      ReplayView{},
+		"rules.html.tmpl":       RulesView{},
+		"archive.html.tmpl":     ArchiveView{},
+		"events.html.tmpl":      EventsView{Names: playerNames{}},
+	}
+	for name, view := range views {
+		var b strings.Builder
+		if err := pageTemplates.ExecuteTemplate(&b, name, view); err != nil {
+			t.Errorf("rendering %s: %v", name, err)
+		}
+	}
+}
+
+func TestPlacementAnnotations(t *testing.T) {
+	rows := [][]interface{}{
+		{"id", "date", "zap", "draw", "notes", "p1", "p2", "p3", "p4", "p5", "p6", "e1", "e2", "e3"},
+		{"1", "Mon, 16 Jan 2023 23:00:00 UTC", "", "", "", "bob (3)", "alice(1)", "carol", "", "", "", "", "23:45", "00:30"},
+		{"2", "Mon, 23 Jan 2023 19:00:00 UTC", "", "", "", "alice(2)", "bob(2)", "carol"},
+		{"3", "Mon, 30 Jan 2023 19:00:00 UTC", "", "", "", "alice", "bob(4)", "carol"},
+	}
+	games, err := parseGameData(rows)
+	if err != nil {
+		t.Fatalf("failed to parse game data: %v", err)
+	}
+	for i, want := range []string{"alice,carol,bob", "alice,bob,carol", "alice,bob,carol"} {
+		if got := strings.Join(games[i].Rankings, ","); got != want {
+			t.Errorf("game %s: expected rankings %s, got %s", games[i].ID, want, got)
+		}
+	}
+
+	var report strings.Builder
+	printValidationReport(&report, rows)
+	for _, want := range []string{
+		"row 3 (game 2): error: alice and bob are both placed 2",
+		"row 4 (game 3): error: bob placed 4 in a game of 3 players",
+	} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, report.String())
+		}
+	}
+	if renamed, ok := renameCell("Bob (3)", "bob", "robert"); !ok || renamed != "robert(3)" {
+		t.Errorf("expected the annotation to survive a rename, got %q", renamed)
+	}
+}
+
+func TestPreferences(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	routes := f.league().routes()
+
+	form := url.Values{"podSize": {"3"}, "favorite": {"carol"}, "dark": {"true"}}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/preferences", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	routes.ServeHTTP(rec, req)
+	cookies := rec.Result().Cookies()
+	if rec.Code != http.StatusSeeOther || len(cookies) != 1 || cookies[0].Name != preferencesCookie {
+		t.Fatalf("expected the preferences to be saved in a cookie, got %d %v", rec.Code, cookies)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	routes.ServeHTTP(rec, req)
+	body := rec.Body.String()
+	for _, want := range []string{"Rated from scratch over the 1 games in 3 player pods", `<li class="favorite"><a href="/player/carol">`, `content="dark"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected the board to open with the preferences, missing %q in:\n%s", want, body)
+		}
+	}
+
+	// a link picking its own pod size overrides the preference
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/?podSize=", nil)
+	req.AddCookie(cookies[0])
+	routes.ServeHTTP(rec, req)
+	if body := rec.Body.String(); strings.Contains(body, "Rated from scratch") || !strings.Contains(body, `href="/player/bob"`) {
+		t.Errorf("expected the whole board, got:\n%s", body)
+	}
+}
+
+func TestClockSetsNow(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	l := f.league()
+	l.clock = fixedClock(time.Date(2023, 1, 20, 12, 0, 0, 0, time.UTC))
+	rec := httptest.NewRecorder()
+	indexHandler(l)(rec, httptest.NewRequest(http.MethodGet, "/?view=hot", nil))
+	if body := rec.Body.String(); strings.Contains(body, "No games in the last") || !strings.Contains(body, `href="/player/alice"`) {
+		t.Fatalf("expected the hot board as of 20 Jan 2023 to rate the January games, got:\n%s", body)
+	}
+	if started := l.fetches.snapshot().Started; !started.Equal(l.clock.Now()) {
+		t.Fatalf("expected the fetch to be stamped by the league's clock, got %s", started)
+	}
+
+	l.random = strings.NewReader(strings.Repeat("\x00", 16))
+	if key, err := newPhotoKey(l.random, "image/png"); err != nil || key != strings.Repeat("0", 32)+".png" {
+		t.Fatalf("expected a key read from the league's randomness, got %q %v", key, err)
+	}
+}
+
+func TestSeedingExport(t *testing.T) {
+	routes := newFakeSheets(t, gameLog).league().routes()
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	rec := get("/api/seeding?min=2")
+	want := "seed,player,name,rating,games\n1,alice,alice,"
+	if body := rec.Body.String(); rec.Code != http.StatusOK || !strings.HasPrefix(body, want) || strings.Count(body, "\n") != 3 {
+		t.Fatalf("expected alice and bob seeded, got %d:\n%s", rec.Code, body)
+	}
+
+	rec = get("/api/seeding?format=challonge&top=2")
+	if body := rec.Body.String(); body != "alice\ncarol\n" {
+		t.Fatalf("expected the top two names in seed order, got %q", body)
+	}
+
+	rec = get("/api/seeding?format=wer&top=1")
+	if body := rec.Body.String(); !strings.HasPrefix(body, "Seed\tName\tRating\tGames\n1\talice\t") {
+		t.Fatalf("expected a tab-separated list, got %q", body)
+	}
+
+	if rec := get("/api/seeding?format=xml"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected an unknown format to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestListenOnUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scoreboard.sock")
+	ln, err := web.Listen(web.UnixPrefix + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := web.Listen(web.UnixPrefix + path); err == nil {
+		t.Fatalf("expected a socket in use to be refused")
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })}
+	go srv.Serve(ln)
+	client := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "unix", path)
+	}}}
+	res, err := client.Get("http://scoreboard/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	srv.Close()
+
+	// a socket left behind by a server that's gone is replaced
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	if ln, err = web.Listen(web.UnixPrefix + path); err != nil {
+		t.Fatalf("expected the stale socket to be replaced, got %v", err)
+	}
+	ln.Close()
+
+	if err := os.WriteFile(path+".txt", nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := web.Listen(web.UnixPrefix + path + ".txt"); err == nil {
+		t.Fatalf("expected a file that isn't a socket to be left alone")
+	}
+}
+
+func TestNightRecap(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 1, d, 19, 0, 0, 0, time.UTC) }
+	games := []*Game{
+		{ID: "1", Timestamp: day(2), Rankings: []string{"alice", "bob", "carol"}},
+		{ID: "2", Timestamp: day(2), Rankings: []string{"alice", "carol", "bob"}},
+		{ID: "3", Timestamp: day(9), Rankings: []string{"carol", "alice", "bob", "dave", "erin"}, TableZap: "TRUE"},
+	}
+	calculateScores(games)
+	recap, ok := nightRecap(games, playerNames{"carol": "Carol"})
+	if !ok {
+		t.Fatalf("expected a recap")
+	}
+	for _, want := range []string{
+		"1 game was played on Mon 9 Jan by 5 players.",
+		"Carol climbed 1 spot to #2 after zapping a 5-player pod.",
+		"Welcome to dave and erin, who played their first games.",
+	} {
+		if !strings.Contains(strings.Join(recap.Lines, "\n"), want) {
+			t.Errorf("expected the recap to say %q, got:\n%s", want, strings.Join(recap.Lines, "\n"))
+		}
+	}
+
+	f := newFakeSheets(t, gameLog)
+	if body := get(t, f, "/").Body.String(); !strings.Contains(body, "Last league night") {
+		t.Fatalf("expected the leaderboard to show the recap, got:\n%s", body)
+	}
+}
+
+func TestTrialEngineSideBySide(t *testing.T) {
+	trialEngine = "points"
+	defer func() { trialEngine = "" }()
+
+	f := newFakeSheets(t, gameLog)
+	body := get(t, f, "/").Body.String()
+	if !strings.Contains(body, "The points rating engine is on trial") || strings.Count(body, "points: ") != 3 {
+		t.Fatalf("expected each player's points rating next to their elo rating, got:\n%s", body)
+	}
+	ds, err := f.league().fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	adjustments := []*Adjustment{{Player: "alice", Amount: -100}}
+	calculateScores(ds.Games, adjustments...)
+	before, adjusted := ds.Games[0].Results[0].After, *adjustments[0]
+	trialScores(ds.Games, adjustments)
+	if ds.Games[0].Results[0].After != before {
+		t.Fatalf("expected the trial to leave the games' elo results alone")
+	}
+	if *adjustments[0] != adjusted {
+		t.Fatalf("expected the trial to leave the adjustment's elo ratings alone, got %+v", adjustments[0])
+	}
+}
+
+func TestPlayerPagesArePrecomputed(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	l := f.league()
+	routes := l.routes()
+	rec := httptest.NewRecorder()
+	routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/player/alice", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "#1 of 3") {
+		t.Fatalf("expected alice's page, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+
+	ds, err := l.fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view, cached := l.players.get(datasetVersion(ds), "carol"); !cached || view == nil || view.Player.Name != "carol" {
+		t.Fatalf("expected every player's page to be computed, got %v %v", view, cached)
+	}
+	rec = httptest.NewRecorder()
+	routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/player/zed", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected an unknown player to be a 404, got %d", rec.Code)
+	}
+
+	l.players.clear()
+	if _, cached := l.players.get(datasetVersion(ds), "carol"); cached {
+		t.Fatalf("expected clearing to drop the computed pages")
+	}
+}
+
+func TestPlayerPagesRequeueWhenTheQueueIsFull(t *testing.T) {
+	l := newFakeSheets(t, gameLog).league()
+	ds, err := l.fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a queue without room for another job
+	defer func() { backgroundJobs = nil }()
+	backgroundJobs = &jobQueue{pending: make(chan *Job), clock: systemClock{}}
+	l.precomputePlayers(ds)
+	if jobs := backgroundJobs.snapshot(); len(jobs) != 1 || jobs[0].Status != jobFailed || jobs[0].LastError != "the job queue is full" {
+		t.Fatalf("expected the precompute to be rejected, got %+v", jobs)
+	}
+	if l.players.pending != "" {
+		t.Fatalf("expected a rejected precompute not to be pending, got %q", l.players.pending)
+	}
+
+	backgroundJobs = nil
+	l.precomputePlayers(ds)
+	if _, cached := l.players.get(datasetVersion(ds), "alice"); !cached {
+		t.Fatal("expected the next request to queue the precompute again")
+	}
+}
+
+func TestExportStreamsGameLog(t *testing.T) {
+	l := newFakeSheets(t, gameLog).league()
+	l.clock = fixedClock(time.Date(2023, 1, 20, 12, 0, 0, 0, time.UTC))
+	routes := l.routes()
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	rec := get("/api/export")
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if rec.Code != http.StatusOK || len(lines) != 3 {
+		t.Fatalf("expected a line per game, got %d:\n%s", rec.Code, rec.Body)
+	}
+	var first GameRecord
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || strings.Join(first.Players, ",") != "alice,bob" {
+		t.Fatalf("expected the first game first, got %s (%v)", lines[0], err)
+	}
+	disposition := rec.Header().Get("Content-Disposition")
+	if want := "-2023-01-20.ndjson\""; !strings.HasSuffix(disposition, want) || !strings.Contains(disposition, rec.Header().Get("X-Dataset-Version")) {
+		t.Fatalf("expected the filename to have the dataset version and date, got %q", disposition)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/export", nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	if routes.ServeHTTP(rec, req); rec.Code != http.StatusNotModified {
+		t.Fatalf("expected an unchanged export to be not modified, got %d", rec.Code)
+	}
+	tag := func(target string) string { return get(target).Header().Get("ETag") }
+	if all := tag("/api/export"); tag("/api/export?format=ndjson") != all || tag("/api/export?player=alice") == all || tag("/api/export?after="+url.QueryEscape(first.ID)) == all {
+		t.Fatalf("expected the ETag to follow the filters and after, not how the query is written")
+	}
+	if tag("/api/export?player=alice&format=csv") != tag("/api/export?format=csv&player=alice") {
+		t.Fatalf("expected the same query in another order to have the same ETag")
+	}
+
+	rec = get("/api/export?format=csv&after=" + url.QueryEscape(first.ID))
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "id,timestamp,players,zap,draw,tags,notes,deltas\n") || strings.Count(body, "\n") != 3 || strings.Contains(body, "alice;bob,") {
+		t.Fatalf("expected the rest of the log as CSV, got:\n%s", body)
+	}
+	if rec := get("/api/export?after=nope"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected resuming after an unknown game to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestDuplicatePlayersAreNotScored(t *testing.T) {
+	ds := &Dataset{
+		Games: []*Game{
+			{ID: "1", Row: 2, Rankings: []string{"alice", "bob"}},
+			{ID: "2", Row: 3, Rankings: []string{"bob", "Ally", "alice"}},
+		},
+		Aliases: map[string]string{"ally": "alice"},
+	}
+	ds.resolvePlayerIDs()
+	ds.dropDuplicatePlayers()
+	if len(ds.Games) != 1 || ds.Games[0].ID != "1" {
+		t.Fatalf("expected the game listing alice under an alias too to be left out, got %+v", ds.Games)
+	}
+	if scores := calculateScores(ds.Games); scores["alice"] <= scores["bob"] {
+		t.Fatalf("expected alice's only scored game to be a win, got %v", scores)
+	}
+}
+
+func TestSuggestPods(t *testing.T) {
+	players := []string{"h", "g", "f", "e", "d", "c", "b", "a"}
+	ratings := map[string]int{"a": 1700, "b": 1650, "c": 1600, "d": 1550, "e": 1500, "f": 1450, "g": 1400, "h": 1350}
+	seated := func(pods []SuggestedPod) string {
+		var s []string
+		for _, pod := range pods {
+			ids := ""
+			for _, seat := range pod.Seats {
+				ids += seat.Player
+			}
+			s = append(s, strconv.Itoa(pod.Tier)+":"+ids)
+		}
+		return strings.Join(s, " ")
+	}
+
+	pods, err := suggestPods(players, ratings, 2, nil, false)
+	if got := seated(pods); err != nil || got != "1:abcd 1:efgh" {
+		t.Fatalf("expected the top four to play each other, got %q (%v)", got, err)
+	}
+	pods, err = suggestPods(players, ratings, 0, []int{1500}, false)
+	if got := seated(pods); err != nil || got != "1:abcde 2:fgh" {
+		t.Fatalf("expected a pod per tier, got %q (%v)", got, err)
+	}
+	pods, err = suggestPods(players, ratings, 2, nil, true)
+	if got := seated(pods); err != nil || got != "1:adeh 1:bcfg" {
+		t.Fatalf("expected mixed pods, got %q (%v)", got, err)
+	}
+	if seats := pods[0].Seats; seats[0].Handicap >= 0 || seats[3].Handicap <= 0 {
+		t.Fatalf("expected the favorite to give up life and the underdog to get it, got %+v", seats)
+	}
+	if _, err := suggestPods(players, ratings, 5, nil, false); err == nil {
+		t.Fatalf("expected too many pods to be refused")
+	}
+
+	rec := httptest.NewRecorder()
+	newFakeSheets(t, gameLog).league().routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/pods?players=alice,bob,carol,dave&pods=2", nil))
+	if body := rec.Body.String(); rec.Code != http.StatusOK || strings.Count(body, "<li>Tier 1") != 2 {
+		t.Fatalf("expected two pods, got %d:\n%s", rec.Code, body)
+	}
+}
+
+func TestPlayerSlugs(t *testing.T) {
+	if slug := slugify("  Zoë O'Neil "); slug != "zoe-o-neil" {
+		t.Fatalf("expected a URL-safe slug, got %q", slug)
+	}
+	f := newFakeSheets(t, [][]interface{}{
+		gameLog[0],
+		{"1", "Mon, 02 Jan 2023 19:00:00 UTC", "", "", "", "Zoë O'Neil", "bob"},
+		{"2", "Mon, 09 Jan 2023 19:00:00 UTC", "", "", "", "Zoe O'Neil", "Zoë O'Neil", "bob"},
+	})
+	routes := f.league().routes()
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	if rec := serve("/player/zoe-o-neil"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Zoë O&#39;Neil") {
+		t.Fatalf("expected the first Zoë's page at their slug, got %d", rec.Code)
+	}
+	if rec := serve("/player/zoe-o-neil-2"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Zoe O&#39;Neil") {
+		t.Fatalf("expected the second Zoe's page numbered, got %d", rec.Code)
+	}
+	if rec := serve("/player/Zo%C3%AB%20O'Neil"); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/player/zoe-o-neil" {
+		t.Fatalf("expected a link by name to redirect to the slug, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	// a player added to the roster keeps their old slug's links
+	ds := &Dataset{
+		Games:   []*Game{{ID: "1", Row: 2, Rankings: []string{"Jane Doe", "bob"}}},
+		Players: []PlayerRecord{{ID: "p1", Name: "Jane Doe"}},
+	}
+	ds.resolvePlayerIDs()
+	if id := ds.playerID("jane-doe"); id != "p1" || ds.Games[0].Rankings[0] != "p1" {
+		t.Fatalf("expected the old slug to lead to the roster ID, got %q", id)
+	}
+}
+
+func TestNameKeyedRecordsFollowPlayerIDs(t *testing.T) {
+	f := newFakeSheets(t, [][]interface{}{
+		gameLog[0],
+		{"1", "Mon, 02 Jan 2023 19:00:00 UTC", "", "", "", "Alice Smith", "bob"},
+		{"2", "Mon, 09 Jan 2023 19:00:00 UTC", "", "", "", "bob", "Alice Smith"},
+	})
+	profiles := filepath.Join(t.TempDir(), "profiles.json")
+	err := os.WriteFile(profiles, []byte(`[
+		{"name": "Alice Smith", "publicProfile": true, "rivals": ["Bob"]}
+	]`), 0600)
+	if err != nil {
+		t.Fatalf("failed to write profiles: %v", err)
+	}
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+	c := &Comment{League: spreadsheetID, Kind: "player", Target: "Alice Smith", Author: "Alice Smith", Body: "gg", CreatedAt: time.Now()}
+	if err := st.addComment(ctx, c); err != nil {
+		t.Fatalf("failed to add comment: %v", err)
+	}
+
+	l := f.league()
+	l.profilesPath = profiles
+	l.snapshots = st
+	mux := l.routes()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/players/alice-smith", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the profile keyed by name to resolve to alice-smith, got %d: %s", rec.Code, rec.Body.String())
+	}
+	ds, err := l.fetch(ctx)
+	if err != nil {
+		t.Fatalf("failed to fetch: %v", err)
+	}
+	keyed, err := loadProfiles(profiles, ds)
+	if err != nil {
+		t.Fatalf("failed to load profiles: %v", err)
+	}
+	if rivals := keyed["alice-smith"].Rivals; len(rivals) != 1 || rivals[0] != "bob" {
+		t.Fatalf("expected the rival named Bob to resolve to bob, got %v", rivals)
+	}
+
+	// the comment made under the name was moved to the ID when the dataset loaded
+	moved, err := st.comments(ctx, spreadsheetID, "player", "alice-smith")
+	if err != nil || len(moved) != 1 || moved[0].Author != "alice-smith" {
+		t.Fatalf("expected the comment moved to alice-smith, got %+v (%v)", moved, err)
+	}
+	if records, err := st.migratePlayerIDs(ctx, spreadsheetID, ds, time.Now()); err != nil || records != nil {
+		t.Fatalf("expected the migration to run once, got %v (%v)", records, err)
+	}
+}
+
+func TestForgetAndRenameRedrawArchive(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	serve := f.handler
+	f.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/values:batchUpdate") {
+			serve(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+	l := f.league()
+	l.archive = st
+	ctx := context.Background()
+	if err := archiveWeek(ctx, l, time.Date(2023, 1, 11, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("failed to archive the week: %v", err)
+	}
+	archived := func() string {
+		a, err := st.archivedWeek(ctx, spreadsheetID, "2023-W01")
+		if err != nil {
+			t.Fatalf("failed to load the archived week: %v", err)
+		}
+		return string(a.HTML)
+	}
+
+	res, err := l.forgetPlayer(ctx, st, "bob", true)
+	if err != nil || res.Records["standings_archive"] != 1 || !strings.Contains(archived(), "bob") {
+		t.Fatalf("expected a dry run to count the archived week without redrawing it, got %+v (%v)", res, err)
+	}
+	if _, err := l.forgetPlayer(ctx, st, "bob", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body := archived(); strings.Contains(body, "bob") || !strings.Contains(body, "Former player 1") {
+		t.Fatalf("expected the archived week redrawn without bob, got %s", body)
+	}
+
+	renamed, err := l.renamePlayer(ctx, st, "alice", "Alicia", false)
+	if err != nil || renamed.Records["standings_archive"] != 1 {
+		t.Fatalf("expected the rename to redraw the archived week, got %+v (%v)", renamed, err)
+	}
+	if body := archived(); !strings.Contains(body, "Alicia") {
+		t.Fatalf("expected the archived week redrawn under the new name, got %s", body)
+	}
+}
+
+func TestForgetAndRenameScrubImportedRows(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	serve := f.handler
+	f.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/values:batchUpdate") {
+			serve(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+	l := f.league()
+	ctx := context.Background()
+	if _, err := st.importTab(ctx, spreadsheetID, sheetTab{"games", readRange}, gameLog, time.Now()); err != nil {
+		t.Fatalf("failed to import the game log: %v", err)
+	}
+	imported := func() string {
+		rows, sums, err := st.importedRows(ctx, spreadsheetID, "games")
+		if err != nil {
+			t.Fatalf("failed to read the imported rows: %v", err)
+		}
+		for i, row := range rows {
+			b, _ := json.Marshal(row)
+			if rowChecksum(string(b)) != sums[i] {
+				t.Fatalf("expected row %d's checksum to match its cells", i+1)
+			}
+		}
+		b, _ := json.Marshal(rows)
+		return string(b)
+	}
+
+	res, err := l.forgetPlayer(ctx, st, "bob", true)
+	if err != nil || res.Records["sheet_rows"] != 2 || !strings.Contains(imported(), `"bob"`) {
+		t.Fatalf("expected a dry run to count bob's imported rows without changing them, got %+v (%v)", res, err)
+	}
+	if _, err := l.forgetPlayer(ctx, st, "bob", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rows := imported(); strings.Contains(rows, `"bob"`) || !strings.Contains(rows, `"Former player 1"`) {
+		t.Fatalf("expected bob's imported rows anonymized, got %s", rows)
+	}
+	if _, err := l.renamePlayer(ctx, st, "alice", "Alicia", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rows := imported(); strings.Contains(rows, `"alice"`) || !strings.Contains(rows, `"Alicia"`) {
+		t.Fatalf("expected alice's imported rows renamed, got %s", rows)
+	}
+}
+
+func TestSnapshotVersionCoversZeroSum(t *testing.T) {
+	ds := &Dataset{Games: []*Game{{ID: "1", Timestamp: time.Date(2023, 1, 2, 19, 0, 0, 0, time.UTC), Rankings: []string{"alice", "bob"}}}}
+	day := time.Date(2023, 1, 8, 0, 0, 0, 0, time.UTC)
+	version := snapshotVersion(ds, ds.Games, day)
+
+	defer func(old bool) { zeroSum = old }(zeroSum)
+	zeroSum = !zeroSum
+	if snapshotVersion(ds, ds.Games, day) == version {
+		t.Fatal("expected toggling zero-sum scoring to change the snapshot version")
+	}
+}
+
+func TestForwardedHeadersTrustedOverUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scoreboard.sock")
+	ln, err := web.Listen(web.UnixPrefix + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(siteURL(r))) })}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "unix", path)
+	}}}
+	req, _ := http.NewRequest(http.MethodGet, "http://scoreboard/", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "scores.example.com")
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if body, _ := ioutil.ReadAll(res.Body); string(body) != "https://scores.example.com" {
+		t.Fatalf("expected the proxy on the socket to be trusted, got %q", body)
+	}
+}
+
+func TestBuildUsesServerScoringSettings(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	rankings := func() string {
+		rec := httptest.NewRecorder()
+		f.league().routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/rankings", nil))
+		return rec.Body.String()
+	}
+	defaults := rankings()
+
+	defer currentScoring().apply()
+	defer func(engine string, multiplier float64, zero bool) {
+		ratingEngine, archenemyMultiplier, zeroSum = engine, multiplier, zero
+	}(ratingEngine, archenemyMultiplier, zeroSum)
+	for name, value := range map[string]string{"SCOREBOARD_K_FACTOR": "64", "SCOREBOARD_ZERO_SUM": "true", "SCOREBOARD_ARCHENEMY_MULTIPLIER": "2"} {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+	if err := configureScoringFromEnv(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dir := t.TempDir()
+	if _, err := buildSite(context.Background(), f.league(), dir, ""); err != nil {
+		t.Fatalf("failed to build the site: %v", err)
+	}
+	built, err := os.ReadFile(filepath.Join(dir, "api", "rankings.json"))
+	if err != nil {
+		t.Fatalf("failed to read the built rankings: %v", err)
+	}
+	if served := rankings(); string(built) != served || served == defaults {
+		t.Fatalf("expected the build to match the server under the configured K-factor, got %s, served %s", built, served)
+	}
+}
+
+// fakeSMTP serves just enough SMTP to accept messages, sending each one's
+// data to the returned channel. It returns the server's address.
+func fakeSMTP(t *testing.T) (string, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	messages := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+				reply("220 fake ESMTP")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch cmd := strings.ToUpper(strings.Fields(line + " x")[0]); cmd {
+					case "DATA":
+						reply("354 go ahead")
+						var msg strings.Builder
+						for {
+							line, err := r.ReadString('\n')
+							if err != nil {
+								return
+							}
+							if line == ".\r\n" {
+								break
+							}
+							msg.WriteString(line)
+						}
+						messages <- msg.String()
+						reply("250 queued")
+					case "QUIT":
+						reply("221 bye")
+						return
+					default:
+						reply("250 ok")
+					}
+				}
+			}(conn)
+		}
+	}()
+	return ln.Addr().String(), messages
+}
+
+func TestRatingChangesAreEmailedAndPosted(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	l := f.league()
+	ds, err := l.fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles := filepath.Join(t.TempDir(), "profiles.json")
+	err = os.WriteFile(profiles, []byte(`[
+		{"name": "alice", "email": "alice@example.com", "notifyRatingChanges": true},
+		{"name": "bob", "email": "bob@example.com"},
+		{"name": "carol", "email": "carol@example.com", "notifyRatingChanges": true}
+	]`), 0600)
+	if err != nil {
+		t.Fatalf("failed to write profiles: %v", err)
+	}
+
+	var posted struct {
+		Event   string         `json:"event"`
+		Changes []RatingChange `json:"changes"`
+	}
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&posted)
+	}))
+	defer hook.Close()
+
+	addr, messages := fakeSMTP(t)
+	n := &notifier{mailer: &mailer{addr: addr, from: "scores@example.com"}, webhookURL: hook.URL, league: l, profilesPath: profiles}
+	before := map[string]int{"alice": 1500, "bob": 1500, "carol": 1500}
+	after := map[string]int{"alice": 1516, "bob": 1484, "carol": 1500, "dave": 1516}
+	n.notifyRatingChanges(before, after, ds, NightRecap{})
+
+	if posted.Event != "ratings.changed" || len(posted.Changes) != 2 || posted.Changes[0].Player != "alice" || posted.Changes[0].After != 1516 || posted.Changes[1].Player != "bob" {
+		t.Fatalf("expected alice's and bob's changes to be posted, got %+v", posted)
+	}
+	select {
+	case msg := <-messages:
+		for _, want := range []string{"To: alice@example.com\r\n", "Subject: Your rating changed\r\n", "Your rating changed from 1500 to 1516 (+16)."} {
+			if !strings.Contains(msg, want) {
+				t.Fatalf("expected %q in the email, got:\n%s", want, msg)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected an email to alice")
+	}
+	select {
+	case msg := <-messages:
+		t.Fatalf("expected only alice to be emailed, since bob didn't opt in and carol's rating didn't change, got:\n%s", msg)
+	default:
+	}
+}
+
+func TestSecretEnvReadsFiles(t *testing.T) {
+	const name = "SCOREBOARD_TEST_SECRET"
+	defer os.Unsetenv(name)
+	defer os.Unsetenv(name + "_FILE")
+
+	os.Setenv(name, "from-env")
+	if v, err := secretEnv(name); err != nil || v != "from-env" {
+		t.Fatalf("expected the variable without a file, got %q %v", v, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("  from-file\n\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+	os.Setenv(name+"_FILE", path)
+	if v, err := secretEnv(name); err != nil || v != "from-file" {
+		t.Fatalf("expected the file to win with its whitespace trimmed, got %q %v", v, err)
+	}
+	if !hasSecretEnv(name) {
+		t.Fatal("expected the secret to be set")
+	}
+
+	os.Setenv(name+"_FILE", filepath.Join(t.TempDir(), "missing"))
+	if v, err := secretEnv(name); err == nil || v != "" || !strings.Contains(err.Error(), name+"_FILE") {
+		t.Fatalf("expected an error naming the variable for a missing file, got %q %v", v, err)
+	}
+}
+
+func TestStatsAPI(t *testing.T) {
+	routes := newFakeSheets(t, gameLog).league().routes()
+	stats := func(target string) (*httptest.ResponseRecorder, map[string]PlayerStats) {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var res struct {
+			Stats []PlayerStats `json:"stats"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &res)
+		byID := map[string]PlayerStats{}
+		for _, s := range res.Stats {
+			byID[s.Player] = s
+		}
+		return rec, byID
+	}
+
+	rec, all := stats("/api/stats")
+	if rec.Code != http.StatusOK || len(all) != 3 {
+		t.Fatalf("expected stats for alice, bob and carol, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if alice := all["alice"]; alice.Games != 2 || alice.Wins != 2 || alice.WinRate != 1 || alice.AvgPlacement != 1 || alice.AvgDelta <= 0 {
+		t.Fatalf("expected alice to have won both their games, got %+v", alice)
+	}
+	if bob := all["bob"]; bob.Games != 2 || bob.Wins != 0 || bob.AvgPlacement != 2.5 {
+		t.Fatalf("expected bob second then third, got %+v", bob)
+	}
+
+	if _, week := stats("/api/stats?dateRange=2023-01-09..2023-01-09"); len(week) != 3 || week["alice"].Games != 1 {
+		t.Fatalf("expected only the second game, got %+v", week)
+	}
+	if _, pods := stats("/api/stats?podSize=2"); len(pods) != 2 || pods["carol"].Games != 0 {
+		t.Fatalf("expected only the two player game, got %+v", pods)
+	}
+
+	rec = httptest.NewRecorder()
+	routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/stats?format=csv", nil))
+	if body := rec.Body.String(); !strings.HasPrefix(body, "player,name,games,wins,") || strings.Count(body, "\n") != 4 {
+		t.Fatalf("expected a CSV row per player, got:\n%s", body)
+	}
+	for _, target := range []string{"/api/stats?dateRange=yesterday", "/api/stats?podSize=1", "/api/stats?format=xml"} {
+		if rec, _ := stats(target); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"error"`) {
+			t.Fatalf("expected %s to be rejected, got %d: %s", target, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestOverlayAPI(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	l := f.league()
+	start := time.Date(2023, 1, 20, 12, 0, 0, 0, time.UTC)
+	l.clock = fixedClock(start)
+	routes := l.routes()
+	get := func(method string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, httptest.NewRequest(method, "/api/overlay", nil))
+		return rec
+	}
+
+	rec := get(http.MethodGet)
+	var res struct {
+		Top      []OverlayPlayer `json:"top"`
+		Streaks  []OverlayPlayer `json:"streaks"`
+		LastGame OverlayGame     `json:"lastGame"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("expected the overlay from any origin, got %d: %s (%v)", rec.Code, rec.Body.String(), err)
+	}
+	if len(res.Top) != 3 || res.Top[0].ID != "alice" || res.Top[0].Streak != 2 || res.Top[2].ID != "bob" || res.Top[2].Streak != -2 {
+		t.Fatalf("expected alice on top on a two game win streak and bob on a losing one, got %+v", res.Top)
+	}
+	if len(res.Streaks) != 1 || res.Streaks[0].ID != "alice" {
+		t.Fatalf("expected only alice's win streak, got %+v", res.Streaks)
+	}
+	if res.LastGame.ID != "2" || len(res.LastGame.Results) != 3 || res.LastGame.Results[0].Name != "alice" || res.LastGame.Results[0].Place != 1 {
+		t.Fatalf("expected the last scored game, got %+v", res.LastGame)
+	}
+
+	if rec := get(http.MethodOptions); rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Methods") == "" {
+		t.Fatalf("expected a preflight response, got %d", rec.Code)
+	}
+
+	// the response is reused until it expires
+	requests := f.requestCount()
+	first := rec.Body.String()
+	if rec := get(http.MethodGet); rec.Body.String() != first || f.requestCount() != requests {
+		t.Fatalf("expected the cached overlay without a fetch, got %d more fetches", f.requestCount()-requests)
+	}
+	l.clock = fixedClock(start.Add(overlayTTL + time.Second))
+	if get(http.MethodGet); f.requestCount() != requests+1 {
+		t.Fatalf("expected an expired overlay to be fetched again, got %d more fetches", f.requestCount()-requests)
+	}
+}
+
+func TestDeclaredRivalries(t *testing.T) {
+	rivals := declaredRivals(map[string]Profile{
+		"alice": {Rivals: []string{"bob", " ", "alice"}},
+		"carol": {Rivals: []string{" dave "}},
+	})
+	if !rivals["alice"]["bob"] || !rivals["bob"]["alice"] || !rivals["carol"]["dave"] || !rivals["dave"]["carol"] {
+		t.Fatalf("expected declared rivalries to go both ways, got %v", rivals)
+	}
+	if rivals["alice"]["alice"] || rivals["alice"][""] || rivals["alice"]["carol"] {
+		t.Fatalf("expected no rivalries with themselves, blanks or undeclared players, got %v", rivals)
+	}
+
+	game := &Game{ID: "1", Rankings: []string{"bob", "carol", "alice", "dave"}}
+	pairs := rivalriesInGame(game, rivals)
+	if len(pairs) != 2 || pairs[0] != [2]string{"bob", "alice"} || pairs[1] != [2]string{"carol", "dave"} {
+		t.Fatalf("expected both rivalries in the game in seating order, got %v", pairs)
+	}
+	if pairs := rivalriesInGame(&Game{Rankings: []string{"alice", "carol"}}, rivals); len(pairs) != 0 {
+		t.Fatalf("expected no rivalries between non-rivals, got %v", pairs)
+	}
+
+	history := []PlayerGame{{Game: game}, {}, {Game: &Game{ID: "2", Rankings: []string{"alice", "carol"}}}}
+	rivalsInHistory(history, rivals["alice"])
+	if len(history[0].Rivals) != 1 || history[0].Rivals[0] != "bob" || history[1].Rivals != nil || history[2].Rivals != nil {
+		t.Fatalf("expected only the game against bob to be marked, got %+v", history)
+	}
+}
+
+func TestCareerAggregatesSeasonsAndOpponents(t *testing.T) {
+	ds, err := newFakeSheets(t, gameLog).league().fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Sort(ByID(ds.Games))
+	calculateScores(ds.Games)
+	seasons := []Season{
+		{Name: "First week", Start: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2023, 1, 8, 0, 0, 0, 0, time.UTC)},
+		{Name: "Second week", Start: time.Date(2023, 1, 8, 0, 0, 0, 0, time.UTC)},
+	}
+
+	c := buildCareer(ds.Games, nil, seasons, "bob", time.Now())
+	if c == nil || c.Games != 2 || c.Wins != 0 {
+		t.Fatalf("expected bob's two losses, got %+v", c)
+	}
+	first, second := ds.Games[0].Results[1].After, ds.Games[1].Results[2].After
+	if c.Rating != second || c.High != first || c.HighGame != ds.Games[0] {
+		t.Fatalf("expected bob's current rating %d and their high of %d after the first game, got %+v", second, first, c)
+	}
+	if len(c.HeadToHead) != 2 || c.HeadToHead[0] != (Rivalry{Opponent: "alice", Games: 2, Below: 2}) || c.HeadToHead[1] != (Rivalry{Opponent: "carol", Games: 1, Below: 1}) {
+		t.Fatalf("expected bob's record against alice then carol, got %+v", c.HeadToHead)
+	}
+	if len(c.Seasons) != 2 || c.Seasons[0].Rank != 2 || c.Seasons[0].Players != 2 || c.Seasons[1].Rank != 3 || c.Seasons[1].Players != 3 || c.Seasons[1].Games != 1 {
+		t.Fatalf("expected bob's place in each season, got %+v", c.Seasons)
+	}
+
+	if alice := buildCareer(ds.Games, nil, seasons, "alice", time.Now()); alice.Wins != 2 || alice.HighGame != ds.Games[1] {
+		t.Fatalf("expected alice's two wins with their high in the last game, got %+v", alice)
+	}
+	if c := buildCareer(ds.Games, nil, seasons, "nobody", time.Now()); c != nil {
+		t.Fatalf("expected no career for a player who never played, got %+v", c)
+	}
+}
+
+func TestPlayerTrends(t *testing.T) {
+	defer func(games, days int) { trendGames, trendDays = games, days }(trendGames, trendDays)
+	day := func(d int) time.Time { return time.Date(2023, 1, d, 19, 0, 0, 0, time.UTC) }
+	var games []*Game
+	for d, delta := range []int{10, -20, 30, 5} {
+		games = append(games, &Game{ID: strconv.Itoa(d + 1), Timestamp: day(d + 1), Results: []Result{
+			{Player: "alice", Delta: delta},
+			{Player: "bob", Delta: -delta},
+		}})
+	}
+	games = append(games, &Game{ID: "5", Timestamp: day(5), Results: []Result{{Player: "carol", Delta: 7}}})
+	now := day(5)
+
+	// over each player's last games, however long ago
+	trendGames, trendDays = 2, 0
+	if trends := playerTrends(games, now); trends["alice"] != 35 || trends["bob"] != -35 || trends["carol"] != 7 {
+		t.Fatalf("expected the sum of each player's last 2 games, got %v", trends)
+	}
+	trendGames = 10
+	if trends := playerTrends(games, now); trends["alice"] != 25 || trends["bob"] != -25 {
+		t.Fatalf("expected every game when there are fewer than the window, got %v", trends)
+	}
+
+	// over the trailing days, however many games
+	trendDays = 2
+	if trends := playerTrends(games, now); trends["alice"] != 35 || trends["carol"] != 7 {
+		t.Fatalf("expected the games of the last 2 days, got %v", trends)
+	}
+	if trends := playerTrends(games, day(20)); len(trends) != 0 {
+		t.Fatalf("expected no trend without recent games, got %v", trends)
+	}
+	// a game logged late, after a game older than the window
+	late := append(games, &Game{ID: "6", Timestamp: day(1), Results: []Result{{Player: "dave", Delta: 3}}},
+		&Game{ID: "7", Timestamp: day(5), Results: []Result{{Player: "dave", Delta: 4}}})
+	if trends := playerTrends(late, now); trends["dave"] != 4 || trends["alice"] != 35 {
+		t.Fatalf("expected games in the window after an older one to count, got %v", trends)
+	}
+
+	if trendArrow(12) != "▲12" || trendArrow(-3) != "▼3" || trendArrow(0) != "–" {
+		t.Fatalf("unexpected arrows %q %q %q", trendArrow(12), trendArrow(-3), trendArrow(0))
+	}
+}
+
+func TestVerboseLogsCalculations(t *testing.T) {
+	defer setVerbose(isVerbose())
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+	f := newFakeSheets(t, gameLog)
+
+	setVerbose(false)
+	logged.Reset()
+	get(t, f, "/")
+	if strings.Contains(logged.String(), "calculated scores") || strings.Contains(logged.String(), "scored game") {
+		t.Fatalf("expected no calculation output when verbose logging is off, got:\n%s", logged.String())
+	}
+
+	setVerbose(true)
+	logged.Reset()
+	get(t, f, "/")
+	for _, want := range []string{"scored game: ", "calculated scores: "} {
+		if !strings.Contains(logged.String(), want) {
+			t.Fatalf("expected %q in the verbose output, got:\n%s", want, logged.String())
+		}
+	}
+
+	set := func(method string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/admin/verbose", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		verboseHandler(rec, req)
+		return rec
+	}
+	if rec := set(http.MethodPost, url.Values{"enabled": {"false"}}); isVerbose() || !strings.Contains(rec.Body.String(), `"verbose":false`) {
+		t.Fatalf("expected verbose logging to be turned off, got %s", rec.Body.String())
+	}
+	if rec := set(http.MethodPost, nil); !isVerbose() || !strings.Contains(rec.Body.String(), `"verbose":true`) {
+		t.Fatalf("expected a post without a value to toggle verbose logging, got %s", rec.Body.String())
+	}
+	if rec := set(http.MethodPost, url.Values{"enabled": {"sometimes"}}); rec.Code != http.StatusBadRequest || !isVerbose() {
+		t.Fatalf("expected an invalid setting to be rejected, got %d", rec.Code)
+	}
+	if rec := set(http.MethodPut, nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected other methods to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestPercentileHistory(t *testing.T) {
+	game := func(id string, results ...Result) *Game {
+		return &Game{ID: id, Results: results}
+	}
+	solo := []*Game{
+		game("1", Result{Player: "alice", After: 1010}),
+		game("2", Result{Player: "alice", After: 1025}),
+	}
+	if points := percentileHistory(solo, "alice"); len(points) != 0 {
+		t.Fatalf("expected no percentiles in a single-player league, got %+v", points)
+	}
+	if chart := percentileChart(percentileHistory(solo, "alice")); chart != "" {
+		t.Fatalf("expected no chart in a single-player league, got %s", chart)
+	}
+
+	games := append(solo,
+		game("3", Result{Player: "alice", After: 1020}, Result{Player: "bob", After: 1000}),
+		game("4", Result{Player: "bob", After: 1020}),
+		game("5", Result{Player: "alice", After: 1020}, Result{Player: "carol", After: 990}),
+		game("6", Result{Player: "alice", After: 980}, Result{Player: "dave", After: 980}),
+	)
+	points := percentileHistory(games, "alice")
+	want := map[string]float64{
+		"3": 100,
+		"5": 75,        // tied with bob, above carol
+		"6": 100.0 / 6, // tied with dave, below bob and carol
+	}
+	if len(points) != len(want) {
+		t.Fatalf("expected a percentile for each of alice's games against others, got %+v", points)
+	}
+	for _, p := range points {
+		if math.Abs(p.Percentile-want[p.Game.ID]) > 1e-9 {
+			t.Errorf("expected alice's percentile after game %s to be %.2f, got %.2f", p.Game.ID, want[p.Game.ID], p.Percentile)
+		}
+	}
+	if points[2].Rating != 980 {
+		t.Errorf("expected the point to carry alice's rating after the game, got %d", points[2].Rating)
+	}
+	if chart := string(percentileChart(points)); !strings.Contains(chart, "<polyline") || !strings.Contains(chart, "game 6: rated 980, percentile 17") {
+		t.Errorf("expected a line chart with a point per game, got %s", chart)
+	}
+}
+
+// TestPlayerPagesPrecomputeACopy scores a dataset while its player pages are
+// precomputed, as a player page does, which go test -race reports if both
+// score the same adjustments.
+func TestPlayerPagesPrecomputeACopy(t *testing.T) {
+	// verbose logging locks the logger in both, which hides the race
+	defer setVerbose(isVerbose())
+	setVerbose(false)
+
+	f := newFakeSheets(t, nil)
+	f.serveRows(gameLog, map[string][][]interface{}{
+		"Adjustments!A:D": {{"Date", "Player", "Amount", "Reason"}, {"2023-01-20", "alice", "-100", "slow play"}},
+	})
+	l := f.league()
+	l.ranges.Adjustments = "Adjustments!A:D"
+	ds, err := l.fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer func() { backgroundJobs = nil }()
+	backgroundJobs = newJobQueue()
+	go backgroundJobs.run(ctx, 1)
+
+	l.precomputePlayers(ds)
+	scores := calculateScores(ds.Games, ds.Adjustments...)
+
+	// wait on the player pages rather than the queue, since the queue's lock
+	// would order the scoring before the job's
+	deadline := time.Now().Add(5 * time.Second)
+	view, cached := l.players.get(datasetVersion(ds), "alice")
+	for !cached {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the precompute to finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+		view, cached = l.players.get(datasetVersion(ds), "alice")
+	}
+	if view == nil || view.Player.Score != scores["alice"] {
+		t.Fatalf("expected alice's precomputed rating to be %d, got %+v", scores["alice"], view)
+	}
+	if adj := ds.Adjustments[0]; adj.After-adj.Before != -100 {
+		t.Fatalf("expected the adjustment to apply once, got %+v", adj)
+	}
+}
+
+func TestPickemRequiresLogin(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+	l := f.league()
+	l.picks = st
+	l.sessions = &sessions{key: []byte("0123456789abcdef0123456789abcdef")}
+	mux := l.routes()
+
+	post := func(form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/picks", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	session := func(player string) *http.Cookie {
+		return &http.Cookie{Name: sessionCookie, Value: l.sessions.sign(player, time.Now().Add(time.Hour))}
+	}
+
+	if rec := post(url.Values{"member": {"bob"}, "game": {"9"}, "pick": {"alice"}}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous picks to be rejected, got %d", rec.Code)
+	}
+	if rec := post(url.Values{"game": {"9"}, "pick": {"alice"}}, session("bob")); rec.Code != http.StatusOK {
+		t.Fatalf("expected bob's pick to be saved, got %d: %s", rec.Code, rec.Body)
+	}
+	// the member field is ignored, so alice can't change bob's pick
+	if rec := post(url.Values{"member": {"bob"}, "game": {"9"}, "pick": {"carol"}}, session("alice")); rec.Code != http.StatusOK {
+		t.Fatalf("expected alice's pick to be saved, got %d: %s", rec.Code, rec.Body)
+	}
+
+	predictions, err := st.predictions(context.Background(), l.spreadsheetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	picks := map[string]string{}
+	for _, p := range predictions {
+		picks[p.Member] = p.Pick
+	}
+	if len(picks) != 2 || picks["bob"] != "alice" || picks["alice"] != "carol" {
+		t.Fatalf("expected a pick for each logged in player, got %v", picks)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/picks", nil))
+	if !strings.Contains(rec.Body.String(), "Log in</a> to pick") || strings.Contains(rec.Body.String(), `name="pick"`) {
+		t.Fatalf("expected anonymous visitors to be asked to log in, got:\n%s", rec.Body)
+	}
+}
+
+func TestStoredRecordsAreKeptPerTenant(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+
+	// two tenants with the same spreadsheet
+	tenant := func(slug string) http.Handler {
+		l := f.league()
+		l.tenant = slug
+		l.comments = st
+		l.sessions = &sessions{key: []byte("0123456789abcdef0123456789abcdef")}
+		return l.routes()
+	}
+	a, b := tenant("a"), tenant("b")
+
+	req := httptest.NewRequest(http.MethodPost, "/comments", strings.NewReader(url.Values{"kind": {"game"}, "target": {"1"}, "body": {"what a comeback"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookie, Value: (&sessions{key: []byte("0123456789abcdef0123456789abcdef")}).sign("alice", time.Now().Add(time.Hour))})
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected the comment to be posted, got %d: %s", rec.Code, rec.Body)
+	}
+
+	page := func(h http.Handler) string {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/game/1", nil))
+		return rec.Body.String()
+	}
+	if !strings.Contains(page(a), "what a comeback") {
+		t.Fatal("expected the tenant's game page to show their comment")
+	}
+	if strings.Contains(page(b), "what a comeback") {
+		t.Fatal("expected another tenant on the same spreadsheet not to see the comment")
+	}
+}
+
+func TestGamesSortByNumericID(t *testing.T) {
+	games := []*Game{{ID: "10"}, {ID: "2"}, {ID: "1"}, {ID: "x"}}
+	sort.Sort(ByID(games))
+	var ids []string
+	for _, g := range games {
+		ids = append(ids, g.ID)
+	}
+	if strings.Join(ids, ",") != "1,2,10,x" {
+		t.Fatalf("expected game 2 to be scored before game 10, got %v", ids)
+	}
+
+	players := []Player{{ID: "10", Score: 1000}, {ID: "2", Score: 1000}}
+	sort.Sort(ByScore(players))
+	if players[0].ID != "2" {
+		t.Fatalf("expected the tie to be broken by numeric ID, got %+v", players)
+	}
+}
+
+func TestArchenemyTeamsShareInterLeagueWins(t *testing.T) {
+	first := &Dataset{Games: []*Game{
+		{ID: "1", Timestamp: time.Date(2023, 1, 2, 19, 0, 0, 0, time.UTC), Rankings: []string{"alice", "bob"}},
+		{ID: "2", Timestamp: time.Date(2023, 1, 9, 19, 0, 0, 0, time.UTC), Rankings: []string{"bob", "alice"}},
+		{ID: "3", Timestamp: time.Date(2023, 1, 16, 19, 0, 0, 0, time.UTC), Rankings: []string{"bob", "dave", "alice"}, Archenemy: "alice"},
+	}}
+	second := &Dataset{Games: []*Game{
+		{ID: "1", Timestamp: time.Date(2023, 1, 3, 19, 0, 0, 0, time.UTC), Rankings: []string{"carol", "dave"}},
+		{ID: "2", Timestamp: time.Date(2023, 1, 10, 19, 0, 0, 0, time.UTC), Rankings: []string{"dave", "carol"}},
+	}}
+	first.resolvePlayerIDs()
+	second.resolvePlayerIDs()
+
+	// bob and dave beat alice as a team, so both their leagues won
+	c := crossLeague([]ComparedLeague{{Slug: "first"}, {Slug: "second"}}, []*Dataset{first, second})
+	if len(c.Games) != 1 || strings.Join(c.Games[0].Winners, ",") != "first,second" {
+		t.Fatalf("expected the team's leagues to share the win, got %+v", c.Games)
+	}
+	if c.Leagues[0].Wins != 1 || c.Leagues[1].Wins != 1 {
+		t.Fatalf("expected a win for each league, got %+v", c.Leagues)
+	}
+}
+
+func TestWinsAboveReplacement(t *testing.T) {
+	// pod returns a scored game with everyone rated 1500 going in, the
+	// players given in finishing order
+	pod := func(players ...string) *Game {
+		g := &Game{Rankings: players}
+		for idx, p := range players {
+			g.Results = append(g.Results, Result{Player: p, Place: idx + 1, Before: replacementRating})
+		}
+		return g
+	}
+	draw := pod("alice", "bob", "carol", "dave")
+	draw.DrawGame = "TRUE"
+	// bob and carol beat alice, the archenemy, as a team
+	archenemy := &Game{Rankings: []string{"bob", "carol", "alice"}, Archenemy: "alice", Results: []Result{
+		{Player: "bob", Place: 1, Before: replacementRating},
+		{Player: "carol", Place: 1, Before: replacementRating},
+		{Player: "alice", Place: 2, Before: replacementRating},
+	}}
+
+	for _, tc := range []struct {
+		name   string
+		player string
+		games  []*Game
+		want   WAR // Expected is checked through the WAR's value
+		value  float64
+	}{
+		{
+			name:   "replacement level",
+			player: "alice",
+			games: []*Game{
+				pod("alice", "bob", "carol", "dave"),
+				pod("bob", "alice", "carol", "dave"),
+				pod("bob", "carol", "alice", "dave"),
+				pod("bob", "carol", "dave", "alice"),
+			},
+			want:  WAR{Wins: 1, Games: 4},
+			value: 0,
+		},
+		{
+			name:   "below replacement",
+			player: "alice",
+			games:  []*Game{pod("bob", "alice", "carol", "dave"), pod("carol", "bob", "alice", "dave"), draw},
+			want:   WAR{Wins: 0, Games: 2},
+			value:  -0.5,
+		},
+		{
+			name:   "team game win",
+			player: "carol",
+			games:  []*Game{archenemy},
+			want:   WAR{Wins: 1, Games: 1},
+			value:  0.5,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			war := winsAboveReplacement(tc.games, tc.player)
+			if war.Wins != tc.want.Wins || war.Games != tc.want.Games || math.Abs(war.Value()-tc.value) > 1e-9 {
+				t.Fatalf("expected %d wins in %d games worth %+.2f, got %+v worth %+.2f", tc.want.Wins, tc.want.Games, tc.value, war, war.Value())
+			}
+		})
+	}
+
+	// alice, the archenemy, only had bob and carol as opponents
+	if war := winsAboveReplacement([]*Game{archenemy}, "alice"); war.Wins != 0 || math.Abs(war.Expected-1.0/3) > 1e-9 {
+		t.Fatalf("expected the archenemy to be expected a third of a win, got %+v", war)
+	}
+}
+
+func TestPerformanceRatings(t *testing.T) {
+	defer func(games int) { performanceGames = games }(performanceGames)
+	performanceGames = 10
+
+	games := []*Game{
+		{ID: "1", Rankings: []string{"alice", "bob", "carol"}, Results: []Result{
+			{Player: "alice", Place: 1, Before: 1600},
+			{Player: "bob", Place: 2, Before: 1500},
+			{Player: "carol", Place: 3, Before: 1400},
+		}},
+		{ID: "2", Rankings: []string{"bob", "alice"}, Results: []Result{
+			{Player: "bob", Place: 1, Before: 1500},
+			{Player: "alice", Place: 2, Before: 1600},
+		}},
+	}
+
+	// alice met opponents averaging 4400/3 and netted one win over three
+	// pairings: 1466.67 + 400/3. bob met 4600/3 and netted one: 1533.33 +
+	// 400/3. carol lost both pairings to an average of 1550.
+	ratings := performanceRatings(games)
+	for player, want := range map[string]int{"alice": 1600, "bob": 1667, "carol": 1150} {
+		if ratings[player] != want {
+			t.Errorf("expected %s's performance rating to be %d, got %d", player, want, ratings[player])
+		}
+	}
+
+	// only the most recent game counts with a window of one
+	performanceGames = 1
+	ratings = performanceRatings(games)
+	for player, want := range map[string]int{"alice": 1100, "bob": 2000, "carol": 1150} {
+		if ratings[player] != want {
+			t.Errorf("expected %s's performance rating over one game to be %d, got %d", player, want, ratings[player])
+		}
+	}
+
+	// a draw counts the opponents but nets nothing
+	draw := &Game{ID: "3", DrawGame: "TRUE", Rankings: []string{"alice", "bob"}, Results: []Result{
+		{Player: "alice", Place: 1, Before: 1600},
+		{Player: "bob", Place: 2, Before: 1400},
+	}}
+	if ratings := performanceRatings([]*Game{draw}); ratings["alice"] != 1400 || ratings["bob"] != 1600 {
+		t.Errorf("expected a draw to rate each player at their opponent's rating, got %v", ratings)
+	}
+}
+
+func TestByPerformance(t *testing.T) {
+	players := []Player{
+		{ID: "alice", Score: 1600, Performance: 1500},
+		{ID: "bob", Score: 1400, Performance: 1700},
+		{ID: "carol", Score: 1550, Performance: 1500},
+		{ID: "dave", Score: 1700},
+	}
+	sort.Stable(ByPerformance(players))
+
+	// alice and carol tie on performance and fall back to their scores
+	var order []string
+	for _, p := range players {
+		order = append(order, p.ID)
+	}
+	if got := strings.Join(order, ","); got != "bob,alice,carol,dave" {
+		t.Fatalf("expected players ordered by performance, then score, got %s", got)
+	}
+}
+
+func TestPlacementHistory(t *testing.T) {
+	games := []*Game{
+		{ID: "1", Rankings: []string{"alice", "bob", "carol"}, Results: []Result{
+			{Player: "alice", Place: 1, Before: 1500},
+			{Player: "bob", Place: 2, Before: 1500},
+			{Player: "carol", Place: 3, Before: 1500},
+		}},
+		{ID: "2", DrawGame: "TRUE", Rankings: []string{"alice", "bob"}, Results: []Result{
+			{Player: "alice", Place: 1, Before: 1600},
+			{Player: "bob", Place: 2, Before: 1400},
+		}},
+		{ID: "3", Rankings: []string{"bob", "alice", "carol"}, Results: []Result{
+			{Player: "bob", Place: 1, Before: 1400},
+			{Player: "alice", Place: 2, Before: 1600},
+			{Player: "carol", Place: 3, Before: 1600},
+		}},
+		{ID: "4", Rankings: []string{"bob", "carol"}, Results: []Result{
+			{Player: "bob", Place: 1, Before: 1450},
+			{Player: "carol", Place: 2, Before: 1550},
+		}},
+	}
+
+	// in an even pod alice is expected to finish in the middle. In game 3
+	// bob, 200 points below her, finishes above her with a chance of
+	// 1/(1+10^(200/400)) and carol, rated the same, with a chance of a half.
+	// The draw and the game alice missed aren't charted.
+	points := placementHistory(games, "alice")
+	want := []PlacementPoint{
+		{Game: games[0], Expected: 2, Actual: 1, Players: 3},
+		{Game: games[2], Expected: 1 + 1/(1+math.Sqrt(10)) + 0.5, Actual: 2, Players: 3},
+	}
+	if len(points) != len(want) {
+		t.Fatalf("expected %d placements, got %+v", len(want), points)
+	}
+	for i, p := range points {
+		if p.Game != want[i].Game || p.Actual != want[i].Actual || p.Players != want[i].Players || math.Abs(p.Expected-want[i].Expected) > 1e-9 {
+			t.Errorf("expected placement %d to be %+v, got %+v", i, want[i], p)
+		}
+	}
+
+	chart := string(placementChart(points))
+	for _, title := range []string{"game 1: finished 1, expected 2.0", "game 3: finished 2, expected 1.7"} {
+		if !strings.Contains(chart, title) {
+			t.Errorf("expected the chart to show %q:\n%s", title, chart)
+		}
+	}
+	if placementChart(points[:1]) != "" {
+		t.Errorf("expected no chart for a single game")
+	}
+}
+
+func TestSnapshotImage(t *testing.T) {
+	f := newFakeSheets(t, gameLog)
+	h := f.league().routes()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/snapshot.png?top=3", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("expected a png, got %d %q:\n%s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	img, err := png.Decode(rec.Body)
+	if err != nil {
+		t.Fatalf("expected the snapshot to decode: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != snapshotWidth*snapshotScale || b.Dy() != snapshotHeight*snapshotScale {
+		t.Fatalf("expected a %dx%d image, got %v", snapshotWidth*snapshotScale, snapshotHeight*snapshotScale, b)
+	}
+
+	// chat apps and proxies may cache it for a few minutes
+	if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=300" {
+		t.Fatalf("expected the snapshot to be cacheable for five minutes, got %q", cc)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/snapshot.png?top=11", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected too many players to be a bad request, got %d", rec.Code)
+	}
+}
+
+func TestWilsonInterval(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		wins, games int
+		low, high   float64
+		rate        string
+	}{
+		{name: "no games", wins: 0, games: 0, low: 0, high: 1, rate: "no games"},
+		{name: "no wins", wins: 0, games: 10, low: 0, high: 0.2775, rate: "0% [0–28%]"},
+		{name: "all wins", wins: 10, games: 10, low: 0.7225, high: 1, rate: "100% [72–100%]"},
+		{name: "one game", wins: 1, games: 1, low: 0.2065, high: 1, rate: "100% [21–100%]"},
+		{name: "half", wins: 5, games: 10, low: 0.2366, high: 0.7634, rate: "50% [24–76%]"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			low, high := wilsonInterval(tc.wins, tc.games)
+			if math.Abs(low-tc.low) > 1e-4 || math.Abs(high-tc.high) > 1e-4 {
+				t.Errorf("expected an interval of [%.4f, %.4f], got [%.4f, %.4f]", tc.low, tc.high, low, high)
+			}
+			if rate := winRate(tc.wins, tc.games); rate != tc.rate {
+				t.Errorf("expected a win rate of %q, got %q", tc.rate, rate)
+			}
+		})
+	}
+}
+
+// flakyPhotos is a photo store whose deletes fail a number of times first.
+type flakyPhotos struct {
+	diskPhotos
+	failures int
+}
+
+func (p *flakyPhotos) delete(ctx context.Context, key string) error {
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("connection reset")
+	}
+	return p.diskPhotos.delete(ctx, key)
+}
+
+func TestForgottenPhotosAreDeletedAfterTheirReports(t *testing.T) {
+	st, err := openStore(filepath.Join(t.TempDir(), "scoreboard.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+	key := strings.Repeat("0", 32) + ".png"
+	photos := &flakyPhotos{diskPhotos: diskPhotos(t.TempDir()), failures: photoDeleteAttempts - 1}
+	if err := photos.put(ctx, key, "image/png", []byte("png")); err != nil {
+		t.Fatalf("failed to store photo: %v", err)
+	}
+	l := newLeague(spreadsheetID, readRange)
+	l.reports = st
+	l.photos = photos
+	if err := st.addReport(ctx, &GameReport{League: l.snapshotKey(), Game: "1", Author: "bob", Photo: key, PhotoType: "image/png", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("failed to add report: %v", err)
+	}
+
+	keys, err := l.reportPhotos(ctx, []string{"Bob"})
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("expected bob's photo, got %v: %v", keys, err)
+	}
+	// a dry run keeps the report, and deleting the photo is retried until it
+	// succeeds
+	if n, err := st.forgetPlayer(ctx, l.snapshotKey(), []string{"bob"}, true); err != nil || n["game_reports"] != 1 {
+		t.Fatalf("expected bob's report to be counted, got %v: %v", n, err)
+	}
+	if reports, _ := st.reports(ctx, l.snapshotKey(), "1"); len(reports) != 1 {
+		t.Fatalf("expected a dry run to keep bob's report, got %+v", reports)
+	}
+	if err := l.deletePhotos(ctx, keys); err != nil {
+		t.Fatalf("expected the photo delete to be retried, got %v", err)
+	}
+	if _, err := photos.get(ctx, key); !errors.Is(err, errNotFound) {
+		t.Fatalf("expected the photo to be deleted, got %v", err)
+	}
+
+	photos.failures = photoDeleteAttempts
+	if err := l.deletePhotos(ctx, keys); err == nil {
+		t.Fatalf("expected a photo delete failing every attempt to fail")
+	}
+}
+
+func TestAdminErrorsUseErrorPages(t *testing.T) {
+	l := newFakeSheets(t, gameLog).league()
+	h := requireAdmin("s3cret", forgetHandler(l, nil))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/forget", nil))
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" || !strings.Contains(rec.Body.String(), "admin token") {
+		t.Fatalf("expected the unauthorized error page, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+
+	for _, tc := range []struct {
+		method string
+		status int
+		want   string
+	}{
+		{http.MethodGet, http.StatusMethodNotAllowed, "accept GET requests"},
+		{http.MethodPost, http.StatusBadRequest, "player is required"},
+	} {
+		req := httptest.NewRequest(tc.method, "/admin/forget", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.status || rec.Header().Get("Content-Type") != "text/html; charset=utf-8" || !strings.Contains(rec.Body.String(), tc.want) {
+			t.Fatalf("expected the %d error page saying %q, got %d:\n%s", tc.status, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+}
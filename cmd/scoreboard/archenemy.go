@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+)
+
+// archenemyMultiplier scales the archenemy's rating change in the elo engine,
+// since a single game against the whole pod decides it. It's configured with
+// SCOREBOARD_ARCHENEMY_MULTIPLIER.
+var archenemyMultiplier = 2.0
+
+// parseArchenemy reports whether the player cells of a game, in finishing
+// order, record an archenemy game: one player against the rest of the pod as
+// a team, written like "alice" and "bob/carol/dave". It returns the archenemy
+// and the team's players.
+func parseArchenemy(cells []string) (string, []string, bool) {
+	if len(cells) != 2 {
+		return "", nil, false
+	}
+	solo, team := cells[0], cells[1]
+	if strings.Contains(solo, "/") {
+		solo, team = team, solo
+	}
+	if strings.Contains(solo, "/") || !strings.Contains(team, "/") {
+		return "", nil, false
+	}
+
+	var players []string
+	for _, name := range strings.Split(team, "/") {
+		if name = strings.TrimSpace(name); name != "" {
+			players = append(players, name)
+		}
+	}
+	if len(players) < 2 {
+		return "", nil, false
+	}
+	return solo, players, true
+}
+
+// parseTeams parses the player cells of a two-headed giant game, in finishing
+// order, into its teams, with each team's players written like "alice/bob".
+func parseTeams(cells []string) [][]string {
+	var teams [][]string
+	for _, cell := range cells {
+		var team []string
+		for _, name := range strings.Split(cell, "/") {
+			if name = strings.TrimSpace(name); name != "" {
+				team = append(team, name)
+			}
+		}
+		if len(team) > 0 {
+			teams = append(teams, team)
+		}
+	}
+	return teams
+}
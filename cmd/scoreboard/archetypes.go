@@ -0,0 +1,104 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// deckColumn is the index of the first deck column, R. The deck played by the
+// player in column F+n is tagged in column R+n.
+const deckColumn = eliminationColumn + maxPlayers
+
+// colorOrder is the order of the colors in a color identity, WUBRG with
+// colorless last.
+const colorOrder = "WUBRGC"
+
+// parseArchetypes parses a deck cell into its tags, like "combo" or
+// "control, UB". Tags are separated by commas. Color identities are written
+// in WUBRG order so "bu" and "UB" count together, and other tags are
+// lowercased.
+func parseArchetypes(cell string) []string {
+	var tags []string
+	for _, tag := range strings.Split(cell, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if identity, ok := colorIdentity(tag); ok {
+			tags = append(tags, identity)
+		} else {
+			tags = append(tags, strings.ToLower(tag))
+		}
+	}
+	return tags
+}
+
+// colorIdentity returns the tag as a color identity in WUBRG order, or false
+// if it isn't one.
+func colorIdentity(tag string) (string, bool) {
+	tag = strings.ToUpper(tag)
+	var identity strings.Builder
+	for _, c := range colorOrder {
+		if strings.ContainsRune(tag, c) {
+			identity.WriteRune(c)
+		}
+	}
+	if identity.Len() != len(tag) {
+		// a letter that isn't a color, or a repeated one
+		return "", false
+	}
+	return identity.String(), true
+}
+
+// ArchetypeStats are the results of the decks tagged with an archetype or
+// color identity over the queried games.
+type ArchetypeStats struct {
+	Archetype   string  `json:"archetype"`
+	Games       int     `json:"games"`
+	Wins        int     `json:"wins"`
+	WinRate     float64 `json:"winRate"`
+	WinRateLow  float64 `json:"winRateLow"`  // the low end of the win rate's 95% confidence interval.
+	WinRateHigh float64 `json:"winRateHigh"` // the high end of the win rate's 95% confidence interval.
+}
+
+// archetypeStats aggregates the results of each archetype in the scored games
+// that match the query, ordered by games played. Draws count as games but
+// not wins.
+func archetypeStats(games []*Game, sq StatsQuery) []ArchetypeStats {
+	archetypes := map[string]*ArchetypeStats{}
+	for _, g := range games {
+		if len(g.Decks) == 0 || !sq.matches(g) {
+			continue
+		}
+		for _, res := range g.Results {
+			if sq.Player != "" && res.Player != sq.Player {
+				continue
+			}
+			for _, tag := range g.Decks[res.Player] {
+				s, ok := archetypes[tag]
+				if !ok {
+					s = &ArchetypeStats{Archetype: tag}
+					archetypes[tag] = s
+				}
+				s.Games++
+				if res.Place == 1 && !g.IsDraw() {
+					s.Wins++
+				}
+			}
+		}
+	}
+
+	stats := make([]ArchetypeStats, 0, len(archetypes))
+	for _, s := range archetypes {
+		s.WinRate = float64(s.Wins) / float64(s.Games)
+		s.WinRateLow, s.WinRateHigh = wilsonInterval(s.Wins, s.Games)
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Games != stats[j].Games {
+			return stats[i].Games > stats[j].Games
+		}
+		return stats[i].Archetype < stats[j].Archetype
+	})
+	return stats
+}
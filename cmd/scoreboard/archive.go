@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image/png"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// archiveInterval is how often the archiver checks whether last week's
+// standings have been archived yet.
+const archiveInterval = time.Hour
+
+// weekPattern matches ISO week keys like "2024-W21".
+var weekPattern = regexp.MustCompile(`^\d{4}-W\d{2}$`)
+
+// ArchivedWeek is the standings as they stood at the end of a week, rendered
+// when the week ended so the archive keeps how they looked at the time.
+type ArchivedWeek struct {
+	Week     string // the ISO week, like "2024-W21".
+	HTML     []byte
+	PNG      []byte
+	Archived time.Time
+}
+
+// isoWeek returns the ISO week the day falls in, like "2024-W21".
+func isoWeek(day time.Time) string {
+	year, week := day.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// lastCompletedWeek returns the Sunday that ended the last full ISO week
+// before now.
+func lastCompletedWeek(now time.Time) time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monday := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	return monday.AddDate(0, 0, -1)
+}
+
+// archivedWeek returns the league's archived standings for the week.
+func (s *store) archivedWeek(ctx context.Context, league, week string) (*ArchivedWeek, error) {
+	a := &ArchivedWeek{Week: week}
+	err := s.db.QueryRowContext(ctx, `SELECT html, png, created_at FROM standings_archive WHERE league = ? AND week = ?`,
+		league, week).Scan(&a.HTML, &a.PNG, &a.Archived)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, errNotFound
+	case err != nil:
+		return nil, fmt.Errorf("failed to load archived week: %w", err)
+	}
+	return a, nil
+}
+
+// archivedWeeks returns the weeks the league has archived standings for,
+// newest first.
+func (s *store) archivedWeeks(ctx context.Context, league string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT week FROM standings_archive WHERE league = ?`, league)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archived weeks: %w", err)
+	}
+	defer rows.Close()
+
+	var weeks []string
+	for rows.Next() {
+		var week string
+		if err := rows.Scan(&week); err != nil {
+			return nil, fmt.Errorf("failed to list archived weeks: %w", err)
+		}
+		weeks = append(weeks, week)
+	}
+	// zero padded week numbers sort in date order
+	sort.Sort(sort.Reverse(sort.StringSlice(weeks)))
+	return weeks, rows.Err()
+}
+
+// saveArchivedWeek stores the week's standings. A week is only archived
+// once, so later edits to the game log don't rewrite history.
+func (s *store) saveArchivedWeek(ctx context.Context, league string, a *ArchivedWeek) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO standings_archive (league, week, html, png, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (league, week) DO NOTHING`,
+		league, a.Week, a.HTML, a.PNG, a.Archived.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save archived week: %w", err)
+	}
+	return nil
+}
+
+// weekEnd returns the Sunday that ends the ISO week, like "2024-W21".
+func weekEnd(week string, loc *time.Location) (time.Time, error) {
+	var year, n int
+	if _, err := fmt.Sscanf(week, "%d-W%d", &year, &n); err != nil {
+		return time.Time{}, fmt.Errorf("invalid week %q: %w", week, err)
+	}
+	// the 4th of January is always in the first week
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, loc)
+	monday := jan4.AddDate(0, 0, -(int(jan4.Weekday())+6)%7)
+	return monday.AddDate(0, 0, 7*(n-1)+6), nil
+}
+
+// replaceArchivedWeek rewrites the week's archived standings, keeping when
+// they were archived.
+func (s *store) replaceArchivedWeek(ctx context.Context, league string, a *ArchivedWeek) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE standings_archive SET html = ?, png = ? WHERE league = ? AND week = ?`,
+		a.HTML, a.PNG, league, a.Week)
+	if err != nil {
+		return fmt.Errorf("failed to replace archived week: %w", err)
+	}
+	return nil
+}
+
+// deleteArchivedWeek deletes the week's archived standings.
+func (s *store) deleteArchivedWeek(ctx context.Context, league, week string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM standings_archive WHERE league = ? AND week = ?`, league, week); err != nil {
+		return fmt.Errorf("failed to delete archived week: %w", err)
+	}
+	return nil
+}
+
+// rerenderArchive renders every archived week again from the dataset, for
+// when players are renamed or forgotten and the archive mustn't keep their
+// old names. Weeks with no games left in the dataset are deleted. It returns
+// the number of weeks changed, which on a dry run are only counted.
+func (l *league) rerenderArchive(ctx context.Context, ds *Dataset, dryRun bool) (int64, error) {
+	if l.archive == nil {
+		return 0, nil
+	}
+	weeks, err := l.archive.archivedWeeks(ctx, l.snapshotKey())
+	if err != nil || dryRun {
+		return int64(len(weeks)), err
+	}
+	sort.Sort(ByID(ds.Games))
+	for _, week := range weeks {
+		sunday, err := weekEnd(week, l.clock.Now().Location())
+		if err != nil {
+			return 0, err
+		}
+		a, err := renderArchivedWeek(ctx, l, ds, sunday)
+		if err != nil {
+			return 0, err
+		}
+		if a == nil {
+			err = l.archive.deleteArchivedWeek(ctx, l.snapshotKey(), week)
+		} else {
+			err = l.archive.replaceArchivedWeek(ctx, l.snapshotKey(), a)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(weeks)), nil
+}
+
+// archiveWeek renders and stores the standings at the end of the last
+// completed week, unless they're already archived or no games had been
+// played by then.
+func archiveWeek(ctx context.Context, l *league, now time.Time) error {
+	sunday := lastCompletedWeek(now)
+	week := isoWeek(sunday)
+	if _, err := l.archive.archivedWeek(ctx, l.snapshotKey(), week); err == nil {
+		return nil
+	} else if !errors.Is(err, errNotFound) {
+		return err
+	}
+
+	ds, err := l.fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("archiver: error fetching game data: %w", err)
+	}
+	sort.Sort(ByID(ds.Games))
+	a, err := renderArchivedWeek(ctx, l, ds, sunday)
+	if err != nil || a == nil {
+		return err
+	}
+	a.Archived = now
+	return l.archive.saveArchivedWeek(ctx, l.snapshotKey(), a)
+}
+
+// renderArchivedWeek renders the standings at the end of the week ending on
+// sunday, or returns nil if no games had been played by then.
+func renderArchivedWeek(ctx context.Context, l *league, ds *Dataset, sunday time.Time) (*ArchivedWeek, error) {
+	week := isoWeek(sunday)
+	rankings, played := rankingsAsOf(ctx, l, ds, sunday)
+	if played == 0 {
+		return nil, nil
+	}
+	season := ""
+	if seasons := leagueSeasons(gamesAsOf(ds.Games, sunday), ds.Seasons); len(seasons) > 0 {
+		season = seasons[len(seasons)-1].Name
+	}
+
+	a := &ArchivedWeek{Week: week}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, snapshotImage(rankings, season, sunday)); err != nil {
+		return nil, fmt.Errorf("failed to draw archived week: %w", err)
+	}
+	a.PNG = buf.Bytes()
+
+	var page bytes.Buffer
+	err := t.ExecuteTemplate(&page, "archive_week.html.tmpl", ArchivedWeekView{
+		PageView: PageView{
+			Version: version,
+			Meta: PageMeta{
+				Title:       "Standings, week " + week,
+				Description: fmt.Sprintf("The standings after %d games, as of %s.", played, shortDate(sunday)),
+				URL:         absoluteURL("/archive/" + week),
+				Image:       absoluteURL("/archive/" + week + ".png"),
+				NoIndex:     noIndex,
+			},
+		},
+		Week:     week,
+		Ended:    sunday,
+		Season:   season,
+		Games:    played,
+		Rankings: rankings,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render archived week: %w", err)
+	}
+	a.HTML = page.Bytes()
+	return a, nil
+}
+
+// runArchiver archives each week's standings once the week is over, checking
+// every archiveInterval until the context is cancelled.
+func runArchiver(ctx context.Context, l *league) {
+	ticker := time.NewTicker(archiveInterval)
+	defer ticker.Stop()
+
+	for {
+		job := backgroundJobs.enqueue("archive", 3, func(ctx context.Context) error {
+			return archiveWeek(ctx, l, l.clock.Now())
+		})
+		select {
+		case <-ctx.Done():
+			return
+		case <-job.done:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// archiveHandler returns the handler for the standings archive: the list of
+// archived weeks at /archive, and each week's standings at /archive/2024-W21
+// with its image at /archive/2024-W21.png.
+func archiveHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.archive == nil {
+			notFoundRes(w, r)
+			return
+		}
+
+		week := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/archive"), "/")
+		if week == "" {
+			weeks, err := l.archive.archivedWeeks(r.Context(), l.snapshotKey())
+			if err != nil {
+				log.Printf("error listing archived weeks: %+v", err)
+				errorRes(w, r, err)
+				return
+			}
+			t.ExecuteTemplate(w, "archive.html.tmpl", ArchiveView{
+				PageView: newPageView(r, "Archive", "The league's standings at the end of every week."),
+				Weeks:    weeks,
+			})
+			return
+		}
+
+		image := strings.HasSuffix(week, ".png")
+		week = strings.TrimSuffix(week, ".png")
+		if !weekPattern.MatchString(week) {
+			notFoundRes(w, r)
+			return
+		}
+		a, err := l.archive.archivedWeek(r.Context(), l.snapshotKey(), week)
+		if errors.Is(err, errNotFound) {
+			notFoundRes(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("error loading archived week: %+v", err)
+			errorRes(w, r, err)
+			return
+		}
+
+		// archived weeks never change
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		if image {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(a.PNG)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(a.HTML)
+	}
+}
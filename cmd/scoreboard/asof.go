@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// gamesAsOf returns the games played by the end of the given day.
+func gamesAsOf(games []*Game, day time.Time) []*Game {
+	end := day.AddDate(0, 0, 1)
+	var played []*Game
+	for _, g := range games {
+		if !g.Timestamp.IsZero() && g.Timestamp.Before(end) {
+			played = append(played, g)
+		}
+	}
+	return played
+}
+
+// snapshotVersion identifies the inputs to a leaderboard: the games and
+// adjustments it was built from and how they were scored. A stored snapshot
+// is only served while its version matches, so editing an old game or
+// changing the engine or zero-sum scoring rebuilds it.
+func snapshotVersion(ds *Dataset, games []*Game, day time.Time) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%v\x00%v\x00%d\x00%d\x00%s\x00%t\n", ratingEngine, upsetCurve, archenemyMultiplier, kFactor, startingRating, rewardCurveShape, zeroSum)
+	fmt.Fprintln(h, datasetVersion(&Dataset{Games: games, Players: ds.Players}))
+	end := day.AddDate(0, 0, 1)
+	for _, adj := range ds.Adjustments {
+		if adj.Timestamp.Before(end) {
+			fmt.Fprintf(h, "%s\x00%s\x00%d\n", adj.Date, adj.Player, adj.Amount)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// ratingSnapshot returns the stored leaderboard as of the day, if one was
+// stored from the same version of the data.
+func (s *store) ratingSnapshot(ctx context.Context, league, asOf, version string) ([]Player, bool, error) {
+	var stored, rankings string
+	err := s.db.QueryRowContext(ctx, `SELECT version, rankings FROM rating_snapshots WHERE league = ? AND as_of = ?`,
+		league, asOf).Scan(&stored, &rankings)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, false, nil
+	case err != nil:
+		return nil, false, fmt.Errorf("failed to load rating snapshot: %w", err)
+	case stored != version:
+		return nil, false, nil
+	}
+
+	var players []Player
+	if err := json.Unmarshal([]byte(rankings), &players); err != nil {
+		return nil, false, fmt.Errorf("failed to decode rating snapshot: %w", err)
+	}
+	return players, true, nil
+}
+
+// saveRatingSnapshot stores the leaderboard as of the day at now, replacing
+// any snapshot from an older version of the data.
+func (s *store) saveRatingSnapshot(ctx context.Context, league, asOf, version string, rankings []Player, now time.Time) error {
+	b, err := json.Marshal(rankings)
+	if err != nil {
+		return fmt.Errorf("failed to encode rating snapshot: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO rating_snapshots (league, as_of, version, rankings, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (league, as_of) DO UPDATE SET version = excluded.version, rankings = excluded.rankings, created_at = excluded.created_at`,
+		league, asOf, version, string(b), now.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save rating snapshot: %w", err)
+	}
+	return nil
+}
+
+// rankingsAsOf returns the leaderboard as it stood at the end of the day by
+// replaying the games played by then, served from the league's stored
+// snapshots when it has them. It also returns the number of games replayed.
+func rankingsAsOf(ctx context.Context, l *league, ds *Dataset, day time.Time) ([]Player, int) {
+	games := gamesAsOf(ds.Games, day)
+	asOf := day.Format("2006-01-02")
+	version := snapshotVersion(ds, games, day)
+
+	if l.snapshots != nil {
+		rankings, ok, err := l.snapshots.ratingSnapshot(ctx, l.snapshotKey(), asOf, version)
+		if err != nil {
+			// fall back to replaying the games
+			log.Printf("error loading rating snapshot: %+v", err)
+		}
+		if ok {
+			return rankings, len(games)
+		}
+	}
+
+	end := day.AddDate(0, 0, 1)
+	var adjustments []*Adjustment
+	for _, adj := range ds.Adjustments {
+		if adj.Timestamp.Before(end) {
+			adjustments = append(adjustments, adj)
+		}
+	}
+	rankings := rankPlayers(games, calculateScores(games, adjustments...), l.clock.Now())
+	ds.Names.apply(rankings)
+
+	if l.snapshots != nil {
+		saved := append([]Player(nil), rankings...)
+		backgroundJobs.enqueue("snapshot", 3, func(ctx context.Context) error {
+			return l.snapshots.saveRatingSnapshot(ctx, l.snapshotKey(), asOf, version, saved, l.clock.Now())
+		})
+	}
+	return rankings, len(games)
+}
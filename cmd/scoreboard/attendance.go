@@ -0,0 +1,224 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// splitPodPlayers is the number of players at a league night that could
+// play as two pods of four at once instead of taking turns.
+const splitPodPlayers = 8
+
+// PlayerAttendance is how often a player turns up to league nights.
+type PlayerAttendance struct {
+	ID     string
+	Name   string
+	Games  int
+	Nights int // the league nights the player played at least one game.
+
+	// Participation is the share of the league nights since the player's
+	// first that they played at.
+	Participation float64
+	// GamesPerMonth is the player's average games a month from the month of
+	// their first game to the month of the league's latest.
+	GamesPerMonth float64
+	// LongestAbsence is the most days between two league nights the player
+	// played at, or since the last one they played at to the latest.
+	LongestAbsence int
+
+	First, Last time.Time // the first and last league nights the player played at.
+
+	Chart template.HTML // a sparkline of the player's games each month.
+}
+
+// ActivityMonth is the league's activity in a month.
+type ActivityMonth struct {
+	Month   string // like "2023-01".
+	Games   int
+	Nights  int
+	Players int // the players who played at least one game.
+}
+
+// LeagueActivity is how busy the league's nights are.
+type LeagueActivity struct {
+	Nights          int
+	PlayersPerNight float64 // the average players at a league night.
+	MostPlayers     int     // the most players at one league night.
+	SplitNights     int     // the nights with at least splitPodPlayers players.
+	Months          []ActivityMonth
+
+	GamesChart   template.HTML // a sparkline of the games each month.
+	PlayersChart template.HTML // a sparkline of the players each month.
+}
+
+// attendance reports each player's attendance and the league's activity from
+// the games, counting every calendar day with a game as a league night.
+// Undated games are left out. Players are ordered by the nights they played
+// at, most first.
+func attendance(games []*Game, names playerNames) ([]PlayerAttendance, LeagueActivity) {
+	var activity LeagueActivity
+	type night struct {
+		day     time.Time
+		players map[string]bool
+	}
+	nights := map[string]*night{}
+	gamesOf := map[string]map[string]int{} // each player's games by month.
+	monthGames := map[string]int{}
+	for _, g := range games {
+		if g.Timestamp.IsZero() {
+			continue
+		}
+		key := g.Timestamp.Format("2006-01-02")
+		n, ok := nights[key]
+		if !ok {
+			n = &night{day: time.Date(g.Timestamp.Year(), g.Timestamp.Month(), g.Timestamp.Day(), 0, 0, 0, 0, time.UTC), players: map[string]bool{}}
+			nights[key] = n
+		}
+		month := g.Timestamp.Format("2006-01")
+		monthGames[month]++
+		for _, id := range g.Rankings {
+			n.players[id] = true
+			if gamesOf[id] == nil {
+				gamesOf[id] = map[string]int{}
+			}
+			gamesOf[id][month]++
+		}
+	}
+	if len(nights) == 0 {
+		return nil, activity
+	}
+
+	days := make([]*night, 0, len(nights))
+	for _, n := range nights {
+		days = append(days, n)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].day.Before(days[j].day) })
+	first, latest := days[0].day, days[len(days)-1].day
+
+	// every month from the first league night to the latest, including quiet
+	// ones
+	var months []string
+	for m := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC); !m.After(latest); m = m.AddDate(0, 1, 0) {
+		months = append(months, m.Format("2006-01"))
+	}
+	monthIndex := map[string]int{}
+	activity.Months = make([]ActivityMonth, len(months))
+	for i, m := range months {
+		monthIndex[m] = i
+		activity.Months[i] = ActivityMonth{Month: m, Games: monthGames[m]}
+	}
+
+	seen := map[string][]time.Time{} // the nights each player played at.
+	monthPlayers := make([]map[string]bool, len(months))
+	activity.Nights = len(days)
+	total := 0
+	for _, n := range days {
+		i := monthIndex[n.day.Format("2006-01")]
+		activity.Months[i].Nights++
+		if monthPlayers[i] == nil {
+			monthPlayers[i] = map[string]bool{}
+		}
+		for id := range n.players {
+			seen[id] = append(seen[id], n.day)
+			monthPlayers[i][id] = true
+		}
+		total += len(n.players)
+		if len(n.players) > activity.MostPlayers {
+			activity.MostPlayers = len(n.players)
+		}
+		if len(n.players) >= splitPodPlayers {
+			activity.SplitNights++
+		}
+	}
+	activity.PlayersPerNight = float64(total) / float64(len(days))
+	var gamesChart, playersChart []float64
+	for i := range activity.Months {
+		activity.Months[i].Players = len(monthPlayers[i])
+		gamesChart = append(gamesChart, float64(activity.Months[i].Games))
+		playersChart = append(playersChart, float64(activity.Months[i].Players))
+	}
+	activity.GamesChart = sparkline(gamesChart, "games per month")
+	activity.PlayersChart = sparkline(playersChart, "players per month")
+
+	var players []PlayerAttendance
+	for id, attended := range seen {
+		p := PlayerAttendance{ID: id, Name: names.Of(id), Nights: len(attended), First: attended[0], Last: attended[len(attended)-1]}
+		since := 0
+		for _, n := range days {
+			if !n.day.Before(p.First) {
+				since++
+			}
+		}
+		p.Participation = float64(p.Nights) / float64(since)
+		for i := 1; i < len(attended); i++ {
+			if gap := int(attended[i].Sub(attended[i-1]).Hours() / 24); gap > p.LongestAbsence {
+				p.LongestAbsence = gap
+			}
+		}
+		if gap := int(latest.Sub(p.Last).Hours() / 24); gap > p.LongestAbsence {
+			p.LongestAbsence = gap
+		}
+
+		var chart []float64
+		for _, m := range months[monthIndex[p.First.Format("2006-01")]:] {
+			chart = append(chart, float64(gamesOf[id][m]))
+			p.Games += gamesOf[id][m]
+		}
+		p.GamesPerMonth = float64(p.Games) / float64(len(chart))
+		p.Chart = sparkline(chart, "games per month")
+		players = append(players, p)
+	}
+	sort.Slice(players, func(i, j int) bool {
+		a, b := players[i], players[j]
+		if a.Nights != b.Nights {
+			return a.Nights > b.Nights
+		}
+		if a.Participation != b.Participation {
+			return a.Participation > b.Participation
+		}
+		return a.Name < b.Name
+	})
+	return players, activity
+}
+
+// attendanceHandler returns the handler for the attendance page at
+// /attendance, which shows how often each player plays and how busy the
+// league's nights are, over a season picked with the season parameter like
+// the eras page or all time.
+func attendanceHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		games := append(append([]*Game{}, ds.Games...), ds.Unscored...)
+		seasons := leagueSeasons(ds.Games, ds.Seasons)
+
+		var season Season
+		if v := r.URL.Query().Get("season"); v != "" {
+			if season, err = parseEra(v, seasons); err != nil {
+				badRequestRes(w, r, err.Error())
+				return
+			}
+			var inSeason []*Game
+			for _, g := range games {
+				if !g.Timestamp.IsZero() && season.Contains(g.Timestamp) {
+					inSeason = append(inSeason, g)
+				}
+			}
+			games = inSeason
+		}
+
+		players, activity := attendance(games, ds.Names)
+		t.ExecuteTemplate(w, "attendance.html.tmpl", AttendanceView{
+			PageView: newPageView(r, "Attendance", "How often each player turns up and how busy the league's nights are."),
+			Season:   season,
+			Seasons:  seasons,
+			Players:  players,
+			Activity: activity,
+			Split:    splitPodPlayers,
+		})
+	}
+}
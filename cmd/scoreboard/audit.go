@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/fly-apps/go-example/scoring"
+)
+
+// zeroSum renormalizes each game's rating changes in the elo engine to sum to
+// exactly zero. Configured with SCOREBOARD_ZERO_SUM.
+var zeroSum = false
+
+// Leak is a scored game whose rating changes don't sum to zero.
+type Leak struct {
+	Game *Game
+	Net  int // the sum of the rating changes, positive when points were created.
+}
+
+// auditZeroSum returns the scored games whose rating changes sum to further
+// than tolerance from zero, largest leak first, and the net change over
+// every game. Some leak is expected from rounding each change.
+func auditZeroSum(games []*Game, tolerance int) ([]Leak, int) {
+	var leaks []Leak
+	total := 0
+	for _, g := range games {
+		net := scoring.NetChange(g.Results)
+		total += net
+		if absInt(net) > tolerance {
+			leaks = append(leaks, Leak{Game: g, Net: net})
+		}
+	}
+	sort.SliceStable(leaks, func(i, j int) bool { return absInt(leaks[i].Net) > absInt(leaks[j].Net) })
+	return leaks, total
+}
+
+// printAuditReport writes the leaking games and a summary of the points
+// leaked over the history.
+func printAuditReport(w io.Writer, games []*Game, leaks []Leak, total int, names playerNames) {
+	if len(leaks) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "game\tdate\tnet\tresult")
+		for _, l := range leaks {
+			fmt.Fprintf(tw, "%s\t%s\t%+d\t%s\n", l.Game.ID, l.Game.Date, l.Net, gameSummary(l.Game, names))
+		}
+		tw.Flush()
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "audited %d games: %d leak rating points, %+d points net over the history\n", len(games), len(leaks), total)
+}
+
+// auditCommand scores the league's history with the current parameters and
+// reports the games whose rating changes don't sum to zero. It exits non-zero
+// if any do.
+func auditCommand(args []string) int {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	sheetID := fs.String("sheet", spreadsheetID, "ID of the spreadsheet to audit")
+	sheetRange := fs.String("range", readRange, "range of the game log tab")
+	tolerance := fs.Int("tolerance", 2, "points a game's changes may sum from zero, for rounding")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if ratingEngine != "elo" {
+		fmt.Fprintln(os.Stderr, "audit: only the elo engine is meant to be zero-sum")
+		return 1
+	}
+
+	opts, err := sheetsOptionFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit: %s\n", err)
+		return 1
+	}
+	l := newLeague(*sheetID, *sheetRange, opts)
+	l.configureRangesFromEnv()
+	ds, err := l.fetch(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit: %s\n", err)
+		return 1
+	}
+	sort.Sort(ByID(ds.Games))
+
+	c := ds.copy()
+	scoring.Score(scoring.NewElo(scoringConfig()), c.Games, c.Adjustments...)
+	leaks, total := auditZeroSum(c.Games, *tolerance)
+	printAuditReport(os.Stdout, c.Games, leaks, total, c.Names)
+	if len(leaks) > 0 {
+		fmt.Println("set SCOREBOARD_ZERO_SUM=true to renormalize each game's changes to sum to zero")
+		return 1
+	}
+	return 0
+}
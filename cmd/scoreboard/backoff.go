@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/sheets"
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// The bounds of the refresh interval the league backs off to after Google
+// Sheets rejects a fetch for quota. It doubles with each rejection and halves
+// with each fetch that gets through, until it drops below the minimum and
+// every request fetches again.
+const (
+	minSheetsBackoff = time.Minute
+	maxSheetsBackoff = time.Hour
+)
+
+// isQuotaError reports whether err is the league running out of Sheets quota,
+// either its own tenant quota or a 429 or rate limit error from Google.
+func isQuotaError(err error) bool {
+	return errors.Is(err, errQuotaExceeded) || sheets.IsQuotaError(err)
+}
+
+// QuotaStatus is the league's standing with the Sheets quota, for the admin
+// endpoint.
+type QuotaStatus struct {
+	BackingOff  bool      `json:"backingOff"`          // whether fetches are currently held back.
+	Interval    string    `json:"interval"`            // the refresh interval backed off to, "0s" when every request fetches.
+	NextFetch   time.Time `json:"nextFetch"`           // when Sheets is next fetched from, zero when not backing off.
+	Rejections  int       `json:"rejections"`          // the fetches rejected for quota since the server started.
+	LastError   string    `json:"lastError,omitempty"` // the latest quota error.
+	LastErrorAt time.Time `json:"lastErrorAt"`
+	DataAsOf    time.Time `json:"dataAsOf"` // when the data being served was fetched.
+}
+
+// sheetsBackoff holds off fetching from Google Sheets after it rejects a
+// fetch for quota, serving the last dataset fetched in the meantime, so a
+// busy evening slows the scoreboard's updates down instead of taking it down.
+type sheetsBackoff struct {
+	mu          sync.Mutex
+	interval    time.Duration
+	next        time.Time
+	last        *Dataset
+	lastFetched time.Time
+	rejections  int
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// hold returns whether the league is backing off at now, along with a copy
+// of the last dataset fetched to serve instead, which is nil if there isn't
+// one.
+func (b *sheetsBackoff) hold(now time.Time) (*Dataset, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.interval == 0 || !now.Before(b.next) {
+		return nil, false
+	}
+	if b.last == nil {
+		return nil, true
+	}
+	return b.last.copy(), true
+}
+
+// fetched records a successful fetch, easing off the back off.
+func (b *sheetsBackoff) fetched(ds *Dataset, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.last = ds.copy()
+	b.lastFetched = now
+	if b.interval > 0 {
+		b.interval /= 2
+		if b.interval < minSheetsBackoff {
+			b.interval = 0
+		}
+		b.next = now.Add(b.interval)
+	}
+}
+
+// rejected records a fetch rejected for quota, backing off further. It
+// returns a copy of the last dataset fetched to serve instead, or nil.
+func (b *sheetsBackoff) rejected(err error, now time.Time) *Dataset {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rejections++
+	b.lastError = err.Error()
+	b.lastErrorAt = now
+	b.interval *= 2
+	if b.interval < minSheetsBackoff {
+		b.interval = minSheetsBackoff
+	}
+	if b.interval > maxSheetsBackoff {
+		b.interval = maxSheetsBackoff
+	}
+	b.next = now.Add(b.interval)
+	log.Printf("sheets quota exceeded, backing off fetches for %s: %v", b.interval, err)
+	if b.last == nil {
+		return nil
+	}
+	return b.last.copy()
+}
+
+// status returns the league's standing with the Sheets quota at now.
+func (b *sheetsBackoff) status(now time.Time) QuotaStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := QuotaStatus{
+		BackingOff:  b.interval > 0 && now.Before(b.next),
+		Interval:    b.interval.String(),
+		Rejections:  b.rejections,
+		LastError:   b.lastError,
+		LastErrorAt: b.lastErrorAt,
+		DataAsOf:    b.lastFetched,
+	}
+	if s.BackingOff {
+		s.NextFetch = b.next
+	}
+	return s
+}
+
+// quotaStatusHandler returns the admin handler that reports the league's
+// standing with the Sheets quota, at /admin/quota.
+func quotaStatusHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		web.WriteJSON(w, l.backoff.status(l.clock.Now()))
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// buildCommand renders the leaderboard, game, player and career pages and
+// the rankings API to static files, for groups that host the scoreboard on
+// GitHub Pages or similar instead of running the server.
+func buildCommand(args []string) int {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	out := fs.String("o", "public", "directory to write the site to")
+	base := fs.String("base", "", "path the site is served under, e.g. /scoreboard for a GitHub project page")
+	sheetID := fs.String("sheet", spreadsheetID, "ID of the spreadsheet to build from")
+	sheetRange := fs.String("range", readRange, "range of the game log tab")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	opts, err := sheetsOptionFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "build: %s\n", err)
+		return 1
+	}
+	l := newLeague(*sheetID, *sheetRange, opts)
+	l.configureRangesFromEnv()
+	l.profilesPath = os.Getenv("SCOREBOARD_PROFILES")
+
+	n, err := buildSite(context.Background(), l, *out, strings.TrimSuffix(*base, "/"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "build: %s\n", err)
+		return 1
+	}
+	fmt.Printf("wrote %d pages to %s\n", n, *out)
+	return 0
+}
+
+// buildSite fetches the league's data once and renders every page to files
+// under dir. It returns the number of pages written.
+func buildSite(ctx context.Context, l *league, dir, base string) (int, error) {
+	ds, err := l.fetch(ctx)
+	if err != nil {
+		return 0, err
+	}
+	// serve every page from the same data instead of refetching per page
+	l.frozen = ds
+	defer func() { l.frozen = nil }()
+
+	scored := ds.copy()
+	calculateScores(scored.Games, scored.Adjustments...)
+
+	pages := map[string]string{
+		"/":             "index.html",
+		"/api/rankings": "api/rankings.json",
+		"/snapshot.png": "snapshot.png",
+		"/robots.txt":   "robots.txt",
+		"/sitemap.xml":  "sitemap.xml",
+	}
+	players := map[string]bool{}
+	for _, g := range scored.Games {
+		if safePathSegment(g.ID) {
+			pages["/game/"+g.ID] = filepath.Join("game", g.ID, "index.html")
+			pages["/game/"+g.ID+"/explain"] = filepath.Join("game", g.ID, "explain", "index.html")
+		}
+		for _, res := range g.Results {
+			players[res.Player] = true
+		}
+	}
+	for name := range players {
+		if !safePathSegment(name) {
+			continue
+		}
+		pages["/player/"+name] = filepath.Join("player", name, "index.html")
+		pages["/career/"+name] = filepath.Join("career", name, "index.html")
+	}
+	for a, rivals := range leagueRivals(l, ds) {
+		for b := range rivals {
+			if !safePathSegment(a) || !safePathSegment(b) {
+				continue
+			}
+			pages["/rivalry/"+a+"/"+b] = filepath.Join("rivalry", a, b, "index.html")
+			pages["/rivalry/"+a+"/"+b+".svg"] = filepath.Join("rivalry", a, b+".svg")
+		}
+	}
+
+	mux := l.routes()
+	for path, file := range pages {
+		if err := buildPage(mux, base, path, filepath.Join(dir, file)); err != nil {
+			return 0, err
+		}
+	}
+	return len(pages), nil
+}
+
+// safePathSegment reports whether s can be used as a single directory name
+// without escaping the output directory.
+func safePathSegment(s string) bool {
+	return s != "" && s != "." && s != ".." && !strings.ContainsAny(s, `/\`)
+}
+
+// buildPage renders the page at path and writes it to file.
+func buildPage(h http.Handler, base, path, file string) error {
+	req := httptest.NewRequest(http.MethodGet, (&url.URL{Path: path}).String(), nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, withBasePath(req, base))
+	if rec.Code != http.StatusOK {
+		return fmt.Errorf("failed to render %s: status %d", path, rec.Code)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(file, rec.Body.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
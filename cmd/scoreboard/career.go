@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Career aggregates a player's stats across every season.
+type Career struct {
+	Name       string
+	Games      int
+	Wins       int
+	Rating     int            // the player's current all-time rating.
+	High       int            // the player's career-high rating.
+	HighGame   *Game          // the game the career-high rating was reached in.
+	Seasons    []SeasonRecord // the player's record in each season they played.
+	HeadToHead []Rivalry      // the player's lifetime record against each opponent.
+}
+
+// SeasonRecord is a player's record in a single season.
+type SeasonRecord struct {
+	Season  Season
+	Games   int
+	Wins    int
+	Rating  int // the player's rating at the end of the season.
+	Rank    int // the player's place in the season standings.
+	Players int // the number of players ranked in the season.
+}
+
+// Rivalry is a player's lifetime record against one opponent.
+type Rivalry struct {
+	Opponent string
+	Games    int // games both players were ranked in.
+	Above    int // games the player finished above the opponent.
+	Below    int // games the opponent finished above the player.
+}
+
+// buildCareer aggregates the player's career from the all-time scored games,
+// rating adjustments and the league's seasons, with trends as of now. It
+// returns nil if the player has never played.
+func buildCareer(games []*Game, adjustments []*Adjustment, seasons []Season, name string, now time.Time) *Career {
+	c := &Career{Name: name}
+	rivals := map[string]*Rivalry{}
+
+	for _, game := range games {
+		place := -1
+		for idx, res := range game.Results {
+			if res.Player == name {
+				place = idx
+				break
+			}
+		}
+		if place < 0 {
+			continue
+		}
+
+		res := game.Results[place]
+		c.Games++
+		c.Rating = res.After
+		if place == 0 && !game.IsDraw() {
+			c.Wins++
+		}
+		if c.HighGame == nil || res.After > c.High {
+			c.High = res.After
+			c.HighGame = game
+		}
+
+		if game.IsDraw() {
+			continue
+		}
+		for idx, opp := range game.Results {
+			if idx == place {
+				continue
+			}
+			r, ok := rivals[opp.Player]
+			if !ok {
+				r = &Rivalry{Opponent: opp.Player}
+				rivals[opp.Player] = r
+			}
+			r.Games++
+			if place < idx {
+				r.Above++
+			} else {
+				r.Below++
+			}
+		}
+	}
+	if c.Games == 0 {
+		return nil
+	}
+
+	for _, season := range leagueSeasons(games, seasons) {
+		seasonGames := gamesInSeason(games, season)
+		scores := calculateScores(seasonGames, adjustmentsInSeason(adjustments, season)...)
+		if _, ok := scores[name]; !ok {
+			continue
+		}
+		rankings := rankPlayers(seasonGames, scores, now)
+		for idx, p := range rankings {
+			if p.ID != name {
+				continue
+			}
+			c.Seasons = append(c.Seasons, SeasonRecord{
+				Season:  season,
+				Games:   p.Games,
+				Wins:    p.Wins,
+				Rating:  p.Score,
+				Rank:    idx + 1,
+				Players: len(rankings),
+			})
+		}
+	}
+
+	for _, r := range rivals {
+		c.HeadToHead = append(c.HeadToHead, *r)
+	}
+	sort.Slice(c.HeadToHead, func(i, j int) bool {
+		if c.HeadToHead[i].Games != c.HeadToHead[j].Games {
+			return c.HeadToHead[i].Games > c.HeadToHead[j].Games
+		}
+		return c.HeadToHead[i].Opponent < c.HeadToHead[j].Opponent
+	})
+
+	return c
+}
+
+// careerHandler returns the handler for career pages at /career/{name}.
+func careerHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/career/")
+		if name == "" || strings.Contains(name, "/") {
+			notFoundRes(w, r)
+			return
+		}
+
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		if redirectToPlayerID(w, r, ds, "/career/", name) {
+			return
+		}
+		games := ds.Games
+		calculateScores(games, ds.Adjustments...)
+
+		career := buildCareer(games, ds.Adjustments, ds.Seasons, name, l.clock.Now())
+		if career == nil {
+			notFoundRes(w, r)
+			return
+		}
+
+		t.ExecuteTemplate(w, "career.html.tmpl", CareerView{
+			PageView: newPageView(r, ds.Names.Of(name)+"'s career",
+				fmt.Sprintf("%d wins in %d games, with a career-high rating of %d.", career.Wins, career.Games, career.High)),
+			Career: career,
+			Names:  ds.Names,
+		})
+	}
+}
+
+// shortDate formats a time as a date, e.g. "Jan 2, 2006".
+func shortDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("Jan 2, 2006")
+}
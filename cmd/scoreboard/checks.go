@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// requiredTemplates are the templates the handlers render.
+var requiredTemplates = []string{
+	"index.html.tmpl",
+	"game.html.tmpl",
+	"explain.html.tmpl",
+	"player.html.tmpl",
+	"career.html.tmpl",
+	"rivalry.html.tmpl",
+	"eras.html.tmpl",
+	"timeline.html.tmpl",
+	"projections.html.tmpl",
+	"print.html.tmpl",
+	"archive.html.tmpl",
+	"archive_week.html.tmpl",
+	"picks.html.tmpl",
+	"live.html.tmpl",
+	"onboarding.html.tmpl",
+	"register.html.tmpl",
+	"login.html.tmpl",
+	"comments.html.tmpl",
+	"schedule.html.tmpl",
+	"meta.html.tmpl",
+	"banner.html.tmpl",
+	"error.html.tmpl",
+	"rules.html.tmpl",
+	"honors.html.tmpl",
+	"replay.html.tmpl",
+	"compare.html.tmpl",
+	"curves.html.tmpl",
+	"setup.html.tmpl",
+	"events.html.tmpl",
+	"attendance.html.tmpl",
+	"preferences.html.tmpl",
+	"pods.html.tmpl",
+}
+
+// checkStartup validates the configuration the server depends on before it
+// starts serving, so a bad API key or unshared sheet is reported at boot
+// rather than on the first request. l is nil in hosted mode, where each
+// tenant brings their own sheet. It returns a description of each problem
+// found, with what to do about it.
+func checkStartup(ctx context.Context, l *league) []string {
+	var problems []string
+
+	for _, name := range requiredTemplates {
+		if t.Lookup(name) == nil {
+			problems = append(problems, fmt.Sprintf("template %s is missing from the build, rebuild the binary from a clean checkout", name))
+		}
+	}
+
+	if l == nil {
+		return problems
+	}
+
+	if !hasSecretEnv("SCOREBOARD_API_KEY") && !hasSecretEnv("SCOREBOARD_CREDENTIALS") {
+		return append(problems, "SCOREBOARD_API_KEY is not set. Create an API key with the Google Sheets API enabled at https://console.cloud.google.com/apis/credentials, or set SCOREBOARD_CREDENTIALS_FILE to a service account key")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	if _, err := l.fetch(ctx); err != nil {
+		problems = append(problems, sheetProblem(l, err))
+	}
+	return problems
+}
+
+// sheetProblem explains an error fetching the league's sheet.
+func sheetProblem(l *league, err error) string {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusBadRequest:
+			if strings.Contains(strings.ToLower(apiErr.Message), "range") {
+				return fmt.Sprintf("a range doesn't exist in the sheet, check the game log tab name and the SCOREBOARD_*_RANGE variables: %s", apiErr.Message)
+			}
+			fallthrough
+		case http.StatusUnauthorized:
+			return fmt.Sprintf("the Sheets API rejected SCOREBOARD_API_KEY, check the key is valid and the Google Sheets API is enabled for its project: %s", apiErr.Message)
+		case http.StatusForbidden:
+			return fmt.Sprintf("spreadsheet %s can't be read with SCOREBOARD_API_KEY, share it so anyone with the link can view it: %s", l.spreadsheetID, apiErr.Message)
+		case http.StatusNotFound:
+			return fmt.Sprintf("spreadsheet %s was not found, check the spreadsheet ID", l.spreadsheetID)
+		}
+	}
+	var urlErr *url.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &urlErr) {
+		return fmt.Sprintf("couldn't reach the Google Sheets API, check the server's network access: %s", err)
+	}
+	return fmt.Sprintf("failed to read the game log: %s", err)
+}
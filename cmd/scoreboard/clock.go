@@ -0,0 +1,21 @@
+package main
+
+import (
+	"time"
+)
+
+// Clock tells the time. Each league has its own, which tests swap for a
+// fixedClock to simulate a date.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the Clock of the machine the scoreboard runs on.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// fixedClock is a Clock stopped at a time.
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
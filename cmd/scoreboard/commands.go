@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// runCommand runs a command line subcommand and returns the exit code.
+func runCommand(name string, args []string) int {
+	switch name {
+	case "validate":
+		return validateCommand(args)
+	case "build":
+		return buildCommand(args)
+	case "sensitivity":
+		return sensitivityCommand(args)
+	case "audit":
+		return auditCommand(args)
+	case "migrate":
+		return migrateCommand(args)
+	case "help", "-h", "-help", "--help":
+		usage()
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage()
+	return 2
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `usage: scoreboard [command]
+
+Without a command the scoreboard server is started.
+
+flags:
+  --check    validate the configuration, API key and sheet access, then exit
+
+commands:
+  validate   check the game log for problems and print a report
+  build      render the scoreboard to static HTML and JSON files
+  sensitivity
+             rescore the history under alternative elo parameters and diff
+             the final standings
+  audit      report games whose rating changes don't sum to zero
+  migrate    import the sheet into a SQLite database, verifying row counts
+             and checksums, or sync a previous import with the sheet
+`)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// maxCommentLength is the longest comment that can be posted, in bytes.
+const maxCommentLength = 2000
+
+// Comment is a note a logged in player attached to a game or player page.
+type Comment struct {
+	ID        int64     `json:"id"`
+	League    string    `json:"league"`
+	Kind      string    `json:"kind"`   // "game" or "player".
+	Target    string    `json:"target"` // the game or player ID commented on.
+	Author    string    `json:"author"` // the ID of the player who wrote it.
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+	Hidden    bool      `json:"hidden"` // hidden by a moderator.
+}
+
+// addComment stores a new comment.
+func (s *store) addComment(ctx context.Context, c *Comment) error {
+	res, err := s.db.ExecContext(ctx, `INSERT INTO comments (league, kind, target, author, body, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		c.League, c.Kind, c.Target, c.Author, c.Body, c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add comment: %w", err)
+	}
+	c.ID, err = res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to add comment: %w", err)
+	}
+	return nil
+}
+
+// comments returns the visible comments on a game or player page, oldest
+// first.
+func (s *store) comments(ctx context.Context, league, kind, target string) ([]*Comment, error) {
+	return s.queryComments(ctx, `SELECT id, league, kind, target, author, body, created_at, hidden
+		FROM comments WHERE league = ? AND kind = ? AND target = ? AND hidden = 0 ORDER BY id`,
+		league, kind, target)
+}
+
+// recentComments returns the most recent comments across every league,
+// including hidden ones, for moderation.
+func (s *store) recentComments(ctx context.Context, limit int) ([]*Comment, error) {
+	return s.queryComments(ctx, `SELECT id, league, kind, target, author, body, created_at, hidden
+		FROM comments ORDER BY id DESC LIMIT ?`, limit)
+}
+
+func (s *store) queryComments(ctx context.Context, query string, args ...interface{}) ([]*Comment, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load comments: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []*Comment
+	for rows.Next() {
+		c := &Comment{}
+		if err := rows.Scan(&c.ID, &c.League, &c.Kind, &c.Target, &c.Author, &c.Body, &c.CreatedAt, &c.Hidden); err != nil {
+			return nil, fmt.Errorf("failed to load comments: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load comments: %w", err)
+	}
+	return comments, nil
+}
+
+// moderateComment hides, shows or deletes a comment.
+func (s *store) moderateComment(ctx context.Context, id int64, action string) error {
+	var res sql.Result
+	var err error
+	switch action {
+	case "hide":
+		res, err = s.db.ExecContext(ctx, `UPDATE comments SET hidden = 1 WHERE id = ?`, id)
+	case "show":
+		res, err = s.db.ExecContext(ctx, `UPDATE comments SET hidden = 0 WHERE id = ?`, id)
+	case "delete":
+		res, err = s.db.ExecContext(ctx, `DELETE FROM comments WHERE id = ?`, id)
+	default:
+		return fmt.Errorf("unknown moderation action %q", action)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to %s comment %d: %w", action, id, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errNotFound
+	}
+	return nil
+}
+
+// pageComments returns the view of the comments on a page: the visible
+// comments and who's logged in to post one. It returns nil when comments are
+// off.
+func pageComments(l *league, r *http.Request, names playerNames, kind, target string) *CommentsView {
+	if l.comments == nil {
+		return nil
+	}
+	comments, err := l.comments.comments(r.Context(), l.snapshotKey(), kind, target)
+	if err != nil {
+		// the page is still useful without its comments
+		log.Printf("error loading comments: %+v", err)
+	}
+	return &CommentsView{
+		Base:     basePath(r),
+		Names:    names,
+		Kind:     kind,
+		Target:   target,
+		Comments: comments,
+		User:     l.currentPlayer(r),
+		Path:     r.URL.Path,
+	}
+}
+
+// commentHandler returns the handler that posts comments at /comments. Only
+// logged in players can comment.
+func commentHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.comments == nil {
+			notFoundRes(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			methodNotAllowedRes(w, r, "POST")
+			return
+		}
+		author := l.currentPlayer(r)
+		if author == "" {
+			unauthorizedRes(w, r, "log in to comment")
+			return
+		}
+
+		c := &Comment{
+			League:    l.snapshotKey(),
+			Kind:      r.PostFormValue("kind"),
+			Target:    r.PostFormValue("target"),
+			Author:    author,
+			Body:      strings.TrimSpace(r.PostFormValue("body")),
+			CreatedAt: l.clock.Now().UTC(),
+		}
+		if (c.Kind != "game" && c.Kind != "player") || c.Target == "" || strings.Contains(c.Target, "/") {
+			badRequestRes(w, r, "comments can only be left on games and players")
+			return
+		}
+		if c.Body == "" || len(c.Body) > maxCommentLength {
+			badRequestRes(w, r, fmt.Sprintf("comments must be between 1 and %d characters", maxCommentLength))
+			return
+		}
+		if err := l.comments.addComment(r.Context(), c); err != nil {
+			log.Printf("error adding comment: %+v", err)
+			errorRes(w, r, err)
+			return
+		}
+		http.Redirect(w, r, basePath(r)+"/"+c.Kind+"/"+url.PathEscape(c.Target)+"#comments", http.StatusSeeOther)
+	}
+}
+
+// commentsAdminHandler lists recent comments for moderation, and hides,
+// shows or deletes a comment when posted an id and action.
+func commentsAdminHandler(st *store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			limit := 100
+			if v := r.URL.Query().Get("limit"); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 1 {
+					badRequestRes(w, r, "limit must be a positive number")
+					return
+				}
+				limit = n
+			}
+			comments, err := st.recentComments(r.Context(), limit)
+			if err != nil {
+				log.Printf("error loading comments: %+v", err)
+				errorRes(w, r, err)
+				return
+			}
+			web.WriteJSON(w, map[string]interface{}{"comments": comments})
+		case http.MethodPost:
+			id, err := strconv.ParseInt(r.PostFormValue("id"), 10, 64)
+			if err != nil {
+				badRequestRes(w, r, "id must be a comment ID")
+				return
+			}
+			action := r.PostFormValue("action")
+			if action != "hide" && action != "show" && action != "delete" {
+				badRequestRes(w, r, "action must be hide, show or delete")
+				return
+			}
+			err = st.moderateComment(r.Context(), id, action)
+			switch {
+			case errors.Is(err, errNotFound):
+				notFoundRes(w, r)
+				return
+			case err != nil:
+				log.Printf("error moderating comment: %+v", err)
+				errorRes(w, r, err)
+				return
+			}
+			web.WriteJSON(w, map[string]interface{}{"id": id, "action": action})
+		default:
+			methodNotAllowedRes(w, r, "GET, POST")
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// maxComparedLeagues is the most leagues that can be compared at once, since
+// each is fetched from Google Sheets.
+const maxComparedLeagues = 6
+
+// ComparedLeague is one of the leagues in a cross-league comparison, with the
+// spread of its ratings the players' z-scores are taken against.
+type ComparedLeague struct {
+	Slug    string
+	Name    string
+	Players int     // the players with at least one game.
+	Mean    float64 // the mean rating of those players.
+	StdDev  float64 // the standard deviation of their ratings.
+	Wins    int     // the inter-league games won by the league's players.
+}
+
+// CrossLeagueEntry is a player's standing in a cross-league comparison.
+type CrossLeagueEntry struct {
+	League string // the slug of the player's league.
+	Player string // the player's ID.
+	Name   string
+	Score  int
+	Games  int
+	Z      float64 // how many standard deviations the rating is above the league's mean.
+}
+
+// InterLeagueGame is a game with players from more than one of the compared
+// leagues, each player belonging to the league they've played the most games
+// in.
+type InterLeagueGame struct {
+	League  string // the slug of the league whose game log it's from.
+	Game    *Game
+	Players []InterLeaguePlayer // the pod, in finishing order.
+	Winners []string            // the slugs of the winners' leagues, empty for a draw.
+}
+
+// InterLeaguePlayer is a player in an inter-league game.
+type InterLeaguePlayer struct {
+	Name   string
+	League string
+}
+
+// CrossLeague compares the ratings of the players of several leagues.
+type CrossLeague struct {
+	Leagues []ComparedLeague
+	Entries []CrossLeagueEntry // highest z-score first.
+	Games   []InterLeagueGame  // oldest first.
+}
+
+// crossLeague compares the leagues' scored datasets. Ratings aren't
+// comparable across leagues, since each league's pool of points depends on
+// how many players it has and how much they play, so each player's rating
+// is normalized to a z-score within their league.
+func crossLeague(leagues []ComparedLeague, datasets []*Dataset) CrossLeague {
+	c := CrossLeague{Leagues: leagues}
+	played := map[string][]int{} // games per league, keyed by player ID.
+	for i, ds := range datasets {
+		scores := calculateScores(ds.Games, ds.Adjustments...)
+		games := map[string]int{}
+		for _, g := range ds.Games {
+			for _, id := range g.Rankings {
+				games[id]++
+			}
+		}
+
+		var entries []CrossLeagueEntry
+		sum := 0.0
+		for id, score := range scores {
+			if games[id] == 0 {
+				continue
+			}
+			entries = append(entries, CrossLeagueEntry{League: leagues[i].Slug, Player: id, Name: ds.Names.Of(id), Score: score, Games: games[id]})
+			sum += float64(score)
+			if played[id] == nil {
+				played[id] = make([]int, len(datasets))
+			}
+			played[id][i] = games[id]
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		mean := sum / float64(len(entries))
+		variance := 0.0
+		for _, e := range entries {
+			variance += (float64(e.Score) - mean) * (float64(e.Score) - mean)
+		}
+		stdDev := math.Sqrt(variance / float64(len(entries)))
+		for idx := range entries {
+			if stdDev > 0 {
+				entries[idx].Z = (float64(entries[idx].Score) - mean) / stdDev
+			}
+		}
+		c.Leagues[i].Players, c.Leagues[i].Mean, c.Leagues[i].StdDev = len(entries), mean, stdDev
+		c.Entries = append(c.Entries, entries...)
+	}
+	sort.Slice(c.Entries, func(i, j int) bool {
+		if c.Entries[i].Z != c.Entries[j].Z {
+			return c.Entries[i].Z > c.Entries[j].Z
+		}
+		return c.Entries[i].Name < c.Entries[j].Name
+	})
+
+	// each player belongs to the league they've played the most games in,
+	// the first compared on a tie
+	home := map[string]int{}
+	for id, counts := range played {
+		best := 0
+		for i, n := range counts {
+			if n > counts[best] {
+				best = i
+			}
+		}
+		home[id] = best
+	}
+
+	// a game logged by more than one league is only counted once
+	seen := map[string]bool{}
+	for i, ds := range datasets {
+		for _, g := range ds.Games {
+			leaguesIn := map[int]bool{}
+			for _, id := range g.Rankings {
+				leaguesIn[home[id]] = true
+			}
+			if len(leaguesIn) < 2 {
+				continue
+			}
+			pod := append([]string{}, g.Rankings...)
+			sort.Strings(pod)
+			key := g.Timestamp.Format("2006-01-02") + "\x00" + strings.Join(pod, "\x00")
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			ig := InterLeagueGame{League: leagues[i].Slug, Game: g}
+			for _, id := range g.Rankings {
+				ig.Players = append(ig.Players, InterLeaguePlayer{Name: ds.Names.Of(id), League: leagues[home[id]].Slug})
+			}
+			if !g.IsDraw() {
+				// every league with a player on an archenemy game's winning
+				// team shares the win
+				won := map[int]bool{}
+				for _, id := range g.Rankings {
+					if winner := home[id]; g.Won(id) && !won[winner] {
+						won[winner] = true
+						ig.Winners = append(ig.Winners, leagues[winner].Slug)
+						c.Leagues[winner].Wins++
+					}
+				}
+			}
+			c.Games = append(c.Games, ig)
+		}
+	}
+	sort.SliceStable(c.Games, func(i, j int) bool { return c.Games[i].Game.Timestamp.Before(c.Games[j].Game.Timestamp) })
+	return c
+}
+
+// compare serves the cross-league comparison at /compare?leagues=a,b, which
+// ranks the players of the registered leagues by their rating's z-score
+// within their own league and lists the games played between them.
+func (h *hostedServer) compare(w http.ResponseWriter, r *http.Request) {
+	view := CompareView{
+		PageView: PageView{
+			Version: version,
+			Meta:    pageMeta(r, "Cross-league comparison", "Players from several leagues ranked against each other by normalized rating."),
+		},
+	}
+
+	var slugs []string
+	seen := map[string]bool{}
+	for _, slug := range strings.Split(r.URL.Query().Get("leagues"), ",") {
+		slug = strings.ToLower(strings.TrimSpace(slug))
+		if slug != "" && !seen[slug] {
+			seen[slug] = true
+			slugs = append(slugs, slug)
+		}
+	}
+	view.Slugs = strings.Join(slugs, ",")
+	if len(slugs) > maxComparedLeagues {
+		badRequestRes(w, r, fmt.Sprintf("at most %d leagues can be compared", maxComparedLeagues))
+		return
+	}
+	if len(slugs) < 2 {
+		t.ExecuteTemplate(w, "compare.html.tmpl", view)
+		return
+	}
+
+	leagues := make([]ComparedLeague, len(slugs))
+	datasets := make([]*Dataset, len(slugs))
+	for i, slug := range slugs {
+		hl, err := h.league(r.Context(), slug)
+		if errors.Is(err, errNotFound) {
+			badRequestRes(w, r, fmt.Sprintf("there's no league %q", slug))
+			return
+		}
+		if err != nil {
+			log.Printf("error loading tenant %s: %+v", slug, err)
+			errorRes(w, r, err)
+			return
+		}
+		ds, err := hl.league.fetch(r.Context())
+		if err != nil {
+			log.Printf("error fetching game data for %s: %+v", slug, err)
+			errorRes(w, r, err)
+			return
+		}
+		sort.Sort(ByID(ds.Games))
+		leagues[i] = ComparedLeague{Slug: slug, Name: hl.tenant.Name}
+		datasets[i] = ds
+	}
+
+	compared := crossLeague(leagues, datasets)
+	view.Compare = &compared
+	t.ExecuteTemplate(w, "compare.html.tmpl", view)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+const (
+	// defaultConsoleRows and maxConsoleRows bound the rows the SQL console
+	// returns for a query.
+	defaultConsoleRows, maxConsoleRows = 100, 1000
+
+	// consoleTimeout is how long a console query may run.
+	consoleTimeout = 5 * time.Second
+)
+
+// errNotSelect is returned for console queries that aren't a single SELECT.
+var errNotSelect = errors.New("only a single SELECT query is allowed")
+
+// QueryResult is the result of a SQL console query.
+type QueryResult struct {
+	Columns   []string        `json:"columns"`
+	Rows      [][]interface{} `json:"rows"`
+	Truncated bool            `json:"truncated"` // more rows matched than the limit.
+}
+
+// checkSelect reports whether the query is a single SELECT statement,
+// optionally starting with a WITH clause, ignoring a trailing semicolon.
+func checkSelect(query string) error {
+	q := strings.TrimSuffix(strings.TrimSpace(query), ";")
+	if strings.Contains(q, ";") || strings.Contains(q, "--") || strings.Contains(q, "/*") {
+		return errNotSelect
+	}
+	fields := strings.Fields(q)
+	if len(fields) == 0 {
+		return errNotSelect
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "WITH":
+		return nil
+	}
+	return errNotSelect
+}
+
+// readOnlyQuery runs a SELECT query on a connection set to query only, so
+// even a query that slips past checkSelect can't modify the database, and
+// returns up to limit rows.
+func (s *store) readOnlyQuery(ctx context.Context, query string, limit int) (*QueryResult, error) {
+	if err := checkSelect(query); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, consoleTimeout)
+	defer cancel()
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a connection: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `PRAGMA query_only = ON`); err != nil {
+		return nil, fmt.Errorf("failed to make the connection read-only: %w", err)
+	}
+	// the connection goes back to the pool, so it must be writable again
+	defer conn.ExecContext(context.Background(), `PRAGMA query_only = OFF`)
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := &QueryResult{Rows: [][]interface{}{}}
+	if res.Columns, err = rows.Columns(); err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		if len(res.Rows) == limit {
+			res.Truncated = true
+			break
+		}
+		values := make([]interface{}, len(res.Columns))
+		ptrs := make([]interface{}, len(values))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		res.Rows = append(res.Rows, values)
+	}
+	return res, rows.Err()
+}
+
+// consoleHandler returns the admin SQL console handler, which runs the read
+// only query in the q parameter and returns its rows as JSON. limit caps the
+// rows returned.
+func consoleHandler(st *store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			methodNotAllowedRes(w, r, "GET, POST")
+			return
+		}
+		limit := defaultConsoleRows
+		if v := r.FormValue("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxConsoleRows {
+				badRequestRes(w, r, fmt.Sprintf("limit must be between 1 and %d", maxConsoleRows))
+				return
+			}
+			limit = n
+		}
+
+		res, err := st.readOnlyQuery(r.Context(), r.FormValue("q"), limit)
+		if err != nil {
+			log.Printf("console query failed: %s", err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			web.WriteJSON(w, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		web.WriteJSON(w, res)
+	}
+}
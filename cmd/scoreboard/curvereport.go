@@ -0,0 +1,182 @@
+package main
+
+import (
+	"html/template"
+	"math"
+	"net/http"
+	"sort"
+)
+
+// PodSizeReport compares how games of one pod size are actually scored with
+// the reward curve configured for the size.
+type PodSizeReport struct {
+	Size         int
+	Games        int
+	Share        float64 // the share of the period's games with this pod size.
+	MeanAbsDelta float64 // the average size of a rating change.
+	Places       []PlaceReport
+	Drift        []CurveDrift // the average size of a rating change each month, oldest first.
+
+	Chart template.HTML // a sparkline of the drift.
+}
+
+// PlaceReport is how the players finishing in a place of a pod size fared.
+type PlaceReport struct {
+	Place        int
+	Reward       float64 // the share of the rewards the curve awards the place.
+	MeanDelta    float64 // the average rating change for the place.
+	MeanAbsDelta float64 // the average size of the rating change for the place.
+	Expected     float64 // the average placement the ratings expected of the players who finished here.
+}
+
+// CurveDrift is the average size of the rating changes in a month.
+type CurveDrift struct {
+	Month        string // like "2023-01".
+	Games        int
+	MeanAbsDelta float64
+}
+
+// curveReport reports on the scored games by pod size, smallest pod first.
+// Draws are left out, since they have no placements.
+func curveReport(games []*Game) []PodSizeReport {
+	type tally struct {
+		games, deltas int
+		absDelta      float64
+		placeDelta    []float64
+		placeAbs      []float64
+		placeExpected []float64
+		placeCount    []int
+		months        map[string][2]float64 // the results and their total absolute delta.
+	}
+	tallies := map[int]*tally{}
+	total := 0
+	for _, g := range games {
+		if g.IsDraw() || len(g.Results) < 2 {
+			continue
+		}
+		size := len(g.Results)
+		ty, ok := tallies[size]
+		if !ok {
+			ty = &tally{
+				placeDelta:    make([]float64, size),
+				placeAbs:      make([]float64, size),
+				placeExpected: make([]float64, size),
+				placeCount:    make([]int, size),
+				months:        map[string][2]float64{},
+			}
+			tallies[size] = ty
+		}
+		ty.games++
+		total++
+
+		month := ""
+		if !g.Timestamp.IsZero() {
+			month = g.Timestamp.Format("2006-01")
+		}
+		for idx, res := range g.Results {
+			var opponents []int
+			for oppIdx, opp := range g.Results {
+				if oppIdx != idx {
+					opponents = append(opponents, opp.Before)
+				}
+			}
+			abs := math.Abs(float64(res.Delta))
+			ty.deltas++
+			ty.absDelta += abs
+			place := res.Place - 1
+			if place < 0 || place >= size {
+				continue
+			}
+			ty.placeCount[place]++
+			ty.placeDelta[place] += float64(res.Delta)
+			ty.placeAbs[place] += abs
+			ty.placeExpected[place] += expectedPlacement(res.Before, opponents)
+			if month != "" {
+				m := ty.months[month]
+				ty.months[month] = [2]float64{m[0] + 1, m[1] + abs}
+			}
+		}
+	}
+
+	var reports []PodSizeReport
+	for size, ty := range tallies {
+		rep := PodSizeReport{Size: size, Games: ty.games, Share: float64(ty.games) / float64(total)}
+		if ty.deltas > 0 {
+			rep.MeanAbsDelta = ty.absDelta / float64(ty.deltas)
+		}
+		curve := rewardCurve(size)
+		for place := 0; place < size; place++ {
+			p := PlaceReport{Place: place + 1}
+			if place < len(curve) {
+				p.Reward = curve[place]
+			}
+			if n := float64(ty.placeCount[place]); n > 0 {
+				p.MeanDelta = ty.placeDelta[place] / n
+				p.MeanAbsDelta = ty.placeAbs[place] / n
+				p.Expected = ty.placeExpected[place] / n
+			}
+			rep.Places = append(rep.Places, p)
+		}
+
+		months := make([]string, 0, len(ty.months))
+		for month := range ty.months {
+			months = append(months, month)
+		}
+		sort.Strings(months)
+		var values []float64
+		for _, month := range months {
+			m := ty.months[month]
+			d := CurveDrift{Month: month, Games: int(m[0]) / size, MeanAbsDelta: m[1] / m[0]}
+			rep.Drift = append(rep.Drift, d)
+			values = append(values, d.MeanAbsDelta)
+		}
+		if len(values) > 1 {
+			rep.Chart = sparkline(values, "average rating change each month")
+		}
+		reports = append(reports, rep)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Size < reports[j].Size })
+	return reports
+}
+
+// curvesHandler returns the handler for the reward curve report at /curves,
+// which shows how each pod size is scored over a season, picked with the
+// season parameter like the eras page and defaulting to the latest.
+func curvesHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		games := ds.Games
+		calculateScores(games, ds.Adjustments...)
+		seasons := leagueSeasons(games, ds.Seasons)
+
+		var season Season
+		if v := r.URL.Query().Get("season"); v != "" {
+			if season, err = parseEra(v, seasons); err != nil {
+				badRequestRes(w, r, err.Error())
+				return
+			}
+		} else if len(seasons) > 0 {
+			season = seasons[len(seasons)-1]
+		}
+		if season.Name != "" {
+			var inSeason []*Game
+			for _, g := range games {
+				if !g.Timestamp.IsZero() && season.Contains(g.Timestamp) {
+					inSeason = append(inSeason, g)
+				}
+			}
+			games = inSeason
+		}
+
+		t.ExecuteTemplate(w, "curves.html.tmpl", CurvesView{
+			PageView: newPageView(r, "Reward curves", "How each pod size's rating changes compare with its configured reward curve."),
+			Season:   season,
+			Seasons:  seasons,
+			Curve:    rewardCurveShape,
+			Sizes:    curveReport(games),
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultDateLayouts are the layouts game log dates are parsed with, tried in
+// order: the format live game entry writes, then the formats Google Sheets
+// shows dates in for common locales.
+var defaultDateLayouts = []string{time.RFC1123, "1/2/2006", "2006-01-02", "Jan 2 2006", "Jan 2, 2006"}
+
+// namedDateFormats are the names SCOREBOARD_DATE_FORMAT accepts for common
+// date formats, besides a Go layout like "02.01.2006".
+var namedDateFormats = map[string]string{
+	"rfc1123": time.RFC1123,
+	"us":      "1/2/2006",
+	"eu":      "2/1/2006",
+	"iso":     "2006-01-02",
+	"text":    "Jan 2 2006",
+}
+
+// dateLayouts are the layouts game log dates are parsed with, tried in order.
+// Configured with SCOREBOARD_DATE_FORMAT.
+var dateLayouts = defaultDateLayouts
+
+// configureDateFormat sets the date format tried first for game log dates,
+// either one of namedDateFormats or a Go layout, ahead of the other formats.
+// A day first format like "eu" replaces the month first one, so 3/1/2023 is
+// never read both ways in the same sheet.
+func configureDateFormat(format string) error {
+	if format == "" {
+		dateLayouts = defaultDateLayouts
+		return nil
+	}
+	layout, ok := namedDateFormats[strings.ToLower(format)]
+	if !ok {
+		layout = format
+		ts := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+		if parsed, err := time.Parse(layout, ts.Format(layout)); err != nil || parsed.Day() != 2 || parsed.Month() != 1 {
+			return fmt.Errorf("%q is not a date format, use one of rfc1123, us, eu, iso or text, or a Go layout like 02.01.2006", format)
+		}
+	}
+
+	layouts := []string{layout}
+	for _, l := range defaultDateLayouts {
+		if l == layout || (l == "1/2/2006" && strings.HasPrefix(layout, "2/1/")) {
+			continue
+		}
+		layouts = append(layouts, l)
+	}
+	dateLayouts = layouts
+	return nil
+}
+
+// parseGameDate parses a game log date with the first of dateLayouts that
+// fits it.
+func parseGameDate(date string) (time.Time, error) {
+	date = strings.TrimSpace(date)
+	for _, layout := range dateLayouts {
+		if ts, err := time.Parse(layout, date); err == nil {
+			return ts, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown date format %q, expected a date like %q", date, time.Date(2023, 1, 2, 19, 0, 0, 0, time.UTC).Format(dateLayouts[0]))
+}
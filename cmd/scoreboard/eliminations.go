@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// playerColumn is the index of the first player column, F.
+	playerColumn = 5
+	// eliminationColumn is the index of the first elimination time column, L.
+	// The elimination time for the player in column F+n is in column L+n.
+	eliminationColumn = playerColumn + maxPlayers
+)
+
+// eliminationFormats are the accepted elimination time formats. Times without
+// a date are taken to be on the day the game started.
+var eliminationFormats = []string{"15:04", "15:04:05", "3:04PM", "3:04 PM", "3:04pm", "3:04 pm"}
+
+// parseEliminationTime parses an elimination time cell relative to the start
+// of the game. Blank cells yield the zero time.
+func parseEliminationTime(cell string, start time.Time) (time.Time, error) {
+	cell = strings.TrimSpace(cell)
+	if cell == "" {
+		return time.Time{}, nil
+	}
+	if at, err := time.Parse(time.RFC1123, cell); err == nil {
+		return at, nil
+	}
+	if start.IsZero() {
+		return time.Time{}, fmt.Errorf("can't place elimination time %q without a game date", cell)
+	}
+	for _, layout := range eliminationFormats {
+		clock, err := time.Parse(layout, cell)
+		if err != nil {
+			continue
+		}
+		at := time.Date(start.Year(), start.Month(), start.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, start.Location())
+		if at.Before(start) {
+			// the game ran past midnight
+			at = at.AddDate(0, 0, 1)
+		}
+		return at, nil
+	}
+	return time.Time{}, fmt.Errorf("unknown elimination time format %q", cell)
+}
+
+// applyEliminations records the players' elimination times on the game. When
+// every player but the winner has an elimination time, the times decide the
+// losing players' placements, with later eliminations placing higher and the
+// column order breaking ties between players eliminated at the same time.
+func applyEliminations(g *Game, eliminated []time.Time) {
+	for idx, at := range eliminated {
+		if at.IsZero() {
+			continue
+		}
+		if g.Eliminations == nil {
+			g.Eliminations = map[string]time.Time{}
+		}
+		g.Eliminations[g.Rankings[idx]] = at
+	}
+
+	if len(g.Rankings) < 3 || g.IsDraw() {
+		return
+	}
+	for _, name := range g.Rankings[1:] {
+		if _, ok := g.Eliminations[name]; !ok {
+			return
+		}
+	}
+	losers := g.Rankings[1:]
+	sort.SliceStable(losers, func(i, j int) bool {
+		return g.Eliminations[losers[i]].After(g.Eliminations[losers[j]])
+	})
+}
+
+// placementAnnotation matches a placement written after a player's name, like
+// "Bob(3)" or "Bob (3)", for rows whose player columns were filled out of
+// finishing order.
+var placementAnnotation = regexp.MustCompile(`^(.*?)\s*\(([1-9]\d*)\)$`)
+
+// parsePlacementAnnotation splits a player cell into the player and the
+// placement annotated after their name, 0 if there isn't one.
+func parsePlacementAnnotation(cell string) (string, int) {
+	m := placementAnnotation.FindStringSubmatch(cell)
+	if m == nil {
+		return cell, 0
+	}
+	placement, _ := strconv.Atoi(m[2])
+	return m[1], placement
+}
+
+// applyPlacements reorders the game's rankings by the placements annotated on
+// its players, with players without one filling the remaining places in the
+// order they're ranked. Annotated placements take precedence over elimination
+// times. If a placement is out of range or given twice, the rankings are left
+// as they are and an error returned.
+func applyPlacements(g *Game, placements map[string]int) error {
+	if len(placements) == 0 {
+		return nil
+	}
+	taken := make([]string, len(g.Rankings))
+	for _, name := range g.Rankings {
+		placement, ok := placements[name]
+		if !ok {
+			continue
+		}
+		if placement > len(g.Rankings) {
+			return fmt.Errorf("%s placed %d in a game of %d players", name, placement, len(g.Rankings))
+		}
+		if other := taken[placement-1]; other != "" {
+			return fmt.Errorf("%s and %s are both placed %d", other, name, placement)
+		}
+		taken[placement-1] = name
+	}
+
+	next := 0
+	for _, name := range g.Rankings {
+		if _, ok := placements[name]; ok {
+			continue
+		}
+		for taken[next] != "" {
+			next++
+		}
+		taken[next] = name
+	}
+	g.Rankings = taken
+	return nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
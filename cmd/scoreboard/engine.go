@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fly-apps/go-example/scoring"
+)
+
+// The scoring package's types are used directly, so games parsed from the
+// sheet can be scored by it.
+type (
+	Game       = scoring.Game
+	Result     = scoring.Result
+	Adjustment = scoring.Adjustment
+
+	// RatingEngine rates players from the games they've played. Engines are
+	// registered by name in ratingEngines and selected with
+	// SCOREBOARD_ENGINE, so new algorithms can be added without touching the
+	// handlers.
+	RatingEngine = scoring.RatingEngine
+)
+
+// ratingEngines are the available rating engines by name.
+var ratingEngines = map[string]func() RatingEngine{
+	"elo":    func() RatingEngine { return scoring.NewElo(scoringConfig()) },
+	"points": func() RatingEngine { return scoring.NewPoints() },
+}
+
+// ratingEngine is the name of the engine used to score games.
+var ratingEngine = "elo"
+
+// trialEngine is the name of an engine trialled alongside ratingEngine, whose
+// ratings the leaderboard shows next to the current ones, or "" for none.
+var trialEngine string
+
+// trialScores scores copies of the games and adjustments with the trial
+// engine, so their results stay the current engine's.
+func trialScores(games []*Game, adjustments []*Adjustment) map[string]int {
+	engine, err := newRatingEngine(trialEngine)
+	if err != nil {
+		// the engine name is validated at startup
+		panic(err)
+	}
+	scores, _ := scoring.Score(engine, copyGames(games), copyAdjustments(adjustments)...)
+	return scores
+}
+
+// newRatingEngine returns an initialized instance of the engine with the
+// given name.
+func newRatingEngine(name string) (RatingEngine, error) {
+	factory, ok := ratingEngines[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown rating engine %q, expected one of %s", name, strings.Join(ratingEngineNames(), ", "))
+	}
+	engine := factory()
+	engine.Initialize()
+	return engine, nil
+}
+
+// ratingEngineNames returns the names of the registered engines in order.
+func ratingEngineNames() []string {
+	names := make([]string, 0, len(ratingEngines))
+	for name := range ratingEngines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// scoringConfig returns the elo engine's parameters as currently configured.
+func scoringConfig() scoring.Config {
+	return currentScoring().config()
+}
+
+// rewardCurve returns the score awarded for each place in a pod of the given
+// size, or nil if there's no curve for the size.
+func rewardCurve(numPlayers int) []float64 {
+	return scoringConfig().RewardCurve(numPlayers)
+}
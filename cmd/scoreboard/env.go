@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"google.golang.org/api/option"
+)
+
+// secretEnv returns the contents of the file named by name+"_FILE" if it's
+// set, so secrets can be mounted as files, e.g.
+// SCOREBOARD_API_KEY_FILE=/run/secrets/api_key, or otherwise the value of the
+// environment variable name.
+func secretEnv(name string) (string, error) {
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return os.Getenv(name), nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %w", name, err)
+	}
+	// files written by editors and echo end with a newline
+	return strings.TrimSpace(string(b)), nil
+}
+
+// hasSecretEnv reports whether the secret is set directly or as a file.
+func hasSecretEnv(name string) bool {
+	return os.Getenv(name) != "" || os.Getenv(name+"_FILE") != ""
+}
+
+// sheetsOptionFromEnv returns the option the Sheets API is called with:
+// service account credentials from SCOREBOARD_CREDENTIALS when set, the API
+// key from SCOREBOARD_API_KEY otherwise.
+func sheetsOptionFromEnv() (option.ClientOption, error) {
+	creds, err := secretEnv("SCOREBOARD_CREDENTIALS")
+	if err != nil {
+		return nil, err
+	}
+	if creds != "" {
+		return option.WithCredentialsJSON([]byte(creds)), nil
+	}
+	key, err := secretEnv("SCOREBOARD_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	return option.WithAPIKey(key), nil
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EraStats summarizes the games played in an era, a season or date range, so
+// two eras of the league can be compared side by side.
+type EraStats struct {
+	Era        Season
+	Games      int
+	Players    int
+	AvgPodSize float64
+	ZapRate    float64
+	DrawRate   float64
+	Ratings    RatingDistribution
+	Dominant   []EraPlayer // the players with the most wins in the era.
+
+	Activity template.HTML // a sparkline of the games played each month.
+	PodSizes template.HTML // a sparkline of the average pod size each month.
+}
+
+// RatingDistribution is the spread of the ratings of the players active in
+// an era at the end of it.
+type RatingDistribution struct {
+	Min, Median, Max int
+	StdDev           float64
+	Histogram        template.HTML // a sparkline of the players in each 50 point band.
+}
+
+// EraPlayer is a player's record in an era.
+type EraPlayer struct {
+	ID    string
+	Games int
+	Wins  int
+}
+
+// WinRate returns the share of the era's games the player won.
+func (p EraPlayer) WinRate() float64 {
+	if p.Games == 0 {
+		return 0
+	}
+	return float64(p.Wins) / float64(p.Games)
+}
+
+// parseEra parses an era: the name of one of the seasons, a year like 2022,
+// or a date range like 2022-01-01..2022-06-30 including both days.
+func parseEra(s string, seasons []Season) (Season, error) {
+	s = strings.TrimSpace(s)
+	for _, season := range seasons {
+		if strings.EqualFold(season.Name, s) {
+			return season, nil
+		}
+	}
+	if year, err := time.Parse("2006", s); err == nil {
+		return Season{Name: s, Start: year, End: year.AddDate(1, 0, 0)}, nil
+	}
+	parts := strings.SplitN(s, "..", 2)
+	if len(parts) == 2 {
+		start, err1 := time.Parse("2006-01-02", strings.TrimSpace(parts[0]))
+		end, err2 := time.Parse("2006-01-02", strings.TrimSpace(parts[1]))
+		if err1 == nil && err2 == nil && !end.Before(start) {
+			return Season{Name: s, Start: start, End: end.AddDate(0, 0, 1)}, nil
+		}
+	}
+	return Season{}, fmt.Errorf("era %q must be a season, a year like 2022 or a range like 2022-01-01..2022-06-30", s)
+}
+
+// eraStats summarizes the era's games, which must have been scored in order,
+// with an era that hasn't ended yet running up to now.
+func eraStats(games []*Game, era Season, now time.Time) EraStats {
+	stats := EraStats{Era: era}
+	players := map[string]*EraPlayer{}
+	ratings := map[string]int{}
+	seats, zaps, draws := 0, 0, 0
+
+	monthly := map[string][2]int{} // games and seats by month
+	for _, g := range games {
+		if g.Timestamp.IsZero() || !era.Contains(g.Timestamp) {
+			continue
+		}
+		stats.Games++
+		seats += len(g.Rankings)
+		if isMarked(g.TableZap) {
+			zaps++
+		}
+		if g.IsDraw() {
+			draws++
+		}
+		month := g.Timestamp.UTC().Format("2006-01")
+		m := monthly[month]
+		monthly[month] = [2]int{m[0] + 1, m[1] + len(g.Rankings)}
+
+		for idx, name := range g.Rankings {
+			p := players[name]
+			if p == nil {
+				p = &EraPlayer{ID: name}
+				players[name] = p
+			}
+			p.Games++
+			if !g.IsDraw() && g.Place(idx) == 1 {
+				p.Wins++
+			}
+		}
+		for _, res := range g.Results {
+			ratings[res.Player] = res.After
+		}
+	}
+	if stats.Games == 0 {
+		return stats
+	}
+
+	stats.Players = len(players)
+	stats.AvgPodSize = float64(seats) / float64(stats.Games)
+	stats.ZapRate = float64(zaps) / float64(stats.Games)
+	stats.DrawRate = float64(draws) / float64(stats.Games)
+	stats.Ratings = ratingDistribution(ratings)
+
+	for _, p := range players {
+		stats.Dominant = append(stats.Dominant, *p)
+	}
+	sort.Slice(stats.Dominant, func(i, j int) bool {
+		a, b := stats.Dominant[i], stats.Dominant[j]
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		if a.WinRate() != b.WinRate() {
+			return a.WinRate() > b.WinRate()
+		}
+		return a.ID < b.ID
+	})
+	if len(stats.Dominant) > 3 {
+		stats.Dominant = stats.Dominant[:3]
+	}
+
+	// every month of the era up to its last game, including quiet ones
+	var activity, podSizes []float64
+	last := era.End
+	if last.IsZero() || last.After(now) {
+		last = now
+	}
+	for month := time.Date(era.Start.Year(), era.Start.Month(), 1, 0, 0, 0, 0, time.UTC); month.Before(last); month = month.AddDate(0, 1, 0) {
+		m := monthly[month.Format("2006-01")]
+		activity = append(activity, float64(m[0]))
+		size := 0.0
+		if m[0] > 0 {
+			size = float64(m[1]) / float64(m[0])
+		}
+		podSizes = append(podSizes, size)
+	}
+	stats.Activity = sparkline(activity, "games per month")
+	stats.PodSizes = sparkline(podSizes, "average pod size per month")
+	return stats
+}
+
+// ratingDistribution summarizes the ratings.
+func ratingDistribution(ratings map[string]int) RatingDistribution {
+	if len(ratings) == 0 {
+		return RatingDistribution{}
+	}
+	var values []int
+	sum := 0
+	for _, r := range ratings {
+		values = append(values, r)
+		sum += r
+	}
+	sort.Ints(values)
+
+	d := RatingDistribution{Min: values[0], Median: values[len(values)/2], Max: values[len(values)-1]}
+	mean := float64(sum) / float64(len(values))
+	for _, r := range values {
+		d.StdDev += (float64(r) - mean) * (float64(r) - mean)
+	}
+	d.StdDev = math.Sqrt(d.StdDev / float64(len(values)))
+
+	const band = 50
+	first := int(math.Floor(float64(d.Min) / band))
+	counts := make([]float64, int(math.Floor(float64(d.Max)/band))-first+1)
+	for _, r := range values {
+		counts[int(math.Floor(float64(r)/band))-first]++
+	}
+	d.Histogram = sparkline(counts, fmt.Sprintf("players per %d point band from %d", band, first*band))
+	return d
+}
+
+// sparkline renders the values as a small inline SVG line chart scaled to
+// the largest value.
+func sparkline(values []float64, label string) template.HTML {
+	if len(values) < 2 {
+		return ""
+	}
+	const width, height, pad = 120.0, 30.0, 2.0
+	max := 0.0
+	for _, v := range values {
+		max = math.Max(max, v)
+	}
+	if max == 0 {
+		max = 1
+	}
+
+	var points []string
+	for i, v := range values {
+		x := pad + float64(i)*(width-2*pad)/float64(len(values)-1)
+		y := height - pad - v/max*(height-2*pad)
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	return template.HTML(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %.0f %.0f" width="%.0f" height="%.0f" role="img" aria-label="%s"><polyline points="%s" fill="none" stroke="#000"/></svg>`,
+		width, height, width, height, template.HTMLEscapeString(label), strings.Join(points, " ")))
+}
+
+// erasHandler returns the handler for /eras, which compares two eras of the
+// league side by side. The eras are picked with the a and b parameters, and
+// default to the last two seasons.
+func erasHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		games := ds.Games
+		calculateScores(games, ds.Adjustments...)
+		seasons := leagueSeasons(games, ds.Seasons)
+
+		var eras [2]Season
+		for idx, param := range []string{"a", "b"} {
+			v := r.URL.Query().Get(param)
+			if v == "" {
+				if len(seasons) < 2 {
+					continue
+				}
+				eras[idx] = seasons[len(seasons)-2+idx]
+				continue
+			}
+			eras[idx], err = parseEra(v, seasons)
+			if err != nil {
+				badRequestRes(w, r, err.Error())
+				return
+			}
+		}
+
+		view := StatsView{
+			PageView: newPageView(r, "Eras", "Compare two eras of the league side by side."),
+			Seasons:  seasons,
+			Names:    ds.Names,
+		}
+		if eras[0].Name != "" && eras[1].Name != "" {
+			view.Eras = []EraStats{eraStats(games, eras[0], l.clock.Now()), eraStats(games, eras[1], l.clock.Now())}
+		}
+		t.ExecuteTemplate(w, "eras.html.tmpl", view)
+	}
+}
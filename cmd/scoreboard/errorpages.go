@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+)
+
+// errorPage writes the error page with the status code and a message saying
+// what went wrong. Nothing may have been written to w yet.
+func errorPage(w http.ResponseWriter, r *http.Request, status int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	t.ExecuteTemplate(w, "error.html.tmpl", ErrorView{
+		PageView: barePageView(r),
+		Status:   status,
+		Title:    http.StatusText(status),
+		Message:  message,
+	})
+}
+
+// errorRes writes the error page for a failure loading or saving the
+// league's data, like an error from Google Sheets or the database. It's a
+// 500, or a 429 when the league has used up its Sheets quota. The page only
+// says what kind of failure it was, since err can name the sheet, the
+// database or its queries, so callers log err.
+func errorRes(w http.ResponseWriter, r *http.Request, err error) {
+	if isQuotaError(err) {
+		errorPage(w, r, http.StatusTooManyRequests, "The league has used up its Google Sheets quota for now. Try again in a few minutes.")
+		return
+	}
+	errorPage(w, r, http.StatusInternalServerError, "Something went wrong loading or saving the league's data. Try again in a moment.")
+}
+
+// badRequestRes writes the error page for a request with invalid query
+// parameters or form values.
+func badRequestRes(w http.ResponseWriter, r *http.Request, message string) {
+	errorPage(w, r, http.StatusBadRequest, message)
+}
+
+// notFoundRes writes the page for a page that doesn't exist.
+func notFoundRes(w http.ResponseWriter, r *http.Request) {
+	errorPage(w, r, http.StatusNotFound, "There's no page here. It may have been a game or player that's since been removed from the sheet.")
+}
+
+// unauthorizedRes writes the error page for a request that needs a logged in
+// player, with a message saying what they need to log in to do.
+func unauthorizedRes(w http.ResponseWriter, r *http.Request, message string) {
+	errorPage(w, r, http.StatusUnauthorized, message)
+}
+
+// methodNotAllowedRes writes the error page for a request with a method the
+// route doesn't take, listing the ones it does in allow.
+func methodNotAllowedRes(w http.ResponseWriter, r *http.Request, allow string) {
+	w.Header().Set("Allow", allow)
+	errorPage(w, r, http.StatusMethodNotAllowed, "The page doesn't accept "+r.Method+" requests.")
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fly-apps/go-example/scoring"
+)
+
+// limitedElo is whether the 1v1 matches of draft and limited events are
+// rated in their own elo pool, separate from the league's ratings.
+// Configured with SCOREBOARD_LIMITED_ELO.
+var limitedElo bool
+
+// Match points awarded for a match, as at a Magic tournament.
+const (
+	matchWinPoints  = 3
+	matchDrawPoints = 1
+)
+
+// minMatchWinRate is the floor on a player's match and game win rates when
+// they're counted as an opponent's tie-breaker, so playing someone who
+// dropped winless isn't punished.
+const minMatchWinRate = 1.0 / 3
+
+// Event is a draft or other limited event, played as 1v1 matches within pods.
+type Event struct {
+	Number    int // the event's 1-indexed number in date order.
+	Name      string
+	Date      string
+	Timestamp time.Time
+	Pods      []*EventPod // in the order they're first listed.
+}
+
+// EventPod is a pod of an event, a bracket with its own standings.
+type EventPod struct {
+	Name    string
+	Seats   []string // the player IDs in seat order, if the seating was recorded.
+	Matches []Match  // in round order.
+}
+
+// Match is a 1v1 match of an event.
+type Match struct {
+	Round    int
+	Player   string
+	Opponent string // "" for a bye, which counts as a 2-0 win.
+	Wins     int    // the games the player won.
+	Losses   int    // the games the opponent won.
+	Draws    int    // the games drawn.
+}
+
+// Bye reports whether the match is a bye.
+func (m Match) Bye() bool {
+	return m.Opponent == ""
+}
+
+// EventStanding is a player's standing in an event pod.
+type EventStanding struct {
+	Player string // the player's ID.
+	Name   string
+	Points int // match points.
+	Wins   int // matches won.
+	Losses int
+	Draws  int
+
+	// OMW is the average match win rate of the player's opponents, GW the
+	// player's game win rate and OGW their opponents' average game win rate,
+	// the tie-breakers in that order.
+	OMW, GW, OGW float64
+}
+
+// Record returns the player's match record, like "2-1-0".
+func (s EventStanding) Record() string {
+	return fmt.Sprintf("%d-%d-%d", s.Wins, s.Losses, s.Draws)
+}
+
+// pod returns the event's pod with the name, adding it if it's new.
+func (e *Event) pod(name string) *EventPod {
+	for _, p := range e.Pods {
+		if p.Name == name {
+			return p
+		}
+	}
+	p := &EventPod{Name: name}
+	e.Pods = append(e.Pods, p)
+	return p
+}
+
+// parseMatchResult parses a match result like "2-1", or "1-1-1" with drawn
+// games, as the games won by the player and their opponent.
+func parseMatchResult(s string) (wins, losses, draws int, err error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, 0, 0, fmt.Errorf("invalid result %q, expected games won like 2-1", s)
+	}
+	games := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 0 {
+			return 0, 0, 0, fmt.Errorf("invalid result %q, expected games won like 2-1", s)
+		}
+		games[i] = n
+	}
+	return games[0], games[1], games[2], nil
+}
+
+// parseEventRows parses the events tab. Each row belongs to an event and pod,
+// named in the first and third columns with the event's date in the second.
+// A row with a round number in the fourth column is a match, with the
+// player, their opponent and the games won like 2-1 in the next three
+// columns; a bye has no opponent. A row without a round is the pod's
+// seating, with the players in seat order from the fifth column. The first
+// row holds the labels.
+func parseEventRows(values [][]interface{}) ([]*Event, error) {
+	var events []*Event
+	index := map[string]*Event{}
+	for idx, row := range values {
+		if idx == 0 || len(row) < 5 {
+			continue
+		}
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = strings.TrimSpace(fmt.Sprintf("%s", cell))
+		}
+		name, date, podName, round := cells[0], cells[1], cells[2], cells[3]
+		if name == "" {
+			continue
+		}
+
+		e, ok := index[name]
+		if !ok {
+			e = &Event{Name: name, Date: date}
+			if date != "" {
+				ts, err := parseGameDate(date)
+				if err != nil {
+					return nil, fmt.Errorf("row %d: %w", idx+1, err)
+				}
+				e.Timestamp = ts
+			}
+			index[name] = e
+			events = append(events, e)
+		}
+		if podName == "" {
+			podName = "Pod 1"
+		}
+		pod := e.pod(podName)
+
+		if round == "" {
+			for _, player := range cells[4:] {
+				if player != "" {
+					pod.Seats = append(pod.Seats, player)
+				}
+			}
+			continue
+		}
+		n, err := strconv.Atoi(round)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("row %d: invalid round %q", idx+1, round)
+		}
+		m := Match{Round: n, Player: cells[4]}
+		if m.Player == "" {
+			return nil, fmt.Errorf("row %d: missing player", idx+1)
+		}
+		if len(cells) > 5 {
+			m.Opponent = cells[5]
+		}
+		if m.Bye() {
+			m.Wins = 2
+		} else {
+			result := ""
+			if len(cells) > 6 {
+				result = cells[6]
+			}
+			if m.Wins, m.Losses, m.Draws, err = parseMatchResult(result); err != nil {
+				return nil, fmt.Errorf("row %d: %w", idx+1, err)
+			}
+		}
+		pod.Matches = append(pod.Matches, m)
+	}
+
+	for _, e := range events {
+		for _, pod := range e.Pods {
+			sort.SliceStable(pod.Matches, func(i, j int) bool { return pod.Matches[i].Round < pod.Matches[j].Round })
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Timestamp.Before(events[j].Timestamp) })
+	for i, e := range events {
+		e.Number = i + 1
+	}
+	return events, nil
+}
+
+// podStandings ranks the players of a pod by match points, then by the
+// opponents' match win rate, game win rate and opponents' game win rate.
+func podStandings(pod *EventPod, names playerNames) []EventStanding {
+	type tally struct {
+		points, wins, losses, draws, matches int
+		games, gamePoints                    int
+		opponents                            []string
+	}
+	tallies := map[string]*tally{}
+	get := func(player string) *tally {
+		ty, ok := tallies[player]
+		if !ok {
+			ty = &tally{}
+			tallies[player] = ty
+		}
+		return ty
+	}
+	for _, player := range pod.Seats {
+		get(player)
+	}
+	record := func(player, opponent string, won, lost, drawn int) {
+		ty := get(player)
+		ty.matches++
+		switch {
+		case won > lost:
+			ty.wins++
+			ty.points += matchWinPoints
+		case won < lost:
+			ty.losses++
+		default:
+			ty.draws++
+			ty.points += matchDrawPoints
+		}
+		ty.games += won + lost + drawn
+		ty.gamePoints += won*matchWinPoints + drawn*matchDrawPoints
+		if opponent != "" {
+			ty.opponents = append(ty.opponents, opponent)
+		}
+	}
+	for _, m := range pod.Matches {
+		record(m.Player, m.Opponent, m.Wins, m.Losses, m.Draws)
+		if !m.Bye() {
+			record(m.Opponent, m.Player, m.Losses, m.Wins, m.Draws)
+		}
+	}
+
+	rate := func(points, played int) float64 {
+		if played == 0 {
+			return minMatchWinRate
+		}
+		r := float64(points) / float64(played*matchWinPoints)
+		if r < minMatchWinRate {
+			return minMatchWinRate
+		}
+		return r
+	}
+	standings := make([]EventStanding, 0, len(tallies))
+	for player, ty := range tallies {
+		s := EventStanding{Player: player, Name: names.Of(player), Points: ty.points, Wins: ty.wins, Losses: ty.losses, Draws: ty.draws}
+		if ty.games > 0 {
+			s.GW = float64(ty.gamePoints) / float64(ty.games*matchWinPoints)
+		}
+		for _, opp := range ty.opponents {
+			o := tallies[opp]
+			s.OMW += rate(o.points, o.matches)
+			s.OGW += rate(o.gamePoints, o.games)
+		}
+		if n := float64(len(ty.opponents)); n > 0 {
+			s.OMW /= n
+			s.OGW /= n
+		}
+		standings = append(standings, s)
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		switch {
+		case a.Points != b.Points:
+			return a.Points > b.Points
+		case a.OMW != b.OMW:
+			return a.OMW > b.OMW
+		case a.GW != b.GW:
+			return a.GW > b.GW
+		case a.OGW != b.OGW:
+			return a.OGW > b.OGW
+		}
+		return a.Name < b.Name
+	})
+	return standings
+}
+
+// limitedGames returns the events' matches as 1v1 games in the order they
+// were played, for the limited elo pool. Byes aren't rated.
+func limitedGames(events []*Event) []*Game {
+	var games []*Game
+	for _, e := range events {
+		for _, pod := range e.Pods {
+			for _, m := range pod.Matches {
+				if m.Bye() {
+					continue
+				}
+				g := &Game{
+					ID:        fmt.Sprintf("%s/%s/%d", e.Name, pod.Name, m.Round),
+					Date:      e.Date,
+					Timestamp: e.Timestamp,
+					Rankings:  []string{m.Player, m.Opponent},
+				}
+				switch {
+				case m.Losses > m.Wins:
+					g.Rankings = []string{m.Opponent, m.Player}
+				case m.Losses == m.Wins:
+					g.DrawGame = "TRUE"
+				}
+				games = append(games, g)
+			}
+		}
+	}
+	return games
+}
+
+// limitedRankings rates the events' matches in the limited elo pool, with
+// the league's elo parameters, and returns the players best first.
+func limitedRankings(events []*Event, names playerNames) []Player {
+	games := limitedGames(events)
+	scores, _ := scoring.Score(scoring.NewElo(scoringConfig()), games)
+	played, won := map[string]int{}, map[string]int{}
+	for _, g := range games {
+		for _, id := range g.Rankings {
+			played[id]++
+			if !g.IsDraw() && g.Won(id) {
+				won[id]++
+			}
+		}
+	}
+	rankings := make([]Player, 0, len(scores))
+	for id, score := range scores {
+		rankings = append(rankings, Player{ID: id, Name: names.Of(id), Score: score, Games: played[id], Wins: won[id]})
+	}
+	sort.Slice(rankings, func(i, j int) bool {
+		if rankings[i].Score != rankings[j].Score {
+			return rankings[i].Score > rankings[j].Score
+		}
+		return rankings[i].Name < rankings[j].Name
+	})
+	return rankings
+}
+
+// eventsHandler returns the handler for draft and limited events, listed at
+// /events, with each event's pod standings and matches at /events/N, where N
+// is the event's number in date order.
+func eventsHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.ranges.Events == "" {
+			notFoundRes(w, r)
+			return
+		}
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+
+		view := EventsView{Events: ds.Events, Names: ds.Names}
+		number := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/events"), "/")
+		if number == "" {
+			view.PageView = newPageView(r, "Events", "The league's draft and limited events.")
+			if limitedElo {
+				view.Limited = limitedRankings(ds.Events, ds.Names)
+			}
+			t.ExecuteTemplate(w, "events.html.tmpl", view)
+			return
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil || n < 1 || n > len(ds.Events) {
+			notFoundRes(w, r)
+			return
+		}
+		e := ds.Events[n-1]
+		view.PageView = newPageView(r, e.Name, fmt.Sprintf("Standings and matches of %s.", e.Name))
+		view.Event = e
+		for _, pod := range e.Pods {
+			view.Pods = append(view.Pods, EventPodView{EventPod: pod, Standings: podStandings(pod, ds.Names)})
+		}
+		t.ExecuteTemplate(w, "events.html.tmpl", view)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	elogo "github.com/kortemy/elo-go"
+)
+
+// DeltaExplanation walks through how one player's rating change in a game
+// was calculated by the elo engine.
+type DeltaExplanation struct {
+	Result
+	Expected float64 // the player's expected score against the pod's rank average.
+	Reward   float64 // the reward curve's score for the player's place.
+	Raw      float64 // K * (reward - expected), before truncating to a whole number.
+
+	// Multiplier is the upset multiplier applied to the change, 1 when the
+	// game wasn't an upset for the player.
+	Multiplier float64
+}
+
+// GameExplanation walks through the calculation of every rating change in a
+// scored game.
+type GameExplanation struct {
+	Game        *Game
+	RankAverage int // the average of the pod's ratings before the game.
+	K           int // the most a rating can move in a game.
+	D           int // the rating difference at which the expected score is 10 to 1.
+	Curve       []float64
+	Upsets      []upsetStep // the configured upset multipliers.
+	Players     []DeltaExplanation
+}
+
+// explainGame recreates the elo engine's calculation for the scored game. It
+// returns nil if the game wasn't scored by the elo engine's reward curves,
+// like archenemy games.
+func explainGame(game *Game) *GameExplanation {
+	curve := rewardCurve(len(game.Results))
+	if ratingEngine != "elo" || curve == nil || game.IsArchenemy() {
+		return nil
+	}
+
+	elo := elogo.NewEloWithFactors(kFactor, elogo.D)
+	e := &GameExplanation{
+		Game:        game,
+		RankAverage: game.RankAverage,
+		K:           elo.K,
+		D:           elo.D,
+		Curve:       curve,
+		Upsets:      upsetCurve,
+	}
+	ratings := make([]int, len(game.Results))
+	for idx, res := range game.Results {
+		ratings[idx] = res.Before
+	}
+	for idx, res := range game.Results {
+		expected := elo.ExpectedScore(res.Before, game.RankAverage)
+		raw := float64(elo.K) * (curve[idx] - expected)
+		multiplier := 1.0
+		if len(upsetCurve) > 0 && !game.IsDraw() {
+			multiplier = upsetMultiplier(ratings, idx, int(raw))
+		}
+		e.Players = append(e.Players, DeltaExplanation{
+			Result:     res,
+			Expected:   expected,
+			Reward:     curve[idx],
+			Raw:        raw,
+			Multiplier: multiplier,
+		})
+	}
+	return e
+}
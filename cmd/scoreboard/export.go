@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// exportFlushEvery is the number of games written between flushes of an
+// export, so it reaches the client as it's written rather than all at the end.
+const exportFlushEvery = 100
+
+// exportHandler returns the handler for the game log export at /api/export,
+// which streams every game the query selects in the log's order, as a JSON
+// object per line or as CSV with format=csv. It takes the game log API's
+// filters, and after, the ID of the last game received, to resume an
+// interrupted export; it doesn't support Range requests. Responses are tagged
+// with the dataset version and the query, so a client can tell whether the
+// log changed before resuming.
+func exportHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fail := func(msg string) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			web.WriteJSON(w, map[string]interface{}{"error": msg})
+		}
+		q := r.URL.Query()
+		q.Del("limit")
+		gq, err := parseGamesQuery(q)
+		if err != nil {
+			fail(err.Error())
+			return
+		}
+		format := q.Get("format")
+		if format == "" {
+			format = "ndjson"
+			q.Set("format", format)
+		}
+		if format != "ndjson" && format != "csv" {
+			fail("format must be ndjson or csv")
+			return
+		}
+
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		dataVersion := datasetVersion(ds)
+		etag := exportTag(dataVersion, q)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if gq.Player != "" {
+			gq.Player = ds.playerID(gq.Player)
+		}
+		calculateScores(ds.Games, ds.Adjustments...)
+		games := append(append([]*Game{}, ds.Games...), ds.Unscored...)
+		if id := q.Get("after"); id != "" {
+			for _, g := range games {
+				if g.ID == id {
+					gq.After = &gameCursor{Timestamp: g.Timestamp, ID: g.ID}
+				}
+			}
+			if gq.After == nil {
+				fail(fmt.Sprintf("no game %q to resume after", id))
+				return
+			}
+		}
+
+		h := w.Header()
+		h.Set("ETag", etag)
+		h.Set("Accept-Ranges", "none")
+		h.Set("X-Dataset-Version", dataVersion)
+		if format == "csv" {
+			h.Set("Content-Type", "text/csv; charset=utf-8")
+		} else {
+			h.Set("Content-Type", "application/x-ndjson")
+		}
+		h.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="games-%s-%s.%s"`, dataVersion, l.clock.Now().Format("2006-01-02"), format))
+
+		var write func(GameRecord) error
+		var flush func()
+		if format == "csv" {
+			cw := csv.NewWriter(w)
+			cw.Write([]string{"id", "timestamp", "players", "zap", "draw", "tags", "notes", "deltas"})
+			write = func(rec GameRecord) error { return cw.Write(exportRow(rec)) }
+			flush = cw.Flush
+		} else {
+			enc := json.NewEncoder(w)
+			write = func(rec GameRecord) error { return enc.Encode(rec) }
+			flush = func() {}
+		}
+
+		scale := newPodScale(games)
+		written := 0
+		for _, g := range logOrder(games) {
+			if gq.After != nil && !gq.After.precedes(g) || !gq.matches(g) {
+				continue
+			}
+			if err := write(gameRecord(g, scale)); err != nil {
+				// the client went away
+				return
+			}
+			if written++; written%exportFlushEvery == 0 {
+				flush()
+				if f, ok := w.(http.Flusher); ok {
+					f.Flush()
+				}
+			}
+		}
+		flush()
+	}
+}
+
+// exportTag returns the ETag of an export of the dataset version with the
+// query, which is normalized by sorting its parameters, so the same export
+// requested in another order has the same tag.
+func exportTag(dataVersion string, q url.Values) string {
+	sum := sha256.Sum256([]byte(q.Encode()))
+	return fmt.Sprintf(`"%s-%s"`, dataVersion, hex.EncodeToString(sum[:])[:8])
+}
+
+// exportRow returns the game's row in the CSV export. Players and tags are
+// separated by semicolons, with two-headed giant teams' players joined by
+// slashes, and the rating changes are like alice:+16.
+func exportRow(rec GameRecord) []string {
+	players := rec.Players
+	if len(rec.Teams) > 0 {
+		players = nil
+		for _, team := range rec.Teams {
+			players = append(players, strings.Join(team, "/"))
+		}
+	}
+	deltas := make([]string, len(rec.Results))
+	for i, res := range rec.Results {
+		deltas[i] = res.Player + ":" + signed(res.Delta)
+	}
+	timestamp := ""
+	if !rec.Timestamp.IsZero() {
+		timestamp = rec.Timestamp.Format(time.RFC3339)
+	}
+	return []string{
+		rec.ID,
+		timestamp,
+		strings.Join(players, ";"),
+		strconv.FormatBool(rec.Zap),
+		strconv.FormatBool(rec.Draw),
+		strings.Join(rec.Tags, ";"),
+		rec.Notes,
+		strings.Join(deltas, ";"),
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/sheets"
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// fetchChunkRows is the number of game log rows fetched per request, so the
+// game log of a long running league is fetched and parsed a chunk at a time
+// rather than held in memory all at once. 0 fetches the whole game log in a
+// single request. Configured with SCOREBOARD_FETCH_CHUNK_ROWS.
+var fetchChunkRows = 0
+
+// FetchProgress is how far the league's latest fetch of the game log got.
+type FetchProgress struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"` // zero while the fetch is running.
+	Running  bool      `json:"running"`
+	Chunks   int       `json:"chunks"` // the requests made for the game log so far.
+	Rows     int       `json:"rows"`   // the game log rows fetched so far, including the labels.
+	Games    int       `json:"games"`  // the games parsed so far.
+	Error    string    `json:"error,omitempty"`
+}
+
+// fetchTracker records the progress of the league's fetches for the admin
+// endpoint.
+type fetchTracker struct {
+	mu       sync.Mutex
+	progress FetchProgress
+}
+
+// start records the start of a fetch at now.
+func (t *fetchTracker) start(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress = FetchProgress{Started: now, Running: true}
+}
+
+// chunk records a fetched and parsed chunk of the game log.
+func (t *fetchTracker) chunk(rows, games int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress.Chunks++
+	t.progress.Rows += rows
+	t.progress.Games += games
+}
+
+// finish records the end of a fetch at now and its error, if any.
+func (t *fetchTracker) finish(err error, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress.Running = false
+	t.progress.Finished = now
+	if err != nil {
+		t.progress.Error = err.Error()
+	}
+}
+
+// snapshot returns the progress of the latest fetch.
+func (t *fetchTracker) snapshot() FetchProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.progress
+}
+
+// fetchChunked fetches the game log fetchChunkRows rows at a time, parsing
+// each chunk as it arrives, until a chunk comes back short. The first chunk
+// is fetched in the same batch request as the auxiliary tabs, so a game log
+// that fits in a chunk still takes a single request.
+func (l *league) fetchChunked(ctx context.Context) (*Dataset, error) {
+	sheet, col, first := sheets.RangeStart(l.ranges.Games)
+	lastCol, last := sheets.RangeEnd(l.ranges.Games)
+	chunk := func(from int) string {
+		to := from + fetchChunkRows - 1
+		if last > 0 && to > last {
+			to = last
+		}
+		return fmt.Sprintf("%s%s%d:%s%d", sheet, sheets.ColumnLetter(col), from, lastCol, to)
+	}
+
+	ranges := l.rangeList()
+	ranges[0] = chunk(first)
+	values, err := sheets.FetchRanges(ctx, l.spreadsheetID, ranges, l.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var games, unscored []*Game
+	rows, parsed := values[0], 0
+	values[0] = nil
+	for {
+		g, u := parseGameLogRows(rows, parsed)
+		games = append(games, g...)
+		unscored = append(unscored, u...)
+		parsed += len(rows)
+		l.fetches.chunk(len(rows), len(g)+len(u))
+		if len(rows) < fetchChunkRows || (last > 0 && first+parsed > last) {
+			break
+		}
+
+		next, err := sheets.FetchRanges(ctx, l.spreadsheetID, []string{chunk(first + parsed)}, l.opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch game log from row %d: %w", first+parsed, err)
+		}
+		rows = next[0]
+	}
+	return l.parseTabs(&Dataset{Games: games, Unscored: unscored}, values)
+}
+
+// fetchProgressHandler returns the admin handler that reports the progress
+// of the league's latest fetch, at /admin/fetch.
+func fetchProgressHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		web.WriteJSON(w, map[string]interface{}{
+			"chunkRows": fetchChunkRows,
+			"progress":  l.fetches.snapshot(),
+		})
+	}
+}
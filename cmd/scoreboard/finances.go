@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Finances are the league's entry fees and how the prize pool is paid out,
+// configured with SCOREBOARD_GAME_FEE, SCOREBOARD_SEASON_FEE and
+// SCOREBOARD_PAYOUTS. Amounts are in cents.
+type Finances struct {
+	GameFee   int   // paid by each player for each game they play.
+	SeasonFee int   // paid by each player once for each season they play in.
+	Payouts   []int // the percentage of the pool paid to each place, first place first.
+	Currency  string
+}
+
+// finances are the league's configured finances. Tracking is off unless a fee
+// is set.
+var finances = Finances{Payouts: []int{100}, Currency: "$"}
+
+// enabled reports whether any entry fee is configured.
+func (f Finances) enabled() bool {
+	return f.GameFee > 0 || f.SeasonFee > 0
+}
+
+// format formats an amount in cents in the league's currency, leaving off
+// the cents for whole amounts, e.g. "$12" or "$12.50".
+func (f Finances) format(cents int) string {
+	if cents%100 == 0 {
+		return fmt.Sprintf("%s%d", f.Currency, cents/100)
+	}
+	return fmt.Sprintf("%s%d.%02d", f.Currency, cents/100, cents%100)
+}
+
+// PrizePool is the prize pool collected over a season and what each place
+// would be paid if the season ended with the current standings.
+type PrizePool struct {
+	Season  string
+	Entries int // the number of game fees paid.
+	Players int // the number of season fees paid.
+	Total   int // in cents.
+	Payouts []Payout
+}
+
+// Payout is the amount paid to a place in the season's standings.
+type Payout struct {
+	Place  int
+	Player Player
+	Amount int // in cents.
+}
+
+// financesFromEnv returns the finances configured with the SCOREBOARD_*_FEE,
+// SCOREBOARD_PAYOUTS and SCOREBOARD_CURRENCY variables.
+func financesFromEnv() (Finances, error) {
+	f := finances
+	for name, dst := range map[string]*int{"SCOREBOARD_GAME_FEE": &f.GameFee, "SCOREBOARD_SEASON_FEE": &f.SeasonFee} {
+		if s := os.Getenv(name); s != "" {
+			cents, err := parseMoney(s)
+			if err != nil {
+				return f, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*dst = cents
+		}
+	}
+	if s := os.Getenv("SCOREBOARD_PAYOUTS"); s != "" {
+		payouts, err := parsePayouts(s)
+		if err != nil {
+			return f, fmt.Errorf("invalid SCOREBOARD_PAYOUTS: %w", err)
+		}
+		f.Payouts = payouts
+	}
+	if s := os.Getenv("SCOREBOARD_CURRENCY"); s != "" {
+		f.Currency = s
+	}
+	return f, nil
+}
+
+// parseMoney parses an amount like "5" or "2.50" into cents.
+func parseMoney(s string) (int, error) {
+	whole, frac := strings.TrimSpace(s), ""
+	if idx := strings.Index(whole, "."); idx >= 0 {
+		whole, frac = whole[:idx], whole[idx+1:]
+	}
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("%q has more than 2 decimal places", s)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+	units, err := strconv.Atoi(whole)
+	if err != nil || units < 0 {
+		return 0, fmt.Errorf("%q is not an amount", s)
+	}
+	cents, err := strconv.Atoi(frac)
+	if err != nil || cents < 0 {
+		return 0, fmt.Errorf("%q is not an amount", s)
+	}
+	return units*100 + cents, nil
+}
+
+// parsePayouts parses the comma separated percentages of the prize pool paid
+// to each place, e.g. "50,30,20". They may add up to less than 100, with the
+// rest kept by the league, but not more.
+func parsePayouts(s string) ([]int, error) {
+	var payouts []int
+	total := 0
+	for _, part := range strings.Split(s, ",") {
+		pct, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || pct < 0 {
+			return nil, fmt.Errorf("%q is not a percentage", part)
+		}
+		payouts = append(payouts, pct)
+		total += pct
+	}
+	if total > 100 {
+		return nil, fmt.Errorf("payouts add up to %d%%, more than the whole pool", total)
+	}
+	return payouts, nil
+}
+
+// prizePool returns the current season's prize pool and payouts by its
+// standings so far as of now, or nil if there are no seasons with games.
+// Games and adjustments are copied before they're scored for the season's
+// standings.
+func (f Finances) prizePool(games []*Game, seasons []Season, adjustments []*Adjustment, names playerNames, now time.Time) *PrizePool {
+	played := leagueSeasons(games, seasons)
+	if len(played) == 0 {
+		return nil
+	}
+	season := played[len(played)-1]
+	seasonGames := gamesInSeason(games, season)
+
+	pool := &PrizePool{Season: season.Name}
+	entered := map[string]bool{}
+	for _, g := range seasonGames {
+		pool.Entries += len(g.Rankings)
+		for _, player := range g.Rankings {
+			entered[player] = true
+		}
+	}
+	pool.Players = len(entered)
+	pool.Total = pool.Entries*f.GameFee + pool.Players*f.SeasonFee
+
+	standings := rankPlayers(seasonGames, calculateScores(seasonGames, adjustmentsInSeason(adjustments, season)...), now)
+	names.apply(standings)
+	paid := 0
+	for idx, pct := range f.Payouts {
+		if idx >= len(standings) {
+			break
+		}
+		amount := pool.Total * pct / 100
+		pool.Payouts = append(pool.Payouts, Payout{Place: idx + 1, Player: standings[idx], Amount: amount})
+		paid += amount
+	}
+	// cents lost to rounding go to first place, as long as the payouts
+	// cover the whole pool
+	if len(pool.Payouts) > 0 && len(pool.Payouts) == len(f.Payouts) && sumInts(f.Payouts) == 100 {
+		pool.Payouts[0].Amount += pool.Total - paid
+	}
+	return pool
+}
+
+func sumInts(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
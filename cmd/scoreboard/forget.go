@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/fly-apps/go-example/internal/sheets"
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// ForgetResult is what forgetting a player changes, or would change on a dry
+// run. The player's history is kept under an anonymous name so the league's
+// aggregate statistics don't change.
+type ForgetResult struct {
+	Player    string           `json:"player"`    // the player's ID.
+	Anonymous string           `json:"anonymous"` // the name the player's history is kept under.
+	DryRun    bool             `json:"dryRun"`
+	Cells     []RenamedCell    `json:"cells"`
+	Records   map[string]int64 `json:"records,omitempty"` // stored records anonymized or deleted, keyed by table.
+	Profile   bool             `json:"profile"`           // whether the player's profile was deleted.
+	Before    int              `json:"before"`            // the player's rating.
+	After     int              `json:"after"`             // the rating under the anonymous name, which matches Before.
+}
+
+// namesOf returns every name the player goes by: their ID, their name, the
+// other names the players tab lists for them and their aliases.
+func (ds *Dataset) namesOf(id string) []string {
+	names := []string{id}
+	seen := map[string]bool{strings.ToLower(id): true}
+	add := func(name string) {
+		if !seen[strings.ToLower(name)] && ds.playerID(name) == id {
+			seen[strings.ToLower(name)] = true
+			names = append(names, name)
+		}
+	}
+	add(ds.Names.Of(id))
+	for name := range ds.ids {
+		add(name)
+	}
+	for alias := range ds.Aliases {
+		add(alias)
+	}
+	return names
+}
+
+// anonymousName returns a name for a forgotten player that no one in the
+// league goes by, like "Former player 2".
+func anonymousName(ds *Dataset) string {
+	played := map[string]bool{}
+	for _, g := range ds.Games {
+		for _, name := range g.Rankings {
+			played[strings.ToLower(name)] = true
+		}
+	}
+	for n := 1; ; n++ {
+		name := fmt.Sprintf("Former player %d", n)
+		if _, ok := ds.ids[strings.ToLower(name)]; !ok && !played[strings.ToLower(name)] {
+			return name
+		}
+	}
+}
+
+// forgetPlayer deletes the comments and game reports the player wrote, and
+// the league's rating snapshots, which are rebuilt on demand, from the
+// stored records in one transaction. authors are the names the player may
+// have written under. It returns the number of records deleted from each
+// table. On a dry run the deletions are counted and rolled back.
+func (s *store) forgetPlayer(ctx context.Context, league string, authors []string, dryRun bool) (map[string]int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to forget player: %w", err)
+	}
+	defer tx.Rollback()
+
+	records := map[string]int64{}
+	for _, author := range authors {
+		res, err := tx.ExecContext(ctx, `DELETE FROM comments WHERE league = ? AND author = ? COLLATE NOCASE`, league, author)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete comments: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			records["comments"] += n
+		}
+		res, err = tx.ExecContext(ctx, `DELETE FROM game_reports WHERE league = ? AND author = ? COLLATE NOCASE`, league, author)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete game reports: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			records["game_reports"] += n
+		}
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM rating_snapshots WHERE league = ?`, league)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete rating snapshots: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		records["rating_snapshots"] += n
+	}
+
+	if dryRun {
+		return records, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to forget player: %w", err)
+	}
+	return records, nil
+}
+
+// removeProfile deletes the profile of any of the names from the profiles
+// file, and reports whether there was one.
+func removeProfile(path string, names []string, dryRun bool) (bool, error) {
+	if path == "" {
+		return false, nil
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read profiles: %w", err)
+	}
+	var list []Profile
+	if err := json.Unmarshal(b, &list); err != nil {
+		return false, fmt.Errorf("failed to parse profiles: %w", err)
+	}
+
+	kept := list[:0]
+	for _, p := range list {
+		forget := false
+		for _, name := range names {
+			if strings.EqualFold(p.Name, name) {
+				forget = true
+			}
+		}
+		if !forget {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == len(list) || dryRun {
+		return len(kept) != len(list), nil
+	}
+	b, err = json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("failed to encode profiles: %w", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0600); err != nil {
+		return false, fmt.Errorf("failed to write profiles: %w", err)
+	}
+	return true, nil
+}
+
+// forgetPlayer anonymizes the player across the league at their request.
+// Every name they go by is replaced with an anonymous name in the game log
+// and the players, aliases, seeds and adjustments tabs, and in the stored
+// records when st isn't nil, so their games still count towards everyone
+// else's ratings and the league's statistics. Their comments, game reports
+// with their photos, profile and the rating snapshots naming them are
+// deleted, and the archived standings are drawn again. On a dry run nothing
+// is written and the result previews the changes.
+func (l *league) forgetPlayer(ctx context.Context, st *store, name string, dryRun bool) (*ForgetResult, error) {
+	if l.frozen != nil {
+		return nil, errors.New("a static league can't be changed")
+	}
+	// hold off live games so none are appended between reading and
+	// rewriting the game log
+	l.recording.Lock()
+	defer l.recording.Unlock()
+
+	values, err := sheets.FetchRanges(ctx, l.spreadsheetID, l.rangeList(), l.opts...)
+	if err != nil {
+		return nil, err
+	}
+	before, err := l.parse(values)
+	if err != nil {
+		return nil, err
+	}
+	id := before.playerID(name)
+	scores := calculateScores(before.Games, before.Adjustments...)
+	rating, ok := scores[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	names := before.namesOf(id)
+	anon := anonymousName(before)
+	res := &ForgetResult{Player: id, Anonymous: anon, DryRun: dryRun, Before: rating, Records: map[string]int64{}}
+
+	for _, from := range names {
+		res.Cells = append(res.Cells, l.renameTabs(values, from, anon)...)
+	}
+	after, err := l.parse(values)
+	if err != nil {
+		return nil, err
+	}
+	res.After = calculateScores(after.Games, after.Adjustments...)[after.playerID(anon)]
+
+	if !dryRun && len(res.Cells) > 0 {
+		cells := map[string]string{}
+		for _, c := range res.Cells {
+			cells[c.Cell] = c.New
+		}
+		if err := sheets.UpdateCells(ctx, l.spreadsheetID, cells, l.opts...); err != nil {
+			return nil, err
+		}
+	}
+	if st != nil {
+		// their reports' photos can't be rolled back, so they're only deleted
+		// once their reports are
+		var photos []string
+		if l.reports != nil {
+			photos, err = l.reportPhotos(ctx, names)
+			if err != nil {
+				return nil, err
+			}
+		}
+		// their comments and reports go rather than being kept under the
+		// anonymous name
+		deleted, err := st.forgetPlayer(ctx, l.snapshotKey(), names, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		if !dryRun {
+			if err := l.deletePhotos(ctx, photos); err != nil {
+				return nil, err
+			}
+		}
+		for _, from := range names {
+			renamed, err := st.renamePlayer(ctx, l.snapshotKey(), from, after.playerID(anon), anon, dryRun)
+			if err != nil {
+				return nil, err
+			}
+			for table, n := range renamed {
+				res.Records[table] += n
+			}
+		}
+		for table, n := range deleted {
+			res.Records[table] += n
+		}
+	}
+	// the archived standings are drawn again under the anonymous name
+	weeks, err := l.rerenderArchive(ctx, after, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if weeks > 0 {
+		res.Records["standings_archive"] += weeks
+	}
+	res.Profile, err = removeProfile(l.profilesPath, names, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// forgetHandler returns the admin handler that anonymizes a player who asked
+// to be removed from the league. It previews the changes unless posted
+// apply=true.
+func forgetHandler(l *league, st *store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			methodNotAllowedRes(w, r, "POST")
+			return
+		}
+		player := strings.TrimSpace(r.PostFormValue("player"))
+		if player == "" {
+			badRequestRes(w, r, "player is required")
+			return
+		}
+		dryRun := !isMarked(r.PostFormValue("apply"))
+
+		res, err := l.forgetPlayer(r.Context(), st, player, dryRun)
+		switch {
+		case errors.Is(err, errNotFound):
+			notFoundRes(w, r)
+			return
+		case err != nil:
+			log.Printf("error forgetting %s: %+v", player, err)
+			errorRes(w, r, err)
+			return
+		}
+		if !dryRun {
+			log.Printf("forgot a player as %s in %d cells", res.Anonymous, len(res.Cells))
+		}
+		web.WriteJSON(w, res)
+	}
+}
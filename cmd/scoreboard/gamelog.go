@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+const (
+	// defaultGamesLimit and maxGamesLimit bound the page size of the game
+	// log API.
+	defaultGamesLimit, maxGamesLimit = 50, 500
+)
+
+// GameRecord is a game as returned by the game log API.
+type GameRecord struct {
+	ID        string     `json:"id"`
+	Timestamp time.Time  `json:"timestamp"`
+	Players   []string   `json:"players"`         // player IDs in finishing order.
+	Teams     [][]string `json:"teams,omitempty"` // the teams of a two-headed giant game, in finishing order.
+	Zap       bool       `json:"zap"`
+	Draw      bool       `json:"draw"`
+	Notes     string     `json:"notes,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+	Results   []Result   `json:"results"` // empty if the game couldn't be scored.
+
+	// RankAverage and RankTotal are the average and total of the pod's
+	// ratings going into the game, and Strength how that compares to the
+	// league's other games, when the game was scored with them.
+	RankAverage int          `json:"rankAverage,omitempty"`
+	RankTotal   int          `json:"rankTotal,omitempty"`
+	Strength    *PodStrength `json:"strength,omitempty"`
+}
+
+// GamesQuery filters and pages the game log API.
+type GamesQuery struct {
+	Player   string
+	Since    time.Time // only games on or after this date, zero for no limit.
+	Until    time.Time // only games before the end of this date, zero for no limit.
+	PodSizes []int
+	Zap      *bool
+	Draw     *bool
+	Tag      string // only games with this tag, empty for any.
+	Limit    int
+	After    *gameCursor // only games after this one in the log's order.
+}
+
+// gameCursor marks a position in the game log's order, which is by timestamp
+// then ID, so games added to the sheet later don't shift earlier pages.
+type gameCursor struct {
+	Timestamp time.Time
+	ID        string
+}
+
+func (c gameCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Timestamp.UTC().Format(time.RFC3339) + "|" + c.ID))
+}
+
+func parseGameCursor(s string) (*gameCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	parts := strings.SplitN(string(b), "|", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	ts, err := time.Parse(time.RFC3339, parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &gameCursor{Timestamp: ts, ID: parts[1]}, nil
+}
+
+// precedes reports whether the cursor's game sorts before the game.
+func (c gameCursor) precedes(g *Game) bool {
+	if !g.Timestamp.Equal(c.Timestamp) {
+		return c.Timestamp.Before(g.Timestamp)
+	}
+	return lessGameID(c.ID, g.ID)
+}
+
+// lessGameID orders game IDs numerically when both are numbers, since the
+// log numbers its games, and as strings otherwise.
+func lessGameID(a, b string) bool {
+	x, errX := strconv.Atoi(a)
+	y, errY := strconv.Atoi(b)
+	if errX == nil && errY == nil {
+		return x < y
+	}
+	return a < b
+}
+
+// parseGamesQuery parses the game log API's query parameters: player, since
+// and until as YYYY-MM-DD, podSize as a comma separated list, zap and draw as
+// true or false, tag, limit and cursor.
+func parseGamesQuery(q url.Values) (GamesQuery, error) {
+	gq := GamesQuery{Player: strings.TrimSpace(q.Get("player")), Tag: strings.TrimSpace(q.Get("tag")), Limit: defaultGamesLimit}
+
+	var err error
+	if v := q.Get("since"); v != "" {
+		if gq.Since, err = time.Parse("2006-01-02", v); err != nil {
+			return gq, fmt.Errorf("invalid since date %q, it must look like 2023-01-01", v)
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		if gq.Until, err = time.Parse("2006-01-02", v); err != nil {
+			return gq, fmt.Errorf("invalid until date %q, it must look like 2023-01-31", v)
+		}
+		// the end date is inclusive
+		gq.Until = gq.Until.AddDate(0, 0, 1)
+	}
+	if v := q.Get("podSize"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || n < 2 {
+				return gq, fmt.Errorf("invalid podSize %q", s)
+			}
+			gq.PodSizes = append(gq.PodSizes, n)
+		}
+	}
+	for name, dst := range map[string]**bool{"zap": &gq.Zap, "draw": &gq.Draw} {
+		if v := q.Get(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return gq, fmt.Errorf("%s must be true or false", name)
+			}
+			*dst = &b
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxGamesLimit {
+			return gq, fmt.Errorf("limit must be between 1 and %d", maxGamesLimit)
+		}
+		gq.Limit = n
+	}
+	if v := q.Get("cursor"); v != "" {
+		if gq.After, err = parseGameCursor(v); err != nil {
+			return gq, err
+		}
+	}
+	return gq, nil
+}
+
+// matches reports whether the game is included by the query's filters.
+func (gq GamesQuery) matches(g *Game) bool {
+	if !gq.Since.IsZero() && g.Timestamp.Before(gq.Since) {
+		return false
+	}
+	if !gq.Until.IsZero() && !g.Timestamp.Before(gq.Until) {
+		return false
+	}
+	if gq.Player != "" {
+		found := false
+		for _, p := range g.Rankings {
+			if p == gq.Player {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(gq.PodSizes) > 0 {
+		found := false
+		for _, n := range gq.PodSizes {
+			if len(g.Rankings) == n {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if gq.Zap != nil && isMarked(g.TableZap) != *gq.Zap {
+		return false
+	}
+	if gq.Draw != nil && g.IsDraw() != *gq.Draw {
+		return false
+	}
+	if gq.Tag != "" && !hasTag(g, gq.Tag) {
+		return false
+	}
+	return true
+}
+
+// pageGames returns the page of games the query selects in the log's order,
+// and the cursor for the next page, or nil if this is the last page.
+func pageGames(games []*Game, gq GamesQuery) ([]GameRecord, *gameCursor) {
+	scale := newPodScale(games)
+	records := []GameRecord{}
+	for _, g := range logOrder(games) {
+		if gq.After != nil && !gq.After.precedes(g) {
+			continue
+		}
+		if !gq.matches(g) {
+			continue
+		}
+		if len(records) == gq.Limit {
+			last := records[len(records)-1]
+			return records, &gameCursor{Timestamp: last.Timestamp, ID: last.ID}
+		}
+		records = append(records, gameRecord(g, scale))
+	}
+	return records, nil
+}
+
+// logOrder returns the games in the log's order, by timestamp then ID.
+func logOrder(games []*Game) []*Game {
+	ordered := make([]*Game, len(games))
+	copy(ordered, games)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if !ordered[i].Timestamp.Equal(ordered[j].Timestamp) {
+			return ordered[i].Timestamp.Before(ordered[j].Timestamp)
+		}
+		return lessGameID(ordered[i].ID, ordered[j].ID)
+	})
+	return ordered
+}
+
+// gameRecord returns the game's entry in the game log API, with its pod's
+// strength on the scale.
+func gameRecord(g *Game, scale podScale) GameRecord {
+	return GameRecord{
+		ID:        g.ID,
+		Timestamp: g.Timestamp,
+		Players:   g.Rankings,
+		Teams:     g.Teams,
+		Zap:       isMarked(g.TableZap),
+		Draw:      g.IsDraw(),
+		Notes:     g.Notes,
+		Tags:      g.Tags,
+		Results:   g.Results,
+
+		RankAverage: g.RankAverage,
+		RankTotal:   g.RankTotal,
+		Strength:    scale.strength(g),
+	}
+}
+
+// gamesHandler returns the handler for the game log API at /api/games, which
+// pages through the games oldest first for external tools to sync.
+// Each response includes a nextCursor to pass as cursor for the next page
+// until the end of the log is reached.
+func gamesHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		gq, err := parseGamesQuery(r.URL.Query())
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			web.WriteJSON(w, map[string]interface{}{"error": err.Error()})
+			return
+		}
+
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		if gq.Player != "" {
+			gq.Player = ds.playerID(gq.Player)
+		}
+		calculateScores(ds.Games, ds.Adjustments...)
+
+		games := append(append([]*Game{}, ds.Games...), ds.Unscored...)
+		records, next := pageGames(games, gq)
+		res := map[string]interface{}{
+			"version": version,
+			"games":   records,
+		}
+		if next != nil {
+			res["nextCursor"] = next.String()
+		}
+		web.WriteJSON(w, res)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// healthHandler returns the health check handler. It reports whether the
+// league's game log can be fetched and whether it has any games yet, so a new
+// league with an empty sheet can be told apart from a broken one.
+func healthHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ds, err := l.fetch(r.Context())
+		if err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			web.WriteJSON(w, map[string]interface{}{
+				"status":  "error",
+				"version": version,
+				"error":   err.Error(),
+			})
+			return
+		}
+
+		status := "ok"
+		if len(ds.Games) == 0 {
+			status = "empty"
+		}
+		web.WriteJSON(w, map[string]interface{}{
+			"status":         status,
+			"version":        version,
+			"games":          len(ds.Games),
+			"datasetVersion": datasetVersion(ds),
+		})
+	}
+}
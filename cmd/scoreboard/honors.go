@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// The optional game log columns after the tags that record a game's dubious
+// honors, each naming a player.
+const (
+	firstBloodColumn = tagsColumn + 1 // Y, who dealt the game's first damage.
+	firstOutColumn   = tagsColumn + 2 // Z, who was eliminated first.
+	kingmakerColumn  = tagsColumn + 3 // AA, who decided the winner once out of contention.
+)
+
+// HonorCount is how many games a player earned one of the dubious honors in.
+type HonorCount struct {
+	Player string  // the player's ID.
+	Count  int     // the games they earned it in.
+	Games  int     // the games they played.
+	Rate   float64 // the share of their games they earned it in.
+}
+
+// DubiousHonors are the league's tallies of the honors nobody wants, each
+// with the player who earned it most first.
+type DubiousHonors struct {
+	FirstBlood []HonorCount
+	FirstOut   []HonorCount
+	Kingmaker  []HonorCount
+}
+
+// parseHonors parses the dubious honors columns of a game log row into the
+// game.
+func parseHonors(g *Game, row []interface{}) {
+	cell := func(col int) string {
+		if col >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(fmt.Sprintf("%s", row[col]))
+	}
+	g.FirstBlood = cell(firstBloodColumn)
+	g.FirstEliminated = cell(firstOutColumn)
+	g.Kingmaker = cell(kingmakerColumn)
+}
+
+// dubiousHonors tallies the dubious honors over the games. The first out is
+// the player recorded as eliminated first, or otherwise the one who finished
+// last, so it's counted even for leagues that don't record it.
+func dubiousHonors(games []*Game) DubiousHonors {
+	played := map[string]int{}
+	firstBlood, firstOut, kingmaker := map[string]int{}, map[string]int{}, map[string]int{}
+	for _, g := range games {
+		for _, name := range g.Rankings {
+			played[name]++
+		}
+		if g.FirstBlood != "" {
+			firstBlood[g.FirstBlood]++
+		}
+		if name := g.FirstOut(); name != "" {
+			firstOut[name]++
+		}
+		if g.Kingmaker != "" {
+			kingmaker[g.Kingmaker]++
+		}
+	}
+	return DubiousHonors{
+		FirstBlood: honorCounts(firstBlood, played),
+		FirstOut:   honorCounts(firstOut, played),
+		Kingmaker:  honorCounts(kingmaker, played),
+	}
+}
+
+// honorCounts returns the counts of an honor by player, most first, with
+// ties in name order.
+func honorCounts(counts, played map[string]int) []HonorCount {
+	var honors []HonorCount
+	for name, n := range counts {
+		h := HonorCount{Player: name, Count: n, Games: played[name]}
+		if h.Games > 0 {
+			h.Rate = float64(n) / float64(h.Games)
+		}
+		honors = append(honors, h)
+	}
+	sort.Slice(honors, func(i, j int) bool {
+		if honors[i].Count != honors[j].Count {
+			return honors[i].Count > honors[j].Count
+		}
+		return honors[i].Player < honors[j].Player
+	})
+	return honors
+}
+
+// honorsHandler returns the handler for the dubious honors page at /honors.
+func honorsHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+
+		t.ExecuteTemplate(w, "honors.html.tmpl", HonorsView{
+			PageView: newPageView(r, "Dubious honors", "The league's most frequent first bloods, first outs and kingmakers."),
+			Honors:   dubiousHonors(ds.Games),
+			Names:    ds.Names,
+		})
+	}
+}
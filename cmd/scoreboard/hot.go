@@ -0,0 +1,22 @@
+package main
+
+import "time"
+
+// hotDays is the number of trailing days the hot board rates players over.
+// Configured with SCOREBOARD_HOT_DAYS.
+var hotDays = 30
+
+// hotRankings rates players from a fresh baseline using only the games played
+// in the trailing hotDays, so newcomers and streaky players have a board to
+// climb mid-season. Rating adjustments don't apply to the hot board.
+func hotRankings(games []*Game, now time.Time) []Player {
+	cutoff := now.AddDate(0, 0, -hotDays)
+	var recent []*Game
+	for _, g := range games {
+		if !g.Timestamp.Before(cutoff) && !g.Timestamp.After(now) {
+			recent = append(recent, g)
+		}
+	}
+	recent = copyGames(recent)
+	return rankPlayers(recent, calculateScores(recent), now)
+}
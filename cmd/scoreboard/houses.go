@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// houseScoring is how a house's rating is aggregated from its members'
+// ratings: "average" for the average of all its rated members, or "best-N"
+// for the average of its N best. Configured with SCOREBOARD_HOUSE_SCORING.
+var houseScoring = "average"
+
+// House is a group of players, like a household or the store they play at,
+// ranked against the other houses on a secondary leaderboard.
+type House struct {
+	Name    string
+	Members []string // the members' player IDs.
+}
+
+// HouseStanding is a house's place on the house leaderboard.
+type HouseStanding struct {
+	Name    string
+	Score   int      // the aggregate of the counted members' ratings.
+	Members []Player // the house's rated members, best first.
+	Counted int      // how many of the best members the score counts.
+}
+
+// parseHouseRows parses the houses tab, with a house name and one of its
+// members per row. The first row holds the labels.
+func parseHouseRows(values [][]interface{}) []House {
+	var houses []House
+	index := map[string]int{}
+	for idx, row := range values {
+		if idx == 0 || len(row) < 2 {
+			continue
+		}
+		name := strings.TrimSpace(fmt.Sprintf("%s", row[0]))
+		member := strings.TrimSpace(fmt.Sprintf("%s", row[1]))
+		if name == "" || member == "" {
+			continue
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(houses)
+			index[name] = i
+			houses = append(houses, House{Name: name})
+		}
+		houses[i].Members = append(houses[i].Members, member)
+	}
+	return houses
+}
+
+// parseHouseScoring validates a house scoring method, "average" or "best-N".
+func parseHouseScoring(s string) error {
+	if s == "average" {
+		return nil
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(s, "best-")); strings.HasPrefix(s, "best-") && err == nil && n > 0 {
+		return nil
+	}
+	return fmt.Errorf(`expected "average" or "best-N", like best-3, got %q`, s)
+}
+
+// houseStandings ranks the houses by their members in the rankings, scored
+// with the method, which must be valid. Members who haven't played aren't
+// counted, and houses without any members who have aren't ranked. Houses
+// with fewer members than a best-N method counts are scored on the members
+// they have.
+func houseStandings(houses []House, rankings []Player, method string) []HouseStanding {
+	rated := map[string]Player{}
+	for _, p := range rankings {
+		rated[p.ID] = p
+	}
+	best := 0
+	if strings.HasPrefix(method, "best-") {
+		best, _ = strconv.Atoi(strings.TrimPrefix(method, "best-"))
+	}
+
+	var standings []HouseStanding
+	for _, h := range houses {
+		s := HouseStanding{Name: h.Name}
+		for _, id := range h.Members {
+			if p, ok := rated[id]; ok {
+				s.Members = append(s.Members, p)
+			}
+		}
+		if len(s.Members) == 0 {
+			continue
+		}
+		sort.SliceStable(s.Members, func(i, j int) bool { return s.Members[i].Score > s.Members[j].Score })
+
+		s.Counted = len(s.Members)
+		if best > 0 && best < s.Counted {
+			s.Counted = best
+		}
+		total := 0
+		for _, p := range s.Members[:s.Counted] {
+			total += p.Score
+		}
+		s.Score = total / s.Counted
+		standings = append(standings, s)
+	}
+	sort.SliceStable(standings, func(i, j int) bool { return standings[i].Score > standings[j].Score })
+	return standings
+}
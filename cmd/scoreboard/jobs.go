@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+const (
+	// jobHistory is the number of finished jobs kept for the admin view.
+	jobHistory = 100
+	// jobBackoff is the delay before a failed job's first retry, doubling with
+	// each attempt after.
+	jobBackoff = 30 * time.Second
+)
+
+// Job is a unit of background work, like sending an email or storing a
+// snapshot, run outside of request handlers and retried when it fails.
+type Job struct {
+	ID          int64     `json:"id"`
+	Kind        string    `json:"kind"` // e.g. "refresh", "snapshot", "email" or "webhook".
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"maxAttempts"`
+	LastError   string    `json:"lastError,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+
+	run  func(ctx context.Context) error
+	done chan struct{} // closed once the job succeeds or runs out of attempts.
+}
+
+// job statuses
+const (
+	jobQueued   = "queued"
+	jobRunning  = "running"
+	jobRetrying = "retrying"
+	jobDone     = "done"
+	jobFailed   = "failed"
+)
+
+// jobQueue runs jobs on a fixed pool of workers, retrying failed jobs with
+// exponential backoff, and remembers recent jobs for the admin view.
+type jobQueue struct {
+	mu      sync.Mutex
+	nextID  int64
+	jobs    []*Job // queued and running jobs followed by recently finished ones, oldest first.
+	pending chan *Job
+	backoff time.Duration
+	clock   Clock // stamps the jobs' creation and updates.
+}
+
+// backgroundJobs is the app's job queue, nil when jobs are run inline, like
+// in tests and the commands.
+var backgroundJobs *jobQueue
+
+func newJobQueue() *jobQueue {
+	return &jobQueue{pending: make(chan *Job, 1000), backoff: jobBackoff, clock: systemClock{}}
+}
+
+// now returns the time from the queue's clock, or the system's for a nil
+// queue.
+func (q *jobQueue) now() time.Time {
+	if q == nil {
+		return systemClock{}.Now()
+	}
+	return q.clock.Now()
+}
+
+// enqueue queues fn to run in the background, up to maxAttempts times until
+// it succeeds. On a nil queue it runs fn right away, once.
+func (q *jobQueue) enqueue(kind string, maxAttempts int, fn func(ctx context.Context) error) *Job {
+	now := q.now()
+	job := &Job{Kind: kind, Status: jobQueued, MaxAttempts: maxAttempts, CreatedAt: now, UpdatedAt: now, run: fn, done: make(chan struct{})}
+	if q == nil {
+		job.MaxAttempts = 1
+		runJob(context.Background(), job, systemClock{})
+		return job
+	}
+
+	q.mu.Lock()
+	q.nextID++
+	job.ID = q.nextID
+	q.jobs = append(q.jobs, job)
+	q.mu.Unlock()
+
+	select {
+	case q.pending <- job:
+	default:
+		q.finish(job, jobFailed, "the job queue is full")
+	}
+	return job
+}
+
+// run works through the queue with the given number of workers until the
+// context is cancelled.
+func (q *jobQueue) run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-q.pending:
+					q.attempt(ctx, job)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// attempt runs the job once and schedules a retry if it failed with attempts
+// left.
+func (q *jobQueue) attempt(ctx context.Context, job *Job) {
+	q.mu.Lock()
+	job.Status = jobRunning
+	job.Attempts++
+	job.UpdatedAt = q.now()
+	q.mu.Unlock()
+
+	err := job.run(ctx)
+	if err == nil {
+		q.finish(job, jobDone, "")
+		return
+	}
+	log.Printf("%s job %d failed on attempt %d of %d: %s", job.Kind, job.ID, job.Attempts, job.MaxAttempts, err)
+	if job.Attempts >= job.MaxAttempts {
+		q.finish(job, jobFailed, err.Error())
+		return
+	}
+
+	q.mu.Lock()
+	job.Status = jobRetrying
+	job.LastError = err.Error()
+	job.UpdatedAt = q.now()
+	delay := q.backoff << uint(job.Attempts-1)
+	q.mu.Unlock()
+	time.AfterFunc(delay, func() { q.pending <- job })
+}
+
+// finish records the job's outcome and drops the oldest finished jobs beyond
+// jobHistory.
+func (q *jobQueue) finish(job *Job, status, lastError string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job.Status = status
+	job.LastError = lastError
+	job.UpdatedAt = q.now()
+	close(job.done)
+
+	finished := 0
+	for _, j := range q.jobs {
+		if j.Status == jobDone || j.Status == jobFailed {
+			finished++
+		}
+	}
+	kept := q.jobs[:0]
+	for _, j := range q.jobs {
+		if finished > jobHistory && (j.Status == jobDone || j.Status == jobFailed) {
+			finished--
+			continue
+		}
+		kept = append(kept, j)
+	}
+	q.jobs = kept
+}
+
+// status returns the job's status.
+func (q *jobQueue) status(job *Job) string {
+	if q == nil {
+		return job.Status
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return job.Status
+}
+
+// snapshot returns copies of the queue's jobs, newest first.
+func (q *jobQueue) snapshot() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]Job, 0, len(q.jobs))
+	for i := len(q.jobs) - 1; i >= 0; i-- {
+		j := *q.jobs[i]
+		j.run, j.done = nil, nil
+		jobs = append(jobs, j)
+	}
+	return jobs
+}
+
+// runJob runs a job inline, for a nil queue, stamping it with the clock.
+func runJob(ctx context.Context, job *Job, clock Clock) {
+	job.Attempts = 1
+	if err := job.run(ctx); err != nil {
+		log.Printf("%s job failed: %s", job.Kind, err)
+		job.Status, job.LastError = jobFailed, err.Error()
+	} else {
+		job.Status = jobDone
+	}
+	job.UpdatedAt = clock.Now()
+	close(job.done)
+}
+
+// jobsHandler lists the queued, running and recently finished background
+// jobs, with counts by status.
+func jobsHandler(q *jobQueue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobs := q.snapshot()
+		counts := map[string]int{}
+		for _, j := range jobs {
+			counts[j.Status]++
+		}
+		web.WriteJSON(w, map[string]interface{}{"counts": counts, "jobs": jobs})
+	}
+}
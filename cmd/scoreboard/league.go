@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/sheets"
+	"github.com/fly-apps/go-example/internal/web"
+	"google.golang.org/api/option"
+)
+
+// errQuotaExceeded is returned when a league has used up its Sheets quota.
+var errQuotaExceeded = errors.New("sheets quota exceeded, try again later")
+
+// league is a single scoreboard backed by a game log in Google Sheets. In
+// hosted mode each tenant gets its own league, so caches and quotas are
+// isolated between playgroups.
+type league struct {
+	spreadsheetID string
+	tenant        string // the hosted tenant's slug, "" for a single league.
+	ranges        sheetRanges
+	opts          []option.ClientOption
+	quota         *quota        // limits fetches from Google Sheets, nil for no limit.
+	profilesPath  string        // the player profiles file, for declared rivalries.
+	picks         *store        // stores pick-em predictions, nil when pick-em is off.
+	comments      *store        // stores game and player comments, nil when comments are off.
+	snapshots     *store        // stores past leaderboards served by the rankings API, nil without a database.
+	live          bool          // whether games can be recorded from the live page, which writes to the sheet.
+	pending       *store        // holds live games until another player confirms them, nil when games don't need confirming.
+	polls         *store        // stores scheduling polls, nil when scheduling is off.
+	gameLogGID    string        // the gid of the game log's tab, for linking to games in the sheet.
+	archive       *store        // stores the weekly standings archive, nil without a database.
+	reports       *store        // stores game reports, nil when reports are off.
+	photos        photoStore    // stores the photos attached to game reports.
+	pages         *renderCache  // the rendered leaderboard pages, dropped on refresh.
+	players       playerPages   // the computed player pages, dropped on refresh.
+	namesMigrated sync.Once     // renames the stored records' players to their IDs once.
+	fetches       fetchTracker  // the progress of the latest fetch from Google Sheets.
+	backoff       sheetsBackoff // holds off fetches after Google Sheets rejects one for quota.
+	maintenance   maintenance   // the read-only mode the admin turns on during disputes.
+	clock         Clock         // what the league takes as now, for dates, trends, expiry and timestamps.
+	random        io.Reader     // where the league's random keys are read from.
+
+	// recording serializes appending games to the sheet so two aren't given
+	// the same ID.
+	recording sync.Mutex
+
+	// sessions signs the login cookies of players who comment, record
+	// games or mark their availability, nil when none of those are on.
+	sessions *sessions
+
+	// frozen, when set, is served instead of fetching from Google Sheets.
+	frozen *Dataset
+}
+
+// sheetRanges are the ranges of a league's spreadsheet that hold its data.
+// All of them are fetched in a single batch request. Only the game log is
+// required.
+type sheetRanges struct {
+	Games   string // the game log.
+	Players string // the players tab, with a player name or ID, name and other names per row.
+	Aliases string // the aliases tab, mapping an alternate name in the first column to the canonical name in the second.
+	Seasons string // the seasons tab, with a season name and its YYYY-MM-DD start date.
+	Seeds   string // the seeds tab, with a player and the rating they start at.
+	Houses  string // the houses tab, with a house name and one of its members.
+	Rules   string // the rules tab, with a line of the league's rules per row.
+	Events  string // the events tab, with the seatings and matches of draft and limited events.
+
+	// Adjustments is the adjustments tab, with the date, player, amount and
+	// reason for each manual rating adjustment.
+	Adjustments string
+}
+
+// Dataset is everything loaded from a league's spreadsheet.
+type Dataset struct {
+	Games []*Game
+	// Unscored are the two-headed giant games, which aren't scored yet but
+	// are listed in the game log with their teams.
+	Unscored []*Game
+	Players  []PlayerRecord    // the league's roster from the players tab.
+	Aliases  map[string]string // canonical player names keyed by lowercased alias.
+	Seasons  []Season          // seasons from the seasons tab.
+	Houses   []House           // the houses players are grouped into from the houses tab.
+	Rules    []RuleBlock       // the league's rules from the rules tab.
+	Events   []*Event          // the draft and limited events from the events tab, in date order.
+	Names    playerNames       // the names shown for each player ID.
+
+	// Adjustments are the manual rating adjustments from the adjustments tab,
+	// in date order, preceded by the seeded ratings from the seeds tab.
+	Adjustments []*Adjustment
+
+	ids map[string]string // player IDs keyed by lowercased name.
+}
+
+// newLeague returns a league for the game log at the given spreadsheet and
+// range. The client options are passed through to the Google Sheets client,
+// which lets tests point a league at a fake Sheets server.
+func newLeague(spreadsheetID, readRange string, opts ...option.ClientOption) *league {
+	return &league{
+		spreadsheetID: spreadsheetID,
+		ranges:        sheetRanges{Games: readRange},
+		gameLogGID:    "0",
+		opts:          opts,
+		pages:         newRenderCache(),
+		clock:         systemClock{},
+		random:        rand.Reader,
+	}
+}
+
+// snapshotKey returns the key of the league's stored records, from rating
+// snapshots to comments and picks: the tenant's slug when hosted, since
+// tenants may share a spreadsheet, or otherwise the spreadsheet ID.
+func (l *league) snapshotKey() string {
+	if l.tenant != "" {
+		return l.tenant
+	}
+	return l.spreadsheetID
+}
+
+// fetch fetches the league's game log and auxiliary tabs in a single batch
+// request, or in chunks with SCOREBOARD_FETCH_CHUNK_ROWS, and parses them.
+// While backing off after Google Sheets rejected a fetch for quota, the last
+// dataset fetched is returned instead.
+func (l *league) fetch(ctx context.Context) (*Dataset, error) {
+	if l.frozen != nil {
+		return l.frozen.copy(), nil
+	}
+	if ds := l.maintenance.dataset(); ds != nil {
+		return ds, nil
+	}
+	if last, ok := l.backoff.hold(l.clock.Now()); ok {
+		if last == nil {
+			return nil, errQuotaExceeded
+		}
+		return last, nil
+	}
+	if l.quota != nil && !l.quota.allow(l.clock.Now()) {
+		return nil, errQuotaExceeded
+	}
+
+	l.fetches.start(l.clock.Now())
+	ds, err := l.fetchAll(ctx)
+	l.fetches.finish(err, l.clock.Now())
+	if err != nil && isQuotaError(err) {
+		if last := l.backoff.rejected(err, l.clock.Now()); last != nil {
+			return last, nil
+		}
+		return nil, err
+	}
+	if err == nil {
+		l.backoff.fetched(ds, l.clock.Now())
+	}
+	return ds, err
+}
+
+// fetchAll fetches and parses the game log and auxiliary tabs, in chunks if
+// fetchChunkRows is set and the game log's range has an end column to chunk.
+func (l *league) fetchAll(ctx context.Context) (*Dataset, error) {
+	if col, _ := sheets.RangeEnd(l.ranges.Games); fetchChunkRows > 0 && col != "" {
+		return l.fetchChunked(ctx)
+	}
+	values, err := sheets.FetchRanges(ctx, l.spreadsheetID, l.rangeList(), l.opts...)
+	if err != nil {
+		return nil, err
+	}
+	ds, err := l.parse(values)
+	if err == nil {
+		l.fetches.chunk(len(values[0]), len(ds.Games)+len(ds.Unscored))
+	}
+	return ds, err
+}
+
+// rangeList returns the ranges fetched for the league: the game log followed
+// by the configured auxiliary tabs.
+func (l *league) rangeList() []string {
+	ranges := []string{l.ranges.Games}
+	for _, r := range []string{l.ranges.Players, l.ranges.Aliases, l.ranges.Seasons, l.ranges.Seeds, l.ranges.Adjustments, l.ranges.Houses, l.ranges.Rules, l.ranges.Events} {
+		if r != "" {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// parse parses the rows fetched for the ranges in rangeList.
+func (l *league) parse(values [][][]interface{}) (*Dataset, error) {
+	// an empty or header-only game log is a new league, not an error
+	games, unscored, err := parseGameLog(values[0])
+	if err != nil {
+		return nil, err
+	}
+	return l.parseTabs(&Dataset{Games: games, Unscored: unscored}, values)
+}
+
+// parseTabs parses the auxiliary tabs fetched for the ranges in rangeList
+// into the dataset, which already holds the game log.
+func (l *league) parseTabs(ds *Dataset, values [][][]interface{}) (*Dataset, error) {
+	var err error
+	// the auxiliary tabs follow the game log in the order they were requested
+	next := 1
+	if l.ranges.Players != "" {
+		ds.Players = parsePlayerRows(values[next])
+		next++
+	}
+	if l.ranges.Aliases != "" {
+		ds.Aliases = parseAliasRows(values[next])
+		next++
+	}
+	if l.ranges.Seasons != "" {
+		ds.Seasons, err = parseSeasonRows(values[next])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse seasons tab: %w", err)
+		}
+		next++
+	}
+	if l.ranges.Seeds != "" {
+		ds.Adjustments, err = parseSeedRows(values[next])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse seeds tab: %w", err)
+		}
+		next++
+	}
+	if l.ranges.Adjustments != "" {
+		adjustments, err := parseAdjustmentRows(values[next])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse adjustments tab: %w", err)
+		}
+		ds.Adjustments = append(ds.Adjustments, adjustments...)
+		next++
+	}
+	if l.ranges.Houses != "" {
+		ds.Houses = parseHouseRows(values[next])
+		next++
+	}
+	if l.ranges.Rules != "" {
+		ds.Rules = parseRuleRows(values[next])
+		next++
+	}
+	if l.ranges.Events != "" {
+		ds.Events, err = parseEventRows(values[next])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse events tab: %w", err)
+		}
+	}
+
+	ds.resolvePlayerIDs()
+	ds.dropDuplicatePlayers()
+	return ds, nil
+}
+
+// copy returns a copy of the dataset that can be scored without affecting
+// the original.
+func (ds *Dataset) copy() *Dataset {
+	c := *ds
+	c.Games = copyGames(ds.Games)
+	c.Adjustments = copyAdjustments(ds.Adjustments)
+	return &c
+}
+
+// configureRangesFromEnv sets the league's auxiliary tab ranges from the
+// SCOREBOARD_*_RANGE variables.
+func (l *league) configureRangesFromEnv() {
+	l.ranges.Players = os.Getenv("SCOREBOARD_PLAYERS_RANGE")
+	l.ranges.Aliases = os.Getenv("SCOREBOARD_ALIASES_RANGE")
+	l.ranges.Seasons = os.Getenv("SCOREBOARD_SEASONS_RANGE")
+	l.ranges.Seeds = os.Getenv("SCOREBOARD_SEEDS_RANGE")
+	l.ranges.Adjustments = os.Getenv("SCOREBOARD_ADJUSTMENTS_RANGE")
+	l.ranges.Houses = os.Getenv("SCOREBOARD_HOUSES_RANGE")
+	l.ranges.Rules = os.Getenv("SCOREBOARD_RULES_RANGE")
+	l.ranges.Events = os.Getenv("SCOREBOARD_EVENTS_RANGE")
+	if gid := os.Getenv("SCOREBOARD_GAME_LOG_GID"); gid != "" {
+		l.gameLogGID = gid
+	}
+}
+
+// rowURL returns a link to the game's row in the league's spreadsheet, so a
+// data error can be fixed in one click, or "" if the game's row isn't known.
+func (l *league) rowURL(g *Game) string {
+	if g.Row == 0 {
+		return ""
+	}
+	_, _, first := sheets.RangeStart(l.ranges.Games)
+	row := first + g.Row - 1
+	return fmt.Sprintf("https://docs.google.com/spreadsheets/d/%s/edit#gid=%s&range=%d:%d", l.spreadsheetID, l.gameLogGID, row, row)
+}
+
+// parseAliasRows parses the aliases tab, which maps an alternate spelling of
+// a player's name in the first column to their canonical name in the second.
+// The first row holds the labels.
+func parseAliasRows(values [][]interface{}) map[string]string {
+	aliases := map[string]string{}
+	for idx, row := range values {
+		if idx == 0 || len(row) < 2 {
+			continue
+		}
+		alias := strings.TrimSpace(fmt.Sprintf("%s", row[0]))
+		name := strings.TrimSpace(fmt.Sprintf("%s", row[1]))
+		if alias != "" && name != "" {
+			aliases[strings.ToLower(alias)] = name
+		}
+	}
+	return aliases
+}
+
+// routes returns a mux serving the league's pages and API.
+func (l *league) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", indexHandler(l))
+	mux.HandleFunc("/api/rankings", web.WithCORS(apiCORS, rankingsHandler(l)))
+	mux.HandleFunc("/api/stats", web.WithCORS(apiCORS, statsHandler(l)))
+	mux.HandleFunc("/api/games", web.WithCORS(apiCORS, gamesHandler(l)))
+	mux.HandleFunc("/api/seeding", web.WithCORS(apiCORS, seedingHandler(l)))
+	mux.HandleFunc("/api/export", web.WithCORS(apiCORS, exportHandler(l)))
+	// public profiles are meant to be embedded on players' own sites
+	mux.HandleFunc("/api/players/", publicProfileHandler(l))
+	// overlays can be fetched from any origin regardless of the API's policy
+	mux.HandleFunc("/api/overlay", overlayHandler(l))
+	mux.HandleFunc("/api/refresh", refreshHandler(l))
+	mux.HandleFunc("/game/", gameHandler(l))
+	mux.HandleFunc("/player/", playerHandler(l))
+	mux.HandleFunc("/career/", careerHandler(l))
+	mux.HandleFunc("/rivalry/", rivalryHandler(l))
+	mux.HandleFunc("/eras", erasHandler(l))
+	mux.HandleFunc("/rules", rulesHandler(l))
+	mux.HandleFunc("/honors", honorsHandler(l))
+	mux.HandleFunc("/replay", replayHandler(l))
+	mux.HandleFunc("/curves", curvesHandler(l))
+	mux.HandleFunc("/events", eventsHandler(l))
+	mux.HandleFunc("/events/", eventsHandler(l))
+	mux.HandleFunc("/attendance", attendanceHandler(l))
+	mux.HandleFunc("/timeline", timelineHandler(l))
+	mux.HandleFunc("/projections", projectionsHandler(l))
+	mux.HandleFunc("/print", printHandler(l))
+	mux.HandleFunc("/archive", archiveHandler(l))
+	mux.HandleFunc("/archive/", archiveHandler(l))
+	mux.HandleFunc("/picks", pickemHandler(l))
+	mux.HandleFunc("/live", liveHandler(l))
+	mux.HandleFunc("/live/verify", verifyHandler(l))
+	mux.HandleFunc("/schedule", scheduleHandler(l))
+	mux.HandleFunc("/schedule/", scheduleHandler(l))
+	mux.HandleFunc("/pods", podsHandler(l))
+	mux.HandleFunc("/snapshot.png", snapshotHandler(l))
+	mux.HandleFunc("/comments", commentHandler(l))
+	mux.HandleFunc("/reports", reportHandler(l))
+	mux.HandleFunc("/photos/", photoHandler(l))
+	mux.HandleFunc("/login", loginHandler(l))
+	mux.HandleFunc("/logout", loginHandler(l))
+	mux.HandleFunc("/preferences", preferencesHandler(l))
+	mux.HandleFunc("/healthz", healthHandler(l))
+	mux.HandleFunc("/robots.txt", robotsHandler)
+	mux.HandleFunc("/sitemap.xml", sitemapHandler(l))
+	return l.maintenance.guard(mux)
+}
+
+// quota is a fixed window limit on the number of calls made in each window.
+type quota struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	used   int
+	reset  time.Time
+}
+
+func newQuota(limit int, window time.Duration) *quota {
+	return &quota{limit: limit, window: window}
+}
+
+// allow reports whether another call fits in the window current at now, and
+// counts it if so.
+func (q *quota) allow(now time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !now.Before(q.reset) {
+		q.used = 0
+		q.reset = now.Add(q.window)
+	}
+	if q.used >= q.limit {
+		return false
+	}
+	q.used++
+	return true
+}
+
+type basePathKey struct{}
+
+// withBasePath records the path prefix a league is mounted under so pages
+// can link within the league.
+func withBasePath(r *http.Request, prefix string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), basePathKey{}, prefix))
+}
+
+// basePath returns the path prefix the request's league is mounted under.
+func basePath(r *http.Request) string {
+	prefix, _ := r.Context().Value(basePathKey{}).(string)
+	return prefix
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/sheets"
+)
+
+// LiveGame is a game recorded on the live page as it was played.
+type LiveGame struct {
+	Start      time.Time
+	Players    []string    // the players in finishing order, winner first.
+	Eliminated []time.Time // when each player was eliminated, zero for the winner.
+	Zap        bool
+	Notes      string
+}
+
+// parseLiveGame parses a game submitted from the live page: start as unix
+// milliseconds, player repeated in finishing order with the matching
+// eliminated times in unix milliseconds, blank for the winner, and the
+// optional zap and notes.
+func parseLiveGame(form url.Values) (*LiveGame, error) {
+	ms, err := strconv.ParseInt(form.Get("start"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("start the game before submitting it")
+	}
+	g := &LiveGame{
+		Start: time.Unix(0, ms*int64(time.Millisecond)).UTC(),
+		Zap:   isMarked(form.Get("zap")),
+		Notes: strings.TrimSpace(form.Get("notes")),
+	}
+
+	players, eliminated := form["player"], form["eliminated"]
+	if len(players) < 2 || len(players) > maxPlayers {
+		return nil, fmt.Errorf("games need between 2 and %d players", maxPlayers)
+	}
+	if len(eliminated) != len(players) {
+		return nil, fmt.Errorf("every player but the winner needs an elimination time")
+	}
+	seen := map[string]bool{}
+	for idx, name := range players {
+		name = strings.TrimSpace(name)
+		if name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("invalid player name %q", name)
+		}
+		if seen[strings.ToLower(name)] {
+			return nil, fmt.Errorf("%s is in the pod twice", name)
+		}
+		seen[strings.ToLower(name)] = true
+
+		var at time.Time
+		if idx > 0 {
+			ms, err := strconv.ParseInt(eliminated[idx], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("missing elimination time for %s", name)
+			}
+			at = time.Unix(0, ms*int64(time.Millisecond)).UTC()
+			if at.Before(g.Start) {
+				return nil, fmt.Errorf("%s was eliminated before the game started", name)
+			}
+		}
+		g.Players = append(g.Players, name)
+		g.Eliminated = append(g.Eliminated, at)
+	}
+	return g, nil
+}
+
+// row returns the game as a game log row under the given game ID, with the
+// elimination times in columns L through Q.
+func (g *LiveGame) row(id string) []interface{} {
+	zap := ""
+	if g.Zap {
+		zap = "TRUE"
+	}
+	row := make([]interface{}, eliminationColumn+maxPlayers)
+	for idx := range row {
+		row[idx] = ""
+	}
+	row[0], row[1], row[2], row[4] = id, g.Start.Format(time.RFC1123), zap, g.Notes
+	for idx, name := range g.Players {
+		row[playerColumn+idx] = name
+		if !g.Eliminated[idx].IsZero() {
+			row[eliminationColumn+idx] = g.Eliminated[idx].Format("15:04:05")
+		}
+	}
+	return row
+}
+
+// liveHandler returns the handler for /live, where a logged in player records
+// a game as it's played: they pick the pod, tap players as they're
+// eliminated, and submit the finished game, which is appended to the game
+// log. When games need confirming the game is held as pending until another
+// player from the pod confirms it, and the page lists the pending games.
+func liveHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !l.live {
+			notFoundRes(w, r)
+			return
+		}
+		user := l.currentPlayer(r)
+		if user == "" {
+			if r.Method == http.MethodPost {
+				unauthorizedRes(w, r, "log in to record games")
+				return
+			}
+			http.Redirect(w, r, basePath(r)+"/login?next="+url.QueryEscape("/live"), http.StatusSeeOther)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			l.recording.Lock()
+			defer l.recording.Unlock()
+		}
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		games := ds.Games
+		players := rankPlayers(games, calculateScores(games, ds.Adjustments...), l.clock.Now())
+		ds.Names.apply(players)
+
+		view := LiveView{
+			PageView: barePageView(r),
+			Players:  players,
+			GameID:   nextGameID(games),
+			User:     user,
+		}
+
+		if r.Method == http.MethodPost {
+			r.ParseForm()
+			g, err := parseLiveGame(r.PostForm)
+			if err == nil {
+				// record players by the name they're known by in the log
+				for idx, name := range g.Players {
+					g.Players[idx] = ds.Names.Of(ds.playerID(name))
+				}
+				if l.pending != nil {
+					p := &PendingGame{League: l.snapshotKey(), Game: g, SubmittedBy: user, CreatedAt: l.clock.Now().UTC()}
+					if err := l.pending.addPendingGame(r.Context(), p); err != nil {
+						log.Printf("error saving pending game: %+v", err)
+						errorRes(w, r, err)
+						return
+					}
+					log.Printf("%s recorded pending game %d", user, p.ID)
+					http.Redirect(w, r, fmt.Sprintf("%s/live#pending-%d", basePath(r), p.ID), http.StatusSeeOther)
+					return
+				}
+				id := nextGameID(games)
+				err = sheets.AppendRow(r.Context(), l.spreadsheetID, l.ranges.Games, g.row(id), l.opts...)
+				if err != nil {
+					log.Printf("error recording live game: %+v", err)
+					errorRes(w, r, err)
+					return
+				}
+				log.Printf("%s recorded game %s", user, id)
+				http.Redirect(w, r, basePath(r)+"/game/"+id, http.StatusSeeOther)
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			view.Errors = err.Error()
+		}
+
+		if l.pending != nil {
+			pending, err := l.pending.pendingGames(r.Context(), l.snapshotKey())
+			if err != nil {
+				log.Printf("error loading pending games: %+v", err)
+				errorRes(w, r, err)
+				return
+			}
+			for _, p := range pending {
+				view.Pending = append(view.Pending, PendingView{
+					Game:       p,
+					CanConfirm: p.canConfirm(ds, user),
+					Mine:       p.SubmittedBy == user,
+				})
+			}
+			view.Verify = true
+		}
+
+		t.ExecuteTemplate(w, "live.html.tmpl", view)
+	}
+}
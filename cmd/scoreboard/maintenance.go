@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// defaultMaintenanceMessage is the banner shown in maintenance mode when the
+// admin doesn't give one.
+const defaultMaintenanceMessage = "Scores are frozen pending dispute resolution."
+
+// maintenance is a league's read-only mode, for when a ruling is being
+// argued over. While it's on the league serves the data it had when it was
+// turned on, with a banner on every page, and rejects anything that writes.
+type maintenance struct {
+	mu      sync.Mutex
+	message string // the banner, "" when maintenance mode is off.
+	since   time.Time
+	frozen  *Dataset // the data served while it's on.
+}
+
+// MaintenanceStatus is whether a league is in maintenance mode, for the admin
+// endpoint.
+type MaintenanceStatus struct {
+	Enabled bool      `json:"enabled"`
+	Message string    `json:"message,omitempty"`
+	Since   time.Time `json:"since,omitempty"`
+}
+
+// enable turns maintenance mode on at now with the message, serving ds until
+// it's turned off. Enabling it again only changes the message.
+func (m *maintenance) enable(message string, ds *Dataset, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.message == "" {
+		m.since = now
+		m.frozen = ds
+	}
+	m.message = message
+}
+
+// disable turns maintenance mode off.
+func (m *maintenance) disable() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.message, m.since, m.frozen = "", time.Time{}, nil
+}
+
+// banner returns the banner to show, or "" when maintenance mode is off.
+func (m *maintenance) banner() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.message
+}
+
+// dataset returns a copy of the data to serve while maintenance mode is on,
+// or nil.
+func (m *maintenance) dataset() *Dataset {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.frozen == nil {
+		return nil
+	}
+	return m.frozen.copy()
+}
+
+func (m *maintenance) status() MaintenanceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return MaintenanceStatus{Enabled: m.message != "", Message: m.message, Since: m.since}
+}
+
+type maintenanceKey struct{}
+
+// maintenanceBanner returns the maintenance banner of the request's league,
+// or "" when it isn't in maintenance mode.
+func maintenanceBanner(r *http.Request) string {
+	banner, _ := r.Context().Value(maintenanceKey{}).(string)
+	return banner
+}
+
+// guard serves the league's routes, marking requests with the maintenance
+// banner while maintenance mode is on and rejecting the ones that would
+// write. Players can still log in and out.
+func (m *maintenance) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		banner := m.banner()
+		if banner == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet, r.Method == http.MethodHead, r.Method == http.MethodOptions:
+		case r.URL.Path == "/login", r.URL.Path == "/logout", r.URL.Path == "/preferences":
+		default:
+			w.Header().Set("Retry-After", "3600")
+			errorPage(w, r, http.StatusServiceUnavailable, "The scoreboard is read-only for now. "+banner)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), maintenanceKey{}, banner)))
+	})
+}
+
+// maintenanceHandler returns the admin handler that turns maintenance mode on
+// and off at /admin/maintenance. Posting enabled=true freezes the league's
+// current data, with the message as the banner.
+func maintenanceHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			on, err := strconv.ParseBool(r.PostFormValue("enabled"))
+			if err != nil {
+				badRequestRes(w, r, "enabled must be true or false")
+				return
+			}
+			if !on {
+				l.maintenance.disable()
+				l.pages.clear()
+				break
+			}
+
+			message := strings.TrimSpace(r.PostFormValue("message"))
+			if message == "" {
+				message = defaultMaintenanceMessage
+			}
+			ds, err := l.fetch(r.Context())
+			if err != nil {
+				log.Printf("error fetching game data for maintenance mode: %+v", err)
+				errorRes(w, r, err)
+				return
+			}
+			l.maintenance.enable(message, ds, l.clock.Now())
+			l.pages.clear()
+		default:
+			methodNotAllowedRes(w, r, "GET, POST")
+			return
+		}
+		web.WriteJSON(w, l.maintenance.status())
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/sheets"
+)
+
+// sheetTab is one of the league's tabs as imported into the database.
+type sheetTab struct {
+	Name  string // the tab's name in the database, like "games" or "players".
+	Range string // the range it's read from in the sheet.
+}
+
+// tabs returns the league's game log and configured auxiliary tabs, in the
+// order of rangeList.
+func (l *league) tabs() []sheetTab {
+	var tabs []sheetTab
+	for _, t := range []sheetTab{
+		{"games", l.ranges.Games},
+		{"players", l.ranges.Players},
+		{"aliases", l.ranges.Aliases},
+		{"seasons", l.ranges.Seasons},
+		{"seeds", l.ranges.Seeds},
+		{"adjustments", l.ranges.Adjustments},
+		{"houses", l.ranges.Houses},
+		{"rules", l.ranges.Rules},
+		{"events", l.ranges.Events},
+	} {
+		if t.Range != "" {
+			tabs = append(tabs, t)
+		}
+	}
+	return tabs
+}
+
+// playerColumns are the column ranges of each tab that name players, each
+// from its first column up to but not including its last.
+var playerColumns = map[string][][2]int{
+	"games":       {{playerColumn, eliminationColumn}, {firstBloodColumn, kingmakerColumn + 1}},
+	"players":     {{0, math.MaxInt32}},
+	"aliases":     {{0, 2}},
+	"seeds":       {{0, 1}},
+	"adjustments": {{1, 2}},
+	"houses":      {{1, 2}},
+	"events":      {{4, math.MaxInt32}},
+}
+
+// renameSheetRows renames the player in the cells of the league's imported
+// tabs within the transaction, returning the number of rows changed.
+func renameSheetRows(ctx context.Context, tx *sql.Tx, league, from, to string) (int64, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT tab, row, cells FROM sheet_rows WHERE league = ?`, league)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rename player in sheet_rows: %w", err)
+	}
+	type sheetRow struct {
+		tab   string
+		row   int
+		cells string
+	}
+	var renamed []sheetRow
+	for rows.Next() {
+		var r sheetRow
+		if err := rows.Scan(&r.tab, &r.row, &r.cells); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to rename player in sheet_rows: %w", err)
+		}
+		var values []interface{}
+		if err := json.Unmarshal([]byte(r.cells), &values); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to decode %s row %d: %w", r.tab, r.row, err)
+		}
+		changed := false
+		for _, cols := range playerColumns[r.tab] {
+			for c := cols[0]; c < minInt(len(values), cols[1]); c++ {
+				cell, ok := values[c].(string)
+				if !ok {
+					continue
+				}
+				if name, ok := renameCell(strings.TrimSpace(cell), from, to); ok {
+					values[c] = name
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			continue
+		}
+		b, err := json.Marshal(values)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to encode %s row %d: %w", r.tab, r.row, err)
+		}
+		r.cells = string(b)
+		renamed = append(renamed, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to rename player in sheet_rows: %w", err)
+	}
+	for _, r := range renamed {
+		if _, err := tx.ExecContext(ctx, `UPDATE sheet_rows SET cells = ?, checksum = ? WHERE league = ? AND tab = ? AND row = ?`,
+			r.cells, rowChecksum(r.cells), league, r.tab, r.row); err != nil {
+			return 0, fmt.Errorf("failed to rename player in sheet_rows: %w", err)
+		}
+	}
+	return int64(len(renamed)), nil
+}
+
+// MigrateResult is the outcome of importing one of the league's tabs.
+type MigrateResult struct {
+	Tab      string
+	Rows     int    // the rows in the sheet, and in the database once verified.
+	Added    int    // the rows that weren't in the database yet.
+	Changed  int    // the rows whose cells changed since the last import.
+	Removed  int    // the rows no longer in the sheet.
+	Checksum string // the checksum of the tab's rows in the sheet.
+	Verified bool   // whether the rows read back from the database match.
+}
+
+// rowChecksum returns the checksum of a row's cells as they're stored.
+func rowChecksum(cells string) string {
+	sum := sha256.Sum256([]byte(cells))
+	return hex.EncodeToString(sum[:])
+}
+
+// tabChecksum returns the checksum of a tab from its row checksums in order.
+func tabChecksum(rows []string) string {
+	return rowChecksum(strings.Join(rows, "\n"))
+}
+
+// importTab syncs a tab's rows into the database, only writing the rows that
+// were added or changed and deleting the ones that are gone, so importing
+// the same sheet again changes nothing. The import is recorded at now.
+func (s *store) importTab(ctx context.Context, league string, tab sheetTab, values [][]interface{}, now time.Time) (MigrateResult, error) {
+	res := MigrateResult{Tab: tab.Name, Rows: len(values)}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return res, fmt.Errorf("failed to import %s tab: %w", tab.Name, err)
+	}
+	defer tx.Rollback()
+
+	existing := map[int]string{}
+	rows, err := tx.QueryContext(ctx, `SELECT row, checksum FROM sheet_rows WHERE league = ? AND tab = ?`, league, tab.Name)
+	if err != nil {
+		return res, fmt.Errorf("failed to import %s tab: %w", tab.Name, err)
+	}
+	for rows.Next() {
+		var row int
+		var sum string
+		if err := rows.Scan(&row, &sum); err != nil {
+			rows.Close()
+			return res, fmt.Errorf("failed to import %s tab: %w", tab.Name, err)
+		}
+		existing[row] = sum
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, fmt.Errorf("failed to import %s tab: %w", tab.Name, err)
+	}
+
+	sums := make([]string, len(values))
+	for i, row := range values {
+		if row == nil {
+			row = []interface{}{}
+		}
+		cells, err := json.Marshal(row)
+		if err != nil {
+			return res, fmt.Errorf("failed to encode %s row %d: %w", tab.Name, i+1, err)
+		}
+		sums[i] = rowChecksum(string(cells))
+		before, ok := existing[i+1]
+		delete(existing, i+1)
+		if ok && before == sums[i] {
+			continue
+		}
+		if ok {
+			res.Changed++
+		} else {
+			res.Added++
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO sheet_rows (league, tab, row, cells, checksum) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (league, tab, row) DO UPDATE SET cells = excluded.cells, checksum = excluded.checksum`,
+			league, tab.Name, i+1, string(cells), sums[i]); err != nil {
+			return res, fmt.Errorf("failed to import %s row %d: %w", tab.Name, i+1, err)
+		}
+	}
+	for row := range existing {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM sheet_rows WHERE league = ? AND tab = ? AND row = ?`, league, tab.Name, row); err != nil {
+			return res, fmt.Errorf("failed to import %s tab: %w", tab.Name, err)
+		}
+		res.Removed++
+	}
+
+	res.Checksum = tabChecksum(sums)
+	if _, err := tx.ExecContext(ctx, `INSERT INTO sheet_imports (league, tab, source, rows, checksum, imported_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (league, tab) DO UPDATE SET source = excluded.source, rows = excluded.rows, checksum = excluded.checksum, imported_at = excluded.imported_at`,
+		league, tab.Name, tab.Range, res.Rows, res.Checksum, now.UTC()); err != nil {
+		return res, fmt.Errorf("failed to import %s tab: %w", tab.Name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return res, fmt.Errorf("failed to import %s tab: %w", tab.Name, err)
+	}
+	return res, nil
+}
+
+// importedRows returns the rows of a tab imported into the database, in
+// order, along with their checksums as recomputed from the stored cells.
+func (s *store) importedRows(ctx context.Context, league, tab string) ([][]interface{}, []string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT cells FROM sheet_rows WHERE league = ? AND tab = ? ORDER BY row`, league, tab)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s tab: %w", tab, err)
+	}
+	defer rows.Close()
+
+	var values [][]interface{}
+	var sums []string
+	for rows.Next() {
+		var cells string
+		if err := rows.Scan(&cells); err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s tab: %w", tab, err)
+		}
+		var row []interface{}
+		if err := json.Unmarshal([]byte(cells), &row); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode %s row %d: %w", tab, len(values)+1, err)
+		}
+		values = append(values, row)
+		sums = append(sums, rowChecksum(cells))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s tab: %w", tab, err)
+	}
+	return values, sums, nil
+}
+
+// migrateSheet imports the league's game log and auxiliary tabs from Google
+// Sheets into the database and verifies each tab's row count and checksum
+// when read back. It returns the result for each tab and the games parsed
+// from the imported game log.
+func migrateSheet(ctx context.Context, l *league, st *store) ([]MigrateResult, int, error) {
+	tabs := l.tabs()
+	ranges := make([]string, len(tabs))
+	for i, tab := range tabs {
+		ranges[i] = tab.Range
+	}
+	values, err := sheets.FetchRanges(ctx, l.spreadsheetID, ranges, l.opts...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]MigrateResult, len(tabs))
+	for i, tab := range tabs {
+		results[i], err = st.importTab(ctx, l.spreadsheetID, tab, values[i], l.clock.Now())
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	games := 0
+	for i, tab := range tabs {
+		imported, sums, err := st.importedRows(ctx, l.spreadsheetID, tab.Name)
+		if err != nil {
+			return nil, 0, err
+		}
+		results[i].Verified = len(imported) == results[i].Rows && tabChecksum(sums) == results[i].Checksum
+		if tab.Name == "games" {
+			g, u, err := parseGameLog(imported)
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to parse the imported game log: %w", err)
+			}
+			games = len(g) + len(u)
+		}
+	}
+	return results, games, nil
+}
+
+// printMigrateReport writes what was imported for each tab and whether it
+// was verified, and reports whether every tab was.
+func printMigrateReport(w io.Writer, results []MigrateResult, games int) bool {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "tab\trows\tadded\tchanged\tremoved\tchecksum\tverified")
+	verified := true
+	for _, res := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\t%t\n", res.Tab, res.Rows, res.Added, res.Changed, res.Removed, res.Checksum[:12], res.Verified)
+		verified = verified && res.Verified
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "imported %d tabs with %d games\n", len(results), games)
+	return verified
+}
+
+// migrateCommand imports the league's sheet into a SQLite database. It can be
+// run again to sync the database with the sheet, and exits non-zero if a tab
+// read back from the database doesn't match the sheet.
+func migrateCommand(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	from := fs.String("from", "sheets", "where to migrate from, only sheets is supported")
+	to := fs.String("to", os.Getenv("SCOREBOARD_DATABASE"), "database to migrate to, e.g. sqlite://scoreboard.db")
+	sheetID := fs.String("sheet", spreadsheetID, "ID of the spreadsheet to migrate")
+	sheetRange := fs.String("range", readRange, "range of the game log tab")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *from != "sheets" {
+		fmt.Fprintf(os.Stderr, "migrate: can only migrate from sheets, not %q\n", *from)
+		return 2
+	}
+	if !strings.HasPrefix(*to, "sqlite://") {
+		fmt.Fprintln(os.Stderr, "migrate: --to must be a sqlite:// database")
+		return 2
+	}
+
+	opts, err := sheetsOptionFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %s\n", err)
+		return 1
+	}
+	st, err := openStore(*to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %s\n", err)
+		return 1
+	}
+	defer st.Close()
+
+	l := newLeague(*sheetID, *sheetRange, opts)
+	l.configureRangesFromEnv()
+	results, games, err := migrateSheet(context.Background(), l, st)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %s\n", err)
+		return 1
+	}
+	if !printMigrateReport(os.Stdout, results, games) {
+		fmt.Fprintln(os.Stderr, "migrate: the database doesn't match the sheet")
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// milestoneGames is how many games apart the games played milestones are.
+const milestoneGames = 50
+
+// Milestone is a notable moment in a player's career, like their first win.
+type Milestone struct {
+	Player string
+	Title  string // what the player reached, e.g. "First win" or "100th game".
+	Game   *Game  // the game the milestone was reached in.
+	Rating int    // the player's rating after the game.
+
+	order int // the game's index, for ordering milestones reached in the same game.
+}
+
+// leagueMilestones returns every player's milestones in the scored games,
+// oldest first: their first game, first win, first table zap, every 50th
+// game, and the game they reached their highest rating in.
+func leagueMilestones(games []*Game) []Milestone {
+	var milestones []Milestone
+	played := map[string]int{}
+	won := map[string]bool{}
+	zapped := map[string]bool{}
+	peaks := map[string]Milestone{}
+
+	for idx, g := range games {
+		for _, res := range g.Results {
+			add := func(title string) {
+				milestones = append(milestones, Milestone{Player: res.Player, Title: title, Game: g, Rating: res.After, order: idx})
+			}
+			played[res.Player]++
+			switch n := played[res.Player]; {
+			case n == 1:
+				add("First game")
+			case n%milestoneGames == 0:
+				add(ordinal(n) + " game")
+			}
+			if res.Place == 1 && !g.IsDraw() {
+				if !won[res.Player] {
+					won[res.Player] = true
+					add("First win")
+				}
+				if isMarked(g.TableZap) && !zapped[res.Player] {
+					zapped[res.Player] = true
+					add("First table zap")
+				}
+			}
+			if peak, ok := peaks[res.Player]; !ok || res.After > peak.Rating {
+				peaks[res.Player] = Milestone{Player: res.Player, Game: g, Rating: res.After, order: idx}
+			}
+		}
+	}
+	for _, peak := range peaks {
+		peak.Title = fmt.Sprintf("Highest rating, %d", peak.Rating)
+		milestones = append(milestones, peak)
+	}
+
+	sort.SliceStable(milestones, func(i, j int) bool {
+		if milestones[i].order != milestones[j].order {
+			return milestones[i].order < milestones[j].order
+		}
+		return milestones[i].Player < milestones[j].Player
+	})
+	return milestones
+}
+
+// playerMilestones returns the player's milestones, oldest first.
+func playerMilestones(milestones []Milestone, player string) []Milestone {
+	var mine []Milestone
+	for _, m := range milestones {
+		if m.Player == player {
+			mine = append(mine, m)
+		}
+	}
+	return mine
+}
+
+// ordinal formats n as an ordinal number, e.g. 1st, 22nd or 100th.
+func ordinal(n int) string {
+	suffix := "th"
+	switch n % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix = "th"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
+// timelineHandler returns the handler for /timeline, the league's history
+// told through its players' milestones, most recent first.
+func timelineHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		games := ds.Games
+		calculateScores(games, ds.Adjustments...)
+
+		milestones := leagueMilestones(games)
+		for i, j := 0, len(milestones)-1; i < j; i, j = i+1, j-1 {
+			milestones[i], milestones[j] = milestones[j], milestones[i]
+		}
+
+		t.ExecuteTemplate(w, "timeline.html.tmpl", TimelineView{
+			PageView:   newPageView(r, "Timeline", "The league's history through its players' milestones."),
+			Milestones: milestones,
+			Names:      ds.Names,
+		})
+	}
+}
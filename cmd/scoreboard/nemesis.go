@@ -0,0 +1,98 @@
+package main
+
+import "sort"
+
+// Opponent is a player's record against one opponent, over the games where
+// they finished on different sides of each other.
+type Opponent struct {
+	ID     string
+	Games  int
+	Wins   int // games the player finished above the opponent.
+	Losses int // games the opponent finished above the player.
+}
+
+// WinRate returns the share of their games the player finished above the
+// opponent.
+func (o Opponent) WinRate() float64 {
+	if o.Games == 0 {
+		return 0
+	}
+	return float64(o.Wins) / float64(o.Games)
+}
+
+// LossRate returns the share of their games the opponent finished above the
+// player.
+func (o Opponent) LossRate() float64 {
+	if o.Games == 0 {
+		return 0
+	}
+	return float64(o.Losses) / float64(o.Games)
+}
+
+// opponentRecords returns the player's record against each of their
+// opponents, sorted by ID. Draws and teammates in archenemy games don't count.
+func opponentRecords(games []*Game, player string) []Opponent {
+	records := map[string]*Opponent{}
+	record := func(id string) *Opponent {
+		if records[id] == nil {
+			records[id] = &Opponent{ID: id}
+		}
+		return records[id]
+	}
+
+	for _, g := range games {
+		if g.IsDraw() {
+			continue
+		}
+		idx := -1
+		for i, name := range g.Rankings {
+			if name == player {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			continue
+		}
+		for _, beaten := range g.Beat(idx) {
+			o := record(beaten)
+			o.Games++
+			o.Wins++
+		}
+		for i, name := range g.Rankings {
+			for _, beaten := range g.Beat(i) {
+				if beaten == player {
+					o := record(name)
+					o.Games++
+					o.Losses++
+				}
+			}
+		}
+	}
+
+	opponents := make([]Opponent, 0, len(records))
+	for _, o := range records {
+		opponents = append(opponents, *o)
+	}
+	sort.Slice(opponents, func(i, j int) bool { return opponents[i].ID < opponents[j].ID })
+	return opponents
+}
+
+// nemesisAndVictim returns the opponent the player most often finished below,
+// their nemesis, and the one they most often finished above, their victim.
+// Ties go to the higher rate. Either is nil if the player never lost or won
+// against anyone.
+func nemesisAndVictim(games []*Game, player string) (nemesis, victim *Opponent) {
+	opponents := opponentRecords(games, player)
+	for idx := range opponents {
+		o := &opponents[idx]
+		if o.Losses > 0 && (nemesis == nil || o.Losses > nemesis.Losses ||
+			o.Losses == nemesis.Losses && o.LossRate() > nemesis.LossRate()) {
+			nemesis = o
+		}
+		if o.Wins > 0 && (victim == nil || o.Wins > victim.Wins ||
+			o.Wins == victim.Wins && o.WinRate() > victim.WinRate()) {
+			victim = o
+		}
+	}
+	return nemesis, victim
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mailer sends plain text email through an SMTP relay.
+type mailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// newMailerFromEnv configures a mailer from the SCOREBOARD_SMTP_* variables.
+// It returns a nil mailer if no SMTP host is configured.
+func newMailerFromEnv() (*mailer, error) {
+	host := os.Getenv("SCOREBOARD_SMTP_HOST")
+	if host == "" {
+		return nil, nil
+	}
+	port := os.Getenv("SCOREBOARD_SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	from := os.Getenv("SCOREBOARD_SMTP_FROM")
+	if from == "" {
+		return nil, fmt.Errorf("SCOREBOARD_SMTP_FROM is required when SCOREBOARD_SMTP_HOST is set")
+	}
+
+	m := &mailer{
+		addr: net.JoinHostPort(host, port),
+		from: from,
+	}
+	if user := os.Getenv("SCOREBOARD_SMTP_USERNAME"); user != "" {
+		password, err := secretEnv("SCOREBOARD_SMTP_PASSWORD")
+		if err != nil {
+			return nil, err
+		}
+		m.auth = smtp.PlainAuth("", user, password, host)
+	}
+	return m, nil
+}
+
+// send delivers a plain text message to the given recipients.
+func (m *mailer) send(to []string, subject, body string) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from, to, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send email %q: %w", subject, err)
+	}
+	return nil
+}
+
+// notifier periodically rescores the game log and emails a weekly standings
+// digest to the mailing list, plus a personal note to each opted-in player
+// whose rating changed or who was passed on the leaderboard since the last
+// check. Rating changes and passes are also posted to the webhook. Emails and webhooks are delivered as background jobs.
+type notifier struct {
+	mailer       *mailer // nil when email isn't configured.
+	webhookURL   string  // "" when no webhook is configured.
+	league       *league
+	profilesPath string
+	interval     time.Duration
+	digestTo     []string
+	digestDay    time.Weekday
+	digestHour   int
+
+	nextDigest   time.Time
+	lastScores   map[string]int // scores as of the previous check.
+	digestScores map[string]int // scores as of the previous digest.
+}
+
+// newNotifierFromEnv configures a notifier from the environment. It returns
+// nil if neither email nor a webhook is configured.
+func newNotifierFromEnv(l *league) (*notifier, error) {
+	m, err := newMailerFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	webhookURL := os.Getenv("SCOREBOARD_WEBHOOK_URL")
+	if m == nil && webhookURL == "" {
+		return nil, nil
+	}
+
+	n := &notifier{
+		mailer:       m,
+		webhookURL:   webhookURL,
+		league:       l,
+		profilesPath: os.Getenv("SCOREBOARD_PROFILES"),
+		interval:     15 * time.Minute,
+		digestDay:    time.Monday,
+		digestHour:   9,
+	}
+
+	if v := os.Getenv("SCOREBOARD_NOTIFY_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCOREBOARD_NOTIFY_INTERVAL: %w", err)
+		}
+		n.interval = d
+	}
+	for _, addr := range strings.Split(os.Getenv("SCOREBOARD_DIGEST_TO"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			n.digestTo = append(n.digestTo, addr)
+		}
+	}
+	if v := os.Getenv("SCOREBOARD_DIGEST_DAY"); v != "" {
+		day, err := parseWeekday(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCOREBOARD_DIGEST_DAY: %w", err)
+		}
+		n.digestDay = day
+	}
+	if v := os.Getenv("SCOREBOARD_DIGEST_HOUR"); v != "" {
+		hour, err := strconv.Atoi(v)
+		if err != nil || hour < 0 || hour > 23 {
+			return nil, fmt.Errorf("invalid SCOREBOARD_DIGEST_HOUR: %q", v)
+		}
+		n.digestHour = hour
+	}
+	return n, nil
+}
+
+// run checks for changes every interval until the context is cancelled.
+// Each check runs as a refresh job, and the next one waits for it to finish.
+func (n *notifier) run(ctx context.Context) {
+	n.nextDigest = nextWeekly(n.league.clock.Now(), n.digestDay, n.digestHour)
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+
+	for {
+		job := backgroundJobs.enqueue("refresh", 1, func(ctx context.Context) error {
+			return n.check(ctx, n.league.clock.Now())
+		})
+		select {
+		case <-ctx.Done():
+			return
+		case <-job.done:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check rescores the game log, sends rating change notes, and sends the
+// digest if it's due.
+func (n *notifier) check(ctx context.Context, now time.Time) error {
+	ds, err := n.league.fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("notifier: error fetching game data: %w", err)
+	}
+	n.league.precomputePlayers(ds)
+	games := ds.Games
+	sort.Sort(ByID(games))
+	scores := calculateScores(games, ds.Adjustments...)
+
+	recap, _ := nightRecap(games, ds.Names)
+	if n.lastScores != nil {
+		n.notifyRatingChanges(n.lastScores, scores, ds, recap)
+		n.notifyOvertaken(n.lastScores, scores, ds)
+	} else {
+		// the first check only records a baseline to compare against
+		n.digestScores = scores
+	}
+	n.lastScores = scores
+
+	if n.mailer != nil && len(n.digestTo) > 0 && !now.Before(n.nextDigest) {
+		rankings := rankPlayers(games, scores, now)
+		ds.Names.apply(rankings)
+		n.email(n.digestTo, "Weekly standings", digestBody(rankings, n.digestScores, recap))
+		n.digestScores = scores
+		n.nextDigest = nextWeekly(now, n.digestDay, n.digestHour)
+	}
+	return nil
+}
+
+// email queues an email to be sent, retrying if the relay is unavailable.
+func (n *notifier) email(to []string, subject, body string) {
+	backgroundJobs.enqueue("email", 3, func(ctx context.Context) error {
+		return n.mailer.send(to, subject, body)
+	})
+}
+
+// RatingChange is a player's rating change posted to the webhook.
+type RatingChange struct {
+	Player string `json:"player"` // the player's ID.
+	Name   string `json:"name"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+	URL    string `json:"url,omitempty"` // the player's page, when SCOREBOARD_PUBLIC_URL is set.
+}
+
+// notifyRatingChanges emails each opted-in player whose rating moved, and
+// posts all the changes to the webhook with the latest league night's recap.
+func (n *notifier) notifyRatingChanges(before, after map[string]int, ds *Dataset, recap NightRecap) {
+	var changes []RatingChange
+	for id, now := range after {
+		prev, played := before[id]
+		if played && prev != now {
+			changes = append(changes, RatingChange{Player: id, Name: ds.Names.Of(id), Before: prev, After: now, URL: absoluteURL("/player/" + id)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Player < changes[j].Player })
+	if n.webhookURL != "" && len(changes) > 0 {
+		payload := map[string]interface{}{"event": "ratings.changed", "changes": changes, "recap": recap.Lines}
+		backgroundJobs.enqueue("webhook", 5, func(ctx context.Context) error {
+			return postWebhook(ctx, n.webhookURL, payload)
+		})
+	}
+	if n.mailer == nil {
+		return
+	}
+
+	profiles, err := loadProfiles(n.profilesPath, ds)
+	if err != nil {
+		log.Printf("notifier: %s", err)
+		return
+	}
+
+	for id, p := range profiles {
+		if !p.NotifyRatingChanges || p.Email == "" {
+			continue
+		}
+		now, ok := after[id]
+		if !ok || now == before[id] {
+			continue
+		}
+		prev, played := before[id]
+		if !played {
+			prev = now
+		}
+		body := fmt.Sprintf("Hi %s,\n\nYour rating changed from %d to %d (%s).\n", ds.Names.Of(id), prev, now, signed(now-prev))
+		if link := absoluteURL("/player/" + id); link != "" {
+			body += "\nSee your games: " + link + "\n"
+		}
+		n.email([]string{p.Email}, "Your rating changed", body)
+	}
+}
+
+// postWebhook posts the payload to the webhook as JSON. Responses other than
+// 2xx are errors, so the delivery is retried.
+func postWebhook(ctx context.Context, url string, payload interface{}) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("invalid webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "scoreboard/"+version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// digestBody formats the weekly standings, including each player's change
+// since the previous digest, after the latest league night's recap.
+func digestBody(rankings []Player, previous map[string]int, recap NightRecap) string {
+	var b strings.Builder
+	if len(recap.Lines) > 0 {
+		b.WriteString(strings.Join(recap.Lines, " ") + "\n\n")
+	}
+	b.WriteString("This week's standings:\n\n")
+	for idx, p := range rankings {
+		change := "new"
+		if prev, ok := previous[p.ID]; ok {
+			change = signed(p.Score - prev)
+		}
+		fmt.Fprintf(&b, "%d. %s %d (%s)\n", idx+1, p.Name, p.Score, change)
+	}
+	if link := absoluteURL("/"); link != "" {
+		b.WriteString("\nFull leaderboard: " + link + "\n")
+	}
+	return b.String()
+}
+
+// nextWeekly returns the next time after now that falls on the given weekday
+// and hour.
+func nextWeekly(now time.Time, day time.Weekday, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	next = next.AddDate(0, 0, (int(day)-int(now.Weekday())+7)%7)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+// parseWeekday parses a weekday name such as "monday" or "Mon".
+func parseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", s)
+}
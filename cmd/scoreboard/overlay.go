@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// overlayPlayers is the number of leaders in the overlay.
+	overlayPlayers = 5
+	// overlayTTL is how long an overlay response is reused, so overlays
+	// polling every few seconds don't use up the Sheets quota.
+	overlayTTL = 10 * time.Second
+)
+
+// OverlayPlayer is a player in the overlay, with their current streak:
+// positive for consecutive wins, negative for consecutive losses.
+type OverlayPlayer struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Score  int    `json:"score"`
+	Streak int    `json:"streak"`
+}
+
+// OverlayGame is the last game played, for the overlay.
+type OverlayGame struct {
+	ID      string          `json:"id"`
+	Date    string          `json:"date"`
+	Draw    bool            `json:"draw,omitempty"`
+	Results []OverlayResult `json:"results"`
+}
+
+// OverlayResult is a player's result in the overlay's last game.
+type OverlayResult struct {
+	Name  string `json:"name"`
+	Place int    `json:"place"`
+	Delta int    `json:"delta"`
+}
+
+// playerStreaks returns each player's current streak over the games, which
+// must be in play order: the number of games they've won in a row, or
+// negative the number they've lost in a row. A draw ends a streak.
+func playerStreaks(games []*Game) map[string]int {
+	streaks := map[string]int{}
+	for _, g := range games {
+		for _, name := range g.Rankings {
+			s := streaks[name]
+			switch {
+			case g.IsDraw():
+				s = 0
+			case g.Won(name):
+				if s < 0 {
+					s = 0
+				}
+				s++
+			default:
+				if s > 0 {
+					s = 0
+				}
+				s--
+			}
+			streaks[name] = s
+		}
+	}
+	return streaks
+}
+
+// overlayHandler returns the handler for /api/overlay, a small JSON payload
+// for streaming overlays in OBS or Godot: the top of the leaderboard, the
+// last game and the longest current win streaks. It can be fetched from any
+// origin, and responses are reused for overlayTTL.
+func overlayHandler(l *league) http.HandlerFunc {
+	var mu sync.Mutex
+	var cached []byte
+	var expires time.Time
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if cached == nil || l.clock.Now().After(expires) {
+			ds, err := loadDataset(w, r, l)
+			if err != nil {
+				return
+			}
+			var buf bytes.Buffer
+			if err := json.NewEncoder(&buf).Encode(overlay(ds, l.clock.Now())); err != nil {
+				log.Printf("failed to encode overlay: %s", err)
+				errorRes(w, r, err)
+				return
+			}
+			cached, expires = buf.Bytes(), l.clock.Now().Add(overlayTTL)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=5")
+		w.Write(cached)
+	}
+}
+
+// overlay builds the overlay payload from the league's data as of now.
+func overlay(ds *Dataset, now time.Time) map[string]interface{} {
+	games := ds.Games
+	rankings := rankPlayers(games, calculateScores(games, ds.Adjustments...), now)
+	ds.Names.apply(rankings)
+	streaks := playerStreaks(games)
+
+	top := []OverlayPlayer{}
+	for idx, p := range rankings {
+		if idx == overlayPlayers {
+			break
+		}
+		top = append(top, OverlayPlayer{ID: p.ID, Name: p.Name, Score: p.Score, Streak: streaks[p.ID]})
+	}
+
+	hot := []OverlayPlayer{}
+	for _, p := range rankings {
+		if streaks[p.ID] >= 2 {
+			hot = append(hot, OverlayPlayer{ID: p.ID, Name: p.Name, Score: p.Score, Streak: streaks[p.ID]})
+		}
+	}
+	sort.SliceStable(hot, func(i, j int) bool { return hot[i].Streak > hot[j].Streak })
+	if len(hot) > overlayPlayers {
+		hot = hot[:overlayPlayers]
+	}
+
+	res := map[string]interface{}{
+		"top":     top,
+		"streaks": hot,
+	}
+	var g *Game
+	for _, game := range games {
+		if g == nil || game.Timestamp.After(g.Timestamp) || (game.Timestamp.Equal(g.Timestamp) && lessGameID(g.ID, game.ID)) {
+			g = game
+		}
+	}
+	if g != nil {
+		last := OverlayGame{ID: g.ID, Date: shortDate(g.Timestamp), Draw: g.IsDraw(), Results: []OverlayResult{}}
+		for _, r := range g.Results {
+			last.Results = append(last.Results, OverlayResult{Name: ds.Names.Of(r.Player), Place: r.Place, Delta: r.Delta})
+		}
+		res["lastGame"] = last
+	}
+	return res
+}
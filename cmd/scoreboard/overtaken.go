@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Overtake is a player passing another on the leaderboard between two
+// checks.
+type Overtake struct {
+	Player    string `json:"player"` // the ID of the player who was passed.
+	Name      string `json:"name"`
+	DiscordID string `json:"discordId,omitempty"` // the passed player's Discord user ID, for bots to DM them.
+	By        string `json:"by"`                  // the ID of the player who passed them.
+	ByName    string `json:"byName"`
+	Rating    int    `json:"rating"`   // the passed player's rating now.
+	ByRating  int    `json:"byRating"` // the passing player's rating now.
+}
+
+// Margin returns how far ahead the passing player now is.
+func (o Overtake) Margin() int {
+	return o.ByRating - o.Rating
+}
+
+// overtakes returns every pair of players who swapped places between the
+// before and after scores, where the passed player was strictly ahead before
+// and strictly behind after. Players new to the leaderboard don't pass
+// anyone. They're ordered by the passed player, then by who passed them.
+func overtakes(before, after map[string]int) []Overtake {
+	var passed []Overtake
+	for player, now := range after {
+		prev, ok := before[player]
+		if !ok {
+			continue
+		}
+		for by, byNow := range after {
+			byPrev, ok := before[by]
+			if !ok || by == player {
+				continue
+			}
+			if prev > byPrev && byNow > now {
+				passed = append(passed, Overtake{Player: player, By: by, Rating: now, ByRating: byNow})
+			}
+		}
+	}
+	sort.Slice(passed, func(i, j int) bool {
+		if passed[i].Player != passed[j].Player {
+			return passed[i].Player < passed[j].Player
+		}
+		return passed[i].By < passed[j].By
+	})
+	return passed
+}
+
+// notifyOvertaken tells each opted-in player who was passed on the
+// leaderboard who passed them and by how much, by email and through the
+// webhook, which a Discord bot can turn into a DM.
+func (n *notifier) notifyOvertaken(before, after map[string]int, ds *Dataset) {
+	passed := overtakes(before, after)
+	if len(passed) == 0 {
+		return
+	}
+	profiles, err := loadProfiles(n.profilesPath, ds)
+	if err != nil {
+		log.Printf("notifier: %s", err)
+		return
+	}
+
+	byPlayer := map[string][]Overtake{}
+	var order []string
+	for _, o := range passed {
+		p, ok := profiles[o.Player]
+		if !ok || !p.NotifyOvertaken {
+			continue
+		}
+		o.Name, o.ByName, o.DiscordID = ds.Names.Of(o.Player), ds.Names.Of(o.By), p.DiscordID
+		if len(byPlayer[o.Player]) == 0 {
+			order = append(order, o.Player)
+		}
+		byPlayer[o.Player] = append(byPlayer[o.Player], o)
+	}
+
+	for _, player := range order {
+		list := byPlayer[player]
+		if n.webhookURL != "" {
+			payload := map[string]interface{}{"event": "ratings.overtaken", "overtakes": list}
+			backgroundJobs.enqueue("webhook", 5, func(ctx context.Context) error {
+				return postWebhook(ctx, n.webhookURL, payload)
+			})
+		}
+		if p := profiles[player]; n.mailer != nil && p.Email != "" {
+			n.email([]string{p.Email}, "You've been passed on the leaderboard", overtakenBody(list))
+		}
+	}
+}
+
+// overtakenBody formats the email telling a player who passed them.
+func overtakenBody(list []Overtake) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hi %s,\n\n", list[0].Name)
+	for _, o := range list {
+		fmt.Fprintf(&b, "%s passed you on the leaderboard and is now %d points ahead, %d to your %d.\n", o.ByName, o.Margin(), o.ByRating, o.Rating)
+	}
+	if link := absoluteURL("/player/" + list[0].Player); link != "" {
+		b.WriteString("\nSee your games: " + link + "\n")
+	}
+	return b.String()
+}
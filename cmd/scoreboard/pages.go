@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PlayerGame is an entry in a player's history: either a game paired with the
+// player's result in it, or a manual adjustment to their rating.
+type PlayerGame struct {
+	Game       *Game
+	Result     Result
+	Adjustment *Adjustment
+	Rivals     []string // the player's declared rivals who played in the game.
+}
+
+// gameHandler returns the handler for game permalinks at /game/{id}. The game
+// page shows each participant's placement and rating change, and
+// /game/{id}/explain walks through how the changes were calculated.
+func gameHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/game/")
+		explain := strings.HasSuffix(id, "/explain")
+		id = strings.TrimSuffix(id, "/explain")
+		if id == "" || strings.Contains(id, "/") {
+			notFoundRes(w, r)
+			return
+		}
+
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		games := ds.Games
+		calculateScores(games, ds.Adjustments...)
+
+		var game *Game
+		for _, g := range append(append([]*Game{}, games...), ds.Unscored...) {
+			if g.ID == id {
+				game = g
+				break
+			}
+		}
+		if game == nil || (explain && len(game.Teams) > 0) {
+			notFoundRes(w, r)
+			return
+		}
+
+		if explain {
+			t.ExecuteTemplate(w, "explain.html.tmpl", ExplainView{
+				PageView:    barePageView(r),
+				Game:        game,
+				Engine:      ratingEngine,
+				Explanation: explainGame(game),
+				Names:       ds.Names,
+			})
+			return
+		}
+
+		t.ExecuteTemplate(w, "game.html.tmpl", GameView{
+			PageView:  newPageView(r, "Game "+game.ID, gameSummary(game, ds.Names)),
+			Game:      game,
+			SheetRow:  l.rowURL(game),
+			Strength:  newPodScale(games).strength(game),
+			Rivalries: rivalriesInGame(game, leagueRivals(l, ds)),
+			Names:     ds.Names,
+			Comments:  pageComments(l, r, ds.Names, "game", game.ID),
+			Reports:   pageReports(l, r, game.ID),
+		})
+	}
+}
+
+// playerHandler returns the handler for player pages at /player/{name}. The
+// player page shows the player's current standing and their game history.
+func playerHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/player/")
+		if name == "" || strings.Contains(name, "/") {
+			notFoundRes(w, r)
+			return
+		}
+
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		if redirectToPlayerID(w, r, ds, "/player/", name) {
+			return
+		}
+		// serve the precomputed view if the data hasn't changed, otherwise
+		// queue precomputing every player's and compute this one now
+		version := datasetVersion(ds)
+		view, cached := l.players.get(version, name)
+		if !cached {
+			l.precomputePlayers(ds)
+			view, cached = l.players.get(version, name)
+		}
+		if !cached {
+			games := ds.Games
+			rankings := rankPlayers(games, calculateScores(games, ds.Adjustments...), l.clock.Now())
+			ds.Names.apply(rankings)
+			for idx := range rankings {
+				if rankings[idx].ID == name {
+					view = playerView(ds, games, rankings, idx, leagueRivals(l, ds)[name], leagueMilestones(games))
+					break
+				}
+			}
+		}
+		if view == nil {
+			notFoundRes(w, r)
+			return
+		}
+
+		page := *view
+		page.PageView = newPageView(r, view.Player.Name,
+			fmt.Sprintf("Rated %d, #%d of %d, with %d wins in %d games.", view.Player.Score, view.Rank, view.Players, view.Player.Wins, view.Player.Games))
+		page.Comments = pageComments(l, r, ds.Names, "player", name)
+		t.ExecuteTemplate(w, "player.html.tmpl", page)
+	}
+}
+
+// redirectToPlayerID redirects requests for a player page by one of the
+// player's names to the page for their ID, so links made before a player was
+// given an ID or renamed keep working. It reports whether it redirected.
+func redirectToPlayerID(w http.ResponseWriter, r *http.Request, ds *Dataset, prefix, name string) bool {
+	id := ds.playerID(name)
+	if id == name {
+		return false
+	}
+	http.Redirect(w, r, basePath(r)+prefix+url.PathEscape(id), http.StatusMovedPermanently)
+	return true
+}
+
+// playerHistory returns the player's results in scored games along with
+// their adjustments, most recent first.
+func playerHistory(games []*Game, adjustments []*Adjustment, name string) []PlayerGame {
+	var adjs []*Adjustment
+	for _, adj := range adjustments {
+		if adj.Player == name && !adj.Seed {
+			adjs = append(adjs, adj)
+		}
+	}
+	// adjustments after the last game come first
+	nextAdjustments := func(after time.Time) []PlayerGame {
+		var entries []PlayerGame
+		for len(adjs) > 0 && (after.IsZero() || adjs[len(adjs)-1].Timestamp.After(after)) {
+			entries = append(entries, PlayerGame{Adjustment: adjs[len(adjs)-1]})
+			adjs = adjs[:len(adjs)-1]
+		}
+		return entries
+	}
+
+	var history []PlayerGame
+	for i := len(games) - 1; i >= 0; i-- {
+		for _, res := range games[i].Results {
+			if res.Player == name {
+				if !games[i].Timestamp.IsZero() {
+					history = append(history, nextAdjustments(games[i].Timestamp)...)
+				}
+				history = append(history, PlayerGame{Game: games[i], Result: res})
+				break
+			}
+		}
+	}
+	return append(history, nextAdjustments(time.Time{})...)
+}
+
+// signed formats a rating delta with an explicit sign, e.g. +12 or -8.
+func signed(delta int) string {
+	return fmt.Sprintf("%+d", delta)
+}
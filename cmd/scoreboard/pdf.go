@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// pdfWidth and pdfHeight are the size of a US Letter page in points.
+	pdfWidth, pdfHeight = 612, 792
+	// pdfMargin is the page margin in points.
+	pdfMargin = 54
+	// pdfFontSize and pdfLeading are the size and line spacing of the text.
+	pdfFontSize, pdfLeading = 10, 14
+)
+
+// writePDF writes a PDF with a page for each page of lines, set in Courier
+// so fixed width columns line up. Lines that don't fit on a page run on to
+// the next. The built-in Courier font needs nothing embedded, which keeps the
+// writer small; characters outside Latin-1 are printed as "?".
+func writePDF(w io.Writer, pages [][]string) error {
+	perPage := (pdfHeight - 2*pdfMargin) / pdfLeading
+	var split [][]string
+	for _, lines := range pages {
+		for len(lines) > perPage {
+			split = append(split, lines[:perPage])
+			lines = lines[perPage:]
+		}
+		split = append(split, lines)
+	}
+	if len(split) == 0 {
+		split = [][]string{nil}
+	}
+
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// objects 1 to 3 are the catalog, the page tree and the font, then
+	// each page is followed by its content stream
+	buf.WriteString("%PDF-1.4\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(split))
+	for i := range split {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(split)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	for i, lines := range split {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfWidth, pdfHeight, 5+2*i))
+
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", pdfFontSize, pdfLeading, pdfMargin, pdfHeight-pdfMargin)
+		for _, line := range lines {
+			fmt.Fprintf(&content, "(%s) '\n", pdfString(line))
+		}
+		content.WriteString("ET")
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pdfString escapes a line for a PDF string literal in WinAnsiEncoding,
+// which matches Latin-1 for the characters it has.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
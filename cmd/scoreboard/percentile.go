@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// PercentilePoint is a player's standing in the league after a game, as the
+// share of the other rated players they were rated above. Unlike the rating
+// itself it isn't affected by rating inflation, so it compares across years.
+type PercentilePoint struct {
+	Game       *Game
+	Rating     int
+	Percentile float64 // 0 to 100, with ties counting half.
+}
+
+// percentileHistory returns the player's rating percentile among every player
+// rated so far after each scored game they played, in the order they were
+// played. Games before anyone else was rated are skipped.
+func percentileHistory(games []*Game, name string) []PercentilePoint {
+	ratings := map[string]int{}
+	var points []PercentilePoint
+	for _, game := range games {
+		played := false
+		for _, res := range game.Results {
+			ratings[res.Player] = res.After
+			if res.Player == name {
+				played = true
+			}
+		}
+		if !played || len(ratings) < 2 {
+			continue
+		}
+
+		rating := ratings[name]
+		below := 0.0
+		for player, r := range ratings {
+			switch {
+			case player == name:
+			case r < rating:
+				below++
+			case r == rating:
+				below += 0.5
+			}
+		}
+		points = append(points, PercentilePoint{
+			Game:       game,
+			Rating:     rating,
+			Percentile: below / float64(len(ratings)-1) * 100,
+		})
+	}
+	return points
+}
+
+// percentileChart renders the percentile history as an inline SVG line chart
+// with the top of the league at the top.
+func percentileChart(points []PercentilePoint) template.HTML {
+	if len(points) < 2 {
+		return ""
+	}
+
+	const width, height, pad = 600.0, 200.0, 20.0
+	step := (width - 2*pad) / float64(len(points)-1)
+	x := func(i int) float64 { return pad + float64(i)*step }
+	y := func(percentile float64) float64 {
+		return height - pad - percentile/100*(height-2*pad)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %.0f %.0f" width="%.0f" height="%.0f" role="img" aria-label="rating percentile by game">`, width, height, width, height)
+
+	for _, percentile := range []float64{0, 25, 50, 75, 100} {
+		fmt.Fprintf(&b, `<line x1="%.0f" y1="%.1f" x2="%.0f" y2="%.1f" stroke="#ddd"/>`, pad, y(percentile), width-pad, y(percentile))
+		fmt.Fprintf(&b, `<text x="2" y="%.1f" font-size="10">%.0f</text>`, y(percentile)+3, percentile)
+	}
+
+	// mark the first game of each year, where rating inflation tends to show
+	for i, p := range points {
+		if i > 0 && !p.Game.Timestamp.IsZero() && p.Game.Timestamp.Year() != points[i-1].Game.Timestamp.Year() {
+			fmt.Fprintf(&b, `<line x1="%.1f" y1="%.0f" x2="%.1f" y2="%.0f" stroke="#bbb" stroke-dasharray="2 2"/>`, x(i), pad, x(i), height-pad)
+			fmt.Fprintf(&b, `<text x="%.1f" y="%.0f" font-size="10">%d</text>`, x(i)+2, pad-6, p.Game.Timestamp.Year())
+		}
+	}
+
+	var line []string
+	for i, p := range points {
+		line = append(line, fmt.Sprintf("%.1f,%.1f", x(i), y(p.Percentile)))
+	}
+	fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="#000"/>`, strings.Join(line, " "))
+	for i, p := range points {
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="2.5"><title>game %s: rated %d, percentile %.0f</title></circle>`,
+			x(i), y(p.Percentile), template.HTMLEscapeString(p.Game.ID), p.Rating, p.Percentile)
+	}
+
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
@@ -0,0 +1,79 @@
+package main
+
+import "math"
+
+// performanceGames is the number of each player's most recent games their
+// performance rating is measured over. Configured with
+// SCOREBOARD_PERFORMANCE_GAMES.
+var performanceGames = 10
+
+// performanceRatings returns each player's performance rating over their most
+// recent games. Every game is treated as a set of head-to-head results against
+// each opponent in the pod, and the performance rating is the average rating
+// of those opponents going into the game adjusted by 400 points times the
+// player's net score, so beating strong pods counts for more than beating
+// weak ones. The games must already be scored and in the order they were
+// played.
+func performanceRatings(games []*Game) map[string]int {
+	type tally struct {
+		games     int
+		pairings  int
+		opponents int // the sum of opponent ratings over all pairings.
+		net       float64
+	}
+	tallies := map[string]*tally{}
+
+	for i := len(games) - 1; i >= 0; i-- {
+		game := games[i]
+		for _, res := range game.Results {
+			t, ok := tallies[res.Player]
+			if !ok {
+				t = &tally{}
+				tallies[res.Player] = t
+			}
+			if t.games >= performanceGames {
+				continue
+			}
+			t.games++
+
+			for _, opp := range game.Results {
+				if opp.Place == res.Place {
+					// the player themself, or a teammate in an archenemy game
+					continue
+				}
+				t.pairings++
+				t.opponents += opp.Before
+				switch {
+				case game.IsDraw():
+				case res.Place < opp.Place:
+					t.net++
+				default:
+					t.net--
+				}
+			}
+		}
+	}
+
+	ratings := map[string]int{}
+	for name, t := range tallies {
+		if t.pairings == 0 {
+			continue
+		}
+		avg := float64(t.opponents) / float64(t.pairings)
+		ratings[name] = int(math.Round(avg + 400*t.net/float64(t.pairings)))
+	}
+	return ratings
+}
+
+// ByPerformance implements the sort.Interface for sorting players by their
+// performance rating, falling back to the regular score order.
+type ByPerformance []Player
+
+func (g ByPerformance) Len() int      { return len(g) }
+func (g ByPerformance) Swap(i, j int) { g[i], g[j] = g[j], g[i] }
+func (g ByPerformance) Less(i, j int) bool {
+	if g[i].Performance != g[j].Performance {
+		return g[i].Performance > g[j].Performance
+	}
+	return ByScore(g).Less(i, j)
+}
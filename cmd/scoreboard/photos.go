@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxPhotoSize is the largest photo that can be attached to a game report.
+const maxPhotoSize = 10 << 20
+
+// photoTypes are the image types accepted for game reports, with the file
+// extension each is stored under.
+var photoTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// photoStore stores the photos attached to game reports under random keys.
+type photoStore interface {
+	put(ctx context.Context, key, contentType string, data []byte) error
+	get(ctx context.Context, key string) ([]byte, error)
+	delete(ctx context.Context, key string) error
+}
+
+// newPhotoKey returns a key read from random for a photo of the content type.
+func newPhotoKey(random io.Reader, contentType string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(random, b); err != nil {
+		return "", fmt.Errorf("failed to generate photo key: %w", err)
+	}
+	return hex.EncodeToString(b) + photoTypes[contentType], nil
+}
+
+// photoStoreFromEnv returns the photo store configured by the environment: an
+// S3-compatible bucket when SCOREBOARD_S3_BUCKET is set, or otherwise the
+// directory in SCOREBOARD_PHOTOS_DIR.
+func photoStoreFromEnv() (photoStore, error) {
+	if bucket := os.Getenv("SCOREBOARD_S3_BUCKET"); bucket != "" {
+		endpoint := os.Getenv("SCOREBOARD_S3_ENDPOINT")
+		if endpoint == "" {
+			return nil, fmt.Errorf("SCOREBOARD_S3_ENDPOINT is required when SCOREBOARD_S3_BUCKET is set")
+		}
+		secret, err := secretEnv("SCOREBOARD_S3_SECRET_ACCESS_KEY")
+		if err != nil {
+			return nil, err
+		}
+		s := &s3Photos{
+			endpoint:  strings.TrimSuffix(endpoint, "/"),
+			bucket:    bucket,
+			region:    os.Getenv("SCOREBOARD_S3_REGION"),
+			accessKey: os.Getenv("SCOREBOARD_S3_ACCESS_KEY_ID"),
+			secretKey: secret,
+			client:    &http.Client{Timeout: 30 * time.Second},
+		}
+		if s.region == "" {
+			s.region = "us-east-1"
+		}
+		if s.accessKey == "" || s.secretKey == "" {
+			return nil, fmt.Errorf("SCOREBOARD_S3_ACCESS_KEY_ID and SCOREBOARD_S3_SECRET_ACCESS_KEY are required when SCOREBOARD_S3_BUCKET is set")
+		}
+		return s, nil
+	}
+
+	dir := os.Getenv("SCOREBOARD_PHOTOS_DIR")
+	if dir == "" {
+		return nil, fmt.Errorf("SCOREBOARD_PHOTOS_DIR or SCOREBOARD_S3_BUCKET is required for game reports")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create photos directory: %w", err)
+	}
+	return diskPhotos(dir), nil
+}
+
+// diskPhotos stores photos as files in a directory.
+type diskPhotos string
+
+func (d diskPhotos) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) {
+		return "", fmt.Errorf("invalid photo key %q", key)
+	}
+	return filepath.Join(string(d), key), nil
+}
+
+func (d diskPhotos) put(ctx context.Context, key, contentType string, data []byte) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save photo: %w", err)
+	}
+	return nil
+}
+
+func (d diskPhotos) get(ctx context.Context, key string) ([]byte, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load photo: %w", err)
+	}
+	return b, nil
+}
+
+func (d diskPhotos) delete(ctx context.Context, key string) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete photo: %w", err)
+	}
+	return nil
+}
+
+// s3Photos stores photos in a bucket of an S3-compatible object store, like
+// AWS S3, Cloudflare R2 or MinIO, addressed path style and signed with AWS
+// signature version 4.
+type s3Photos struct {
+	endpoint  string // like "https://s3.us-east-1.amazonaws.com".
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+func (s *s3Photos) put(ctx context.Context, key, contentType string, data []byte) error {
+	res, err := s.do(ctx, http.MethodPut, key, contentType, data)
+	if err != nil {
+		return fmt.Errorf("failed to save photo: %w", err)
+	}
+	res.Body.Close()
+	return nil
+}
+
+func (s *s3Photos) get(ctx context.Context, key string) ([]byte, error) {
+	res, err := s.do(ctx, http.MethodGet, key, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load photo: %w", err)
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(res.Body, maxPhotoSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load photo: %w", err)
+	}
+	return b, nil
+}
+
+func (s *s3Photos) delete(ctx context.Context, key string) error {
+	res, err := s.do(ctx, http.MethodDelete, key, "", nil)
+	if errors.Is(err, errNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete photo: %w", err)
+	}
+	res.Body.Close()
+	return nil
+}
+
+// do sends a signed request for the object. It returns errNotFound for a
+// missing object and an error for any other unsuccessful status.
+func (s *s3Photos) do(ctx context.Context, method, key, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.endpoint+"/"+s.bucket+"/"+key, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, body, time.Now().UTC())
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, errNotFound
+	}
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		res.Body.Close()
+		return nil, fmt.Errorf("%s %s: %s: %s", method, key, res.Status, msg)
+	}
+	return res, nil
+}
+
+// sign adds the AWS signature version 4 headers to the request.
+func (s *s3Photos) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payload := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+
+	names := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	values := []string{req.URL.Host, payload, amzDate}
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		names = append([]string{"content-type"}, names...)
+		values = append([]string{ct}, values...)
+	}
+	var headers strings.Builder
+	for i, name := range names {
+		fmt.Fprintf(&headers, "%s:%s\n", name, values[i])
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{req.Method, req.URL.EscapedPath(), req.URL.RawQuery, headers.String(), signed, payload}, "\n")
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+
+	key := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{day, s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.accessKey, scope, signed, signature))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prediction is a member's pick for the winner of an upcoming game.
+type Prediction struct {
+	Member    string    // the ID of the logged in player who made the pick.
+	GameID    string    // the ID the game will be logged under.
+	Pick      string    // the ID of the player predicted to win.
+	CreatedAt time.Time // when the pick was last changed.
+}
+
+// PickemStanding is a member's record in the pick-em leaderboard.
+type PickemStanding struct {
+	Member  string
+	Picks   int // the member's picks for games that have been logged.
+	Correct int // the picks that named the winner.
+}
+
+// Accuracy returns the share of the member's picks that were correct.
+func (s PickemStanding) Accuracy() float64 {
+	if s.Picks == 0 {
+		return 0
+	}
+	return float64(s.Correct) / float64(s.Picks)
+}
+
+// savePrediction records the member's pick for a game in the league,
+// replacing any earlier pick they made for it.
+func (s *store) savePrediction(ctx context.Context, league string, p *Prediction) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO predictions (league, game_id, member, pick, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (league, game_id, member) DO UPDATE SET pick = excluded.pick, created_at = excluded.created_at`,
+		league, p.GameID, p.Member, p.Pick, p.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save prediction for game %s: %w", p.GameID, err)
+	}
+	return nil
+}
+
+// predictions returns every prediction made in the league.
+func (s *store) predictions(ctx context.Context, league string) ([]*Prediction, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT game_id, member, pick, created_at
+		FROM predictions WHERE league = ? ORDER BY created_at`, league)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load predictions: %w", err)
+	}
+	defer rows.Close()
+
+	var predictions []*Prediction
+	for rows.Next() {
+		p := &Prediction{}
+		if err := rows.Scan(&p.GameID, &p.Member, &p.Pick, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to load predictions: %w", err)
+		}
+		predictions = append(predictions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load predictions: %w", err)
+	}
+	return predictions, nil
+}
+
+// nextGameID returns the ID the next game will be logged under, one past the
+// highest numeric game ID.
+func nextGameID(games []*Game) string {
+	next := 1
+	for _, g := range games {
+		if n, err := strconv.Atoi(g.ID); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+	return strconv.Itoa(next)
+}
+
+// pickemStandings scores the predictions against the logged games. Picks for
+// draws, and picks made after the game's recorded start, don't count.
+func pickemStandings(games []*Game, predictions []*Prediction) []PickemStanding {
+	byID := map[string]*Game{}
+	for _, g := range games {
+		byID[g.ID] = g
+	}
+
+	members := map[string]*PickemStanding{}
+	for _, p := range predictions {
+		g, ok := byID[p.GameID]
+		if !ok || g.IsDraw() || len(g.Rankings) == 0 {
+			continue
+		}
+		if !g.Timestamp.IsZero() && p.CreatedAt.After(g.Timestamp) {
+			continue
+		}
+		s, ok := members[p.Member]
+		if !ok {
+			s = &PickemStanding{Member: p.Member}
+			members[p.Member] = s
+		}
+		s.Picks++
+		if g.Won(p.Pick) {
+			s.Correct++
+		}
+	}
+
+	standings := make([]PickemStanding, 0, len(members))
+	for _, s := range members {
+		standings = append(standings, *s)
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Correct != b.Correct {
+			return a.Correct > b.Correct
+		}
+		if a.Accuracy() != b.Accuracy() {
+			return a.Accuracy() > b.Accuracy()
+		}
+		return a.Member < b.Member
+	})
+	return standings
+}
+
+// openPicks returns the predictions for games that haven't been logged yet,
+// grouped by game ID.
+func openPicks(games []*Game, predictions []*Prediction) map[string][]*Prediction {
+	logged := map[string]bool{}
+	for _, g := range games {
+		logged[g.ID] = true
+	}
+	open := map[string][]*Prediction{}
+	for _, p := range predictions {
+		if !logged[p.GameID] {
+			open[p.GameID] = append(open[p.GameID], p)
+		}
+	}
+	return open
+}
+
+// pickemHandler returns the handler for the pick-em page at /picks, where
+// members predict the winners of upcoming games and see the predictions
+// leaderboard. It's only served when the league has pick-em enabled, and only
+// logged in players can pick.
+func pickemHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.picks == nil {
+			notFoundRes(w, r)
+			return
+		}
+
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		games := ds.Games
+		scores := calculateScores(games, ds.Adjustments...)
+		players := rankPlayers(games, scores, l.clock.Now())
+		ds.Names.apply(players)
+
+		user := l.currentPlayer(r)
+		view := PicksView{
+			PageView: barePageView(r),
+			Path:     r.URL.Path,
+			User:     user,
+			Players:  players,
+			GameID:   nextGameID(games),
+			Names:    ds.Names,
+		}
+
+		if r.Method == http.MethodPost {
+			if user == "" {
+				unauthorizedRes(w, r, "log in to pick")
+				return
+			}
+			p := &Prediction{
+				Member:    user,
+				GameID:    strings.TrimSpace(r.PostFormValue("game")),
+				Pick:      r.PostFormValue("pick"),
+				CreatedAt: l.clock.Now().UTC(),
+			}
+			err := validatePrediction(p, games, scores)
+			if err == nil {
+				err = l.picks.savePrediction(r.Context(), l.snapshotKey(), p)
+				if err != nil {
+					log.Printf("error saving prediction: %+v", err)
+				}
+			}
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				view.Errors = err.Error()
+			} else {
+				view.Saved = p
+			}
+		}
+
+		predictions, err := l.picks.predictions(r.Context(), l.snapshotKey())
+		if err != nil {
+			log.Printf("error loading predictions: %+v", err)
+			errorRes(w, r, err)
+			return
+		}
+		view.Standings = pickemStandings(games, predictions)
+		view.Open = openPicks(games, predictions)
+
+		t.ExecuteTemplate(w, "picks.html.tmpl", view)
+	}
+}
+
+// validatePrediction checks that the pick is for a ranked player in a game
+// that hasn't been logged yet.
+func validatePrediction(p *Prediction, games []*Game, scores map[string]int) error {
+	if _, err := strconv.Atoi(p.GameID); err != nil {
+		return fmt.Errorf("game must be a game number")
+	}
+	for _, g := range games {
+		if g.ID == p.GameID {
+			return fmt.Errorf("game %s has already been played", p.GameID)
+		}
+	}
+	if _, ok := scores[p.Pick]; !ok {
+		return fmt.Errorf("pick a ranked player")
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+)
+
+// placementWindow is the number of games over/under performance is averaged
+// over when highlighting stretches on the placement chart.
+const placementWindow = 5
+
+// PlacementPoint compares a player's expected and actual placement in a game.
+type PlacementPoint struct {
+	Game     *Game
+	Expected float64 // the placement expected from the pod's ratings going into the game.
+	Actual   int
+	Players  int
+}
+
+// expectedPlacement returns a player's expected placement in a pod given the
+// ratings going into the game: one plus the chance of each opponent finishing
+// above them, using the Elo expected score for each pairing.
+func expectedPlacement(rating int, opponents []int) float64 {
+	expected := 1.0
+	for _, r := range opponents {
+		expected += 1 / (1 + math.Pow(10, float64(rating-r)/400))
+	}
+	return expected
+}
+
+// fieldPodSize is the size of the pod a player's expected placement against
+// the field is measured in, the league's usual pod.
+const fieldPodSize = 4
+
+// fieldRatings returns the opponents' ratings in a pod against the field:
+// fieldPodSize-1 players rated at the league's average.
+func fieldRatings(scores map[string]int) []int {
+	if len(scores) == 0 {
+		return nil
+	}
+	total := 0
+	for _, score := range scores {
+		total += score
+	}
+	field := make([]int, fieldPodSize-1)
+	for i := range field {
+		field[i] = int(math.Round(float64(total) / float64(len(scores))))
+	}
+	return field
+}
+
+// placing formats an expected placement like an ordinal with one decimal,
+// e.g. "2.3rd", or "2nd" when it's a whole place.
+func placing(place float64) string {
+	tenths := int(math.Round(place * 10))
+	if tenths%10 == 0 {
+		return ordinal(tenths / 10)
+	}
+	return fmt.Sprintf("%d.%s", tenths/10, ordinal(tenths%10))
+}
+
+// placementHistory returns the player's expected and actual placement in
+// each scored game in the order they were played. Draws are skipped since
+// they have no placement.
+func placementHistory(games []*Game, name string) []PlacementPoint {
+	var points []PlacementPoint
+	for _, game := range games {
+		if game.IsDraw() {
+			continue
+		}
+		for idx, res := range game.Results {
+			if res.Player != name {
+				continue
+			}
+			var opponents []int
+			for oppIdx, opp := range game.Results {
+				if oppIdx != idx {
+					opponents = append(opponents, opp.Before)
+				}
+			}
+			points = append(points, PlacementPoint{
+				Game:     game,
+				Expected: expectedPlacement(res.Before, opponents),
+				Actual:   res.Place,
+				Players:  len(game.Results),
+			})
+			break
+		}
+	}
+	return points
+}
+
+// placementChart renders the placement history as an inline SVG chart with
+// the actual placement as a solid line and the expected placement as a dashed
+// line. Stretches where the player averaged at least half a place better or
+// worse than expected are shaded green or red.
+func placementChart(points []PlacementPoint) template.HTML {
+	if len(points) < 2 {
+		return ""
+	}
+
+	const width, height, pad = 600.0, 200.0, 20.0
+	maxPlace := 2
+	for _, p := range points {
+		if p.Players > maxPlace {
+			maxPlace = p.Players
+		}
+	}
+	step := (width - 2*pad) / float64(len(points)-1)
+	x := func(i int) float64 { return pad + float64(i)*step }
+	y := func(place float64) float64 {
+		return pad + (place-1)/float64(maxPlace-1)*(height-2*pad)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %.0f %.0f" width="%.0f" height="%.0f" role="img" aria-label="expected and actual placement by game">`, width, height, width, height)
+
+	// shade over and under performance stretches using a trailing average of
+	// expected minus actual placement
+	for i := range points {
+		start := i - placementWindow + 1
+		if start < 0 {
+			start = 0
+		}
+		diff := 0.0
+		for _, p := range points[start : i+1] {
+			diff += p.Expected - float64(p.Actual)
+		}
+		diff /= float64(i - start + 1)
+
+		fill := ""
+		switch {
+		case diff >= 0.5:
+			fill = "#2e7d32"
+		case diff <= -0.5:
+			fill = "#c62828"
+		}
+		if fill != "" {
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%.0f" width="%.1f" height="%.0f" fill="%s" fill-opacity="0.15"/>`,
+				x(i)-step/2, pad, step, height-2*pad, fill)
+		}
+	}
+
+	// placement gridlines, 1st at the top
+	for place := 1; place <= maxPlace; place++ {
+		fmt.Fprintf(&b, `<line x1="%.0f" y1="%.1f" x2="%.0f" y2="%.1f" stroke="#ddd"/>`, pad, y(float64(place)), width-pad, y(float64(place)))
+		fmt.Fprintf(&b, `<text x="2" y="%.1f" font-size="10">%d</text>`, y(float64(place))+3, place)
+	}
+
+	var expected, actual []string
+	for i, p := range points {
+		expected = append(expected, fmt.Sprintf("%.1f,%.1f", x(i), y(p.Expected)))
+		actual = append(actual, fmt.Sprintf("%.1f,%.1f", x(i), y(float64(p.Actual))))
+	}
+	fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="#888" stroke-dasharray="4 3"/>`, strings.Join(expected, " "))
+	fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="#000"/>`, strings.Join(actual, " "))
+	for i, p := range points {
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="2.5"><title>game %s: finished %d, expected %.1f</title></circle>`,
+			x(i), y(float64(p.Actual)), template.HTMLEscapeString(p.Game.ID), p.Actual, p.Expected)
+	}
+
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
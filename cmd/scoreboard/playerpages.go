@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// playerPages holds every player's page view computed for a dataset version,
+// so player pages don't rescore the league on each request. They're
+// precomputed in the background after a refresh, or when the first player
+// page of a new version is requested. What varies by request, the page view
+// and comments, is filled in when a page is served.
+type playerPages struct {
+	mu      sync.Mutex
+	version string
+	views   map[string]*PlayerView // by player ID.
+	pending string                 // the version being precomputed, so it's only queued once.
+}
+
+// get returns the player's view for the dataset version, and whether the
+// version has been computed, in which case a nil view means there's no such
+// player.
+func (c *playerPages) get(version, id string) (*PlayerView, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != version {
+		return nil, false
+	}
+	return c.views[id], true
+}
+
+// clear drops the computed views, so they're computed afresh even if the
+// dataset version hasn't changed, like after an adjustment.
+func (c *playerPages) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version, c.pending = "", ""
+	c.views = nil
+}
+
+// precomputePlayers queues computing every player's page view for the
+// dataset's version, unless they're computed or queued already.
+func (l *league) precomputePlayers(ds *Dataset) {
+	version := datasetVersion(ds)
+	c := &l.players
+	c.mu.Lock()
+	if c.version == version || c.pending == version {
+		c.mu.Unlock()
+		return
+	}
+	c.pending = version
+	c.mu.Unlock()
+
+	// the games and adjustments are scored as copies, taken now so a request
+	// scoring the same dataset meanwhile isn't disturbed
+	scored := ds.copy()
+	job := backgroundJobs.enqueue("precompute", 1, func(ctx context.Context) error {
+		views := playerViews(l, scored)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.pending != version {
+			// a newer version was queued since, or the views were cleared
+			return nil
+		}
+		c.version, c.views, c.pending = version, views, ""
+		return nil
+	})
+	if backgroundJobs.status(job) == jobFailed {
+		// the queue was full, so the next request queues it again
+		c.mu.Lock()
+		if c.pending == version {
+			c.pending = ""
+		}
+		c.mu.Unlock()
+	}
+}
+
+// playerViews scores the dataset's games and computes the page view of every
+// ranked player. It scores the dataset in place, so pass it a copy.
+func playerViews(l *league, ds *Dataset) map[string]*PlayerView {
+	games := ds.Games
+	rankings := rankPlayers(games, calculateScores(games, ds.Adjustments...), l.clock.Now())
+	ds.Names.apply(rankings)
+	rivals := leagueRivals(l, ds)
+	milestones := leagueMilestones(games)
+
+	views := make(map[string]*PlayerView, len(rankings))
+	for idx := range rankings {
+		views[rankings[idx].ID] = playerView(ds, games, rankings, idx, rivals[rankings[idx].ID], milestones)
+	}
+	return views
+}
+
+// playerView computes the page view of the player ranked at idx, from the
+// scored games, apart from what varies by request.
+func playerView(ds *Dataset, games []*Game, rankings []Player, idx int, rivals map[string]bool, milestones []Milestone) *PlayerView {
+	player := &rankings[idx]
+	name := player.ID
+	history := playerHistory(games, ds.Adjustments, name)
+	rivalsInHistory(history, rivals)
+	nemesis, victim := nemesisAndVictim(games, name)
+
+	return &PlayerView{
+		Player:     player,
+		Rank:       idx + 1,
+		Players:    len(rankings),
+		History:    history,
+		Rivals:     sortedNames(rivals),
+		Nemesis:    nemesis,
+		Victim:     victim,
+		Names:      ds.Names,
+		Milestones: playerMilestones(milestones, name),
+		WAR:        winsAboveReplacement(games, name),
+
+		PlacementChart:  placementChart(placementHistory(games, name)),
+		PercentileChart: percentileChart(percentileHistory(games, name)),
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// PlayerRecord is a player on the league's roster. Games are scored by the
+// player's ID rather than the name written in the game log, so renaming a
+// player only changes the name shown and keeps their rating history.
+type PlayerRecord struct {
+	ID    string
+	Name  string   // the name shown on the scoreboard.
+	Names []string // other names the player appears under in the game log.
+}
+
+// playerNames maps player IDs to the names shown for them.
+type playerNames map[string]string
+
+// Of returns the name shown for the player ID, which is the ID itself for
+// players that aren't on the roster.
+func (n playerNames) Of(id string) string {
+	if name, ok := n[id]; ok {
+		return name
+	}
+	return id
+}
+
+// apply replaces the IDs the rankings were built with by the players' names.
+func (n playerNames) apply(rankings []Player) {
+	for idx := range rankings {
+		rankings[idx].Name = n.Of(rankings[idx].ID)
+	}
+}
+
+// parsePlayerRows parses the players tab. When the first label is "ID" each
+// row holds a player's ID, their name, and any other names they appear under
+// in the game log. Otherwise each row holds just a name, and the player's ID
+// is its slug. The first row holds the labels.
+func parsePlayerRows(values [][]interface{}) []PlayerRecord {
+	withIDs := len(values) > 0 && len(values[0]) > 0 &&
+		strings.EqualFold(strings.TrimSpace(fmt.Sprintf("%s", values[0][0])), "id")
+
+	var players []PlayerRecord
+	slugs := map[string]bool{}
+	for idx, row := range values {
+		if idx == 0 || len(row) == 0 {
+			continue
+		}
+		var cells []string
+		for _, cell := range row {
+			cells = append(cells, strings.TrimSpace(fmt.Sprintf("%s", cell)))
+		}
+		if cells[0] == "" {
+			continue
+		}
+
+		p := PlayerRecord{ID: cells[0], Name: cells[0]}
+		if withIDs {
+			if len(cells) > 1 && cells[1] != "" {
+				p.Name = cells[1]
+			}
+			for _, name := range cells[minInt(len(cells), 2):] {
+				if name != "" {
+					p.Names = append(p.Names, name)
+				}
+			}
+		} else {
+			slug := slugify(p.Name)
+			p.ID = slug
+			for n := 2; slugs[p.ID]; n++ {
+				p.ID = fmt.Sprintf("%s-%d", slug, n)
+			}
+			slugs[p.ID] = true
+		}
+		players = append(players, p)
+	}
+	return players
+}
+
+// resolvePlayerIDs replaces the player names in the games and adjustments
+// with the players' IDs. Names are matched case-insensitively against the
+// roster's IDs, names and other names, after mapping aliases to canonical
+// names. Names that don't match a player on the roster are given a slug of the
+// name as their ID, in the order they first appear in the game log so the
+// slugs don't change as games are added.
+func (ds *Dataset) resolvePlayerIDs() {
+	ds.ids = map[string]string{}
+	ds.Names = playerNames{}
+	for _, p := range ds.Players {
+		ds.Names[p.ID] = p.Name
+		for _, name := range append([]string{p.ID, p.Name}, p.Names...) {
+			ds.ids[strings.ToLower(name)] = p.ID
+		}
+	}
+	// links by the slugs of a player's names lead to them too, like those
+	// made before they were added to the roster
+	for _, p := range ds.Players {
+		for _, name := range append([]string{p.ID, p.Name}, p.Names...) {
+			if _, ok := ds.ids[slugify(name)]; !ok {
+				ds.ids[slugify(name)] = p.ID
+			}
+		}
+	}
+
+	logged := append(append([]*Game{}, ds.Games...), ds.Unscored...)
+	sort.SliceStable(logged, func(i, j int) bool { return logged[i].Row < logged[j].Row })
+	for _, g := range logged {
+		for _, name := range g.Rankings {
+			ds.assignID(name)
+		}
+	}
+
+	for _, g := range ds.Unscored {
+		for idx, name := range g.Rankings {
+			g.Rankings[idx] = ds.assignID(name)
+		}
+		for _, team := range g.Teams {
+			for idx, name := range team {
+				team[idx] = ds.assignID(name)
+			}
+		}
+	}
+	for _, g := range ds.Games {
+		for idx, name := range g.Rankings {
+			g.Rankings[idx] = ds.assignID(name)
+		}
+		if g.IsArchenemy() {
+			g.Archenemy = ds.assignID(g.Archenemy)
+		}
+		for _, name := range []*string{&g.FirstBlood, &g.FirstEliminated, &g.Kingmaker} {
+			if *name != "" {
+				*name = ds.assignID(*name)
+			}
+		}
+		if len(g.Eliminations) > 0 {
+			eliminations := map[string]time.Time{}
+			for name, at := range g.Eliminations {
+				eliminations[ds.assignID(name)] = at
+			}
+			g.Eliminations = eliminations
+		}
+		if len(g.Decks) > 0 {
+			decks := map[string][]string{}
+			for name, tags := range g.Decks {
+				decks[ds.assignID(name)] = tags
+			}
+			g.Decks = decks
+		}
+	}
+	for _, adj := range ds.Adjustments {
+		adj.Player = ds.assignID(adj.Player)
+	}
+	for _, h := range ds.Houses {
+		for idx, name := range h.Members {
+			h.Members[idx] = ds.assignID(name)
+		}
+	}
+	for _, e := range ds.Events {
+		for _, pod := range e.Pods {
+			for idx, name := range pod.Seats {
+				pod.Seats[idx] = ds.assignID(name)
+			}
+			for idx := range pod.Matches {
+				m := &pod.Matches[idx]
+				m.Player = ds.assignID(m.Player)
+				if !m.Bye() {
+					m.Opponent = ds.assignID(m.Opponent)
+				}
+			}
+		}
+	}
+}
+
+// dropDuplicatePlayers leaves out the games that list a player twice once
+// names are resolved to IDs, like a player and one of their aliases, which
+// would otherwise count the player's rating change twice. Each is logged with
+// its row so it can be fixed in the sheet.
+func (ds *Dataset) dropDuplicatePlayers() {
+	games := ds.Games[:0]
+	for _, g := range ds.Games {
+		if id := duplicatePlayer(g.Rankings); id != "" {
+			log.Printf("game %s on row %d lists %s twice, leaving it unscored", g.ID, g.Row, id)
+			continue
+		}
+		games = append(games, g)
+	}
+	ds.Games = games
+}
+
+// duplicatePlayer returns the first name listed twice in the rankings, or ""
+// if each is listed once.
+func duplicatePlayer(rankings []string) string {
+	seen := map[string]bool{}
+	for _, name := range rankings {
+		if seen[name] {
+			return name
+		}
+		seen[name] = true
+	}
+	return ""
+}
+
+// assignID returns the ID of the player the name refers to, giving a player
+// who isn't on the roster yet the slug of their name, numbered if another
+// player has it.
+func (ds *Dataset) assignID(name string) string {
+	if canonical, ok := ds.Aliases[strings.ToLower(name)]; ok {
+		name = canonical
+	}
+	if id, ok := ds.ids[strings.ToLower(name)]; ok {
+		return id
+	}
+	slug := slugify(name)
+	id := slug
+	for n := 2; ds.taken(id); n++ {
+		id = fmt.Sprintf("%s-%d", slug, n)
+	}
+	ds.ids[strings.ToLower(name)] = id
+	ds.Names[id] = name
+	return id
+}
+
+// taken reports whether the ID is a player's, or a slug leading to one.
+func (ds *Dataset) taken(id string) bool {
+	_, ok := ds.ids[id]
+	return ok || ds.Names[id] != ""
+}
+
+// playerID returns the ID of the player the name in the game log, or their
+// slug, refers to. Names nobody played under are slugged the way a new
+// player's would be.
+func (ds *Dataset) playerID(name string) string {
+	if canonical, ok := ds.Aliases[strings.ToLower(name)]; ok {
+		name = canonical
+	}
+	if _, ok := ds.Names[name]; ok {
+		return name
+	}
+	if id, ok := ds.ids[strings.ToLower(name)]; ok {
+		return id
+	}
+	return slugify(name)
+}
+
+// accents folds the accented Latin letters slugs are written without.
+var accents = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+	"ç", "c", "č", "c", "ć", "c", "ð", "d", "đ", "d",
+	"è", "e", "é", "e", "ê", "e", "ë", "e", "ě", "e", "ę", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ł", "l",
+	"ñ", "n", "ń", "n", "ň", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+	"ř", "r", "š", "s", "ś", "s", "ß", "ss", "þ", "th",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ů", "u",
+	"ý", "y", "ÿ", "y", "ž", "z", "ź", "z", "ż", "z",
+)
+
+// slugify returns the URL-safe slug of a name: lowercase, with accents
+// folded and anything but letters and digits between words replaced by a
+// hyphen, so "Zoë O'Neil" is zoe-o-neil. A name without any letters or
+// digits is slugged player.
+func slugify(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range accents.Replace(strings.ToLower(name)) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			hyphen = b.Len() > 0
+			continue
+		}
+		if hyphen {
+			b.WriteByte('-')
+			hyphen = false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "player"
+	}
+	return b.String()
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// handicapLife is the starting life suggested per place a player is expected
+// to finish below the middle of their pod, or taken away above it.
+const handicapLife = 5
+
+// PodSeat is a player's seat in a suggested pod.
+type PodSeat struct {
+	Player   string // the player's ID.
+	Name     string
+	Rating   int
+	Expected float64 // the placement expected from the pod's ratings.
+	Handicap int     // the starting life to add, or take away if negative.
+}
+
+// SuggestedPod is a pod suggested for a league night.
+type SuggestedPod struct {
+	Tier  int // the 1-indexed rating tier the pod was drawn from.
+	Seats []PodSeat
+}
+
+// parseAttendees parses the players attending, one per line or comma
+// separated, leaving out repeats.
+func parseAttendees(s string) []string {
+	var players []string
+	seen := map[string]bool{}
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		players = append(players, name)
+	}
+	return players
+}
+
+// parseTierBoundaries parses the comma separated ratings tiers start at, like
+// 1600,1450, into descending order.
+func parseTierBoundaries(s string) ([]int, error) {
+	var boundaries []int
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tier boundary %q, it must be a rating like 1600", field)
+		}
+		boundaries = append(boundaries, n)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(boundaries)))
+	return boundaries, nil
+}
+
+// ratingTiers splits the players, sorted highest rated first, into tiers at
+// the boundaries, leaving out empty tiers.
+func ratingTiers(sorted []string, ratings map[string]int, boundaries []int) [][]string {
+	var tiers [][]string
+	tier := []string{}
+	b := 0
+	for _, id := range sorted {
+		for b < len(boundaries) && ratings[id] < boundaries[b] {
+			if len(tier) > 0 {
+				tiers = append(tiers, tier)
+				tier = []string{}
+			}
+			b++
+		}
+		tier = append(tier, id)
+	}
+	if len(tier) > 0 {
+		tiers = append(tiers, tier)
+	}
+	return tiers
+}
+
+// allocatePods divides the pods between the tiers by their number of players,
+// giving each tier at least one.
+func allocatePods(tiers [][]string, pods int) []int {
+	alloc := make([]int, len(tiers))
+	for i := range alloc {
+		alloc[i] = 1
+	}
+	for left := pods - len(tiers); left > 0; left-- {
+		most := 0
+		for i := range tiers {
+			if len(tiers[i])*alloc[most] > len(tiers[most])*alloc[i] {
+				most = i
+			}
+		}
+		alloc[most]++
+	}
+	return alloc
+}
+
+// suggestPods seats the players in pods by rating. The players are split into
+// tiers at the boundaries, so the top tier plays each other, and each tier
+// into pods: the number asked for shared between the tiers, or else pods of
+// about podSize. Tiered pods take the tier's players in rating order, while
+// mixed pods deal them out in snake order for an even spread, where the
+// handicaps even out the rest.
+func suggestPods(players []string, ratings map[string]int, pods int, boundaries []int, mixed bool) ([]SuggestedPod, error) {
+	if len(players) < 2 {
+		return nil, fmt.Errorf("pods need at least 2 players")
+	}
+	sorted := append([]string(nil), players...)
+	sort.SliceStable(sorted, func(i, j int) bool { return ratings[sorted[i]] > ratings[sorted[j]] })
+
+	tiers := ratingTiers(sorted, ratings, boundaries)
+	var alloc []int
+	if pods > 0 {
+		if pods < len(tiers) {
+			return nil, fmt.Errorf("%d pods can't seat %d tiers", pods, len(tiers))
+		}
+		alloc = allocatePods(tiers, pods)
+	} else {
+		for _, tier := range tiers {
+			alloc = append(alloc, podCount(len(tier)))
+		}
+	}
+
+	var suggested []SuggestedPod
+	for i, tier := range tiers {
+		if len(tier) < 2*alloc[i] {
+			return nil, fmt.Errorf("tier %d has %d %s, too few for %d %s of at least 2", i+1, len(tier), plural(len(tier), "player", "players"), alloc[i], plural(alloc[i], "pod", "pods"))
+		}
+		var split [][]string
+		if mixed {
+			split = snakePods(tier, alloc[i])
+		} else {
+			// the earlier pods take the players left over
+			for n, start := alloc[i], 0; n > 0; n-- {
+				size := (len(tier) - start + n - 1) / n
+				split = append(split, tier[start:start+size])
+				start += size
+			}
+		}
+		for _, pod := range split {
+			suggested = append(suggested, SuggestedPod{Tier: i + 1, Seats: podSeats(pod, ratings)})
+		}
+	}
+	return suggested, nil
+}
+
+// podSeats seats the pod's players with their expected placement against the
+// rest of the pod, and the handicap that evens it out with the middle of the
+// pod.
+func podSeats(pod []string, ratings map[string]int) []PodSeat {
+	middle := float64(len(pod)+1) / 2
+	seats := make([]PodSeat, len(pod))
+	for i, id := range pod {
+		var opponents []int
+		for j, opp := range pod {
+			if j != i {
+				opponents = append(opponents, ratings[opp])
+			}
+		}
+		expected := expectedPlacement(ratings[id], opponents)
+		seats[i] = PodSeat{
+			Player:   id,
+			Rating:   ratings[id],
+			Expected: expected,
+			Handicap: int(math.Round((expected - middle) * handicapLife)),
+		}
+	}
+	return seats
+}
+
+// planPods suggests pods for the attendees by their current ratings, with
+// players new to the league at the replacement rating.
+func planPods(ds *Dataset, attendees []string, q url.Values) ([]SuggestedPod, error) {
+	pods := 0
+	if v := q.Get("pods"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid pods %q, it must be a whole number", v)
+		}
+		pods = n
+	}
+	boundaries, err := parseTierBoundaries(q.Get("tiers"))
+	if err != nil {
+		return nil, err
+	}
+
+	scores := calculateScores(ds.Games, ds.Adjustments...)
+	var ids []string
+	ratings := map[string]int{}
+	for _, name := range attendees {
+		id := ds.playerID(name)
+		if _, ok := ratings[id]; ok {
+			// another of the player's names
+			continue
+		}
+		ids = append(ids, id)
+		ratings[id] = replacementRating
+		if score, ok := scores[id]; ok {
+			ratings[id] = score
+		}
+	}
+	suggested, err := suggestPods(ids, ratings, pods, boundaries, q.Get("mode") == "mixed")
+	for _, pod := range suggested {
+		for i := range pod.Seats {
+			pod.Seats[i].Name = ds.Names.Of(pod.Seats[i].Player)
+		}
+	}
+	return suggested, err
+}
+
+// podsHandler returns the handler for the pod planner at /pods, which seats
+// tonight's players in pods by rating. It takes players, one per line or
+// comma separated, pods for the number of pods, tiers for the comma separated
+// ratings tiers start at, and mode, tiered or mixed.
+func podsHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		q := r.URL.Query()
+		view := PodsView{
+			PageView: newPageView(r, "Pods", "Seat tonight's players in pods by rating."),
+			Players:  q.Get("players"),
+			Pods:     q.Get("pods"),
+			Tiers:    q.Get("tiers"),
+			Mixed:    q.Get("mode") == "mixed",
+		}
+
+		if attendees := parseAttendees(q.Get("players")); len(attendees) > 0 {
+			suggested, err := planPods(ds, attendees, q)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				view.Errors = err.Error()
+			}
+			view.Suggested = suggested
+		}
+		t.ExecuteTemplate(w, "pods.html.tmpl", view)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import "sort"
+
+// PodStrength is how strong a game's pod was next to the league's other
+// games, by the average of the players' ratings going into it.
+type PodStrength struct {
+	Label      string  `json:"label"`      // like "high-stakes pod" or "casual pod".
+	Percentile float64 `json:"percentile"` // 0 to 100, the share of the other scored games with a weaker pod, ties counting half.
+}
+
+// podStrengthLabels label a pod by the lowest percentile each applies from,
+// strongest first.
+var podStrengthLabels = []struct {
+	min   float64
+	label string
+}{
+	{90, "high-stakes pod"},
+	{60, "strong pod"},
+	{25, "typical pod"},
+	{0, "casual pod"},
+}
+
+// podScale is the rank averages of the league's scored games in increasing
+// order, which each game's pod is compared against.
+type podScale []int
+
+// newPodScale returns the scale of the games' pods. The games must have been
+// scored.
+func newPodScale(games []*Game) podScale {
+	var s podScale
+	for _, g := range games {
+		if len(g.Results) > 0 && g.RankAverage > 0 {
+			s = append(s, g.RankAverage)
+		}
+	}
+	sort.Ints(s)
+	return s
+}
+
+// strength returns the strength of the game's pod, or nil if the game wasn't
+// scored with a rank average, like in the points engine, or there are no
+// other games to compare it to.
+func (s podScale) strength(g *Game) *PodStrength {
+	if len(g.Results) == 0 || g.RankAverage == 0 || len(s) < 2 {
+		return nil
+	}
+	below := sort.SearchInts(s, g.RankAverage)
+	// the game is one of the pods with its rank average
+	ties := sort.SearchInts(s, g.RankAverage+1) - below - 1
+	pct := (float64(below) + float64(ties)/2) / float64(len(s)-1) * 100
+	for _, l := range podStrengthLabels {
+		if pct >= l.min {
+			return &PodStrength{Label: l.label, Percentile: pct}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+const (
+	// preferencesCookie holds a visitor's preferences, so they work without
+	// logging in.
+	preferencesCookie = "scoreboard_prefs"
+	// preferencesLength is how long preferences are kept since last saved.
+	preferencesLength = 365 * 24 * time.Hour
+)
+
+// Preferences are how a visitor likes the leaderboard to open.
+type Preferences struct {
+	Season   string // the season the leaderboard opens on, "" for all time.
+	PodSize  int    // the pod size the leaderboard is rated over, 0 for every pod.
+	Dark     bool
+	Favorite string // the ID of the player highlighted on the leaderboard.
+}
+
+// encode returns the preferences as a cookie value.
+func (p Preferences) encode() string {
+	v := url.Values{}
+	if p.Season != "" {
+		v.Set("season", p.Season)
+	}
+	if p.PodSize != 0 {
+		v.Set("podSize", strconv.Itoa(p.PodSize))
+	}
+	if p.Dark {
+		v.Set("dark", "true")
+	}
+	if p.Favorite != "" {
+		v.Set("favorite", p.Favorite)
+	}
+	return v.Encode()
+}
+
+// parsePreferences parses preferences from a cookie value or form. Values
+// that don't make sense are left at their defaults rather than rejected, so
+// an old cookie can't break the board.
+func parsePreferences(v url.Values) Preferences {
+	p := Preferences{
+		Season:   strings.TrimSpace(v.Get("season")),
+		Dark:     v.Get("dark") == "true",
+		Favorite: strings.TrimSpace(v.Get("favorite")),
+	}
+	if n, err := strconv.Atoi(v.Get("podSize")); err == nil && n >= 2 && n <= maxPlayers {
+		p.PodSize = n
+	}
+	return p
+}
+
+// requestPreferences returns the preferences saved by the request's visitor,
+// or the defaults if they haven't saved any.
+func requestPreferences(r *http.Request) Preferences {
+	c, err := r.Cookie(preferencesCookie)
+	if err != nil {
+		return Preferences{}
+	}
+	v, err := url.ParseQuery(c.Value)
+	if err != nil {
+		return Preferences{}
+	}
+	return parsePreferences(v)
+}
+
+// gamesWithPodSize returns copies of the games played by pods of the size, so
+// they can be scored without overwriting the league's results.
+func gamesWithPodSize(games []*Game, size int) []*Game {
+	var sized []*Game
+	for _, g := range games {
+		if len(g.Rankings) == size {
+			sized = append(sized, g)
+		}
+	}
+	return copyGames(sized)
+}
+
+// preferencesHandler returns the handler for /preferences, where visitors
+// pick how the leaderboard opens for them. Saving an empty form clears them.
+func preferencesHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			if err := r.ParseForm(); err != nil {
+				badRequestRes(w, r, err.Error())
+				return
+			}
+			cookie := &http.Cookie{
+				Name:     preferencesCookie,
+				Path:     basePath(r) + "/",
+				HttpOnly: true,
+				Secure:   web.RequestScheme(r) == "https",
+				SameSite: http.SameSiteLaxMode,
+			}
+			if p := parsePreferences(r.PostForm); p == (Preferences{}) {
+				cookie.MaxAge = -1
+			} else {
+				cookie.Value = p.encode()
+				cookie.Expires = l.clock.Now().Add(preferencesLength)
+			}
+			http.SetCookie(w, cookie)
+			http.Redirect(w, r, basePath(r)+"/", http.StatusSeeOther)
+			return
+		}
+
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		seen := map[string]bool{}
+		var players []Player
+		for _, g := range ds.Games {
+			for _, id := range g.Rankings {
+				if !seen[id] {
+					seen[id] = true
+					players = append(players, Player{ID: id, Name: ds.Names.Of(id)})
+				}
+			}
+		}
+		sort.Slice(players, func(i, j int) bool { return players[i].Name < players[j].Name })
+
+		var podSizes []int
+		for n := 2; n <= maxPlayers; n++ {
+			podSizes = append(podSizes, n)
+		}
+		t.ExecuteTemplate(w, "preferences.html.tmpl", PreferencesView{
+			PageView:    newPageView(r, "Preferences", "Pick how the leaderboard opens for you."),
+			Preferences: requestPreferences(r),
+			Seasons:     leagueSeasons(ds.Games, ds.Seasons),
+			PodSizes:    podSizes,
+			Players:     players,
+		})
+	}
+}
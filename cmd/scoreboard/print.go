@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// printRowsPerPage is the number of standings printed on each page.
+	printRowsPerPage = 40
+	// printRecentGames is the number of recent results printed after the
+	// standings.
+	printRecentGames = 10
+)
+
+// PrintSheet is the printable standings sheet: the full standings split
+// into pages, followed by the most recent results.
+type PrintSheet struct {
+	Printed time.Time
+	Games   int
+	Pages   [][]PrintRow
+	Recent  []string // summaries of the most recent games, newest first.
+}
+
+// PrintRow is a line of the printed standings.
+type PrintRow struct {
+	Rank   int
+	Player Player
+}
+
+// printSheet lays out the rankings and the most recent of the scored games,
+// which must be in the order they were played, for printing.
+func printSheet(games []*Game, rankings []Player, names playerNames, now time.Time) PrintSheet {
+	sheet := PrintSheet{Printed: now, Games: len(games)}
+	for idx, p := range rankings {
+		if idx%printRowsPerPage == 0 {
+			sheet.Pages = append(sheet.Pages, nil)
+		}
+		page := len(sheet.Pages) - 1
+		sheet.Pages[page] = append(sheet.Pages[page], PrintRow{Rank: idx + 1, Player: p})
+	}
+	for i := len(games) - 1; i >= 0 && len(sheet.Recent) < printRecentGames; i-- {
+		sheet.Recent = append(sheet.Recent, fmt.Sprintf("Game %s: %s", games[i].ID, gameSummary(games[i], names)))
+	}
+	return sheet
+}
+
+// lines lays the sheet out as fixed width text lines for the PDF, with the
+// page breaks of the standings kept.
+func (s PrintSheet) lines() [][]string {
+	header := fmt.Sprintf("%-4s  %-28s  %6s  %4s  %5s", "#", "Player", "Rating", "Wins", "Games")
+	var pages [][]string
+	for idx, rows := range s.Pages {
+		page := []string{
+			fmt.Sprintf("Standings, %d games as of %s", s.Games, shortDate(s.Printed)),
+			fmt.Sprintf("Page %d of %d", idx+1, len(s.Pages)),
+			"",
+			header,
+		}
+		for _, row := range rows {
+			page = append(page, fmt.Sprintf("%-4d  %-28s  %6d  %4d  %5d", row.Rank, truncate(row.Player.Name, 28), row.Player.Score, row.Player.Wins, row.Player.Games))
+		}
+		pages = append(pages, page)
+	}
+	if len(s.Recent) > 0 {
+		page := []string{"Recent results", ""}
+		for _, summary := range s.Recent {
+			page = append(page, truncate(summary, 80))
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// printHandler returns the handler for /print, the standings and recent
+// results laid out for printing and pinning up at the store. With
+// format=pdf it's a PDF instead of a page.
+func printHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "html" && format != "pdf" {
+			badRequestRes(w, r, "format must be html or pdf")
+			return
+		}
+
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		games := ds.Games
+		scores := calculateScores(games, ds.Adjustments...)
+		rankings := rankPlayers(games, scores, l.clock.Now())
+		ds.Names.apply(rankings)
+		sheet := printSheet(games, rankings, ds.Names, l.clock.Now())
+
+		if format == "pdf" {
+			var buf bytes.Buffer
+			if err := writePDF(&buf, sheet.lines()); err != nil {
+				log.Printf("error writing standings pdf: %+v", err)
+				errorRes(w, r, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", `inline; filename="standings.pdf"`)
+			w.Write(buf.Bytes())
+			return
+		}
+
+		t.ExecuteTemplate(w, "print.html.tmpl", PrintView{
+			PageView: newPageView(r, "Standings", "The league's standings, ready to print."),
+			Sheet:    sheet,
+		})
+	}
+}
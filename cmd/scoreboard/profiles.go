@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Profile holds the settings a player has chosen for themselves. Profiles are
+// kept in a JSON file alongside the app since the game log only records names.
+type Profile struct {
+	Name                string   `json:"name"`                 // the player's name or ID, as the game log refers to them.
+	Email               string   `json:"email,omitempty"`      // where to send the player's notifications.
+	NotifyRatingChanges bool     `json:"notifyRatingChanges"`  // opts the player in to "your rating changed" emails.
+	NotifyOvertaken     bool     `json:"notifyOvertaken"`      // opts the player in to "you've been passed" emails and webhooks.
+	DiscordID           string   `json:"discordId,omitempty"`  // the player's Discord user ID, passed on in webhooks.
+	Rivals              []string `json:"rivals,omitempty"`     // the players they've declared rivalries with.
+	LoginToken          string   `json:"loginToken,omitempty"` // the secret the player logs in with to comment.
+	PublicProfile       bool     `json:"publicProfile"`        // opts the player in to sharing their profile at /api/players/{name}.
+	PublicGames         bool     `json:"publicGames"`          // also shares their recent games in their public profile.
+}
+
+// loadProfiles reads the player profiles file at path and returns the
+// profiles keyed by the ID of the player each names in the dataset, with
+// their rivals' names resolved to IDs too, so a profile written before the
+// player was given an ID or slug still finds them. A missing path or file
+// yields no profiles.
+func loadProfiles(path string, ds *Dataset) (map[string]Profile, error) {
+	profiles := map[string]Profile{}
+	if path == "" {
+		return profiles, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return profiles, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+
+	var list []Profile
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles: %w", err)
+	}
+	for _, p := range list {
+		p.Name = ds.playerID(p.Name)
+		for i, rival := range p.Rivals {
+			if rival = strings.TrimSpace(rival); rival != "" {
+				p.Rivals[i] = ds.playerID(rival)
+			}
+		}
+		profiles[p.Name] = p
+	}
+	return profiles, nil
+}
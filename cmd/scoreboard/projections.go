@@ -0,0 +1,122 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Projection is a player's projected rating at the end of the season, from
+// how often they've been playing this season and how their rating has been
+// trending over their recent games.
+type Projection struct {
+	Player       Player  // the player's current season standing.
+	Rank         int     // the player's current place in the season standings.
+	GamesPerWeek float64 // how often they've played this season.
+	PerGame      float64 // their average rating change over their recent games.
+	Remaining    int     // the games they're expected to play before the season ends.
+	Projected    int
+
+	// WinsToFirst is how many wins in a row, at their average gain from a
+	// win this season, it would take to pass the current #1. It's 0 for the
+	// leader and -1 for players who haven't won a game this season.
+	WinsToFirst int
+}
+
+// seasonProjections projects the current season's final standings as of now,
+// ordered by projected rating. It reports false if there is no season in
+// progress with a known end date to project to.
+func seasonProjections(games []*Game, seasons []Season, adjustments []*Adjustment, now time.Time) (Season, []Projection, bool) {
+	played := leagueSeasons(games, seasons)
+	if len(played) == 0 {
+		return Season{}, nil, false
+	}
+	season := played[len(played)-1]
+	if season.End.IsZero() || !now.Before(season.End) || now.Before(season.Start) {
+		return season, nil, false
+	}
+
+	seasonGames := gamesInSeason(games, season)
+	standings := rankPlayers(seasonGames, calculateScores(seasonGames, adjustmentsInSeason(adjustments, season)...), now)
+	if len(standings) == 0 {
+		return season, nil, false
+	}
+
+	// each player's results this season, most recent last
+	results := map[string][]Result{}
+	for _, g := range seasonGames {
+		for _, res := range g.Results {
+			results[res.Player] = append(results[res.Player], res)
+		}
+	}
+
+	weeks := math.Max(now.Sub(season.Start).Hours()/24/7, 1)
+	weeksLeft := season.End.Sub(now).Hours() / 24 / 7
+	leader := standings[0]
+
+	projections := make([]Projection, 0, len(standings))
+	for idx, p := range standings {
+		history := results[p.ID]
+		pr := Projection{Player: p, Rank: idx + 1, GamesPerWeek: float64(len(history)) / weeks}
+
+		recent := history
+		if len(recent) > trendGames {
+			recent = recent[len(recent)-trendGames:]
+		}
+		total, winGain, wins := 0, 0, 0
+		for _, res := range recent {
+			total += res.Delta
+		}
+		for _, res := range history {
+			if res.Place == 1 && res.Delta > 0 {
+				winGain += res.Delta
+				wins++
+			}
+		}
+		if len(recent) > 0 {
+			pr.PerGame = float64(total) / float64(len(recent))
+		}
+		pr.Remaining = int(math.Round(pr.GamesPerWeek * weeksLeft))
+		pr.Projected = p.Score + int(math.Round(pr.PerGame*float64(pr.Remaining)))
+
+		switch {
+		case idx == 0:
+			pr.WinsToFirst = 0
+		case wins == 0:
+			pr.WinsToFirst = -1
+		default:
+			perWin := float64(winGain) / float64(wins)
+			pr.WinsToFirst = int(math.Floor(float64(leader.Score-p.Score)/perWin)) + 1
+		}
+		projections = append(projections, pr)
+	}
+
+	sort.SliceStable(projections, func(i, j int) bool {
+		return projections[i].Projected > projections[j].Projected
+	})
+	return season, projections, true
+}
+
+// projectionsHandler returns the handler for the season projections page at
+// /projections.
+func projectionsHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		season, projections, ok := seasonProjections(ds.Games, ds.Seasons, ds.Adjustments, l.clock.Now())
+		for idx := range projections {
+			projections[idx].Player.Name = ds.Names.Of(projections[idx].Player.ID)
+		}
+
+		t.ExecuteTemplate(w, "projections.html.tmpl", ProjectionsView{
+			PageView:    newPageView(r, "Projections", "Where the season's standings are heading."),
+			Season:      season,
+			OK:          ok,
+			Projections: projections,
+			TrendGames:  trendGames,
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// publicRecentGames is the number of recent games in a public profile.
+const publicRecentGames = 10
+
+// PublicProfile is what a player who opted in shares at /api/players/{name},
+// for embedding their stats on their own site.
+type PublicProfile struct {
+	ID      string        `json:"id"`
+	Name    string        `json:"name"`
+	URL     string        `json:"url,omitempty"` // the player's page, when SCOREBOARD_PUBLIC_URL is set.
+	Rank    int           `json:"rank"`
+	Rating  int           `json:"rating"`
+	Wins    int           `json:"wins"`
+	Games   int           `json:"games"`
+	History []RatingPoint `json:"history"` // the player's rating after each game, oldest first.
+
+	// Recent are the player's most recent games, newest first, only shared
+	// by players who opted in to sharing their games.
+	Recent []GameRecord `json:"recentGames,omitempty"`
+}
+
+// RatingPoint is a player's rating after a game.
+type RatingPoint struct {
+	Game      string    `json:"game"`
+	Timestamp time.Time `json:"timestamp"`
+	Rating    int       `json:"rating"`
+}
+
+// publicProfile returns the player's public profile from the scored games
+// and rankings, with their recent games if withGames is set. It reports
+// false if the player isn't ranked.
+func publicProfile(games []*Game, rankings []Player, id string, withGames bool) (PublicProfile, bool) {
+	var pp PublicProfile
+	found := false
+	for idx, p := range rankings {
+		if p.ID == id {
+			pp = PublicProfile{ID: p.ID, Name: p.Name, Rank: idx + 1, Rating: p.Score, Wins: p.Wins, Games: p.Games}
+			found = true
+			break
+		}
+	}
+	if !found {
+		return pp, false
+	}
+
+	pp.URL = absoluteURL("/player/" + id)
+	pp.History = []RatingPoint{}
+	var played []*Game
+	for _, g := range games {
+		for _, res := range g.Results {
+			if res.Player == id {
+				pp.History = append(pp.History, RatingPoint{Game: g.ID, Timestamp: g.Timestamp, Rating: res.After})
+				played = append(played, g)
+			}
+		}
+	}
+	if withGames {
+		scale := newPodScale(games)
+		for i := len(played) - 1; i >= 0 && len(pp.Recent) < publicRecentGames; i-- {
+			pp.Recent = append(pp.Recent, gameRecord(played[i], scale))
+		}
+	}
+	return pp, true
+}
+
+// publicProfileHandler returns the handler for /api/players/{name}, the
+// public profile of a player who opted in with publicProfile in their
+// profile. Players who haven't opted in aren't found. It can be fetched from
+// any origin.
+func publicProfileHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		notFound := func() {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			web.WriteJSON(w, map[string]interface{}{"error": "no public profile for this player"})
+		}
+
+		name := strings.TrimPrefix(r.URL.Path, "/api/players/")
+		if name == "" || strings.Contains(name, "/") {
+			notFound()
+			return
+		}
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		id := ds.playerID(name)
+
+		profiles, err := loadProfiles(l.profilesPath, ds)
+		if err != nil {
+			log.Printf("error loading profiles: %s", err)
+			errorRes(w, r, err)
+			return
+		}
+		profile, ok := profiles[id]
+		if !ok || !profile.PublicProfile {
+			notFound()
+			return
+		}
+
+		games := ds.Games
+		rankings := rankPlayers(games, calculateScores(games, ds.Adjustments...), l.clock.Now())
+		ds.Names.apply(rankings)
+		pp, ok := publicProfile(games, rankings, id, profile.PublicGames)
+		if !ok {
+			notFound()
+			return
+		}
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		web.WriteJSON(w, pp)
+	}
+}
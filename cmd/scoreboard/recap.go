@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// recapStreak is the winning streak long enough to be worth a line in a
+// recap, and losing streaks twice as long.
+const recapStreak = 3
+
+// NightRecap is the story of a league night, told from its games' rating
+// changes and the streaks they left.
+type NightRecap struct {
+	Date    time.Time
+	Games   int
+	Players int
+	Lines   []string // the recap's sentences, the night's summary first.
+}
+
+// nightRecap recaps the latest league night among the games, which must be
+// scored and in play order, or returns false if there are no dated games.
+// Standings are by the ratings the games left, without adjustments.
+func nightRecap(games []*Game, names playerNames) (NightRecap, bool) {
+	var recap NightRecap
+	night := ""
+	for _, g := range games {
+		if !g.Timestamp.IsZero() && g.Timestamp.Format("2006-01-02") > night {
+			night = g.Timestamp.Format("2006-01-02")
+			recap.Date = g.Timestamp
+		}
+	}
+	if night == "" {
+		return recap, false
+	}
+
+	before, after := map[string]int{}, map[string]int{}
+	wins, played, gained := map[string]int{}, map[string]int{}, map[string]int{}
+	var tonight []*Game
+	for _, g := range games {
+		if g.Timestamp.IsZero() || g.Timestamp.Format("2006-01-02") != night {
+			for _, res := range g.Results {
+				before[res.Player] = res.After
+				after[res.Player] = res.After
+			}
+			continue
+		}
+		tonight = append(tonight, g)
+		for _, res := range g.Results {
+			after[res.Player] = res.After
+			played[res.Player]++
+			gained[res.Player] += res.Delta
+			if res.Place == 1 && !g.IsDraw() {
+				wins[res.Player]++
+			}
+		}
+	}
+	recap.Games, recap.Players = len(tonight), len(played)
+	recap.Lines = append(recap.Lines, fmt.Sprintf("%d %s played on %s by %d players.",
+		recap.Games, plural(recap.Games, "game was", "games were"), recap.Date.Format("Mon 2 Jan"), recap.Players))
+
+	rankBefore, rankAfter := standingRanks(before), standingRanks(after)
+	ids := make([]string, 0, len(played))
+	for id := range played {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return names.Of(ids[i]) < names.Of(ids[j]) })
+
+	// who's on top, and whether that changed
+	if leader := leaderOf(rankAfter); leader != "" && played[leader] > 0 {
+		if prev := leaderOf(rankBefore); prev != "" && prev != leader {
+			recap.Lines = append(recap.Lines, fmt.Sprintf("%s took first place from %s.", names.Of(leader), names.Of(prev)))
+		}
+	}
+
+	// the biggest climb and fall in the standings, among players who were
+	// already ranked
+	var climber, faller string
+	climb, fall := 0, 0
+	for _, id := range ids {
+		prev, ok := rankBefore[id]
+		if !ok {
+			continue
+		}
+		switch moved := prev - rankAfter[id]; {
+		case moved > climb:
+			climber, climb = id, moved
+		case -moved > fall:
+			faller, fall = id, -moved
+		}
+	}
+	if climber != "" {
+		recap.Lines = append(recap.Lines, fmt.Sprintf("%s climbed %d %s to #%d %s.",
+			names.Of(climber), climb, plural(climb, "spot", "spots"), rankAfter[climber], highlight(climber, tonight, wins, played)))
+	}
+	if faller != "" {
+		recap.Lines = append(recap.Lines, fmt.Sprintf("%s dropped %d %s to #%d.", names.Of(faller), fall, plural(fall, "spot", "spots"), rankAfter[faller]))
+	}
+
+	// the night's biggest rating gain, if it wasn't the climber's
+	best := ""
+	for _, id := range ids {
+		if best == "" || gained[id] > gained[best] {
+			best = id
+		}
+	}
+	if best != "" && best != climber && gained[best] > 0 {
+		recap.Lines = append(recap.Lines, fmt.Sprintf("%s gained the most, %s %s.", names.Of(best), signed(gained[best]), highlight(best, tonight, wins, played)))
+	}
+
+	streaks := playerStreaks(games)
+	var newcomers []string
+	for _, id := range ids {
+		if _, ok := rankBefore[id]; !ok {
+			newcomers = append(newcomers, names.Of(id))
+		}
+		switch s := streaks[id]; {
+		case s >= recapStreak:
+			recap.Lines = append(recap.Lines, fmt.Sprintf("%s has won %d in a row.", names.Of(id), s))
+		case -s >= 2*recapStreak:
+			recap.Lines = append(recap.Lines, fmt.Sprintf("%s has lost %d in a row.", names.Of(id), -s))
+		}
+	}
+	if len(newcomers) > 0 {
+		recap.Lines = append(recap.Lines, fmt.Sprintf("Welcome to %s, who played their first %s.",
+			joinNames(newcomers), plural(len(newcomers), "game", "games")))
+	}
+	return recap, true
+}
+
+// highlight describes the player's night for a recap line: a table zap if
+// they won one, or else their record.
+func highlight(player string, games []*Game, wins, played map[string]int) string {
+	for _, g := range games {
+		if isMarked(g.TableZap) && g.Won(player) {
+			return fmt.Sprintf("after zapping a %d-player pod", len(g.Rankings))
+		}
+	}
+	return fmt.Sprintf("after winning %d of %d", wins[player], played[player])
+}
+
+// standingRanks returns each player's 1-indexed place in the standings by
+// rating, with ties broken by ID so the ranks are stable.
+func standingRanks(ratings map[string]int) map[string]int {
+	ids := make([]string, 0, len(ratings))
+	for id := range ratings {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ratings[ids[i]] != ratings[ids[j]] {
+			return ratings[ids[i]] > ratings[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	ranks := make(map[string]int, len(ids))
+	for idx, id := range ids {
+		ranks[id] = idx + 1
+	}
+	return ranks
+}
+
+// leaderOf returns the player ranked first, or "" if nobody is ranked.
+func leaderOf(ranks map[string]int) string {
+	for id, rank := range ranks {
+		if rank == 1 {
+			return id
+		}
+	}
+	return ""
+}
+
+// plural returns one if n is 1, and many otherwise.
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// joinNames joins names into a list like "alice, bob and carol".
+func joinNames(names []string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	}
+	list := names[0]
+	for _, name := range names[1 : len(names)-1] {
+		list += ", " + name
+	}
+	return list + " and " + names[len(names)-1]
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"time"
+
+	"github.com/fly-apps/go-example/scoring"
+)
+
+// recencyHalfLife is how long it takes a game to count half as much on the
+// recency board. Configured in days with SCOREBOARD_RECENCY_HALF_LIFE.
+var recencyHalfLife = 180 * 24 * time.Hour
+
+// recencyRankings rates players with the elo engine, decaying the K-factor of
+// each game by its age so older games move ratings less and the board
+// reflects current skill rather than past dominance.
+func recencyRankings(games []*Game, adjustments []*Adjustment, now time.Time) []Player {
+	config := scoringConfig()
+	config.HalfLife = recencyHalfLife
+	config.Now = now
+
+	games = copyGames(games)
+	scores, _ := scoring.Score(scoring.NewElo(config), games, copyAdjustments(adjustments)...)
+	return rankPlayers(games, scores, now)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// refreshHandler returns the handler for POST /api/refresh, which drops the
+// league's rendered pages and refetches and rescores its data right away, like
+// after results are entered at the table. Only logged in players can refresh.
+// It returns the new dataset version, or redirects to next when posted from a
+// page.
+func refreshHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.sessions == nil {
+			notFoundRes(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			methodNotAllowedRes(w, r, "POST")
+			return
+		}
+		if l.currentPlayer(r) == "" {
+			unauthorizedRes(w, r, "log in to refresh")
+			return
+		}
+
+		l.pages.clear()
+		l.players.clear()
+		ds, err := l.fetch(r.Context())
+		if err != nil {
+			log.Printf("error refreshing game data: %+v", err)
+			status, message := http.StatusBadGateway, "the game log couldn't be fetched"
+			if isQuotaError(err) {
+				status, message = http.StatusTooManyRequests, "the league has used up its Google Sheets quota for now"
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			web.WriteJSON(w, map[string]interface{}{"error": message})
+			return
+		}
+		scores := calculateScores(ds.Games, ds.Adjustments...)
+		l.precomputePlayers(ds)
+
+		if next := r.PostFormValue("next"); next != "" {
+			http.Redirect(w, r, basePath(r)+safeRedirect(next), http.StatusSeeOther)
+			return
+		}
+		web.WriteJSON(w, map[string]interface{}{
+			"datasetVersion": datasetVersion(ds),
+			"games":          len(ds.Games),
+			"players":        len(scores),
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fly-apps/go-example/internal/sheets"
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// RenamedCell is a cell of the spreadsheet changed by renaming a player.
+type RenamedCell struct {
+	Cell string `json:"cell"` // the cell in A1 notation.
+	Tab  string `json:"tab"`  // the tab, like "games" or "adjustments".
+	Row  string `json:"row"`  // the game ID, or the adjustment's date.
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// RenameResult is what renaming a player changes, or would change on a dry
+// run: the cells of the game log and adjustments tab, the stored records, and
+// the player's rating after the history is re-scored under the new name.
+type RenameResult struct {
+	From    string           `json:"from"`
+	To      string           `json:"to"`
+	DryRun  bool             `json:"dryRun"`
+	Cells   []RenamedCell    `json:"cells"`
+	Records map[string]int64 `json:"records,omitempty"` // stored records changed, keyed by table.
+	Before  map[string]int   `json:"before"`            // the ratings of both names before the rename.
+	After   int              `json:"after"`             // the rating under the new name after the rename.
+}
+
+// renameCell returns the cell's value with the player renamed, or false if
+// the cell doesn't name them. Two-headed giant and archenemy cells name
+// several players separated by slashes, and a placement annotated after a
+// player's name is kept.
+func renameCell(value, from, to string) (string, bool) {
+	parts := strings.Split(value, "/")
+	changed := false
+	for idx, part := range parts {
+		if strings.EqualFold(strings.TrimSpace(part), from) {
+			parts[idx] = to
+			changed = true
+		} else if name, placement := parsePlacementAnnotation(strings.TrimSpace(part)); placement > 0 && strings.EqualFold(name, from) {
+			parts[idx] = fmt.Sprintf("%s(%d)", to, placement)
+			changed = true
+		} else if len(parts) > 1 {
+			parts[idx] = strings.TrimSpace(part)
+		}
+	}
+	return strings.Join(parts, "/"), changed
+}
+
+// renameRows renames the player in the given columns of every row after the
+// labels, updating the rows in place. It returns the changed cells, with
+// addresses relative to the top left cell of rng.
+func renameRows(rows [][]interface{}, rng, tab string, from, to string, first, last int) []RenamedCell {
+	sheet, col, row := sheets.RangeStart(rng)
+	var cells []RenamedCell
+	for idx, values := range rows {
+		if idx == 0 || len(values) == 0 {
+			continue
+		}
+		for c := first; c < minInt(len(values), last); c++ {
+			old := strings.TrimSpace(fmt.Sprintf("%s", values[c]))
+			renamed, ok := renameCell(old, from, to)
+			if !ok {
+				continue
+			}
+			values[c] = renamed
+			cells = append(cells, RenamedCell{
+				Cell: sheet + sheets.ColumnLetter(col+c) + strconv.Itoa(row+idx),
+				Tab:  tab,
+				Row:  strings.TrimSpace(fmt.Sprintf("%s", values[0])),
+				Old:  old,
+				New:  renamed,
+			})
+		}
+	}
+	return cells
+}
+
+// renameTabs renames the player in the columns naming players of each of the
+// league's tabs, fetched in the order of rangeList, updating values in place.
+// It returns the changed cells.
+func (l *league) renameTabs(values [][][]interface{}, from, to string) []RenamedCell {
+	var cells []RenamedCell
+	for idx, tab := range l.tabs() {
+		for _, cols := range playerColumns[tab.Name] {
+			cells = append(cells, renameRows(values[idx], tab.Range, tab.Name, from, to, cols[0], cols[1])...)
+		}
+	}
+	return cells
+}
+
+// renamePlayer renames the player across the league's stored records:
+// pick-em predictions, comments, game reports, games waiting for
+// confirmation and scheduling polls. The imported sheet rows naming them are
+// renamed to cell, which is the name the sheet now has for them, unless it's
+// empty. It returns the number of records changed in each table. On a dry
+// run the changes are counted and rolled back.
+func (s *store) renamePlayer(ctx context.Context, league, from, to, cell string, dryRun bool) (map[string]int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename player: %w", err)
+	}
+	defer tx.Rollback()
+
+	records := map[string]int64{}
+	updates := []struct {
+		table string
+		query string
+		args  []interface{}
+	}{
+		// a player who picked under both names keeps the pick made under the
+		// new name
+		{"predictions", `UPDATE OR IGNORE predictions SET member = ? WHERE league = ? AND member = ? COLLATE NOCASE`, []interface{}{to, league, from}},
+		{"predictions", `DELETE FROM predictions WHERE league = ? AND member = ? COLLATE NOCASE AND member != ?`, []interface{}{league, from, to}},
+		{"predictions", `UPDATE predictions SET pick = ? WHERE league = ? AND pick = ? COLLATE NOCASE`, []interface{}{to, league, from}},
+		{"comments", `UPDATE comments SET author = ? WHERE league = ? AND author = ? COLLATE NOCASE`, []interface{}{to, league, from}},
+		{"comments", `UPDATE comments SET target = ? WHERE league = ? AND kind = 'player' AND target = ? COLLATE NOCASE`, []interface{}{to, league, from}},
+		{"game_reports", `UPDATE game_reports SET author = ? WHERE league = ? AND author = ? COLLATE NOCASE`, []interface{}{to, league, from}},
+		{"pending_games", `UPDATE pending_games SET submitted_by = ? WHERE league = ? AND submitted_by = ? COLLATE NOCASE`, []interface{}{to, league, from}},
+		{"polls", `UPDATE polls SET created_by = ? WHERE league = ? AND created_by = ? COLLATE NOCASE`, []interface{}{to, league, from}},
+		{"poll_availability", `UPDATE OR IGNORE poll_availability SET player = ?
+			WHERE player = ? COLLATE NOCASE AND poll_id IN (SELECT id FROM polls WHERE league = ?)`, []interface{}{to, from, league}},
+		{"poll_availability", `DELETE FROM poll_availability
+			WHERE player = ? COLLATE NOCASE AND player != ? AND poll_id IN (SELECT id FROM polls WHERE league = ?)`, []interface{}{from, to, league}},
+	}
+	for _, u := range updates {
+		res, err := tx.ExecContext(ctx, u.query, u.args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rename player in %s: %w", u.table, err)
+		}
+		if n, err := res.RowsAffected(); err == nil && !strings.HasPrefix(u.query, "DELETE") {
+			records[u.table] += n
+		}
+	}
+
+	// the pods of pending games are stored as JSON
+	rows, err := tx.QueryContext(ctx, `SELECT id, game FROM pending_games WHERE league = ?`, league)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename player in pending_games: %w", err)
+	}
+	renamed := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var game string
+		if err := rows.Scan(&id, &game); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to rename player in pending_games: %w", err)
+		}
+		g := &LiveGame{}
+		if err := json.Unmarshal([]byte(game), g); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to decode pending game %d: %w", id, err)
+		}
+		changed := false
+		for idx, name := range g.Players {
+			if strings.EqualFold(name, from) {
+				g.Players[idx] = to
+				changed = true
+			}
+		}
+		if changed {
+			b, err := json.Marshal(g)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to encode pending game %d: %w", id, err)
+			}
+			renamed[id] = string(b)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to rename player in pending_games: %w", err)
+	}
+	for id, game := range renamed {
+		if _, err := tx.ExecContext(ctx, `UPDATE pending_games SET game = ? WHERE id = ?`, game, id); err != nil {
+			return nil, fmt.Errorf("failed to rename player in pending_games: %w", err)
+		}
+		records["pending_games"]++
+	}
+	if cell != "" {
+		n, err := renameSheetRows(ctx, tx, league, from, cell)
+		if err != nil {
+			return nil, err
+		}
+		records["sheet_rows"] += n
+	}
+
+	if dryRun {
+		return records, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to rename player: %w", err)
+	}
+	return records, nil
+}
+
+// renamePlayer renames the player across the league's history: every cell
+// of the game log, players, aliases, seeds, adjustments, houses and events
+// tabs naming them is rewritten, along with their stored records when st
+// isn't nil and the archived standings. Renaming to a name already in the
+// game log merges the two histories. On a dry run nothing is written and the
+// result previews the changes.
+func (l *league) renamePlayer(ctx context.Context, st *store, from, to string, dryRun bool) (*RenameResult, error) {
+	if l.frozen != nil {
+		return nil, errors.New("a static league can't be renamed")
+	}
+	// hold off live games so none are appended between reading and
+	// rewriting the game log
+	l.recording.Lock()
+	defer l.recording.Unlock()
+
+	values, err := sheets.FetchRanges(ctx, l.spreadsheetID, l.rangeList(), l.opts...)
+	if err != nil {
+		return nil, err
+	}
+	before, err := l.parse(values)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &RenameResult{From: from, To: to, DryRun: dryRun}
+	res.Cells = l.renameTabs(values, from, to)
+	after, err := l.parse(values)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := calculateScores(before.Games, before.Adjustments...)
+	res.Before = map[string]int{}
+	for _, name := range []string{from, to} {
+		if score, ok := scores[before.playerID(name)]; ok {
+			res.Before[name] = score
+		}
+	}
+	res.After = calculateScores(after.Games, after.Adjustments...)[after.playerID(to)]
+
+	if !dryRun && len(res.Cells) > 0 {
+		cells := map[string]string{}
+		for _, c := range res.Cells {
+			cells[c.Cell] = c.New
+		}
+		if err := sheets.UpdateCells(ctx, l.spreadsheetID, cells, l.opts...); err != nil {
+			return nil, err
+		}
+	}
+	if st != nil {
+		// records are stored under the players' IDs, or under the name from
+		// before they were
+		res.Records = map[string]int64{}
+		names := []string{from}
+		if id := before.playerID(from); id != from {
+			names = append(names, id)
+		}
+		for i, name := range names {
+			// the imported sheet only ever had the name
+			cell := ""
+			if i == 0 {
+				cell = to
+			}
+			renamed, err := st.renamePlayer(ctx, l.snapshotKey(), name, after.playerID(to), cell, dryRun)
+			if err != nil {
+				return nil, err
+			}
+			for table, n := range renamed {
+				res.Records[table] += n
+			}
+		}
+	}
+	// the archived standings are drawn again under the new name
+	weeks, err := l.rerenderArchive(ctx, after, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if weeks > 0 {
+		if res.Records == nil {
+			res.Records = map[string]int64{}
+		}
+		res.Records["standings_archive"] = weeks
+	}
+	return res, nil
+}
+
+// renameHandler returns the admin handler that renames a player across the
+// league's history. It previews the rename unless posted apply=true.
+func renameHandler(l *league, st *store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			methodNotAllowedRes(w, r, "POST")
+			return
+		}
+		from := strings.TrimSpace(r.PostFormValue("from"))
+		to := strings.TrimSpace(r.PostFormValue("to"))
+		if from == "" || to == "" || strings.EqualFold(from, to) || strings.Contains(to, "/") {
+			badRequestRes(w, r, "from and to must be two different player names")
+			return
+		}
+		dryRun := !isMarked(r.PostFormValue("apply"))
+
+		res, err := l.renamePlayer(r.Context(), st, from, to, dryRun)
+		if err != nil {
+			log.Printf("error renaming %s to %s: %+v", from, to, err)
+			errorRes(w, r, err)
+			return
+		}
+		if !dryRun {
+			log.Printf("renamed %s to %s in %d cells", from, to, len(res.Cells))
+		}
+		web.WriteJSON(w, res)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// maxCachedPages bounds the number of rendered variants (e.g. different date
+// filters) kept for a single dataset version.
+const maxCachedPages = 64
+
+// renderCache holds rendered pages for the current dataset version. Pages are
+// rendered once per version and served from memory until the data changes.
+// The last page rendered for each key is kept across versions, to serve
+// stale when the server is too busy to render a fresh one.
+type renderCache struct {
+	mu      sync.Mutex
+	version string
+	pages   map[string]*renderedPage
+	last    map[string]*renderedPage
+}
+
+// renderedPage is a rendered template along with its gzip pre-compressed form.
+type renderedPage struct {
+	body    []byte
+	gzipped []byte
+	etag    string
+}
+
+func newRenderCache() *renderCache {
+	return &renderCache{pages: map[string]*renderedPage{}, last: map[string]*renderedPage{}}
+}
+
+// get returns the page cached under key for the dataset version, or nil.
+func (c *renderCache) get(version, key string) *renderedPage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != version {
+		return nil
+	}
+	return c.pages[key]
+}
+
+// stale returns the last page rendered under key for any dataset version, or
+// nil.
+func (c *renderCache) stale(key string) *renderedPage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last[key]
+}
+
+// render executes the named template and caches the output under key for
+// the dataset version. Pages cached for older versions are dropped.
+func (c *renderCache) render(version, key, name string, data interface{}) (*renderedPage, error) {
+	var body bytes.Buffer
+	if err := t.ExecuteTemplate(&body, name, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", name, err)
+	}
+
+	var gzipped bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&gzipped, gzip.BestCompression)
+	zw.Write(body.Bytes())
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress %s: %w", name, err)
+	}
+
+	sum := sha256.Sum256([]byte(version + "\x00" + key))
+	page := &renderedPage{
+		body:    body.Bytes(),
+		gzipped: gzipped.Bytes(),
+		etag:    `"` + hex.EncodeToString(sum[:8]) + `"`,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != version || len(c.pages) >= maxCachedPages {
+		c.version = version
+		c.pages = map[string]*renderedPage{}
+	}
+	c.pages[key] = page
+	if len(c.last) >= maxCachedPages {
+		c.last = map[string]*renderedPage{}
+	}
+	c.last[key] = page
+	return page, nil
+}
+
+// clear drops every cached page, so they're rendered afresh even if the
+// dataset version hasn't changed.
+func (c *renderCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version = ""
+	c.pages = map[string]*renderedPage{}
+}
+
+// serve writes the page, using the compressed bytes when the client accepts
+// gzip and answering conditional requests with 304 Not Modified.
+func (p *renderedPage) serve(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("ETag", p.etag)
+	h.Add("Vary", "Accept-Encoding")
+
+	if r.Header.Get("If-None-Match") == p.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if web.AcceptsGzip(r.Header.Get("Accept-Encoding")) {
+		h.Set("Content-Encoding", "gzip")
+		w.Write(p.gzipped)
+		return
+	}
+	w.Write(p.body)
+}
+
+// datasetVersion returns a short hash identifying everything parsed from
+// the league's tabs, which changes whenever a game, a player, an adjustment
+// or any other tab the pages are rendered from is added or edited.
+func datasetVersion(ds *Dataset) string {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, games := range [][]*Game{ds.Games, ds.Unscored} {
+		for _, g := range games {
+			fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%s\x00%s\x00%s\x00%t\x00%s\x00%s\x00%s\x00%d\n",
+				g.ID, g.Date, g.TableZap, g.DrawGame, g.Notes, strings.Join(g.Rankings, "\x00"),
+				g.Archenemy, g.TwoHeadedGiant, g.FirstBlood, g.FirstEliminated, g.Kingmaker, g.Row)
+			// maps are encoded with their keys sorted
+			enc.Encode([]interface{}{g.Teams, g.Tags, g.Eliminations, g.Decks})
+		}
+		fmt.Fprintln(h)
+	}
+	for _, p := range ds.Players {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\n", p.ID, p.Name, strings.Join(p.Names, "\x00"))
+	}
+	for _, adj := range ds.Adjustments {
+		fmt.Fprintf(h, "%s\x00%s\x00%d\x00%s\x00%t\n", adj.Date, adj.Player, adj.Amount, adj.Reason, adj.Seed)
+	}
+	enc.Encode([]interface{}{ds.Aliases, ds.Seasons, ds.Houses, ds.Rules, ds.Events, ds.Names})
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
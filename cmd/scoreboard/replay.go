@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// replayTop is the number of players shown in each frame of the replay.
+const replayTop = 10
+
+// ReplayFrame is the top of the leaderboard at the end of a day games were
+// played, one frame of the replay.
+type ReplayFrame struct {
+	Day   string        `json:"day"`   // the day, like "2023-01-02".
+	Games []string      `json:"games"` // the IDs of the games played that day.
+	Total int           `json:"total"` // the games played by the end of the day.
+	Top   []ReplayEntry `json:"top"`
+}
+
+// ReplayEntry is a player's place in a frame of the replay.
+type ReplayEntry struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Score  int    `json:"score"`
+	Change int    `json:"change"` // the change in rating since the previous frame.
+	New    bool   `json:"new"`    // whether the player played their first game that day.
+}
+
+// replayFrames returns a frame of the replay for each day games were played,
+// oldest first. Each day's leaderboard comes from the league's stored rating
+// snapshots when it has them, and is stored for next time when it doesn't.
+func replayFrames(ctx context.Context, l *league, ds *Dataset) []ReplayFrame {
+	games := make([]*Game, 0, len(ds.Games))
+	for _, g := range ds.Games {
+		if !g.Timestamp.IsZero() {
+			games = append(games, g)
+		}
+	}
+	sort.SliceStable(games, func(i, j int) bool { return games[i].Timestamp.Before(games[j].Timestamp) })
+
+	var frames []ReplayFrame
+	previous := map[string]int{}
+	for i := 0; i < len(games); {
+		ts := games[i].Timestamp
+		day := time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, ts.Location())
+		frame := ReplayFrame{Day: day.Format("2006-01-02")}
+		for ; i < len(games) && games[i].Timestamp.Format("2006-01-02") == frame.Day; i++ {
+			frame.Games = append(frame.Games, games[i].ID)
+		}
+
+		rankings, total := rankingsAsOf(ctx, l, ds, day)
+		frame.Total = total
+		for idx, p := range rankings {
+			if idx < replayTop {
+				before, rated := previous[p.ID]
+				e := ReplayEntry{ID: p.ID, Name: p.Name, Score: p.Score, New: !rated}
+				if rated {
+					e.Change = p.Score - before
+				}
+				frame.Top = append(frame.Top, e)
+			}
+			previous[p.ID] = p.Score
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// replayHandler returns the handler for the replay page at /replay, which
+// animates the leaderboard evolving from the league's first game night.
+func replayHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+
+		frames := replayFrames(r.Context(), l, ds)
+		t.ExecuteTemplate(w, "replay.html.tmpl", ReplayView{
+			PageView: newPageView(r, "Replay", "The leaderboard evolving game night by game night since the league began."),
+			Frames:   frames,
+			Last:     len(frames) - 1,
+		})
+	}
+}
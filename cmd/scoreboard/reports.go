@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxWriteupLength is the longest writeup a game report can have, in bytes.
+const maxWriteupLength = 2000
+
+// GameReport is a player's report of a game: a photo of the board or the
+// winning play and a short writeup, shown on the game's page.
+type GameReport struct {
+	ID        int64
+	League    string
+	Game      string // the ID of the game reported on.
+	Author    string // the ID of the player who wrote it.
+	Writeup   string
+	Photo     string // the key of the photo in the photo store, empty without one.
+	PhotoType string // the photo's content type.
+	CreatedAt time.Time
+}
+
+// addReport stores a new game report.
+func (s *store) addReport(ctx context.Context, rep *GameReport) error {
+	res, err := s.db.ExecContext(ctx, `INSERT INTO game_reports (league, game, author, writeup, photo, photo_type, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		rep.League, rep.Game, rep.Author, rep.Writeup, rep.Photo, rep.PhotoType, rep.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add game report: %w", err)
+	}
+	rep.ID, err = res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to add game report: %w", err)
+	}
+	return nil
+}
+
+// report returns one of the league's game reports.
+func (s *store) report(ctx context.Context, league string, id int64) (*GameReport, error) {
+	reports, err := s.queryReports(ctx, `SELECT id, league, game, author, writeup, photo, photo_type, created_at
+		FROM game_reports WHERE league = ? AND id = ?`, league, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return nil, errNotFound
+	}
+	return reports[0], nil
+}
+
+// reports returns the reports of a game, oldest first.
+func (s *store) reports(ctx context.Context, league, game string) ([]*GameReport, error) {
+	return s.queryReports(ctx, `SELECT id, league, game, author, writeup, photo, photo_type, created_at
+		FROM game_reports WHERE league = ? AND game = ? ORDER BY id`, league, game)
+}
+
+// reportsBy returns the reports the authors wrote in the league.
+func (s *store) reportsBy(ctx context.Context, league string, authors []string) ([]*GameReport, error) {
+	var reports []*GameReport
+	for _, author := range authors {
+		r, err := s.queryReports(ctx, `SELECT id, league, game, author, writeup, photo, photo_type, created_at
+			FROM game_reports WHERE league = ? AND author = ? COLLATE NOCASE`, league, author)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r...)
+	}
+	return reports, nil
+}
+
+func (s *store) queryReports(ctx context.Context, query string, args ...interface{}) ([]*GameReport, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load game reports: %w", err)
+	}
+	defer rows.Close()
+
+	var reports []*GameReport
+	for rows.Next() {
+		rep := &GameReport{}
+		if err := rows.Scan(&rep.ID, &rep.League, &rep.Game, &rep.Author, &rep.Writeup, &rep.Photo, &rep.PhotoType, &rep.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to load game reports: %w", err)
+		}
+		reports = append(reports, rep)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load game reports: %w", err)
+	}
+	return reports, nil
+}
+
+// pageReports returns the view of the reports on a game's page: the reports
+// and who's logged in to add one. It returns nil when reports are off.
+func pageReports(l *league, r *http.Request, game string) *ReportsView {
+	if l.reports == nil {
+		return nil
+	}
+	reports, err := l.reports.reports(r.Context(), l.snapshotKey(), game)
+	if err != nil {
+		// the page is still useful without its reports
+		log.Printf("error loading game reports: %+v", err)
+	}
+	return &ReportsView{
+		Game:    game,
+		Reports: reports,
+		User:    l.currentPlayer(r),
+		Path:    r.URL.Path,
+	}
+}
+
+// reportHandler returns the handler that adds game reports at /reports, from
+// a multipart form with the game, a writeup and a photo. Only logged in
+// players can add reports.
+func reportHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.reports == nil {
+			notFoundRes(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			methodNotAllowedRes(w, r, "POST")
+			return
+		}
+		author := l.currentPlayer(r)
+		if author == "" {
+			unauthorizedRes(w, r, "log in to add a game report")
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxPhotoSize+maxWriteupLength+4096)
+		if err := r.ParseMultipartForm(maxPhotoSize); err != nil {
+			badRequestRes(w, r, fmt.Sprintf("reports can have a photo of up to %d MB", maxPhotoSize>>20))
+			return
+		}
+		rep := &GameReport{
+			League:    l.snapshotKey(),
+			Game:      r.FormValue("game"),
+			Author:    author,
+			Writeup:   strings.TrimSpace(r.FormValue("writeup")),
+			CreatedAt: l.clock.Now().UTC(),
+		}
+		if rep.Game == "" || strings.Contains(rep.Game, "/") {
+			badRequestRes(w, r, "reports can only be added to games")
+			return
+		}
+		if len(rep.Writeup) > maxWriteupLength {
+			badRequestRes(w, r, fmt.Sprintf("writeups can be at most %d characters", maxWriteupLength))
+			return
+		}
+
+		var photo []byte
+		if f, _, err := r.FormFile("photo"); err == nil {
+			photo, err = io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				badRequestRes(w, r, "the photo couldn't be read")
+				return
+			}
+		}
+		if len(photo) > 0 {
+			rep.PhotoType = http.DetectContentType(photo)
+			if _, ok := photoTypes[rep.PhotoType]; !ok {
+				badRequestRes(w, r, "photos must be JPEG, PNG, GIF or WebP images")
+				return
+			}
+			key, err := newPhotoKey(l.random, rep.PhotoType)
+			if err != nil {
+				log.Printf("error naming photo: %+v", err)
+				errorRes(w, r, err)
+				return
+			}
+			if err := l.photos.put(r.Context(), key, rep.PhotoType, photo); err != nil {
+				log.Printf("error saving game report photo: %+v", err)
+				errorRes(w, r, err)
+				return
+			}
+			rep.Photo = key
+		}
+		if rep.Photo == "" && rep.Writeup == "" {
+			badRequestRes(w, r, "add a photo or a writeup to the report")
+			return
+		}
+
+		if err := l.reports.addReport(r.Context(), rep); err != nil {
+			log.Printf("error adding game report: %+v", err)
+			errorRes(w, r, err)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("%s/game/%s#report-%d", basePath(r), url.PathEscape(rep.Game), rep.ID), http.StatusSeeOther)
+	}
+}
+
+// photoHandler returns the handler that serves the photo of a game report at
+// /photos/{report ID}. Only photos of the league's reports are served, so a
+// deleted report's photo is gone with it.
+func photoHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.reports == nil {
+			notFoundRes(w, r)
+			return
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/photos/"), 10, 64)
+		if err != nil {
+			notFoundRes(w, r)
+			return
+		}
+		rep, err := l.reports.report(r.Context(), l.snapshotKey(), id)
+		if errors.Is(err, errNotFound) || (err == nil && rep.Photo == "") {
+			notFoundRes(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("error loading game report: %+v", err)
+			errorRes(w, r, err)
+			return
+		}
+		photo, err := l.photos.get(r.Context(), rep.Photo)
+		if errors.Is(err, errNotFound) {
+			notFoundRes(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("error loading game report photo: %+v", err)
+			errorRes(w, r, err)
+			return
+		}
+
+		// photos never change once uploaded
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		w.Header().Set("Content-Type", rep.PhotoType)
+		w.Write(photo)
+	}
+}
+
+// reportPhotos returns the keys of the photos attached to the reports the
+// authors wrote in the league.
+func (l *league) reportPhotos(ctx context.Context, authors []string) ([]string, error) {
+	reports, err := l.reports.reportsBy(ctx, l.snapshotKey(), authors)
+	if err != nil {
+		return nil, err
+	}
+	var photos []string
+	for _, rep := range reports {
+		if rep.Photo != "" {
+			photos = append(photos, rep.Photo)
+		}
+	}
+	return photos, nil
+}
+
+// photoDeleteAttempts is how many times deleting a photo is tried before
+// giving up.
+const photoDeleteAttempts = 3
+
+// deletePhotos deletes the photos with the keys. Deleting a photo that's
+// already gone succeeds, so a failed delete is retried.
+func (l *league) deletePhotos(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		var err error
+		for attempt := 0; attempt < photoDeleteAttempts; attempt++ {
+			if err = l.photos.delete(ctx, key); err == nil {
+				break
+			}
+			log.Printf("error deleting photo %s, attempt %d: %+v", key, attempt+1, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Matchup is the running head-to-head record between two declared rivals.
+type Matchup struct {
+	Players [2]string
+	Wins    [2]int        // the games each player finished above the other.
+	Games   []MatchupGame // the non-draw games both players were ranked in, oldest first.
+}
+
+// MatchupGame is a single game between two rivals and the tally after it.
+type MatchupGame struct {
+	Game   *Game
+	Places [2]int
+	Wins   [2]int
+}
+
+// Leader returns the index of the player leading the rivalry, or -1 if it's
+// tied.
+func (m *Matchup) Leader() int {
+	switch {
+	case m.Wins[0] > m.Wins[1]:
+		return 0
+	case m.Wins[1] > m.Wins[0]:
+		return 1
+	}
+	return -1
+}
+
+// declaredRivals returns each player's rivals from the profiles. Rivalries go
+// both ways, so a player declaring a rival makes them rivals of each other.
+func declaredRivals(profiles map[string]Profile) map[string]map[string]bool {
+	rivals := map[string]map[string]bool{}
+	add := func(a, b string) {
+		if rivals[a] == nil {
+			rivals[a] = map[string]bool{}
+		}
+		rivals[a][b] = true
+	}
+	for name, p := range profiles {
+		for _, rival := range p.Rivals {
+			rival = strings.TrimSpace(rival)
+			if rival == "" || rival == name {
+				continue
+			}
+			add(name, rival)
+			add(rival, name)
+		}
+	}
+	return rivals
+}
+
+// leagueRivals loads the declared rivalries from the league's profiles, by
+// the players' IDs in the dataset. Errors are logged rather than failing the
+// page, since rivalries are decoration.
+func leagueRivals(l *league, ds *Dataset) map[string]map[string]bool {
+	profiles, err := loadProfiles(l.profilesPath, ds)
+	if err != nil {
+		log.Printf("error loading rivalries: %s", err)
+		return nil
+	}
+	return declaredRivals(profiles)
+}
+
+// rivalriesInGame returns the pairs of rivals who met in the game.
+func rivalriesInGame(game *Game, rivals map[string]map[string]bool) [][2]string {
+	var pairs [][2]string
+	for i, a := range game.Rankings {
+		for _, b := range game.Rankings[i+1:] {
+			if rivals[a][b] {
+				pairs = append(pairs, [2]string{a, b})
+			}
+		}
+	}
+	return pairs
+}
+
+// rivalsInHistory marks the entries of a player's history where they met one
+// of their rivals.
+func rivalsInHistory(history []PlayerGame, rivals map[string]bool) {
+	for idx := range history {
+		if history[idx].Game == nil {
+			continue
+		}
+		for _, name := range history[idx].Game.Rankings {
+			if rivals[name] {
+				history[idx].Rivals = append(history[idx].Rivals, name)
+			}
+		}
+	}
+}
+
+// sortedNames returns the names in the set in alphabetical order.
+func sortedNames(set map[string]bool) []string {
+	var names []string
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// buildMatchup tallies the head-to-head record between a and b across the
+// games, which must be in play order.
+func buildMatchup(games []*Game, a, b string) *Matchup {
+	m := &Matchup{Players: [2]string{a, b}}
+	for _, game := range games {
+		if game.IsDraw() {
+			continue
+		}
+		places := [2]int{}
+		for idx, name := range game.Rankings {
+			switch name {
+			case a:
+				places[0] = game.Place(idx)
+			case b:
+				places[1] = game.Place(idx)
+			}
+		}
+		if places[0] == 0 || places[1] == 0 || places[0] == places[1] {
+			continue
+		}
+		if places[0] < places[1] {
+			m.Wins[0]++
+		} else {
+			m.Wins[1]++
+		}
+		m.Games = append(m.Games, MatchupGame{Game: game, Places: places, Wins: m.Wins})
+	}
+	return m
+}
+
+// rivalryHandler returns the handler for rivalry pages at /rivalry/{a}/{b}
+// and their shareable cards at /rivalry/{a}/{b}.svg. Only declared rivalries
+// have pages.
+func rivalryHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/rivalry/")
+		card := strings.HasSuffix(path, ".svg")
+		parts := strings.Split(strings.TrimSuffix(path, ".svg"), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			notFoundRes(w, r)
+			return
+		}
+		a, b := parts[0], parts[1]
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		if !leagueRivals(l, ds)[a][b] {
+			notFoundRes(w, r)
+			return
+		}
+		matchup := buildMatchup(ds.Games, a, b)
+
+		if card {
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Write([]byte(rivalryCard(matchup, ds.Names)))
+			return
+		}
+
+		nameA, nameB := ds.Names.Of(a), ds.Names.Of(b)
+		view := RivalryView{
+			PageView: newPageView(r, nameA+" vs "+nameB,
+				fmt.Sprintf("%s %d, %s %d in %d games.", nameA, matchup.Wins[0], nameB, matchup.Wins[1], len(matchup.Games))),
+			Matchup: matchup,
+			Games:   reverseMatchupGames(matchup.Games),
+			Names:   ds.Names,
+		}
+		view.Meta.Image = siteURL(r) + "/rivalry/" + url.PathEscape(a) + "/" + url.PathEscape(b) + ".svg"
+		t.ExecuteTemplate(w, "rivalry.html.tmpl", view)
+	}
+}
+
+// reverseMatchupGames returns the games most recent first.
+func reverseMatchupGames(games []MatchupGame) []MatchupGame {
+	reversed := make([]MatchupGame, len(games))
+	for idx, g := range games {
+		reversed[len(games)-1-idx] = g
+	}
+	return reversed
+}
+
+// rivalryCard renders the rivalry's tally as an SVG card for sharing.
+func rivalryCard(m *Matchup, names playerNames) string {
+	const width, height = 600, 315
+	players := [2]string{names.Of(m.Players[0]), names.Of(m.Players[1])}
+
+	headline := fmt.Sprintf("%s and %s are all square", players[0], players[1])
+	if leader := m.Leader(); leader >= 0 {
+		headline = fmt.Sprintf("%s owns %s", players[leader], players[1-leader])
+	}
+	if len(m.Games) == 0 {
+		headline = fmt.Sprintf("%s and %s have yet to meet", players[0], players[1])
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" role="img" aria-label="%s">`,
+		width, height, width, height, template.HTMLEscapeString(headline))
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#111"/>`, width, height)
+	fmt.Fprintf(&b, `<text x="%d" y="70" fill="#fff" font-family="sans-serif" font-size="28" text-anchor="middle">%s</text>`,
+		width/2, template.HTMLEscapeString(headline))
+	for idx, x := range []int{width / 4, 3 * width / 4} {
+		fmt.Fprintf(&b, `<text x="%d" y="190" fill="#fff" font-family="sans-serif" font-size="96" font-weight="bold" text-anchor="middle">%d</text>`,
+			x, m.Wins[idx])
+		fmt.Fprintf(&b, `<text x="%d" y="240" fill="#aaa" font-family="sans-serif" font-size="24" text-anchor="middle">%s</text>`,
+			x, template.HTMLEscapeString(players[idx]))
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="190" fill="#aaa" font-family="sans-serif" font-size="48" text-anchor="middle">-</text>`, width/2)
+	fmt.Fprintf(&b, `<text x="%d" y="290" fill="#666" font-family="sans-serif" font-size="16" text-anchor="middle">%d games head to head</text>`,
+		width/2, len(m.Games))
+	b.WriteString(`</svg>`)
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// RuleBlock is a block of the league's rules: a heading, a paragraph or a
+// list.
+type RuleBlock struct {
+	Kind  string          // "heading", "subheading", "paragraph", "list" or "numbered".
+	Lines []template.HTML // the block's text, or each item of a list.
+}
+
+var (
+	ruleLink     = regexp.MustCompile(`\[([^\]]+)\]\(((?:https?://|/|#)[^)\s]*)\)`)
+	ruleBold     = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	ruleItalic   = regexp.MustCompile(`\*(.+?)\*`)
+	ruleCode     = regexp.MustCompile("`(.+?)`")
+	ruleNumbered = regexp.MustCompile(`^\d+[.)]\s+`)
+)
+
+// ruleInline renders the inline formatting of a line of the rules: **bold**,
+// *italic*, `code` and [links](https://example.com). Everything else is
+// escaped.
+func ruleInline(s string) template.HTML {
+	s = html.EscapeString(s)
+	s = ruleCode.ReplaceAllString(s, "<code>$1</code>")
+	s = ruleLink.ReplaceAllString(s, `<a href="$2">$1</a>`)
+	s = ruleBold.ReplaceAllString(s, "<strong>$1</strong>")
+	s = ruleItalic.ReplaceAllString(s, "<em>$1</em>")
+	return template.HTML(s)
+}
+
+// parseRuleRows parses the rules tab, with a line of the rules in the first
+// cell of each row. Lines starting with "#" or "##" are headings, with "-"
+// or "*" bullet points, with "1." numbered points, and every other line is a
+// paragraph.
+func parseRuleRows(values [][]interface{}) []RuleBlock {
+	var blocks []RuleBlock
+	for _, row := range values {
+		if len(row) == 0 {
+			continue
+		}
+		line := strings.TrimSpace(fmt.Sprintf("%s", row[0]))
+		kind := "paragraph"
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "## "):
+			kind, line = "subheading", line[3:]
+		case strings.HasPrefix(line, "# "):
+			kind, line = "heading", line[2:]
+		case strings.HasPrefix(line, "- "), strings.HasPrefix(line, "* "):
+			kind, line = "list", line[2:]
+		case ruleNumbered.MatchString(line):
+			kind, line = "numbered", ruleNumbered.ReplaceAllString(line, "")
+		}
+
+		text := ruleInline(strings.TrimSpace(line))
+		if n := len(blocks); n > 0 && (kind == "list" || kind == "numbered") && blocks[n-1].Kind == kind {
+			blocks[n-1].Lines = append(blocks[n-1].Lines, text)
+			continue
+		}
+		blocks = append(blocks, RuleBlock{Kind: kind, Lines: []template.HTML{text}})
+	}
+	return blocks
+}
+
+// rulesHandler returns the handler for the league's rules page at /rules,
+// from the rules tab of the sheet.
+func rulesHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.ranges.Rules == "" {
+			notFoundRes(w, r)
+			return
+		}
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+
+		t.ExecuteTemplate(w, "rules.html.tmpl", RulesView{
+			PageView: newPageView(r, "Rules", "The league's rules and how games are recorded."),
+			Rules:    ds.Rules,
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// podSize is the pod size predicted pods aim for.
+const podSize = 4
+
+// Poll asks the league which dates they're available for the next league
+// night.
+type Poll struct {
+	ID        int64
+	League    string
+	Title     string
+	Dates     []time.Time
+	CreatedBy string // the ID of the player who created the poll.
+	CreatedAt time.Time
+
+	// Available holds the players available on each date, keyed by
+	// YYYY-MM-DD.
+	Available map[string][]string
+}
+
+// DateAvailability is who's available on one of a poll's dates.
+type DateAvailability struct {
+	Date    time.Time
+	Players []string
+}
+
+// addPoll stores a new poll.
+func (s *store) addPoll(ctx context.Context, p *Poll) error {
+	var dates []string
+	for _, d := range p.Dates {
+		dates = append(dates, d.Format("2006-01-02"))
+	}
+	res, err := s.db.ExecContext(ctx, `INSERT INTO polls (league, title, dates, created_by, created_at) VALUES (?, ?, ?, ?, ?)`,
+		p.League, p.Title, strings.Join(dates, ","), p.CreatedBy, p.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add poll: %w", err)
+	}
+	p.ID, err = res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to add poll: %w", err)
+	}
+	return nil
+}
+
+// polls returns the league's most recent polls, newest first, without their
+// availability.
+func (s *store) polls(ctx context.Context, league string, limit int) ([]*Poll, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, league, title, dates, created_by, created_at
+		FROM polls WHERE league = ? ORDER BY id DESC LIMIT ?`, league, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load polls: %w", err)
+	}
+	defer rows.Close()
+
+	var polls []*Poll
+	for rows.Next() {
+		p, err := scanPoll(rows)
+		if err != nil {
+			return nil, err
+		}
+		polls = append(polls, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load polls: %w", err)
+	}
+	return polls, nil
+}
+
+// poll returns one of the league's polls with its availability.
+func (s *store) poll(ctx context.Context, league string, id int64) (*Poll, error) {
+	p, err := scanPoll(s.db.QueryRowContext(ctx, `SELECT id, league, title, dates, created_by, created_at
+		FROM polls WHERE league = ? AND id = ?`, league, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT date, player FROM poll_availability WHERE poll_id = ? ORDER BY player`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load availability: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var date, player string
+		if err := rows.Scan(&date, &player); err != nil {
+			return nil, fmt.Errorf("failed to load availability: %w", err)
+		}
+		p.Available[date] = append(p.Available[date], player)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load availability: %w", err)
+	}
+	return p, nil
+}
+
+func scanPoll(row interface{ Scan(...interface{}) error }) (*Poll, error) {
+	p := &Poll{Available: map[string][]string{}}
+	var dates string
+	if err := row.Scan(&p.ID, &p.League, &p.Title, &dates, &p.CreatedBy, &p.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to load poll: %w", err)
+	}
+	for _, d := range strings.Split(dates, ",") {
+		date, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q in poll %d", d, p.ID)
+		}
+		p.Dates = append(p.Dates, date)
+	}
+	return p, nil
+}
+
+// setAvailability replaces the dates the player is available for in the poll.
+func (s *store) setAvailability(ctx context.Context, pollID int64, player string, dates []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to save availability: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM poll_availability WHERE poll_id = ? AND player = ?`, pollID, player); err != nil {
+		return fmt.Errorf("failed to save availability: %w", err)
+	}
+	for _, date := range dates {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO poll_availability (poll_id, player, date) VALUES (?, ?, ?)`, pollID, player, date); err != nil {
+			return fmt.Errorf("failed to save availability: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save availability: %w", err)
+	}
+	return nil
+}
+
+// availability returns who's available on each of the poll's dates, in date
+// order.
+func (p *Poll) availability() []DateAvailability {
+	var dates []DateAvailability
+	for _, d := range p.Dates {
+		dates = append(dates, DateAvailability{Date: d, Players: p.Available[d.Format("2006-01-02")]})
+	}
+	return dates
+}
+
+// bestDate returns the date the most players are available, the earliest
+// one on a tie, or false if nobody has marked their availability.
+func (p *Poll) bestDate() (DateAvailability, bool) {
+	var best DateAvailability
+	for _, d := range p.availability() {
+		if len(d.Players) > len(best.Players) {
+			best = d
+		}
+	}
+	return best, len(best.Players) > 0
+}
+
+// predictPods splits the players into pods of about podSize with similar
+// strength, by dealing them out from the highest rated in snake order.
+func predictPods(players []string, ratings map[string]int) [][]string {
+	if len(players) < 2 {
+		return nil
+	}
+	sorted := append([]string(nil), players...)
+	sort.SliceStable(sorted, func(i, j int) bool { return ratings[sorted[i]] > ratings[sorted[j]] })
+	return snakePods(sorted, podCount(len(sorted)))
+}
+
+// podCount returns the number of pods of about podSize the players fit in.
+func podCount(players int) int {
+	n := (players + podSize - 1) / podSize
+	if n > 1 && players/n < 3 {
+		// avoid pods of 2 when fewer, bigger pods fit
+		n--
+	}
+	return n
+}
+
+// snakePods deals the players, highest rated first, into n pods in snake
+// order, so each pod gets a similar spread of ratings.
+func snakePods(sorted []string, n int) [][]string {
+	pods := make([][]string, n)
+	for idx, name := range sorted {
+		pod := idx % n
+		if (idx/n)%2 == 1 {
+			pod = n - 1 - pod
+		}
+		pods[pod] = append(pods[pod], name)
+	}
+	return pods
+}
+
+// parsePollDates parses the dates of a new poll, one YYYY-MM-DD date per
+// line or comma separated.
+func parsePollDates(s string) ([]time.Time, error) {
+	var dates []time.Time
+	seen := map[string]bool{}
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[field] {
+			continue
+		}
+		d, err := time.Parse("2006-01-02", field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q, dates must look like 2024-03-14", field)
+		}
+		seen[field] = true
+		dates = append(dates, d)
+	}
+	if len(dates) == 0 || len(dates) > 14 {
+		return nil, fmt.Errorf("polls need between 1 and 14 dates")
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates, nil
+}
+
+// scheduleHandler returns the handler for the scheduling polls at /schedule,
+// which lists the recent polls and creates new ones, and /schedule/{id}, where
+// logged in players mark the dates they're available. Each poll shows the
+// best date and the pods predicted from who's available and their ratings.
+func scheduleHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.polls == nil {
+			notFoundRes(w, r)
+			return
+		}
+		user := l.currentPlayer(r)
+		if r.Method == http.MethodPost && user == "" {
+			unauthorizedRes(w, r, "log in to create polls and mark your availability")
+			return
+		}
+		view := ScheduleView{PageView: barePageView(r), User: user, Path: r.URL.Path}
+
+		id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/schedule"), "/")
+		if id == "" {
+			if r.Method == http.MethodPost {
+				title := strings.TrimSpace(r.PostFormValue("title"))
+				dates, err := parsePollDates(r.PostFormValue("dates"))
+				if err == nil && (title == "" || len(title) > 100) {
+					err = fmt.Errorf("title must be between 1 and 100 characters")
+				}
+				if err == nil {
+					p := &Poll{League: l.snapshotKey(), Title: title, Dates: dates, CreatedBy: user, CreatedAt: l.clock.Now().UTC()}
+					if err := l.polls.addPoll(r.Context(), p); err != nil {
+						log.Printf("error adding poll: %+v", err)
+						errorRes(w, r, err)
+						return
+					}
+					http.Redirect(w, r, fmt.Sprintf("%s/schedule/%d", basePath(r), p.ID), http.StatusSeeOther)
+					return
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				view.Errors = err.Error()
+				view.Title = title
+				view.DatesInput = r.PostFormValue("dates")
+			}
+			polls, err := l.polls.polls(r.Context(), l.snapshotKey(), 20)
+			if err != nil {
+				log.Printf("error loading polls: %+v", err)
+				errorRes(w, r, err)
+				return
+			}
+			view.Polls = polls
+			t.ExecuteTemplate(w, "schedule.html.tmpl", view)
+			return
+		}
+
+		pollID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			notFoundRes(w, r)
+			return
+		}
+		p, err := l.polls.poll(r.Context(), l.snapshotKey(), pollID)
+		switch {
+		case errors.Is(err, errNotFound):
+			notFoundRes(w, r)
+			return
+		case err != nil:
+			log.Printf("error loading poll: %+v", err)
+			errorRes(w, r, err)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			r.ParseForm()
+			var dates []string
+			for _, d := range p.Dates {
+				date := d.Format("2006-01-02")
+				for _, v := range r.PostForm["date"] {
+					if v == date {
+						dates = append(dates, date)
+					}
+				}
+			}
+			if err := l.polls.setAvailability(r.Context(), p.ID, user, dates); err != nil {
+				log.Printf("error saving availability: %+v", err)
+				errorRes(w, r, err)
+				return
+			}
+			http.Redirect(w, r, fmt.Sprintf("%s/schedule/%d", basePath(r), p.ID), http.StatusSeeOther)
+			return
+		}
+
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		mine := map[string]bool{}
+		for date, players := range p.Available {
+			for _, name := range players {
+				if name == user {
+					mine[date] = true
+				}
+			}
+		}
+		view.Poll = p
+		view.Dates = p.availability()
+		view.Mine = mine
+		if best, ok := p.bestDate(); ok {
+			view.Best = &best
+			scores := calculateScores(ds.Games, ds.Adjustments...)
+			ratings := map[string]int{}
+			for _, name := range best.Players {
+				ratings[name] = replacementRating
+				if score, ok := scores[ds.playerID(name)]; ok {
+					ratings[name] = score
+				}
+			}
+			view.Pods = predictPods(best.Players, ratings)
+			view.Ratings = ratings
+		}
+		t.ExecuteTemplate(w, "schedule.html.tmpl", view)
+	}
+}
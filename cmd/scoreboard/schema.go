@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/sheets"
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// schemaSampleRows is the number of games sampled to infer what a column of
+// the game log holds.
+const schemaSampleRows = 50
+
+// The roles a game log column can play.
+const (
+	roleID          = "id"
+	roleDate        = "date"
+	roleZap         = "zap"
+	roleDraw        = "draw"
+	roleNotes       = "notes"
+	rolePlayer      = "player"
+	roleElimination = "elimination"
+	roleDeck        = "deck"
+	roleTags        = "tags"
+	roleFirstBlood  = "first blood"
+	roleFirstOut    = "first out"
+	roleKingmaker   = "kingmaker"
+)
+
+// headerRoles are the roles recognized from words in a column's label, in the
+// order they're checked, so "Eliminated 1st" is an elimination column rather
+// than a player column.
+var headerRoles = []struct {
+	role  string
+	words []string
+}{
+	{roleFirstBlood, []string{"first blood", "blood"}},
+	{roleFirstOut, []string{"first out", "first elim"}},
+	{roleKingmaker, []string{"kingmak"}},
+	{roleElimination, []string{"elim", "knocked", "out at", "time out"}},
+	{roleDeck, []string{"deck", "archetype", "commander"}},
+	{roleTags, []string{"tag"}},
+	{roleZap, []string{"zap"}},
+	{roleDraw, []string{"draw"}},
+	{roleNotes, []string{"note", "comment"}},
+	{roleDate, []string{"date", "when", "played on"}},
+	{rolePlayer, []string{"1st", "2nd", "3rd", "4th", "5th", "6th", "player", "place", "winner", "first", "second", "third", "fourth"}},
+	{roleID, []string{"game", "id", "#", "no."}},
+}
+
+// ColumnMismatch is a game log column that looks like it holds something
+// other than what the parser reads from it, like dates in a player column
+// after a column was inserted in the sheet.
+type ColumnMismatch struct {
+	Column   string `json:"column"` // the column's letter, like "B".
+	Header   string `json:"header"`
+	Expected string `json:"expected"` // the role the parser reads the column as.
+	Inferred string `json:"inferred"` // the role the column's label and contents suggest.
+}
+
+func (m ColumnMismatch) String() string {
+	return fmt.Sprintf("column %s (%q) is read as %s but looks like %s", m.Column, m.Header, m.Expected, m.Inferred)
+}
+
+// expectedRole returns the role the parser reads the column at idx as.
+func expectedRole(idx int) string {
+	switch {
+	case idx == 0:
+		return roleID
+	case idx == 1:
+		return roleDate
+	case idx == 2:
+		return roleZap
+	case idx == 3:
+		return roleDraw
+	case idx == 4:
+		return roleNotes
+	case idx < eliminationColumn:
+		return rolePlayer
+	case idx < deckColumn:
+		return roleElimination
+	case idx < tagsColumn:
+		return roleDeck
+	case idx == tagsColumn:
+		return roleTags
+	case idx == firstBloodColumn:
+		return roleFirstBlood
+	case idx == firstOutColumn:
+		return roleFirstOut
+	case idx == kingmakerColumn:
+		return roleKingmaker
+	}
+	return ""
+}
+
+// headerRole returns the role a column's label suggests, or "" if the label
+// isn't recognized.
+func headerRole(label string) string {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if label == "" {
+		return ""
+	}
+	for _, hr := range headerRoles {
+		for _, word := range hr.words {
+			if strings.Contains(label, word) {
+				return hr.role
+			}
+		}
+	}
+	return ""
+}
+
+// contentRole returns the role a column's sampled cells clearly belong to:
+// dates, clock times or game numbers. Text could be names, notes, decks or
+// tags, so it returns "" for anything else.
+func contentRole(cells []string) string {
+	if len(cells) == 0 {
+		return ""
+	}
+	dates, times, numbers, flags := 0, 0, 0, 0
+	for _, cell := range cells {
+		if _, err := parseGameDate(cell); err == nil {
+			dates++
+		}
+		for _, layout := range eliminationFormats {
+			if _, err := time.Parse(layout, cell); err == nil {
+				times++
+				break
+			}
+		}
+		if _, err := strconv.Atoi(cell); err == nil {
+			numbers++
+			if cell == "0" || cell == "1" {
+				flags++
+			}
+		}
+	}
+	switch len(cells) {
+	case dates:
+		return roleDate
+	case times:
+		return roleElimination
+	case numbers:
+		// checkbox columns hold 0s and 1s
+		if flags < numbers {
+			return roleID
+		}
+	}
+	return ""
+}
+
+// inferRole returns the role of a column from its label and sampled cells.
+// Contents that are clearly dates, times or numbers win over a label naming
+// a text column, since that's the mistake that would otherwise be parsed
+// silently.
+func inferRole(label string, cells []string) string {
+	fromHeader := headerRole(label)
+	fromContent := contentRole(cells)
+	switch fromHeader {
+	case "", rolePlayer, roleNotes, roleDeck, roleTags, roleFirstBlood, roleFirstOut, roleKingmaker:
+		if fromContent != "" {
+			return fromContent
+		}
+	}
+	return fromHeader
+}
+
+// compatibleRoles reports whether a column inferred as one role can be read
+// as the expected role. Elimination times can be full dates.
+func compatibleRoles(expected, inferred string) bool {
+	return inferred == "" || inferred == expected || (expected == roleElimination && inferred == roleDate)
+}
+
+// schemaDiff infers the role of each column of the game log from its label
+// and the first schemaSampleRows games, and returns the columns whose role
+// doesn't match what the parser expects.
+func schemaDiff(values [][]interface{}) []ColumnMismatch {
+	if len(values) == 0 {
+		return nil
+	}
+	width := len(values[0])
+	for _, row := range values {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+
+	var mismatches []ColumnMismatch
+	for col := 0; col < width && col <= kingmakerColumn; col++ {
+		label, cells := sampleColumn(values, col)
+		expected := expectedRole(col)
+		if inferred := inferRole(label, cells); !compatibleRoles(expected, inferred) {
+			mismatches = append(mismatches, ColumnMismatch{
+				Column:   sheets.ColumnLetter(col),
+				Header:   label,
+				Expected: expected,
+				Inferred: inferred,
+			})
+		}
+	}
+	return mismatches
+}
+
+// sampleColumn returns the label of the game log column at col and its
+// non-empty cells in the first schemaSampleRows games.
+func sampleColumn(values [][]interface{}, col int) (string, []string) {
+	label := ""
+	if col < len(values[0]) {
+		label = strings.TrimSpace(fmt.Sprintf("%s", values[0][col]))
+	}
+	var cells []string
+	for _, row := range values[1:minInt(len(values), schemaSampleRows+1)] {
+		if col < len(row) {
+			if cell := strings.TrimSpace(fmt.Sprintf("%s", row[col])); cell != "" {
+				cells = append(cells, cell)
+			}
+		}
+	}
+	return label, cells
+}
+
+// schemaHandler returns the admin handler that checks the game log's layout
+// against the columns the parser expects, at /admin/schema.
+func schemaHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		values, err := sheets.FetchRows(r.Context(), l.spreadsheetID, l.ranges.Games, l.opts...)
+		if err != nil {
+			log.Printf("error fetching game log for schema check: %+v", err)
+			errorRes(w, r, err)
+			return
+		}
+		mismatches := schemaDiff(values)
+		if mismatches == nil {
+			mismatches = []ColumnMismatch{}
+		}
+		web.WriteJSON(w, map[string]interface{}{
+			"ok":         len(mismatches) == 0,
+			"mismatches": mismatches,
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Season is a named span of the league's history. Ratings start fresh at the
+// beginning of each season.
+type Season struct {
+	Name  string
+	Start time.Time // the first instant of the season.
+	End   time.Time // the first instant after the season, zero if ongoing.
+}
+
+// Contains reports whether t falls within the season.
+func (s Season) Contains(t time.Time) bool {
+	return !t.Before(s.Start) && (s.End.IsZero() || t.Before(s.End))
+}
+
+// configuredSeasons are the seasons set with SCOREBOARD_SEASONS. When none are
+// configured each calendar year is a season.
+var configuredSeasons []Season
+
+// parseSeasons parses a comma separated list of seasons and their start
+// dates, e.g. "Season 1=2022-01-01,Season 2=2022-09-01". Each season ends when
+// the next one starts.
+func parseSeasons(s string) ([]Season, error) {
+	var pairs [][2]string
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("expected name=YYYY-MM-DD, got %q", entry)
+		}
+		pairs = append(pairs, [2]string{parts[0], parts[1]})
+	}
+	return buildSeasons(pairs)
+}
+
+// parseSeasonRows parses the rows of a seasons tab, with the season name in
+// the first column and its YYYY-MM-DD start date in the second. The first row
+// holds the labels.
+func parseSeasonRows(values [][]interface{}) ([]Season, error) {
+	var pairs [][2]string
+	for idx, row := range values {
+		if idx == 0 || len(row) < 2 {
+			continue
+		}
+		pairs = append(pairs, [2]string{fmt.Sprintf("%s", row[0]), fmt.Sprintf("%s", row[1])})
+	}
+	return buildSeasons(pairs)
+}
+
+// buildSeasons builds seasons from name and start date pairs in order. Each
+// season ends when the next one starts.
+func buildSeasons(pairs [][2]string) ([]Season, error) {
+	var seasons []Season
+	for _, pair := range pairs {
+		name := strings.TrimSpace(pair[0])
+		start, err := time.Parse("2006-01-02", strings.TrimSpace(pair[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid start date for season %q: %w", name, err)
+		}
+		if n := len(seasons); n > 0 {
+			if !start.After(seasons[n-1].Start) {
+				return nil, fmt.Errorf("season %q must start after %q", name, seasons[n-1].Name)
+			}
+			seasons[n-1].End = start
+		}
+		seasons = append(seasons, Season{Name: name, Start: start})
+	}
+	return seasons, nil
+}
+
+// leagueSeasons returns the seasons that have games, in order. The given
+// seasons, usually from the league's seasons tab, take precedence over
+// configured seasons.
+func leagueSeasons(games []*Game, seasons []Season) []Season {
+	candidates := seasons
+	if len(candidates) == 0 {
+		candidates = configuredSeasons
+	}
+	if len(candidates) == 0 {
+		candidates = calendarSeasons(games)
+	}
+
+	var played []Season
+	for _, s := range candidates {
+		if len(gamesInSeason(games, s)) > 0 {
+			played = append(played, s)
+		}
+	}
+	return played
+}
+
+// calendarSeasons returns a season for each calendar year spanned by games.
+func calendarSeasons(games []*Game) []Season {
+	first, last := 0, 0
+	for _, g := range games {
+		if g.Timestamp.IsZero() {
+			continue
+		}
+		year := g.Timestamp.UTC().Year()
+		if first == 0 || year < first {
+			first = year
+		}
+		if year > last {
+			last = year
+		}
+	}
+
+	var seasons []Season
+	for year := first; first != 0 && year <= last; year++ {
+		seasons = append(seasons, Season{
+			Name:  fmt.Sprintf("%d", year),
+			Start: time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC),
+			End:   time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC),
+		})
+	}
+	return seasons
+}
+
+// gamesInSeason returns copies of the games played during the season, so
+// they can be scored without overwriting the all-time results.
+func gamesInSeason(games []*Game, s Season) []*Game {
+	var season []*Game
+	for _, g := range games {
+		if !g.Timestamp.IsZero() && s.Contains(g.Timestamp) {
+			season = append(season, g)
+		}
+	}
+	return copyGames(season)
+}
+
+// copyGames returns shallow copies of the games with their results cleared.
+func copyGames(games []*Game) []*Game {
+	copies := make([]*Game, len(games))
+	for i, g := range games {
+		c := *g
+		c.Results = nil
+		copies[i] = &c
+	}
+	return copies
+}
+
+// copyAdjustments returns copies of the adjustments, so scoring them doesn't
+// overwrite the ratings recorded on the originals.
+func copyAdjustments(adjustments []*Adjustment) []*Adjustment {
+	copies := make([]*Adjustment, len(adjustments))
+	for i, adj := range adjustments {
+		a := *adj
+		copies[i] = &a
+	}
+	return copies
+}
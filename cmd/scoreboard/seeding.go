@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// Seed is a player's seed in a tournament bracket, from their league rating.
+type Seed struct {
+	Seed   int    `json:"seed"`
+	Player string `json:"player"` // the player's ID.
+	Name   string `json:"name"`
+	Rating int    `json:"rating"`
+	Games  int    `json:"games"`
+}
+
+// seedingFormats are the formats the seeding export is written in, with their
+// content type and file extension.
+var seedingFormats = map[string][2]string{
+	"csv":       {"text/csv; charset=utf-8", ".csv"},
+	"challonge": {"text/plain; charset=utf-8", ".txt"},
+	"wer":       {"text/tab-separated-values; charset=utf-8", ".tsv"},
+	"json":      {"application/json", ".json"},
+}
+
+// tournamentSeeding seeds the ranked players in rating order, leaving out
+// those with fewer than minGames games and keeping the top players, or all of
+// them if top is 0.
+func tournamentSeeding(rankings []Player, minGames, top int) []Seed {
+	var seeds []Seed
+	for _, p := range rankings {
+		if p.Games < minGames {
+			continue
+		}
+		if top > 0 && len(seeds) == top {
+			break
+		}
+		seeds = append(seeds, Seed{Seed: len(seeds) + 1, Player: p.ID, Name: p.Name, Rating: p.Score, Games: p.Games})
+	}
+	return seeds
+}
+
+// writeSeeding writes the seeds in the format:
+//   - csv, with a header row of seed, player, name, rating and games.
+//   - challonge, a name per line in seed order, which Challonge's bulk add
+//     seeds participants by.
+//   - wer, tab-separated seed, name, rating and games with a header row, like
+//     the player lists event reporting software imports.
+func writeSeeding(w http.ResponseWriter, seeds []Seed, format string) {
+	switch format {
+	case "json":
+		web.WriteJSON(w, map[string]interface{}{"version": version, "seeds": seeds})
+		return
+	case "challonge":
+		for _, s := range seeds {
+			// a comma would start Challonge's email or username field
+			fmt.Fprintln(w, strings.ReplaceAll(s.Name, ",", ""))
+		}
+		return
+	}
+
+	cw := csv.NewWriter(w)
+	if format == "wer" {
+		cw.Comma = '\t'
+		cw.Write([]string{"Seed", "Name", "Rating", "Games"})
+	} else {
+		cw.Write([]string{"seed", "player", "name", "rating", "games"})
+	}
+	for _, s := range seeds {
+		row := []string{strconv.Itoa(s.Seed), s.Player, s.Name, strconv.Itoa(s.Rating), strconv.Itoa(s.Games)}
+		if format == "wer" {
+			row = append(row[:1], row[2:]...)
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+}
+
+// seedingHandler returns the handler for the seeding export at
+// /api/seeding, which seeds a tournament bracket by league rating. It takes
+// a season like the eras page to seed by the season's ratings, min for the
+// games a player needs to qualify, top for the number of seeds, and format.
+func seedingHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fail := func(msg string) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			web.WriteJSON(w, map[string]interface{}{"error": msg})
+		}
+		q := r.URL.Query()
+		format := q.Get("format")
+		if format == "" {
+			format = "csv"
+		}
+		if _, ok := seedingFormats[format]; !ok {
+			fail("format must be csv, challonge, wer or json")
+			return
+		}
+		var minGames, top int
+		for _, param := range []struct {
+			name string
+			n    *int
+		}{{"min", &minGames}, {"top", &top}} {
+			if v := q.Get(param.name); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 0 {
+					fail(fmt.Sprintf("invalid %s %q, it must be a whole number", param.name, v))
+					return
+				}
+				*param.n = n
+			}
+		}
+
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		games, adjustments := ds.Games, ds.Adjustments
+		if v := q.Get("season"); v != "" {
+			season, err := parseEra(v, leagueSeasons(ds.Games, ds.Seasons))
+			if err != nil {
+				fail(err.Error())
+				return
+			}
+			games, adjustments = gamesInSeason(games, season), adjustmentsInSeason(adjustments, season)
+		}
+		rankings := rankPlayers(games, calculateScores(games, adjustments...), l.clock.Now())
+		ds.Names.apply(rankings)
+		seeds := tournamentSeeding(rankings, minGames, top)
+
+		w.Header().Set("Content-Type", seedingFormats[format][0])
+		if format != "json" {
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="seeding%s"`, seedingFormats[format][1]))
+		}
+		writeSeeding(w, seeds, format)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseSeedRows parses the seeds tab, with a player and the rating they start
+// at in the first two columns. The first row holds the labels. Seeds are
+// returned as adjustments so they're resolved and scored along with them.
+func parseSeedRows(values [][]interface{}) ([]*Adjustment, error) {
+	var seeds []*Adjustment
+	for idx, row := range values {
+		if idx == 0 || len(row) < 2 {
+			continue
+		}
+		player := strings.TrimSpace(fmt.Sprintf("%s", row[0]))
+		if player == "" {
+			continue
+		}
+		rating, err := strconv.Atoi(strings.TrimSpace(fmt.Sprintf("%s", row[1])))
+		if err != nil || rating < 1 {
+			return nil, fmt.Errorf("row %d: invalid rating %q", idx+1, row[1])
+		}
+		seeds = append(seeds, &Adjustment{Player: player, Amount: rating, Reason: "seeded rating", Seed: true})
+	}
+	return seeds, nil
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fly-apps/go-example/scoring"
+	elogo "github.com/kortemy/elo-go"
+)
+
+var (
+	// kFactor is the elo engine's K-factor, the most a rating can move in a
+	// game before upsets are amplified.
+	kFactor = elogo.K
+	// startingRating is the rating players start at in the elo engine.
+	startingRating = 1500
+	// rewardCurveShape is the shape of the reward curves: "default" for the
+	// league's original curves, "linear" to split the rewards evenly between
+	// the places, or "winner" to only reward the winner.
+	rewardCurveShape = "default"
+)
+
+// rewardCurveShapes are the accepted values of rewardCurveShape.
+var rewardCurveShapes = scoring.Curves
+
+// scoringVariant is a set of elo parameters the league's history can be
+// scored under.
+type scoringVariant struct {
+	Name   string
+	K      int
+	Start  int
+	Curve  string
+	Upsets []upsetStep
+}
+
+// currentScoring returns the parameters games are currently scored with.
+func currentScoring() scoringVariant {
+	return scoringVariant{Name: "current", K: kFactor, Start: startingRating, Curve: rewardCurveShape, Upsets: upsetCurve}
+}
+
+// scoringFromEnv returns the current parameters with any overrides from
+// SCOREBOARD_K_FACTOR, SCOREBOARD_STARTING_RATING, SCOREBOARD_REWARD_CURVE and
+// SCOREBOARD_UPSET_CURVE applied.
+func scoringFromEnv() (scoringVariant, error) {
+	v := currentScoring()
+	if s := os.Getenv("SCOREBOARD_K_FACTOR"); s != "" {
+		k, err := strconv.Atoi(s)
+		if err != nil || k < 1 {
+			return v, fmt.Errorf("invalid SCOREBOARD_K_FACTOR: %q", s)
+		}
+		v.K = k
+	}
+	if s := os.Getenv("SCOREBOARD_STARTING_RATING"); s != "" {
+		start, err := strconv.Atoi(s)
+		if err != nil || start < 1 {
+			return v, fmt.Errorf("invalid SCOREBOARD_STARTING_RATING: %q", s)
+		}
+		v.Start = start
+	}
+	if s := os.Getenv("SCOREBOARD_REWARD_CURVE"); s != "" {
+		if !validCurveShape(s) {
+			return v, fmt.Errorf("invalid SCOREBOARD_REWARD_CURVE %q, expected one of %s", s, strings.Join(rewardCurveShapes, ", "))
+		}
+		v.Curve = s
+	}
+	if s := os.Getenv("SCOREBOARD_UPSET_CURVE"); s != "" {
+		curve, err := parseUpsetCurve(s)
+		if err != nil {
+			return v, fmt.Errorf("invalid SCOREBOARD_UPSET_CURVE: %w", err)
+		}
+		v.Upsets = curve
+	}
+	return v, nil
+}
+
+func validCurveShape(s string) bool {
+	for _, shape := range rewardCurveShapes {
+		if s == shape {
+			return true
+		}
+	}
+	return false
+}
+
+// apply makes the variant the parameters games are scored with. The scoring
+// parameters are global, so variants are only applied at startup, never
+// while serving; analyses score with the variant's config instead.
+func (v scoringVariant) apply() {
+	kFactor = v.K
+	startingRating = v.Start
+	rewardCurveShape = v.Curve
+	upsetCurve = v.Upsets
+}
+
+// config returns the elo engine's parameters under the variant.
+func (v scoringVariant) config() scoring.Config {
+	return scoring.Config{
+		K:                   v.K,
+		Start:               v.Start,
+		Curve:               v.Curve,
+		Upsets:              v.Upsets,
+		ArchenemyMultiplier: archenemyMultiplier,
+		ZeroSum:             zeroSum,
+	}
+}
+
+// StandingChange is a player's place in the final standings under the
+// current parameters and under a variant.
+type StandingChange struct {
+	Name          string
+	Rank, Rating  int // under the current parameters.
+	VariantRank   int
+	VariantRating int
+}
+
+// Moved returns how many places the player moved under the variant, positive
+// when they moved up.
+func (c StandingChange) Moved() int {
+	return c.Rank - c.VariantRank
+}
+
+// SensitivityResult diffs the final standings under a variant against the
+// current parameters.
+type SensitivityResult struct {
+	Variant scoringVariant
+	Changes []StandingChange // in the order of the current standings.
+}
+
+// finalStandings scores a copy of the dataset with the elo config and returns
+// the final standings as of now. The dataset isn't modified, so standings
+// under different configs can be computed at once.
+func finalStandings(ds *Dataset, config scoring.Config, now time.Time) []Player {
+	c := ds.copy()
+	scores, _ := scoring.Score(scoring.NewElo(config), c.Games, c.Adjustments...)
+	rankings := rankPlayers(c.Games, scores, now)
+	c.Names.apply(rankings)
+	return rankings
+}
+
+// sensitivity recomputes the full history under each variant, in parallel,
+// and diffs the final standings as of now against the current parameters.
+func sensitivity(ds *Dataset, variants []scoringVariant, now time.Time) []SensitivityResult {
+	baseline := finalStandings(ds, scoringConfig(), now)
+
+	standings := make([][]Player, len(variants))
+	var wg sync.WaitGroup
+	for idx, v := range variants {
+		wg.Add(1)
+		go func(idx int, v scoringVariant) {
+			defer wg.Done()
+			standings[idx] = finalStandings(ds, v.config(), now)
+		}(idx, v)
+	}
+	wg.Wait()
+
+	results := make([]SensitivityResult, 0, len(variants))
+	for idx, v := range variants {
+		ranks := map[string]int{}
+		ratings := map[string]int{}
+		for rank, p := range standings[idx] {
+			ranks[p.ID] = rank + 1
+			ratings[p.ID] = p.Score
+		}
+		res := SensitivityResult{Variant: v}
+		for rank, p := range baseline {
+			res.Changes = append(res.Changes, StandingChange{
+				Name:          p.Name,
+				Rank:          rank + 1,
+				Rating:        p.Score,
+				VariantRank:   ranks[p.ID],
+				VariantRating: ratings[p.ID],
+			})
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// sensitivityVariants returns a variant for each alternative value, changing
+// one parameter from the current ones at a time.
+func sensitivityVariants(ks, starts, curves, upsets string) ([]scoringVariant, error) {
+	var variants []scoringVariant
+	for _, s := range splitList(ks, ",") {
+		k, err := strconv.Atoi(s)
+		if err != nil || k < 1 {
+			return nil, fmt.Errorf("invalid K-factor %q", s)
+		}
+		v := currentScoring()
+		v.Name, v.K = "K = "+s, k
+		variants = append(variants, v)
+	}
+	for _, s := range splitList(starts, ",") {
+		start, err := strconv.Atoi(s)
+		if err != nil || start < 1 {
+			return nil, fmt.Errorf("invalid starting rating %q", s)
+		}
+		v := currentScoring()
+		v.Name, v.Start = "starting rating "+s, start
+		variants = append(variants, v)
+	}
+	for _, s := range splitList(curves, ",") {
+		if !validCurveShape(s) {
+			return nil, fmt.Errorf("invalid reward curve %q, expected one of %s", s, strings.Join(rewardCurveShapes, ", "))
+		}
+		v := currentScoring()
+		v.Name, v.Curve = s+" reward curve", s
+		variants = append(variants, v)
+	}
+	for _, s := range splitList(upsets, ";") {
+		curve, err := parseUpsetCurve(s)
+		if err != nil {
+			return nil, err
+		}
+		v := currentScoring()
+		v.Name, v.Upsets = "upset curve "+s, curve
+		if s == "none" {
+			v.Upsets = nil
+		}
+		variants = append(variants, v)
+	}
+	return variants, nil
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func splitList(s, sep string) []string {
+	var items []string
+	for _, item := range strings.Split(s, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// printSensitivityReport writes a summary of how far each variant moves the
+// standings, followed by the players whose place changed.
+func printSensitivityReport(w io.Writer, results []SensitivityResult) {
+	for idx, res := range results {
+		if idx > 0 {
+			fmt.Fprintln(w)
+		}
+		var moved []StandingChange
+		largest := 0
+		for _, c := range res.Changes {
+			if c.Moved() != 0 {
+				moved = append(moved, c)
+			}
+			if absInt(c.Moved()) > largest {
+				largest = absInt(c.Moved())
+			}
+		}
+		leader := "leader unchanged"
+		for _, c := range res.Changes {
+			if c.VariantRank == 1 && c.Rank != 1 {
+				leader = "new leader " + c.Name
+			}
+		}
+		fmt.Fprintf(w, "%s: %d of %d players move, by up to %d places, %s\n", res.Variant.Name, len(moved), len(res.Changes), largest, leader)
+		if len(moved) == 0 {
+			continue
+		}
+
+		sort.SliceStable(moved, func(i, j int) bool { return absInt(moved[i].Moved()) > absInt(moved[j].Moved()) })
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  player\trank\trating\tvariant rank\tvariant rating\tmoved")
+		for _, c := range moved {
+			fmt.Fprintf(tw, "  %s\t%d\t%d\t%d\t%d\t%+d\n", c.Name, c.Rank, c.Rating, c.VariantRank, c.VariantRating, c.Moved())
+		}
+		tw.Flush()
+	}
+}
+
+// sensitivityCommand recomputes the league's history under alternative elo
+// parameters and prints how the final standings change, to help tune the
+// parameters for the next season.
+func sensitivityCommand(args []string) int {
+	fs := flag.NewFlagSet("sensitivity", flag.ContinueOnError)
+	sheetID := fs.String("sheet", spreadsheetID, "ID of the spreadsheet to analyze")
+	sheetRange := fs.String("range", readRange, "range of the game log tab")
+	ks := fs.String("k", "24,40", "comma separated K-factors to try")
+	starts := fs.String("start", "1200,1800", "comma separated starting ratings to try")
+	curves := fs.String("curve", "linear,winner", "comma separated reward curves to try: "+strings.Join(rewardCurveShapes, ", "))
+	upsets := fs.String("upsets", "", `semicolon separated upset curves to try, e.g. "100:1.25,200:1.5;none"`)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if ratingEngine != "elo" {
+		fmt.Fprintln(os.Stderr, "sensitivity: only the elo engine has parameters to tune")
+		return 1
+	}
+	variants, err := sensitivityVariants(*ks, *starts, *curves, *upsets)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sensitivity: %s\n", err)
+		return 2
+	}
+
+	opts, err := sheetsOptionFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sensitivity: %s\n", err)
+		return 1
+	}
+	l := newLeague(*sheetID, *sheetRange, opts)
+	l.configureRangesFromEnv()
+	ds, err := l.fetch(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sensitivity: %s\n", err)
+		return 1
+	}
+	sort.Sort(ByID(ds.Games))
+
+	printSensitivityReport(os.Stdout, sensitivity(ds, variants, l.clock.Now()))
+	return 0
+}
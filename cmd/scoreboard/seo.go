@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// publicURL is the URL the scoreboard is publicly served at, e.g.
+// https://scoreboard.example.com, used for canonical links, the sitemap and
+// link previews. It's configured with SCOREBOARD_PUBLIC_URL and, when unset,
+// taken from each request's host.
+var publicURL = strings.TrimSuffix(os.Getenv("SCOREBOARD_PUBLIC_URL"), "/")
+
+// noIndex asks search engines not to index any page, configured with
+// SCOREBOARD_NOINDEX for leagues that want to stay private.
+var noIndex = isMarked(os.Getenv("SCOREBOARD_NOINDEX"))
+
+// absoluteURL returns the public URL of a path for links sent outside of a
+// request, like in emails and webhooks, or "" if SCOREBOARD_PUBLIC_URL isn't
+// set.
+func absoluteURL(path string) string {
+	if publicURL == "" {
+		return ""
+	}
+	return publicURL + path
+}
+
+// siteURL returns the absolute URL of the request's league, as the client
+// requested it through any trusted proxies.
+func siteURL(r *http.Request) string {
+	if publicURL != "" {
+		return publicURL + basePath(r)
+	}
+	return web.RequestScheme(r) + "://" + web.RequestHost(r) + basePath(r)
+}
+
+// PageMeta is the canonical link and OpenGraph tags of a public page, which
+// chat apps use to unfurl shared links.
+type PageMeta struct {
+	Title       string
+	Description string
+	URL         string // the canonical URL of the page, without query parameters.
+	Image       string // the preview image, the top 3 of the leaderboard.
+	NoIndex     bool
+	Banner      string // the maintenance mode banner, empty when it's off.
+	Dark        bool   // whether the visitor prefers the dark theme.
+}
+
+// pageMeta returns the meta tags for the page at the request's path.
+func pageMeta(r *http.Request, title, description string) PageMeta {
+	site := siteURL(r)
+	return PageMeta{
+		Title:       title,
+		Description: description,
+		URL:         site + r.URL.Path,
+		Image:       site + "/snapshot.png?top=3",
+		NoIndex:     noIndex,
+		Banner:      maintenanceBanner(r),
+		Dark:        requestPreferences(r).Dark,
+	}
+}
+
+// robotsHandler serves /robots.txt, which keeps crawlers to the public pages
+// and points them at the sitemap.
+func robotsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	base := basePath(r)
+	fmt.Fprintln(w, "User-agent: *")
+	if noIndex {
+		fmt.Fprintln(w, "Disallow: /")
+		return
+	}
+	for _, path := range []string{"/api/", "/admin/", "/login", "/logout", "/live", "/comments", "/picks", "/game/*/explain"} {
+		fmt.Fprintf(w, "Disallow: %s%s\n", base, path)
+	}
+	fmt.Fprintf(w, "Allow: %s/\n", base)
+	fmt.Fprintf(w, "Sitemap: %s/sitemap.xml\n", siteURL(r))
+}
+
+// gameSummary describes the result of a game for its link preview.
+func gameSummary(g *Game, names playerNames) string {
+	var players []string
+	for _, name := range g.Rankings {
+		players = append(players, names.Of(name))
+	}
+	if len(g.Teams) > 0 {
+		players = nil
+		for _, team := range g.Teams {
+			var teammates []string
+			for _, name := range team {
+				teammates = append(teammates, names.Of(name))
+			}
+			players = append(players, strings.Join(teammates, " and "))
+		}
+	}
+	switch {
+	case len(players) == 0:
+		return "Game " + g.ID
+	case g.IsDraw():
+		return fmt.Sprintf("A draw between %s on %s.", strings.Join(players, ", "), shortDate(g.Timestamp))
+	}
+	return fmt.Sprintf("%s won against %s on %s.", players[0], strings.Join(players[1:], ", "), shortDate(g.Timestamp))
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// sitemapDate formats a date for the sitemap, or "" if it's unknown.
+func sitemapDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+type sitemap struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// sitemapHandler returns the handler for /sitemap.xml, which lists the
+// leaderboard, the rules and every game, player, career and rivalry page.
+func sitemapHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if noIndex {
+			notFoundRes(w, r)
+			return
+		}
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+
+		site := siteURL(r)
+		sm := sitemap{URLs: []sitemapURL{{Loc: site + "/"}}}
+		var players []string
+		lastPlayed := map[string]time.Time{}
+		var latest time.Time
+		for _, g := range ds.Games {
+			sm.URLs = append(sm.URLs, sitemapURL{Loc: site + "/game/" + url.PathEscape(g.ID), LastMod: sitemapDate(g.Timestamp)})
+			if g.Timestamp.After(latest) {
+				latest = g.Timestamp
+			}
+			for _, name := range g.Rankings {
+				if _, ok := lastPlayed[name]; !ok {
+					players = append(players, name)
+				}
+				if g.Timestamp.After(lastPlayed[name]) {
+					lastPlayed[name] = g.Timestamp
+				}
+			}
+		}
+		sm.URLs[0].LastMod = sitemapDate(latest)
+		if l.ranges.Rules != "" {
+			sm.URLs = append(sm.URLs, sitemapURL{Loc: site + "/rules"})
+		}
+		for _, name := range players {
+			lastmod := sitemapDate(lastPlayed[name])
+			sm.URLs = append(sm.URLs,
+				sitemapURL{Loc: site + "/player/" + url.PathEscape(name), LastMod: lastmod},
+				sitemapURL{Loc: site + "/career/" + url.PathEscape(name), LastMod: lastmod})
+		}
+		rivals := leagueRivals(l, ds)
+		for _, a := range sortedNames(setOf(rivals)) {
+			for _, b := range sortedNames(rivals[a]) {
+				sm.URLs = append(sm.URLs, sitemapURL{Loc: site + "/rivalry/" + url.PathEscape(a) + "/" + url.PathEscape(b)})
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.Write([]byte(xml.Header))
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		enc.Encode(sm)
+	}
+}
+
+// setOf returns the keys of the map as a set.
+func setOf(m map[string]map[string]bool) map[string]bool {
+	set := make(map[string]bool, len(m))
+	for k := range m {
+		set[k] = true
+	}
+	return set
+}
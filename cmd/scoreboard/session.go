@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+const (
+	sessionCookie = "scoreboard_session"
+	// sessionLength is how long a login lasts.
+	sessionLength = 30 * 24 * time.Hour
+)
+
+// sessions signs and verifies the login cookies of players who have a login
+// token in their profile.
+type sessions struct {
+	key []byte
+}
+
+// sign returns a cookie value for the player that expires at the given time.
+func (s *sessions) sign(player string, expires time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(player)) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + s.mac(payload)
+}
+
+// verify returns the player the cookie value was signed for, or false if it's
+// forged or expired.
+func (s *sessions) verify(value string, now time.Time) (string, bool) {
+	idx := strings.LastIndex(value, ".")
+	if idx < 0 {
+		return "", false
+	}
+	payload, sig := value[:idx], value[idx+1:]
+	if !hmac.Equal([]byte(sig), []byte(s.mac(payload))) {
+		return "", false
+	}
+	parts := strings.Split(payload, ".")
+	if len(parts) != 2 {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.Unix() > expires {
+		return "", false
+	}
+	player, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", false
+	}
+	return string(player), true
+}
+
+func (s *sessions) mac(payload string) string {
+	m := hmac.New(sha256.New, s.key)
+	m.Write([]byte(payload))
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// currentPlayer returns the logged in player, or "" if the request isn't
+// logged in.
+func (l *league) currentPlayer(r *http.Request) string {
+	if l.sessions == nil {
+		return ""
+	}
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return ""
+	}
+	player, ok := l.sessions.verify(c.Value, l.clock.Now())
+	if !ok {
+		return ""
+	}
+	return player
+}
+
+// loginHandler returns the handler for /login, where players log in with the
+// login token from their profile, and /logout.
+func loginHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.sessions == nil {
+			notFoundRes(w, r)
+			return
+		}
+		cookiePath := basePath(r) + "/"
+
+		if r.URL.Path == "/logout" {
+			http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: cookiePath, MaxAge: -1})
+			http.Redirect(w, r, basePath(r)+"/", http.StatusSeeOther)
+			return
+		}
+
+		view := LoginView{PageView: barePageView(r), Next: safeRedirect(r.FormValue("next"))}
+		if r.Method == http.MethodPost {
+			name := strings.TrimSpace(r.PostFormValue("player"))
+			token := r.PostFormValue("token")
+
+			ds, err := loadDataset(w, r, l)
+			if err != nil {
+				return
+			}
+			// sessions are signed with the player's ID, whichever of their
+			// names they logged in with
+			profiles, err := loadProfiles(l.profilesPath, ds)
+			if err != nil {
+				log.Printf("error loading profiles: %+v", err)
+				errorRes(w, r, err)
+				return
+			}
+			id := ds.playerID(name)
+			p, ok := profiles[id]
+			if ok && p.LoginToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(p.LoginToken)) == 1 {
+				expires := l.clock.Now().Add(sessionLength)
+				http.SetCookie(w, &http.Cookie{
+					Name:     sessionCookie,
+					Value:    l.sessions.sign(id, expires),
+					Path:     cookiePath,
+					Expires:  expires,
+					HttpOnly: true,
+					Secure:   web.RequestScheme(r) == "https",
+					SameSite: http.SameSiteLaxMode,
+				})
+				http.Redirect(w, r, basePath(r)+view.Next, http.StatusSeeOther)
+				return
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+			view.Player = name
+			view.Errors = "unknown player or wrong login token"
+		}
+		t.ExecuteTemplate(w, "login.html.tmpl", view)
+	}
+}
+
+// safeRedirect returns the path to redirect to after logging in, which must
+// stay within the league.
+func safeRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.Contains(next, `\`) {
+		return "/"
+	}
+	return next
+}
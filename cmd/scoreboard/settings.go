@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// configureScoringFromEnv applies the settings that decide how games are
+// scored and the pages rendered: the rating engine and its parameters,
+// tiebreakers, seasons, trends, houses, finances and dates. The server and
+// every command load them the same way, so a static build or an audit shows
+// what the server would.
+func configureScoringFromEnv() error {
+	if name := os.Getenv("SCOREBOARD_ENGINE"); name != "" {
+		if _, err := newRatingEngine(name); err != nil {
+			return fmt.Errorf("invalid SCOREBOARD_ENGINE: %w", err)
+		}
+		ratingEngine = name
+	}
+	if name := os.Getenv("SCOREBOARD_TRIAL_ENGINE"); name != "" {
+		if _, err := newRatingEngine(name); err != nil {
+			return fmt.Errorf("invalid SCOREBOARD_TRIAL_ENGINE: %w", err)
+		}
+		if name == ratingEngine {
+			return fmt.Errorf("invalid SCOREBOARD_TRIAL_ENGINE: %q is already the rating engine", name)
+		}
+		trialEngine = name
+	}
+	scoring, err := scoringFromEnv()
+	if err != nil {
+		return fmt.Errorf("invalid rating configuration: %w", err)
+	}
+	scoring.apply()
+	if v := os.Getenv("SCOREBOARD_ARCHENEMY_MULTIPLIER"); v != "" {
+		m, err := strconv.ParseFloat(v, 64)
+		if err != nil || m <= 0 {
+			return fmt.Errorf("invalid SCOREBOARD_ARCHENEMY_MULTIPLIER: %q", v)
+		}
+		archenemyMultiplier = m
+	}
+	zeroSum = isMarked(os.Getenv("SCOREBOARD_ZERO_SUM"))
+	limitedElo = isMarked(os.Getenv("SCOREBOARD_LIMITED_ELO"))
+
+	if tb := os.Getenv("SCOREBOARD_TIEBREAKERS"); tb != "" {
+		breakers, err := parseTieBreakers(tb)
+		if err != nil {
+			return fmt.Errorf("invalid SCOREBOARD_TIEBREAKERS: %w", err)
+		}
+		tieBreakers = breakers
+	}
+	if v := os.Getenv("SCOREBOARD_SEASONS"); v != "" {
+		seasons, err := parseSeasons(v)
+		if err != nil {
+			return fmt.Errorf("invalid SCOREBOARD_SEASONS: %w", err)
+		}
+		configuredSeasons = seasons
+	}
+	if v := os.Getenv("SCOREBOARD_TREND_GAMES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid SCOREBOARD_TREND_GAMES: %q", v)
+		}
+		trendGames = n
+	}
+	if v := os.Getenv("SCOREBOARD_TREND_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid SCOREBOARD_TREND_DAYS: %q", v)
+		}
+		trendDays = n
+	}
+	if v := os.Getenv("SCOREBOARD_PERFORMANCE_GAMES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid SCOREBOARD_PERFORMANCE_GAMES: %q", v)
+		}
+		performanceGames = n
+	}
+	if v := os.Getenv("SCOREBOARD_HOT_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid SCOREBOARD_HOT_DAYS: %q", v)
+		}
+		hotDays = n
+	}
+	if v := os.Getenv("SCOREBOARD_RECENCY_HALF_LIFE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid SCOREBOARD_RECENCY_HALF_LIFE: %q", v)
+		}
+		recencyHalfLife = time.Duration(n) * 24 * time.Hour
+	}
+	if v := os.Getenv("SCOREBOARD_HOUSE_SCORING"); v != "" {
+		if err := parseHouseScoring(v); err != nil {
+			return fmt.Errorf("invalid SCOREBOARD_HOUSE_SCORING: %w", err)
+		}
+		houseScoring = v
+	}
+	if finances, err = financesFromEnv(); err != nil {
+		return fmt.Errorf("invalid finances configuration: %w", err)
+	}
+	if err := configureDateFormat(os.Getenv("SCOREBOARD_DATE_FORMAT")); err != nil {
+		return fmt.Errorf("invalid SCOREBOARD_DATE_FORMAT: %w", err)
+	}
+
+	if v := os.Getenv("SCOREBOARD_FETCH_CHUNK_ROWS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid SCOREBOARD_FETCH_CHUNK_ROWS: %q", v)
+		}
+		fetchChunkRows = n
+	}
+	if v := os.Getenv("SCOREBOARD_GAME_LOG_GID"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			return fmt.Errorf("invalid SCOREBOARD_GAME_LOG_GID: %q", v)
+		}
+	}
+	return nil
+}
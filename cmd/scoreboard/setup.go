@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/sheets"
+	"github.com/fly-apps/go-example/internal/web"
+	"google.golang.org/api/option"
+)
+
+// defaultConfigFile is the config file the setup wizard writes and the
+// server loads at startup, unless SCOREBOARD_CONFIG names another.
+const defaultConfigFile = "scoreboard.env"
+
+// configFile returns the path of the config file.
+func configFile() string {
+	if path := os.Getenv("SCOREBOARD_CONFIG"); path != "" {
+		return path
+	}
+	return defaultConfigFile
+}
+
+// parseConfigFile parses a config file of VAR=value lines, skipping blank
+// lines and # comments. Values may be quoted like Go strings.
+func parseConfigFile(r io.Reader) (map[string]string, error) {
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("line %d: expected VAR=value", n)
+		}
+		name, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid quoted value for %s", n, name)
+			}
+			value = unquoted
+		}
+		vars[name] = value
+	}
+	return vars, scanner.Err()
+}
+
+// loadConfigFile sets the variables in the config file at path that aren't
+// already set, so the environment always wins over the file. It reports
+// whether there was a config file.
+func loadConfigFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer f.Close()
+
+	vars, err := parseConfigFile(f)
+	if err != nil {
+		return true, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	for name, value := range vars {
+		if _, ok := os.LookupEnv(name); !ok {
+			os.Setenv(name, value)
+		}
+	}
+	return true, nil
+}
+
+// configureSheetFromEnv points the scoreboard at the spreadsheet and game log
+// range in SCOREBOARD_SHEET_ID and SCOREBOARD_GAMES_RANGE, when set.
+func configureSheetFromEnv() error {
+	if v := os.Getenv("SCOREBOARD_SHEET_ID"); v != "" {
+		if !sheetPattern.MatchString(v) {
+			return fmt.Errorf("invalid SCOREBOARD_SHEET_ID: %q", v)
+		}
+		spreadsheetID = v
+	}
+	if v := os.Getenv("SCOREBOARD_GAMES_RANGE"); v != "" {
+		readRange = v
+	}
+	return nil
+}
+
+// needsSetup reports whether the server has nothing to serve yet: no config
+// file and no Sheets credentials in the environment.
+func needsSetup(haveConfig bool) bool {
+	return !haveConfig && !isMarked(os.Getenv("SCOREBOARD_HOSTED")) &&
+		!hasSecretEnv("SCOREBOARD_API_KEY") && !hasSecretEnv("SCOREBOARD_CREDENTIALS")
+}
+
+// sheetURLPattern matches the spreadsheet ID in a Google Sheets URL, so the
+// wizard takes either.
+var sheetURLPattern = regexp.MustCompile(`/spreadsheets/d/([A-Za-z0-9_-]+)`)
+
+// SetupConfig is the configuration the setup wizard collects, kept as the
+// form's values so they can be shown again.
+type SetupConfig struct {
+	SheetID        string
+	Range          string
+	APIKey         string
+	Engine         string
+	KFactor        string
+	StartingRating string
+	RewardCurve    string
+	DateFormat     string
+}
+
+// setupConfigFrom reads the wizard's form, with the defaults for anything
+// not filled in.
+func setupConfigFrom(r *http.Request) SetupConfig {
+	c := SetupConfig{
+		SheetID:        strings.TrimSpace(r.PostFormValue("sheetID")),
+		Range:          strings.TrimSpace(r.PostFormValue("range")),
+		APIKey:         strings.TrimSpace(r.PostFormValue("apiKey")),
+		Engine:         r.PostFormValue("engine"),
+		KFactor:        strings.TrimSpace(r.PostFormValue("kFactor")),
+		StartingRating: strings.TrimSpace(r.PostFormValue("startingRating")),
+		RewardCurve:    r.PostFormValue("rewardCurve"),
+		DateFormat:     r.PostFormValue("dateFormat"),
+	}
+	if m := sheetURLPattern.FindStringSubmatch(c.SheetID); m != nil {
+		c.SheetID = m[1]
+	}
+	if c.Range == "" {
+		c.Range = readRange
+	}
+	if c.Engine == "" {
+		c.Engine = ratingEngine
+	}
+	if c.KFactor == "" {
+		c.KFactor = strconv.Itoa(kFactor)
+	}
+	if c.StartingRating == "" {
+		c.StartingRating = strconv.Itoa(startingRating)
+	}
+	if c.RewardCurve == "" {
+		c.RewardCurve = rewardCurveShape
+	}
+	return c
+}
+
+// validateSheet checks the sheet fields of the config.
+func (c SetupConfig) validateSheet() error {
+	if !sheetPattern.MatchString(c.SheetID) {
+		return fmt.Errorf("that isn't a spreadsheet ID or URL")
+	}
+	if c.APIKey == "" {
+		return fmt.Errorf("a Google Sheets API key is required")
+	}
+	return nil
+}
+
+// validate checks the whole config before it's written.
+func (c SetupConfig) validate() error {
+	if err := c.validateSheet(); err != nil {
+		return err
+	}
+	if _, err := newRatingEngine(c.Engine); err != nil {
+		return err
+	}
+	if k, err := strconv.Atoi(c.KFactor); err != nil || k < 1 {
+		return fmt.Errorf("the K-factor must be a positive whole number")
+	}
+	if start, err := strconv.Atoi(c.StartingRating); err != nil || start < 1 {
+		return fmt.Errorf("the starting rating must be a positive whole number")
+	}
+	if !validCurveShape(c.RewardCurve) {
+		return fmt.Errorf("unknown reward curve %q, expected one of %s", c.RewardCurve, strings.Join(rewardCurveShapes, ", "))
+	}
+	if _, ok := namedDateFormats[c.DateFormat]; c.DateFormat != "" && !ok {
+		return fmt.Errorf("unknown date format %q", c.DateFormat)
+	}
+	return nil
+}
+
+// write writes the config to a new config file at path, readable only by its
+// owner since it holds the API key. It fails if the file already exists.
+func (c SetupConfig) write(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write the config file: %w", err)
+	}
+	fmt.Fprintln(f, "# written by the scoreboard setup wizard, see the README for the other variables")
+	for _, v := range [][2]string{
+		{"SCOREBOARD_SHEET_ID", c.SheetID},
+		{"SCOREBOARD_GAMES_RANGE", c.Range},
+		{"SCOREBOARD_API_KEY", c.APIKey},
+		{"SCOREBOARD_ENGINE", c.Engine},
+		{"SCOREBOARD_K_FACTOR", c.KFactor},
+		{"SCOREBOARD_STARTING_RATING", c.StartingRating},
+		{"SCOREBOARD_REWARD_CURVE", c.RewardCurve},
+		{"SCOREBOARD_DATE_FORMAT", c.DateFormat},
+	} {
+		if v[1] != "" {
+			fmt.Fprintf(f, "%s=%s\n", v[0], strconv.Quote(v[1]))
+		}
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write the config file: %w", err)
+	}
+	return nil
+}
+
+// DetectedColumn is a game log column as the setup wizard detected it.
+type DetectedColumn struct {
+	ColumnMismatch
+	OK bool // whether the column can be read as the parser reads it.
+}
+
+// detectColumns infers what each of the game log's columns holds, like the
+// schema check, and returns every labelled or filled column.
+func detectColumns(values [][]interface{}) []DetectedColumn {
+	if len(values) == 0 {
+		return nil
+	}
+	var columns []DetectedColumn
+	for col := 0; col <= kingmakerColumn; col++ {
+		label, cells := sampleColumn(values, col)
+		if label == "" && len(cells) == 0 {
+			continue
+		}
+		expected := expectedRole(col)
+		inferred := inferRole(label, cells)
+		columns = append(columns, DetectedColumn{
+			ColumnMismatch: ColumnMismatch{Column: sheets.ColumnLetter(col), Header: label, Expected: expected, Inferred: inferred},
+			OK:             compatibleRoles(expected, inferred),
+		})
+	}
+	return columns
+}
+
+// setupDateFormats are the date formats the wizard offers, in the order
+// they're tried when detecting the sheet's.
+var setupDateFormats = []string{"rfc1123", "us", "eu", "iso", "text"}
+
+// detectDateFormat returns the first of setupDateFormats that every sampled
+// date in the game log is written in, or "" if there's none.
+func detectDateFormat(values [][]interface{}) string {
+	if len(values) < 2 {
+		return ""
+	}
+	_, cells := sampleColumn(values, 1)
+	if len(cells) == 0 {
+		return ""
+	}
+formats:
+	for _, name := range setupDateFormats {
+		for _, cell := range cells {
+			if _, err := time.Parse(namedDateFormats[name], cell); err != nil {
+				continue formats
+			}
+		}
+		return name
+	}
+	return ""
+}
+
+// setupWizard serves the first-run setup wizard, which checks the sheet can
+// be read, shows how its columns will be read, takes the rating parameters
+// and writes the config file. saved is closed once it's written.
+//
+// The wizard is served on the public port before there's an admin token, so
+// every request must carry token, a one-time token only printed to the log.
+type setupWizard struct {
+	path  string
+	token string
+	opts  []option.ClientOption // passed to the Sheets client after the API key.
+	saved chan struct{}
+	once  sync.Once
+}
+
+func newSetupWizard(path, token string, opts ...option.ClientOption) *setupWizard {
+	return &setupWizard{path: path, token: token, opts: opts, saved: make(chan struct{})}
+}
+
+func (s *setupWizard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if subtle.ConstantTimeCompare([]byte(r.FormValue("token")), []byte(s.token)) != 1 {
+		errorPage(w, r, http.StatusForbidden, "Open the setup link printed in the scoreboard's log, which has the setup token.")
+		return
+	}
+	if r.URL.Path != "/" {
+		http.Redirect(w, r, "/?token="+url.QueryEscape(s.token), http.StatusSeeOther)
+		return
+	}
+	config := setupConfigFrom(r)
+	view := SetupView{
+		PageView:    barePageView(r),
+		Step:        "sheet",
+		Token:       s.token,
+		Config:      config,
+		Path:        s.path,
+		Engines:     ratingEngineNames(),
+		Curves:      rewardCurveShapes,
+		DateFormats: setupDateFormats,
+	}
+	fail := func(err error) {
+		w.WriteHeader(http.StatusBadRequest)
+		view.Errors = err.Error()
+		t.ExecuteTemplate(w, "setup.html.tmpl", view)
+	}
+
+	switch r.PostFormValue("step") {
+	case "check":
+		if err := config.validateSheet(); err != nil {
+			fail(err)
+			return
+		}
+		opts := append([]option.ClientOption{option.WithAPIKey(config.APIKey)}, s.opts...)
+		values, err := sheets.FetchRows(r.Context(), config.SheetID, config.Range, opts...)
+		if err != nil && !errors.Is(err, sheets.ErrNoData) {
+			log.Printf("setup: error reading the sheet: %+v", err)
+			fail(fmt.Errorf("couldn't read the sheet, check the API key and that the sheet is shared so anyone with the link can view it: %s", err))
+			return
+		}
+		games, unscored, _ := parseGameLog(values)
+		if config.DateFormat == "" {
+			config.DateFormat = detectDateFormat(values)
+		}
+		view.Config = config
+		view.Columns = detectColumns(values)
+		view.Games = len(games) + len(unscored)
+		view.Step = "rating"
+	case "save":
+		if err := config.validate(); err != nil {
+			view.Step = "rating"
+			fail(err)
+			return
+		}
+		if err := config.write(s.path); err != nil {
+			log.Printf("setup: %+v", err)
+			view.Step = "rating"
+			fail(err)
+			return
+		}
+		log.Printf("setup: wrote %s", s.path)
+		view.Step = "saved"
+		s.once.Do(func() { close(s.saved) })
+	}
+	t.ExecuteTemplate(w, "setup.html.tmpl", view)
+}
+
+// runSetupWizard serves the setup wizard at addr until the config file is
+// written. The link to it, with a new setup token, is printed to the log.
+func runSetupWizard(addr, path string) error {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("failed to generate the setup token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	ln, err := web.Listen(addr)
+	if err != nil {
+		return err
+	}
+	wizard := newSetupWizard(path, token)
+	srv := &http.Server{Handler: wizard}
+	go srv.Serve(ln)
+	log.Printf("no configuration found, set up the scoreboard at http://%s/?token=%s", ln.Addr(), token)
+	<-wizard.saved
+	// let the page saying it's saved finish before the server starts
+	return srv.Shutdown(context.Background())
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// requestTimeout is how long a request may take before its context is
+// cancelled, which abandons any Sheets fetch it's waiting on. Configured with
+// SCOREBOARD_REQUEST_TIMEOUT.
+var requestTimeout = 30 * time.Second
+
+// recomputes bounds how many leaderboards are scored and rendered at once
+// across every league, so a burst of traffic after the data changes can't
+// exhaust a small server. Configured with SCOREBOARD_MAX_RECOMPUTES.
+var recomputes = make(chan struct{}, 4)
+
+// streamingRoutes are the routes that stream a response for as long as the
+// client keeps reading, so they're exempt from requestTimeout. They match
+// under any league's path prefix.
+var streamingRoutes = []string{"/api/export"}
+
+// withDeadline gives each request but the streaming ones a deadline of
+// timeout.
+func withDeadline(next http.Handler, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, route := range streamingRoutes {
+			if strings.HasSuffix(r.URL.Path, route) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// tryRecompute claims a recompute slot, reporting false if they're all taken.
+// Callers that get a slot must release it by calling done.
+func tryRecompute() (done func(), ok bool) {
+	select {
+	case recomputes <- struct{}{}:
+		return func() { <-recomputes }, true
+	default:
+		return nil, false
+	}
+}
+
+// shed answers a request that couldn't get a recompute slot with 503 Service
+// Unavailable, serving the stale page if there is one so visitors still see
+// the leaderboard as it was.
+func shed(w http.ResponseWriter, r *http.Request, stale *renderedPage) {
+	w.Header().Set("Retry-After", strconv.Itoa(5))
+	if stale == nil {
+		errorPage(w, r, http.StatusServiceUnavailable, "The scoreboard is busy, try again in a few seconds.")
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Add("Vary", "Accept-Encoding")
+	if web.AcceptsGzip(r.Header.Get("Accept-Encoding")) {
+		h.Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write(stale.gzipped)
+		return
+	}
+	w.WriteHeader(http.StatusServiceUnavailable)
+	w.Write(stale.body)
+}
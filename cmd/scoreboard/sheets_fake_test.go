@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fly-apps/go-example/internal/sheets"
+	"google.golang.org/api/option"
+)
+
+// fakeSheets is an httptest-backed stand-in for the Google Sheets values API.
+// Each request is answered by the current handler, which tests swap out to
+// inject failures such as quota errors or malformed payloads.
+type fakeSheets struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	handler  http.HandlerFunc
+	requests []string
+}
+
+// newFakeSheets starts a fake Sheets server that serves the given rows as the
+// game log. The server is closed when the test finishes.
+func newFakeSheets(t *testing.T, rows [][]interface{}) *fakeSheets {
+	t.Helper()
+	f := &fakeSheets{}
+	f.serveRows(rows)
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.requests = append(f.requests, r.URL.Path)
+		h := f.handler
+		f.mu.Unlock()
+		h(w, r)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// options returns the client options that route the Sheets client to the
+// fake server.
+func (f *fakeSheets) options() []option.ClientOption {
+	return []option.ClientOption{
+		option.WithEndpoint(f.URL + "/"),
+		option.WithHTTPClient(f.Client()),
+	}
+}
+
+// league returns a league whose game log is served by the fake.
+func (f *fakeSheets) league() *league {
+	return newLeague(spreadsheetID, readRange, f.options()...)
+}
+
+// serveRows answers values requests for the game log with the given rows,
+// or the rows in the chunk of it requested. Batch requests for other ranges
+// are answered from tabs.
+func (f *fakeSheets) serveRows(rows [][]interface{}, tabs ...map[string][][]interface{}) {
+	f.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/values:batchGet"):
+			var valueRanges []map[string]interface{}
+			for _, rng := range r.URL.Query()["ranges"] {
+				values := rows
+				if sheet, _, _ := sheets.RangeStart(rng); rng != readRange && strings.HasPrefix(readRange, sheet) {
+					values = chunkRows(rows, rng)
+				} else if rng != readRange {
+					values = nil
+					for _, tab := range tabs {
+						if v, ok := tab[rng]; ok {
+							values = v
+						}
+					}
+				}
+				valueRanges = append(valueRanges, map[string]interface{}{
+					"range":          rng,
+					"majorDimension": "ROWS",
+					"values":         values,
+				})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"spreadsheetId": spreadsheetID,
+				"valueRanges":   valueRanges,
+			})
+		case strings.Contains(r.URL.Path, "/values/"):
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"range":          readRange,
+				"majorDimension": "ROWS",
+				"values":         rows,
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// chunkRows returns the rows of the game log that fall in a chunk of it,
+// like "Ranked game log!A3:X4", trimmed the way Sheets trims empty rows off
+// the end of a range.
+func chunkRows(rows [][]interface{}, rng string) [][]interface{} {
+	_, _, from := sheets.RangeStart(rng)
+	_, to := sheets.RangeEnd(rng)
+	if from > len(rows) {
+		return nil
+	}
+	if to == 0 || to > len(rows) {
+		to = len(rows)
+	}
+	return rows[from-1 : to]
+}
+
+// serveError answers every request with a Google API style error.
+func (f *fakeSheets) serveError(code int, status, message string) {
+	f.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]interface{}{
+				"code":    code,
+				"message": message,
+				"status":  status,
+			},
+		})
+	})
+}
+
+// serveRaw answers every request with the given body verbatim.
+func (f *fakeSheets) serveRaw(body string) {
+	f.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	})
+}
+
+func (f *fakeSheets) setHandler(h http.HandlerFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handler = h
+}
+
+// requestCount returns the number of requests the fake has received.
+func (f *fakeSheets) requestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+// requestPaths returns the paths of the requests made so far.
+func (f *fakeSheets) requestPaths() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.requests...)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// snapshotPlayers is the number of players shown in the snapshot.
+	snapshotPlayers = 10
+	// snapshotWidth and snapshotHeight are the size the snapshot is drawn
+	// at before it's scaled up by snapshotScale, which keeps the bitmap font
+	// legible when chat apps show the image.
+	snapshotWidth, snapshotHeight, snapshotScale = 400, 225, 2
+)
+
+var (
+	snapshotBackground = color.RGBA{0x11, 0x11, 0x11, 0xff}
+	snapshotText       = color.RGBA{0xee, 0xee, 0xee, 0xff}
+	snapshotMuted      = color.RGBA{0x99, 0x99, 0x99, 0xff}
+)
+
+// snapshotHandler returns the handler for /snapshot.png, an image of the
+// current top of the leaderboard sized for posting in group chats. The top
+// parameter shows fewer players, like the top 3 in link previews.
+func snapshotHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		top := snapshotPlayers
+		if v := r.URL.Query().Get("top"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > snapshotPlayers {
+				badRequestRes(w, r, fmt.Sprintf("top must be between 1 and %d", snapshotPlayers))
+				return
+			}
+			top = n
+		}
+
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		games := ds.Games
+		scores := calculateScores(games, ds.Adjustments...)
+		rankings := rankPlayers(games, scores, l.clock.Now())
+		ds.Names.apply(rankings)
+		if len(rankings) > top {
+			rankings = rankings[:top]
+		}
+
+		season := ""
+		if seasons := leagueSeasons(games, ds.Seasons); len(seasons) > 0 {
+			season = seasons[len(seasons)-1].Name
+		}
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, snapshotImage(rankings, season, l.clock.Now())); err != nil {
+			log.Printf("error encoding snapshot: %+v", err)
+			errorRes(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.Write(buf.Bytes())
+	}
+}
+
+// snapshotImage draws the top of the rankings with the season and date
+// stamped on it.
+func snapshotImage(rankings []Player, season string, now time.Time) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, snapshotWidth, snapshotHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(snapshotBackground), image.Point{}, draw.Src)
+
+	face := basicfont.Face7x13
+	text := func(x, y int, c color.Color, s string) {
+		d := &font.Drawer{Dst: img, Src: image.NewUniform(c), Face: face, Dot: fixed.P(x, y)}
+		d.DrawString(s)
+	}
+
+	title := "Scoreboard"
+	if season != "" {
+		title += " - " + season
+	}
+	text(12, 22, snapshotText, truncate(title, 40))
+	date := shortDate(now)
+	text(snapshotWidth-12-len(date)*7, 22, snapshotMuted, date)
+
+	if len(rankings) == 0 {
+		text(12, 60, snapshotMuted, "No games yet")
+	}
+	for idx, p := range rankings {
+		if idx == snapshotPlayers {
+			break
+		}
+		y := 50 + idx*17
+		text(12, y, snapshotMuted, fmt.Sprintf("%2d.", idx+1))
+		text(40, y, snapshotText, truncate(p.Name, 34))
+		score := fmt.Sprintf("%d", p.Score)
+		text(snapshotWidth-12-len(score)*7, y, snapshotText, score)
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, snapshotWidth*snapshotScale, snapshotHeight*snapshotScale))
+	draw.NearestNeighbor.Scale(scaled, scaled.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return scaled
+}
+
+// truncate shortens s to at most n characters, marking it with "..." if it
+// was cut.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
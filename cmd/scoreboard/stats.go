@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// replacementRating is the rating of a freshly joined, replacement level player.
+const replacementRating = 1500
+
+// winProbability is the chance a player with the given rating wins a pod
+// against opponents with the given ratings. Each player's strength is
+// 10^(rating/400), as in Elo, and the chance of winning is their share of the
+// pod's total strength.
+func winProbability(rating int, opponents []int) float64 {
+	strength := math.Pow(10, float64(rating)/400)
+	total := strength
+	for _, r := range opponents {
+		total += math.Pow(10, float64(r)/400)
+	}
+	return strength / total
+}
+
+// WAR is a player's wins above replacement: how many more games they won than
+// a replacement level player would have won against the same opponents.
+type WAR struct {
+	Wins     int     // the games the player won.
+	Expected float64 // the games a replacement player would be expected to win.
+	Games    int     // the games counted, which excludes draws.
+}
+
+// Value is the number of wins above replacement.
+func (w WAR) Value() float64 {
+	return float64(w.Wins) - w.Expected
+}
+
+func (w WAR) String() string {
+	return fmt.Sprintf("%+.1f", w.Value())
+}
+
+// winsAboveReplacement calculates the player's WAR across the scored games,
+// using each opponent's rating going into the game so the player's schedule
+// difficulty is accounted for.
+func winsAboveReplacement(games []*Game, name string) WAR {
+	var war WAR
+	for _, game := range games {
+		if game.IsDraw() {
+			continue
+		}
+
+		place := 0
+		for _, res := range game.Results {
+			if res.Player == name {
+				place = res.Place
+			}
+		}
+		if place == 0 {
+			continue
+		}
+		// teammates in an archenemy game share the player's place and aren't
+		// opponents
+		var opponents []int
+		for _, res := range game.Results {
+			if res.Place != place {
+				opponents = append(opponents, res.Before)
+			}
+		}
+
+		war.Games++
+		war.Expected += winProbability(replacementRating, opponents)
+		if place == 1 {
+			war.Wins++
+		}
+	}
+	return war
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/fly-apps/go-example/internal/storage"
+)
+
+// errNotFound is returned by the store when a record doesn't exist.
+var errNotFound = errors.New("not found")
+
+// store is the app's persistent storage, backed by SQLite.
+type store struct {
+	db *sql.DB
+}
+
+// openStore opens the database at dsn, e.g. "sqlite://scoreboard.db", and
+// applies any pending migrations.
+func openStore(dsn string) (*store, error) {
+	db, err := storage.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *store) Close() error {
+	return s.db.Close()
+}
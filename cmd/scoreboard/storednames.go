@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// storedNames are the queries for the player names in the league's stored
+// records, which were written under whatever name the player went by before
+// players were keyed by ID.
+var storedNames = []string{
+	`SELECT DISTINCT member FROM predictions WHERE league = ?`,
+	`SELECT DISTINCT pick FROM predictions WHERE league = ?`,
+	`SELECT DISTINCT author FROM comments WHERE league = ?`,
+	`SELECT DISTINCT target FROM comments WHERE league = ? AND kind = 'player'`,
+	`SELECT DISTINCT author FROM game_reports WHERE league = ?`,
+	`SELECT DISTINCT submitted_by FROM pending_games WHERE league = ?`,
+	`SELECT DISTINCT created_by FROM polls WHERE league = ?`,
+	`SELECT DISTINCT player FROM poll_availability WHERE poll_id IN (SELECT id FROM polls WHERE league = ?)`,
+}
+
+// migratePlayerIDs renames the players in the league's stored records to
+// their IDs in the dataset, once per league, so comments, picks and the rest
+// made under a player's name before their ID was its slug stay with them,
+// recording the migration at now. It returns the number of records changed
+// in each table.
+func (s *store) migratePlayerIDs(ctx context.Context, league string, ds *Dataset, now time.Time) (map[string]int64, error) {
+	var done int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM player_id_migrations WHERE league = ?`, league).Scan(&done); err != nil {
+		return nil, fmt.Errorf("failed to check player ID migration: %w", err)
+	}
+	if done > 0 {
+		return nil, nil
+	}
+
+	names := map[string]bool{}
+	for _, query := range storedNames {
+		rows, err := s.db.QueryContext(ctx, query, league)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stored names: %w", err)
+		}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to read stored names: %w", err)
+			}
+			names[name] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read stored names: %w", err)
+		}
+	}
+
+	records := map[string]int64{}
+	for _, name := range sortedNames(names) {
+		id := ds.playerID(name)
+		if id == name {
+			continue
+		}
+		renamed, err := s.renamePlayer(ctx, league, name, id, "", false)
+		if err != nil {
+			return nil, err
+		}
+		for table, n := range renamed {
+			records[table] += n
+		}
+	}
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO player_id_migrations (league, migrated_at) VALUES (?, ?)`, league, now.UTC()); err != nil {
+		return nil, fmt.Errorf("failed to record player ID migration: %w", err)
+	}
+	return records, nil
+}
+
+// migrateStoredNames queues renaming the players in the league's stored
+// records to their IDs in the dataset, the first time one is loaded.
+func (l *league) migrateStoredNames(ds *Dataset) {
+	st := l.snapshots
+	if st == nil {
+		return
+	}
+	l.namesMigrated.Do(func() {
+		backgroundJobs.enqueue("player-ids", 3, func(ctx context.Context) error {
+			records, err := st.migratePlayerIDs(ctx, l.snapshotKey(), ds, l.clock.Now())
+			if err != nil {
+				return err
+			}
+			if len(records) > 0 {
+				log.Printf("renamed stored players to their IDs: %v", records)
+			}
+			return nil
+		})
+	})
+}
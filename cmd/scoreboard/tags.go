@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// tagsColumn is the index of the game's tags column, X.
+const tagsColumn = deckColumn + maxPlayers
+
+// TagCount is a game tag and the number of games tagged with it.
+type TagCount struct {
+	Tag   string
+	Games int
+}
+
+// parseTags parses a game's tags cell, like "budget-night, cEDH". Tags are
+// separated by commas and lowercased so they match however they're written.
+func parseTags(cell string) []string {
+	var tags []string
+	seen := map[string]bool{}
+	for _, tag := range strings.Split(cell, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// hasTag reports whether the game is tagged with tag, in any case.
+func hasTag(g *Game, tag string) bool {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	for _, t := range g.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// gamesWithTag returns copies of the games tagged with tag, so they can be
+// scored for the tag's leaderboard without overwriting the league's results.
+func gamesWithTag(games []*Game, tag string) []*Game {
+	var tagged []*Game
+	for _, g := range games {
+		if hasTag(g, tag) {
+			tagged = append(tagged, g)
+		}
+	}
+	return copyGames(tagged)
+}
+
+// leagueTags returns the tags used in the game log, most used first.
+func leagueTags(games []*Game) []TagCount {
+	counts := map[string]int{}
+	for _, g := range games {
+		for _, tag := range g.Tags {
+			counts[tag]++
+		}
+	}
+	tags := make([]TagCount, 0, len(counts))
+	for tag, n := range counts {
+		tags = append(tags, TagCount{Tag: tag, Games: n})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Games != tags[j].Games {
+			return tags[i].Games > tags[j].Games
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags
+}
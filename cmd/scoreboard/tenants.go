@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/web"
+	"google.golang.org/api/option"
+)
+
+// Tenant is a playgroup registered in hosted mode, with its own game log and
+// settings.
+type Tenant struct {
+	Slug          string    // the tenant's subdomain and path prefix.
+	Name          string    // the playgroup's display name.
+	SpreadsheetID string    // the Google Sheet holding the tenant's game log.
+	ReadRange     string    // the range of the game log tab.
+	APIKey        string    // the tenant's own Sheets API key, if they brought one.
+	QuotaPerHour  int       // the number of Sheets fetches allowed per hour, 0 for the host default.
+	CreatedAt     time.Time // when the tenant registered.
+}
+
+var (
+	slugPattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,30}[a-z0-9]$`)
+	sheetPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{10,100}$`)
+
+	// reservedSlugs can't be registered since they're used by the host.
+	reservedSlugs = map[string]bool{"www": true, "api": true, "admin": true, "register": true, "t": true}
+)
+
+// validate checks that the tenant can be registered.
+func (t *Tenant) validate() error {
+	if !slugPattern.MatchString(t.Slug) || reservedSlugs[t.Slug] {
+		return fmt.Errorf("slug must be 3-32 lowercase letters, numbers or dashes and not reserved")
+	}
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !sheetPattern.MatchString(t.SpreadsheetID) {
+		return fmt.Errorf("spreadsheet ID is invalid")
+	}
+	if t.ReadRange == "" {
+		return fmt.Errorf("read range is required")
+	}
+	return nil
+}
+
+// createTenant registers a new tenant.
+func (s *store) createTenant(ctx context.Context, t *Tenant) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO tenants
+		(slug, name, spreadsheet_id, read_range, api_key, quota_per_hour, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		t.Slug, t.Name, t.SpreadsheetID, t.ReadRange, t.APIKey, t.QuotaPerHour, t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create tenant %s: %w", t.Slug, err)
+	}
+	return nil
+}
+
+// tenant returns the tenant registered under slug, or errNotFound.
+func (s *store) tenant(ctx context.Context, slug string) (*Tenant, error) {
+	t := &Tenant{}
+	err := s.db.QueryRowContext(ctx, `SELECT slug, name, spreadsheet_id, read_range, api_key, quota_per_hour, created_at
+		FROM tenants WHERE slug = ?`, slug).
+		Scan(&t.Slug, &t.Name, &t.SpreadsheetID, &t.ReadRange, &t.APIKey, &t.QuotaPerHour, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenant %s: %w", slug, err)
+	}
+	return t, nil
+}
+
+// hostedServer serves a league per registered tenant. Tenants are resolved
+// from the subdomain of the hosted domain, e.g. mypod.scoreboard.example, or
+// from a path prefix, e.g. /t/mypod/.
+type hostedServer struct {
+	store        *store
+	domain       string
+	opts         []option.ClientOption
+	defaultQuota int
+
+	mu      sync.Mutex
+	leagues map[string]*hostedLeague
+}
+
+// hostedLeague is a tenant's league along with the handler serving it.
+type hostedLeague struct {
+	tenant  *Tenant
+	league  *league
+	handler http.Handler
+}
+
+// newHostedServerFromEnv configures hosted mode from the environment.
+func newHostedServerFromEnv(opts ...option.ClientOption) (*hostedServer, error) {
+	dsn, err := secretEnv("SCOREBOARD_DATABASE")
+	if err != nil {
+		return nil, err
+	}
+	if dsn == "" {
+		return nil, fmt.Errorf("SCOREBOARD_DATABASE is required in hosted mode")
+	}
+	if isMarked(os.Getenv("SCOREBOARD_PICKEM")) {
+		// picks are made by logged in players, and tenants' players have no
+		// login tokens
+		return nil, fmt.Errorf("SCOREBOARD_PICKEM is not supported in hosted mode, since tenants' players can't log in")
+	}
+	st, err := openStore(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &hostedServer{
+		store:        st,
+		domain:       strings.ToLower(os.Getenv("SCOREBOARD_HOSTED_DOMAIN")),
+		opts:         opts,
+		defaultQuota: 600,
+		leagues:      map[string]*hostedLeague{},
+	}
+	if v := os.Getenv("SCOREBOARD_TENANT_QUOTA"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid SCOREBOARD_TENANT_QUOTA: %q", v)
+		}
+		h.defaultQuota = n
+	}
+	return h, nil
+}
+
+func (h *hostedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// tenants on their own subdomain
+	if slug := h.subdomain(web.RequestHost(r)); slug != "" {
+		h.serveTenant(w, r, slug, "")
+		return
+	}
+
+	// tenants under a path prefix
+	if strings.HasPrefix(r.URL.Path, "/t/") {
+		rest := strings.TrimPrefix(r.URL.Path, "/t/")
+		slug := rest
+		if idx := strings.Index(rest, "/"); idx >= 0 {
+			slug = rest[:idx]
+		}
+		prefix := "/t/" + slug
+		if r.URL.Path == prefix {
+			http.Redirect(w, r, prefix+"/", http.StatusMovedPermanently)
+			return
+		}
+		http.StripPrefix(prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.serveTenant(w, r, slug, prefix)
+		})).ServeHTTP(w, r)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/", "/register":
+		h.register(w, r)
+	case "/compare":
+		h.compare(w, r)
+	default:
+		notFoundRes(w, r)
+	}
+}
+
+// subdomain returns the tenant slug from a host under the hosted domain.
+func (h *hostedServer) subdomain(host string) string {
+	if h.domain == "" {
+		return ""
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.ToLower(host)
+	if !strings.HasSuffix(host, "."+h.domain) {
+		return ""
+	}
+	slug := strings.TrimSuffix(host, "."+h.domain)
+	if slug == "www" || strings.Contains(slug, ".") {
+		return ""
+	}
+	return slug
+}
+
+// serveTenant serves the request from the tenant's league.
+func (h *hostedServer) serveTenant(w http.ResponseWriter, r *http.Request, slug, prefix string) {
+	hl, err := h.league(r.Context(), slug)
+	if errors.Is(err, errNotFound) {
+		notFoundRes(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("error loading tenant %s: %+v", slug, err)
+		errorRes(w, r, err)
+		return
+	}
+	hl.handler.ServeHTTP(w, withBasePath(r, prefix))
+}
+
+// league returns the tenant's league, creating it on first use. Each tenant
+// has its own league so caches and quotas are isolated.
+func (h *hostedServer) league(ctx context.Context, slug string) (*hostedLeague, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if hl, ok := h.leagues[slug]; ok {
+		return hl, nil
+	}
+
+	tenant, err := h.store.tenant(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := h.opts
+	if tenant.APIKey != "" {
+		opts = []option.ClientOption{option.WithAPIKey(tenant.APIKey)}
+	}
+	l := newLeague(tenant.SpreadsheetID, tenant.ReadRange, opts...)
+	l.tenant = tenant.Slug
+	limit := tenant.QuotaPerHour
+	if limit == 0 {
+		limit = h.defaultQuota
+	}
+	l.quota = newQuota(limit, time.Hour)
+	l.snapshots = h.store
+
+	hl := &hostedLeague{tenant: tenant, league: l, handler: l.routes()}
+	h.leagues[slug] = hl
+	return hl, nil
+}
+
+// register shows the tenant registration form and registers new tenants.
+func (h *hostedServer) register(w http.ResponseWriter, r *http.Request) {
+	view := RegisterView{PageView: barePageView(r), Domain: h.domain}
+
+	if r.Method == http.MethodPost {
+		tenant := &Tenant{
+			Slug:          strings.ToLower(strings.TrimSpace(r.PostFormValue("slug"))),
+			Name:          strings.TrimSpace(r.PostFormValue("name")),
+			SpreadsheetID: strings.TrimSpace(r.PostFormValue("spreadsheetID")),
+			ReadRange:     strings.TrimSpace(r.PostFormValue("readRange")),
+			APIKey:        strings.TrimSpace(r.PostFormValue("apiKey")),
+			CreatedAt:     time.Now().UTC(),
+		}
+		if tenant.ReadRange == "" {
+			tenant.ReadRange = readRange
+		}
+		view.Tenant = tenant
+
+		err := tenant.validate()
+		if err == nil {
+			if _, lookupErr := h.store.tenant(r.Context(), tenant.Slug); lookupErr == nil {
+				err = fmt.Errorf("slug %q is already taken", tenant.Slug)
+			}
+		}
+		if err == nil {
+			err = h.store.createTenant(r.Context(), tenant)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			view.Errors = err.Error()
+		} else {
+			view.Registered = true
+		}
+	}
+
+	t.ExecuteTemplate(w, "register.html.tmpl", view)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tieBreaker orders two players with identical scores. Compare returns a
+// positive number if a should rank above b, a negative number if b should
+// rank above a, and zero if the tie-breaker can't separate them.
+type tieBreaker struct {
+	Name    string
+	Compare func(a, b Player) int
+}
+
+// tieBreakers are applied in order by ByScore when two players have the same
+// score. They can be configured with SCOREBOARD_TIEBREAKERS.
+var tieBreakers = []tieBreaker{
+	headToHead,
+	moreWins,
+	fewerGames,
+	alphabetical,
+}
+
+// allTieBreakers are all the tie-breakers that can be configured.
+var allTieBreakers = []tieBreaker{headToHead, moreWins, fewerGames, longerSurvival, alphabetical}
+
+var (
+	// headToHead favors the player who finished above the other more often
+	// in games they both played.
+	headToHead = tieBreaker{"h2h", func(a, b Player) int {
+		return a.HeadToHead[b.ID] - b.HeadToHead[a.ID]
+	}}
+	// moreWins favors the player with more outright wins.
+	moreWins = tieBreaker{"wins", func(a, b Player) int {
+		return a.Wins - b.Wins
+	}}
+	// fewerGames favors the player who reached the score in fewer games.
+	fewerGames = tieBreaker{"games", func(a, b Player) int {
+		return b.Games - a.Games
+	}}
+	// longerSurvival favors the player who survives longer on average in
+	// games with recorded elimination times.
+	longerSurvival = tieBreaker{"survival", func(a, b Player) int {
+		switch {
+		case a.AvgSurvival > b.AvgSurvival:
+			return 1
+		case a.AvgSurvival < b.AvgSurvival:
+			return -1
+		}
+		return 0
+	}}
+	// alphabetical favors the player whose name sorts first.
+	alphabetical = tieBreaker{"alpha", func(a, b Player) int {
+		return strings.Compare(b.Name, a.Name)
+	}}
+)
+
+// parseTieBreakers parses a comma separated list of tie-breaker names, e.g.
+// "h2h,wins,games,alpha", into an ordered list of tie-breakers.
+func parseTieBreakers(s string) ([]tieBreaker, error) {
+	known := map[string]tieBreaker{}
+	for _, tb := range allTieBreakers {
+		known[tb.Name] = tb
+	}
+
+	var breakers []tieBreaker
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		tb, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown tie-breaker %q", name)
+		}
+		breakers = append(breakers, tb)
+	}
+	return breakers, nil
+}
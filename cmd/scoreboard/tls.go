@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// autocertFromEnv returns a manager that gets certificates from Let's Encrypt
+// for the comma separated domains in SCOREBOARD_TLS_DOMAIN, caching them in
+// SCOREBOARD_TLS_CACHE. It returns nil when built-in TLS is off, like when a
+// proxy terminates TLS in front of the scoreboard.
+func autocertFromEnv() *autocert.Manager {
+	var domains []string
+	for _, d := range strings.Split(os.Getenv("SCOREBOARD_TLS_DOMAIN"), ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	if len(domains) == 0 {
+		return nil
+	}
+	cache := os.Getenv("SCOREBOARD_TLS_CACHE")
+	if cache == "" {
+		cache = "certs"
+	}
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Cache:      autocert.DirCache(cache),
+	}
+}
+
+// serveACMEChallenges answers Let's Encrypt's HTTP challenges on port 80 and
+// redirects everything else there to HTTPS. Certificates can still be issued
+// over the TLS port when port 80 isn't available, so failing to listen is
+// only logged.
+func serveACMEChallenges(m *autocert.Manager) {
+	addr := net.JoinHostPort(os.Getenv("SCOREBOARD_BIND"), "80")
+	if err := http.ListenAndServe(addr, m.HTTPHandler(nil)); err != nil {
+		log.Printf("not redirecting HTTP to HTTPS, %s is not available: %s", addr, err)
+	}
+}
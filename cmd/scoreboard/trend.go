@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+var (
+	// trendGames is the number of each player's most recent games that their
+	// momentum is measured over. Configured with SCOREBOARD_TREND_GAMES.
+	trendGames = 5
+	// trendDays, when non-zero, measures momentum over the trailing number of
+	// days instead of games. Configured with SCOREBOARD_TREND_DAYS.
+	trendDays = 0
+)
+
+// playerTrends returns each player's net rating change over the trend window.
+// The games must already be scored and sorted in the order they were played.
+func playerTrends(games []*Game, now time.Time) map[string]int {
+	trends := map[string]int{}
+	counted := map[string]int{}
+	cutoff := now.AddDate(0, 0, -trendDays)
+
+	for i := len(games) - 1; i >= 0; i-- {
+		game := games[i]
+		if trendDays > 0 && game.Timestamp.Before(cutoff) {
+			// games are in log order, which may not be date order, so a later
+			// one can still be inside the window
+			continue
+		}
+		for _, res := range game.Results {
+			if trendDays == 0 && counted[res.Player] >= trendGames {
+				continue
+			}
+			counted[res.Player]++
+			trends[res.Player] += res.Delta
+		}
+	}
+	return trends
+}
+
+// trendArrow formats a rating trend as an arrow with its magnitude.
+func trendArrow(trend int) string {
+	switch {
+	case trend > 0:
+		return fmt.Sprintf("▲%d", trend)
+	case trend < 0:
+		return fmt.Sprintf("▼%d", -trend)
+	}
+	return "–"
+}
+
+// trendWindow describes the configured trend window, e.g. "5 games".
+func trendWindow() string {
+	if trendDays > 0 {
+		return fmt.Sprintf("%d days", trendDays)
+	}
+	return fmt.Sprintf("%d games", trendGames)
+}
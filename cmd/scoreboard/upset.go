@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fly-apps/go-example/scoring"
+)
+
+// upsetStep multiplies the rating changes in an upset where the rating gap
+// between the players is at least Gap.
+type upsetStep = scoring.UpsetStep
+
+// upsetCurve amplifies upsets in the elo engine beyond the usual expectation
+// scaling, ordered by gap. It's empty unless configured with
+// SCOREBOARD_UPSET_CURVE.
+var upsetCurve []upsetStep
+
+// parseUpsetCurve parses a comma separated list of gap:multiplier steps, e.g.
+// "100:1.25,200:1.5,300:2".
+func parseUpsetCurve(s string) ([]upsetStep, error) {
+	var curve []upsetStep
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("upset step %q must look like 200:1.5", part)
+		}
+		gap, err := strconv.Atoi(strings.TrimSpace(kv[0]))
+		if err != nil || gap <= 0 {
+			return nil, fmt.Errorf("invalid rating gap in upset step %q", part)
+		}
+		m, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil || m < 1 {
+			return nil, fmt.Errorf("invalid multiplier in upset step %q, it must be at least 1", part)
+		}
+		curve = append(curve, upsetStep{Gap: gap, Multiplier: m})
+	}
+	sort.Slice(curve, func(i, j int) bool { return curve[i].Gap < curve[j].Gap })
+	return curve, nil
+}
+
+// upsetMultiplier returns the multiplier for the rating change of the player
+// at idx in a game finished in the order of ratings, the players' ratings
+// before the game.
+func upsetMultiplier(ratings []int, idx, delta int) float64 {
+	return scoringConfig().UpsetMultiplier(ratings, idx, delta)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fly-apps/go-example/internal/sheets"
+	"github.com/fly-apps/go-example/scoring"
+)
+
+// maxPlayers is the largest pod we have a reward curve for.
+const maxPlayers = scoring.MaxPlayers
+
+// severity is how serious a problem found in the game log is.
+type severity string
+
+const (
+	severityError   severity = "error"   // the row can't be scored correctly.
+	severityWarning severity = "warning" // the row is scored, or skipped on purpose, but looks suspicious.
+)
+
+// rowProblem is a problem found in a row of the game log.
+type rowProblem struct {
+	Row      int // the 1-indexed row number in the sheet.
+	GameID   string
+	Severity severity
+	Message  string
+}
+
+func (p rowProblem) String() string {
+	game := ""
+	if p.GameID != "" {
+		game = fmt.Sprintf(" (game %s)", p.GameID)
+	}
+	return fmt.Sprintf("row %d%s: %s: %s", p.Row, game, p.Severity, p.Message)
+}
+
+// validateGameData checks the raw game log rows against the schema expected by
+// parseGameData and returns every problem found, in row order.
+func validateGameData(values [][]interface{}) []rowProblem {
+	var problems []rowProblem
+	seen := map[string]int{}
+
+	// a changed layout would otherwise be parsed silently, like dates as names
+	for _, m := range schemaDiff(values) {
+		problems = append(problems, rowProblem{Row: 1, Severity: severityWarning, Message: m.String()})
+	}
+
+	for idx, row := range values {
+		if idx == 0 {
+			// skip the first row, it contains the game sheet labels
+			continue
+		}
+		rowNum := idx + 1
+		report := func(gameID string, sev severity, format string, args ...interface{}) {
+			problems = append(problems, rowProblem{
+				Row:      rowNum,
+				GameID:   gameID,
+				Severity: sev,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+
+		if len(row) == 0 {
+			continue
+		}
+		if len(row) < 4 {
+			report("", severityError, "malformed row, expected at least 4 columns but found %d", len(row))
+			continue
+		}
+
+		gameID := strings.TrimSpace(fmt.Sprintf("%s", row[0]))
+		if gameID == "" {
+			report("", severityError, "missing game ID")
+		} else if first, ok := seen[gameID]; ok {
+			report(gameID, severityError, "duplicate game ID, first used on row %d", first)
+		} else {
+			seen[gameID] = rowNum
+		}
+
+		date := fmt.Sprintf("%s", row[1])
+		if strings.TrimSpace(date) == "" {
+			report(gameID, severityError, "missing date")
+		} else if _, err := parseGameDate(date); err != nil {
+			report(gameID, severityError, "bad date %q, expected a date like %q", date, dateLayouts[0])
+		}
+
+		var players, cells []string
+		placements := map[string]int{}
+		blank := false
+		twoHeadedGiant := false
+		if len(row) > playerColumn {
+			for _, cell := range row[playerColumn:minInt(len(row), eliminationColumn)] {
+				name, placement := parsePlacementAnnotation(strings.TrimSpace(fmt.Sprintf("%s", cell)))
+				if name != "" {
+					cells = append(cells, name)
+				}
+				switch {
+				case name == "":
+					blank = true
+				case strings.Contains(name, "/"):
+					twoHeadedGiant = true
+				default:
+					if blank {
+						report(gameID, severityWarning, "blank player column before %q", name)
+						blank = false
+					}
+					players = append(players, name)
+					if placement > 0 {
+						placements[name] = placement
+					}
+				}
+			}
+		}
+
+		switch {
+		case twoHeadedGiant:
+			// one player against a team is an archenemy game, which is scored
+			if _, _, ok := parseArchenemy(cells); !ok {
+				report(gameID, severityWarning, "two-headed giant game is not scored")
+			}
+		case len(players) == 0:
+			report(gameID, severityError, "missing players")
+		case len(players) < 2:
+			report(gameID, severityError, "only one player, games need at least 2")
+		case len(players) > maxPlayers:
+			report(gameID, severityError, "%d players, games can have at most %d", len(players), maxPlayers)
+		default:
+			lowered := make([]string, len(players))
+			for i, name := range players {
+				lowered[i] = strings.ToLower(name)
+			}
+			if name := duplicatePlayer(lowered); name != "" {
+				report(gameID, severityError, "%q is listed twice, the game is not scored", name)
+			} else if err := applyPlacements(&Game{Rankings: players}, placements); err != nil {
+				report(gameID, severityError, "%s, the placements are ignored", err)
+			}
+		}
+	}
+
+	return problems
+}
+
+// validateCommand fetches the game log, validates it, and prints a report.
+// It exits non-zero if any errors were found.
+func validateCommand(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	sheetID := fs.String("sheet", spreadsheetID, "ID of the spreadsheet to validate")
+	sheetRange := fs.String("range", readRange, "range of the game log tab")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	opts, err := sheetsOptionFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "validate: %s\n", err)
+		return 1
+	}
+	values, err := sheets.FetchRows(context.Background(), *sheetID, *sheetRange, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "validate: %s\n", err)
+		return 1
+	}
+
+	if printValidationReport(os.Stdout, values) {
+		return 1
+	}
+	return 0
+}
+
+// printValidationReport writes the validation report for the rows and
+// reports whether any errors were found.
+func printValidationReport(w io.Writer, values [][]interface{}) bool {
+	problems := validateGameData(values)
+	errors, warnings := 0, 0
+	for _, p := range problems {
+		fmt.Fprintln(w, p)
+		if p.Severity == severityError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+
+	games := 0
+	if len(values) > 0 {
+		games = len(values) - 1
+	}
+	fmt.Fprintf(w, "checked %d rows: %d errors, %d warnings\n", games, errors, warnings)
+	return errors > 0
+}
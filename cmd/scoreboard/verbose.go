@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/fly-apps/go-example/internal/web"
+)
+
+// verbose can be turned on to log calculation output for debugging. It is
+// toggled at runtime with SIGUSR1 or the admin endpoint, so use isVerbose and
+// setVerbose rather than reading it directly.
+var verbose int32 = 1
+
+// isVerbose reports whether verbose logging is turned on.
+func isVerbose() bool {
+	return atomic.LoadInt32(&verbose) == 1
+}
+
+// setVerbose turns verbose logging on or off.
+func setVerbose(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	if atomic.SwapInt32(&verbose, v) != v {
+		log.Printf("verbose logging set to %t", on)
+	}
+}
+
+// toggleVerbose flips verbose logging and returns the new setting.
+func toggleVerbose() bool {
+	for {
+		old := atomic.LoadInt32(&verbose)
+		if atomic.CompareAndSwapInt32(&verbose, old, 1-old) {
+			log.Printf("verbose logging set to %t", old == 0)
+			return old == 0
+		}
+	}
+}
+
+// verboseHandler reports the verbose logging setting on GET and changes it on
+// POST, either to the value of the enabled form field or toggling it if the
+// field is absent.
+func verboseHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if v := r.PostFormValue("enabled"); v != "" {
+			on, err := strconv.ParseBool(v)
+			if err != nil {
+				badRequestRes(w, r, "enabled must be true or false")
+				return
+			}
+			setVerbose(on)
+		} else {
+			toggleVerbose()
+		}
+	default:
+		methodNotAllowedRes(w, r, "GET, POST")
+		return
+	}
+	web.WriteJSON(w, map[string]bool{"verbose": isVerbose()})
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchVerboseSignal toggles verbose logging whenever the process receives
+// SIGUSR1, e.g. `kill -USR1 $(pidof scoreboard)`.
+func watchVerboseSignal() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1)
+	go func() {
+		for range ch {
+			toggleVerbose()
+		}
+	}()
+}
@@ -0,0 +1,5 @@
+package main
+
+// watchVerboseSignal is a no-op on Windows, which has no SIGUSR1. Use the
+// admin endpoint instead.
+func watchVerboseSignal() {}
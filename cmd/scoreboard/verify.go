@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fly-apps/go-example/internal/sheets"
+)
+
+// PendingGame is a game recorded on the live page that's waiting for a
+// second player from the pod to confirm it before it's added to the game log.
+type PendingGame struct {
+	ID          int64
+	League      string
+	Game        *LiveGame
+	SubmittedBy string // the ID of the player who recorded the game.
+	CreatedAt   time.Time
+}
+
+// addPendingGame stores a game waiting for confirmation.
+func (s *store) addPendingGame(ctx context.Context, p *PendingGame) error {
+	b, err := json.Marshal(p.Game)
+	if err != nil {
+		return fmt.Errorf("failed to encode pending game: %w", err)
+	}
+	res, err := s.db.ExecContext(ctx, `INSERT INTO pending_games (league, game, submitted_by, created_at) VALUES (?, ?, ?, ?)`,
+		p.League, string(b), p.SubmittedBy, p.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add pending game: %w", err)
+	}
+	p.ID, err = res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to add pending game: %w", err)
+	}
+	return nil
+}
+
+// pendingGames returns the league's games waiting for confirmation, oldest
+// first.
+func (s *store) pendingGames(ctx context.Context, league string) ([]*PendingGame, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, league, game, submitted_by, created_at
+		FROM pending_games WHERE league = ? ORDER BY id`, league)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pending games: %w", err)
+	}
+	defer rows.Close()
+
+	var games []*PendingGame
+	for rows.Next() {
+		p, err := scanPendingGame(rows)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load pending games: %w", err)
+	}
+	return games, nil
+}
+
+// pendingGame returns one of the league's games waiting for confirmation.
+func (s *store) pendingGame(ctx context.Context, league string, id int64) (*PendingGame, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT id, league, game, submitted_by, created_at
+		FROM pending_games WHERE league = ? AND id = ?`, league, id)
+	p, err := scanPendingGame(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errNotFound
+	}
+	return p, err
+}
+
+func scanPendingGame(row interface{ Scan(...interface{}) error }) (*PendingGame, error) {
+	p := &PendingGame{}
+	var game string
+	if err := row.Scan(&p.ID, &p.League, &game, &p.SubmittedBy, &p.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to load pending game: %w", err)
+	}
+	if err := json.Unmarshal([]byte(game), &p.Game); err != nil {
+		return nil, fmt.Errorf("failed to decode pending game %d: %w", p.ID, err)
+	}
+	return p, nil
+}
+
+// deletePendingGame removes a game that was confirmed or rejected.
+func (s *store) deletePendingGame(ctx context.Context, id int64) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM pending_games WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete pending game %d: %w", id, err)
+	}
+	return nil
+}
+
+// canConfirm reports whether the player can confirm the pending game: they
+// must have played in it and not be the one who recorded it.
+func (p *PendingGame) canConfirm(ds *Dataset, player string) bool {
+	if player == p.SubmittedBy {
+		return false
+	}
+	for _, name := range p.Game.Players {
+		if ds.playerID(name) == player {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyHandler returns the handler for /live/verify, where a second player
+// from the pod confirms a pending game, which adds it to the game log, or
+// rejects it. The player who recorded the game can also withdraw it.
+func verifyHandler(l *league) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !l.live || l.pending == nil {
+			notFoundRes(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			methodNotAllowedRes(w, r, "POST")
+			return
+		}
+		user := l.currentPlayer(r)
+		if user == "" {
+			unauthorizedRes(w, r, "log in to confirm games")
+			return
+		}
+		id, err := strconv.ParseInt(r.PostFormValue("id"), 10, 64)
+		if err != nil {
+			badRequestRes(w, r, "id must be a pending game ID")
+			return
+		}
+		action := r.PostFormValue("action")
+		if action != "confirm" && action != "reject" {
+			badRequestRes(w, r, "action must be confirm or reject")
+			return
+		}
+
+		l.recording.Lock()
+		defer l.recording.Unlock()
+
+		p, err := l.pending.pendingGame(r.Context(), l.snapshotKey(), id)
+		switch {
+		case errors.Is(err, errNotFound):
+			errorPage(w, r, http.StatusNotFound, "the game was already confirmed or rejected")
+			return
+		case err != nil:
+			log.Printf("error loading pending game: %+v", err)
+			errorRes(w, r, err)
+			return
+		}
+		ds, err := loadDataset(w, r, l)
+		if err != nil {
+			return
+		}
+		withdraw := action == "reject" && user == p.SubmittedBy
+		if !withdraw && !p.canConfirm(ds, user) {
+			errorPage(w, r, http.StatusForbidden, "only another player from the pod can confirm or reject the game")
+			return
+		}
+
+		if action == "confirm" {
+			id := nextGameID(ds.Games)
+			if err := sheets.AppendRow(r.Context(), l.spreadsheetID, l.ranges.Games, p.Game.row(id), l.opts...); err != nil {
+				log.Printf("error recording confirmed game: %+v", err)
+				errorRes(w, r, err)
+				return
+			}
+			log.Printf("%s confirmed game %s recorded by %s", user, id, p.SubmittedBy)
+			if err := l.pending.deletePendingGame(r.Context(), p.ID); err != nil {
+				log.Printf("error removing confirmed game: %+v", err)
+			}
+			http.Redirect(w, r, basePath(r)+"/game/"+id, http.StatusSeeOther)
+			return
+		}
+
+		if err := l.pending.deletePendingGame(r.Context(), p.ID); err != nil {
+			log.Printf("error rejecting pending game: %+v", err)
+			errorRes(w, r, err)
+			return
+		}
+		log.Printf("%s rejected pending game %d recorded by %s", user, p.ID, p.SubmittedBy)
+		http.Redirect(w, r, basePath(r)+"/live", http.StatusSeeOther)
+	}
+}
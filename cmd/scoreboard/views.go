@@ -0,0 +1,413 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"time"
+)
+
+// PageView is what every page's template is given: the scoreboard's version,
+// the base path links are relative to and the page's meta tags.
+type PageView struct {
+	Version string
+	Base    string
+	Meta    PageMeta
+}
+
+// newPageView returns the view shared by every page for the request.
+func newPageView(r *http.Request, title, description string) PageView {
+	return PageView{Version: version, Base: basePath(r), Meta: pageMeta(r, title, description)}
+}
+
+// barePageView returns the view shared by every page for a page without meta
+// tags, like forms and error pages.
+func barePageView(r *http.Request) PageView {
+	return PageView{Version: version, Base: basePath(r)}
+}
+
+// IndexView is the data of the leaderboard, index.html.tmpl.
+type IndexView struct {
+	PageView
+
+	View             string // the board shown: rating, performance, hot or recency.
+	PerformanceGames int
+	HotDays          int
+	FieldPodSize     int
+	HalfLifeDays     int
+	TrendWindow      string
+
+	Games    []*Game
+	Scores   map[string]int
+	Rankings []Player
+	Total    int
+
+	Tag      string // the tag the board is rated over, "" for every game.
+	Tags     []TagCount
+	Season   string // the season the board is rated over, "" for all time.
+	PodSize  int    // the pod size the board is rated over, 0 for every pod.
+	Favorite string // the ID of the player the visitor highlights.
+
+	// Trial is the engine trialled alongside the current one on the rating
+	// board, with the ratings and ranks it gives each player by ID.
+	Trial       string
+	TrialScores map[string]int
+	TrialRanks  map[string]int
+
+	Recap *NightRecap // the latest league night's recap, on the all-time rating board.
+
+	Houses       []HouseStanding
+	HouseScoring string
+	PrizePool    *PrizePool
+
+	// the links shown for the league's optional features
+	Pickem, Live, Archive, Rules, Refresh bool
+}
+
+// PlayerView is the data of a player's page, player.html.tmpl.
+type PlayerView struct {
+	PageView
+
+	Player     *Player
+	Rank       int
+	Players    int // the number of ranked players.
+	History    []PlayerGame
+	Rivals     []string // the IDs of the player's declared rivals.
+	Nemesis    *Opponent
+	Victim     *Opponent
+	Names      playerNames
+	Milestones []Milestone
+	WAR        WAR
+	Comments   *CommentsView
+
+	PlacementChart  template.HTML
+	PercentileChart template.HTML
+}
+
+// StatsView is the data of the eras page, eras.html.tmpl, which compares the
+// stats of two eras of the league.
+type StatsView struct {
+	PageView
+
+	Seasons []Season
+	Names   playerNames
+	Eras    []EraStats // the two eras compared, or nil until both are picked.
+}
+
+// CommentsView is the data of a page's comments, comments.html.tmpl.
+type CommentsView struct {
+	Base     string
+	Names    playerNames
+	Kind     string
+	Target   string
+	Comments []*Comment
+	User     string // the logged in player, "" if there isn't one.
+	Path     string // the page's path, to come back to after logging in.
+}
+
+// ErrorView is the data of the error page, error.html.tmpl.
+type ErrorView struct {
+	PageView
+
+	Status  int
+	Title   string
+	Message string
+}
+
+// LoginView is the data of the login page, login.html.tmpl.
+type LoginView struct {
+	PageView
+
+	Next   string // where to go once logged in.
+	Player string // the name a failed login was for.
+	Errors string
+}
+
+// GameView is the data of a game's page, game.html.tmpl.
+type GameView struct {
+	PageView
+
+	Game      *Game
+	SheetRow  string // a link to the game's row in the sheet, "" if unknown.
+	Strength  *PodStrength
+	Rivalries [][2]string
+	Names     playerNames
+	Comments  *CommentsView
+	Reports   *ReportsView
+}
+
+// ExplainView is the data of the page explaining how a game was scored,
+// explain.html.tmpl.
+type ExplainView struct {
+	PageView
+
+	Game        *Game
+	Engine      string
+	Explanation *GameExplanation // nil when the engine has no explanation.
+	Names       playerNames
+}
+
+// ReportsView is the data of a game's reports, shown on its page.
+type ReportsView struct {
+	Game    string
+	Reports []*GameReport
+	User    string // the logged in player, "" if there isn't one.
+	Path    string // the page's path, to come back to after logging in.
+}
+
+// PicksView is the data of the pick-em page, picks.html.tmpl.
+type PicksView struct {
+	PageView
+
+	Path    string // the page's path, to come back to after logging in.
+	User    string // the logged in player, "" if there isn't one.
+	Players []Player
+	GameID  string // the ID the next game will be logged under.
+	Names   playerNames
+
+	Saved     *Prediction // the pick just made.
+	Errors    string
+	Standings []PickemStanding
+	Open      map[string][]*Prediction // the picks for games not logged yet, by game ID.
+}
+
+// LiveView is the data of the live game entry page, live.html.tmpl.
+type LiveView struct {
+	PageView
+
+	Players []Player
+	GameID  string // the ID the game will be logged under.
+	User    string // the logged in player recording the game.
+	Errors  string
+
+	// Verify is whether games are held until confirmed, with the games
+	// waiting in Pending.
+	Verify  bool
+	Pending []PendingView
+}
+
+// PendingView is a game waiting for confirmation on the live game entry page.
+type PendingView struct {
+	Game       *PendingGame
+	CanConfirm bool // whether the logged in player may confirm it.
+	Mine       bool // whether the logged in player recorded it.
+}
+
+// ScheduleView is the data of the scheduling polls, schedule.html.tmpl: the
+// list of polls, or one poll when Poll is set.
+type ScheduleView struct {
+	PageView
+
+	User string // the logged in player, "" if there isn't one.
+	Path string // the page's path, to come back to after logging in.
+
+	Polls      []*Poll
+	Title      string // the title of a poll that failed to be created.
+	DatesInput string // the dates of a poll that failed to be created.
+	Errors     string
+
+	Poll    *Poll
+	Dates   []DateAvailability
+	Mine    map[string]bool // the dates the logged in player is available, as YYYY-MM-DD.
+	Best    *DateAvailability
+	Pods    [][]string
+	Ratings map[string]int // the ratings of the players available on the best date, by name.
+}
+
+// SetupView is the data of the setup wizard, setup.html.tmpl.
+type SetupView struct {
+	PageView
+
+	Step    string // the wizard's step: sheet, rating or saved.
+	Token   string // the setup token, which the wizard's forms post back.
+	Config  SetupConfig
+	Path    string // where the configuration is written.
+	Columns []DetectedColumn
+	Games   int // the number of games found in the sheet.
+	Errors  string
+
+	Engines     []string
+	Curves      []string
+	DateFormats []string
+}
+
+// OnboardingView is the data of the page explaining how to fill in an empty
+// game log, onboarding.html.tmpl.
+type OnboardingView struct {
+	PageView
+
+	ReadRange   string
+	DateFormat  string
+	DateExample string
+	TemplateURL string // a template sheet to copy, "" if there isn't one.
+}
+
+// RegisterView is the data of the hosted mode registration page,
+// register.html.tmpl.
+type RegisterView struct {
+	PageView
+
+	Domain     string // the hosted domain tenants get subdomains of.
+	Tenant     *Tenant
+	Registered bool
+	Errors     string
+}
+
+// ArchiveView is the data of the list of archived weeks, archive.html.tmpl.
+type ArchiveView struct {
+	PageView
+
+	Weeks []string
+}
+
+// ArchivedWeekView is the data of an archived week's standings,
+// archive_week.html.tmpl. It's rendered once and stored, so its links are
+// relative to the root rather than the request's base path.
+type ArchivedWeekView struct {
+	PageView
+
+	Week     string
+	Ended    time.Time
+	Season   string // the season the week was in, "" without seasons.
+	Games    int    // the number of games played by the end of the week.
+	Rankings []Player
+}
+
+// AttendanceView is the data of the attendance page, attendance.html.tmpl.
+type AttendanceView struct {
+	PageView
+
+	Season   Season // the season shown, the zero season for all time.
+	Seasons  []Season
+	Players  []PlayerAttendance
+	Activity LeagueActivity
+	Split    int // the number of players from which a night splits into pods.
+}
+
+// CareerView is the data of a player's career page, career.html.tmpl.
+type CareerView struct {
+	PageView
+
+	Career *Career
+	Names  playerNames
+}
+
+// CompareView is the data of the cross-league comparison, compare.html.tmpl.
+// It's served outside any league, so its links are relative to the root.
+type CompareView struct {
+	PageView
+
+	Slugs   string       // the compared leagues' slugs, comma separated.
+	Compare *CrossLeague // nil until at least two leagues are picked.
+}
+
+// CurvesView is the data of the reward curves report, curves.html.tmpl.
+type CurvesView struct {
+	PageView
+
+	Season  Season // the season reported on, the zero season for all time.
+	Seasons []Season
+	Curve   string // the configured reward curve's shape.
+	Sizes   []PodSizeReport
+}
+
+// EventsView is the data of the events pages, events.html.tmpl: the list of
+// events, or one event's pods when Event is set.
+type EventsView struct {
+	PageView
+
+	Events  []*Event
+	Names   playerNames
+	Limited []Player // the limited rating board, with SCOREBOARD_LIMITED_ELO.
+
+	Event *Event
+	Pods  []EventPodView
+}
+
+// EventPodView is a pod of an event with its standings.
+type EventPodView struct {
+	*EventPod
+	Standings []EventStanding
+}
+
+// HonorsView is the data of the dubious honors page, honors.html.tmpl.
+type HonorsView struct {
+	PageView
+
+	Honors DubiousHonors
+	Names  playerNames
+}
+
+// TimelineView is the data of the timeline, timeline.html.tmpl.
+type TimelineView struct {
+	PageView
+
+	Milestones []Milestone // most recent first.
+	Names      playerNames
+}
+
+// PodsView is the data of the pod planner, pods.html.tmpl. Players, Pods and
+// Tiers are the form's inputs as given.
+type PodsView struct {
+	PageView
+
+	Players string
+	Pods    string
+	Tiers   string
+	Mixed   bool
+
+	Suggested []SuggestedPod
+	Errors    string
+}
+
+// PreferencesView is the data of the preferences page,
+// preferences.html.tmpl.
+type PreferencesView struct {
+	PageView
+
+	Preferences Preferences
+	Seasons     []Season
+	PodSizes    []int
+	Players     []Player // every player who has played, by name.
+}
+
+// PrintView is the data of the printable standings, print.html.tmpl.
+type PrintView struct {
+	PageView
+
+	Sheet PrintSheet
+}
+
+// ProjectionsView is the data of the projections page,
+// projections.html.tmpl. OK is false when no season is running.
+type ProjectionsView struct {
+	PageView
+
+	Season      Season
+	OK          bool
+	Projections []Projection
+	TrendGames  int
+}
+
+// ReplayView is the data of the replay page, replay.html.tmpl.
+type ReplayView struct {
+	PageView
+
+	Frames []ReplayFrame
+	Last   int // the index of the last frame.
+}
+
+// RivalryView is the data of a rivalry page, rivalry.html.tmpl.
+type RivalryView struct {
+	PageView
+
+	Matchup *Matchup
+	Games   []MatchupGame // most recent first.
+	Names   playerNames
+}
+
+// RulesView is the data of the rules page, rules.html.tmpl.
+type RulesView struct {
+	PageView
+
+	Rules []RuleBlock
+}
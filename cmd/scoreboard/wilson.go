@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// wilsonZ is the z-score of the 95% confidence level win rate intervals are
+// shown at.
+const wilsonZ = 1.96
+
+// wilsonInterval returns the Wilson score confidence interval of a win rate,
+// which unlike the plain rate stays wide for players with only a few games,
+// so 1 win in 1 game is 21% to 100% rather than a flat 100%.
+func wilsonInterval(wins, games int) (low, high float64) {
+	if games <= 0 {
+		return 0, 1
+	}
+	n := float64(games)
+	p := float64(wins) / n
+	z2 := wilsonZ * wilsonZ
+	center := (p + z2/(2*n)) / (1 + z2/n)
+	spread := wilsonZ / (1 + z2/n) * math.Sqrt(p*(1-p)/n+z2/(4*n*n))
+	return math.Max(0, center-spread), math.Min(1, center+spread)
+}
+
+// winRate formats a win rate along with its 95% confidence interval, e.g.
+// "50% [24–76%]".
+func winRate(wins, games int) string {
+	if games <= 0 {
+		return "no games"
+	}
+	low, high := wilsonInterval(wins, games)
+	return fmt.Sprintf("%.0f%% [%.0f–%.0f%%]", float64(wins)/float64(games)*100, low*100, high*100)
+}
@@ -4,6 +4,9 @@ go 1.16
 
 require (
 	github.com/kortemy/elo-go v0.0.0-20190919090953-f9d3a99fd7b7
+	golang.org/x/crypto v0.10.0
+	golang.org/x/image v0.0.0-20220302094943-723b81ca9867
 	golang.org/x/oauth2 v0.9.0 // indirect
 	google.golang.org/api v0.128.0
+	modernc.org/sqlite v1.23.1
 )
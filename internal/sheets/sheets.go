@@ -0,0 +1,172 @@
+// Package sheets reads and writes the league's spreadsheet through the
+// Google Sheets API, and works with ranges in A1 notation.
+package sheets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// ErrNoData is returned when a range has no rows at all.
+var ErrNoData = errors.New("no game data found")
+
+// FetchRows fetches the raw rows for the given spreadsheet and range from
+// Google Sheets API.
+func FetchRows(ctx context.Context, spreadsheetID, readRange string, opts ...option.ClientOption) ([][]interface{}, error) {
+	srv, err := sheets.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve Google Sheets client: %w", err)
+	}
+
+	resp, err := srv.Spreadsheets.Values.Get(spreadsheetID, readRange).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve data from sheet: %w", err)
+	}
+	if len(resp.Values) == 0 {
+		return nil, ErrNoData
+	}
+
+	return resp.Values, nil
+}
+
+// AppendRow appends a row to the table in the given range of the spreadsheet,
+// which needs credentials with write access to the sheet.
+func AppendRow(ctx context.Context, spreadsheetID, writeRange string, row []interface{}, opts ...option.ClientOption) error {
+	srv, err := sheets.NewService(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve Google Sheets client: %w", err)
+	}
+
+	vr := &sheets.ValueRange{Values: [][]interface{}{row}}
+	_, err = srv.Spreadsheets.Values.Append(spreadsheetID, writeRange, vr).ValueInputOption("RAW").InsertDataOption("INSERT_ROWS").Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("unable to append row to sheet: %w", err)
+	}
+	return nil
+}
+
+// UpdateCells overwrites single cells of the spreadsheet in one batch
+// request. The values are keyed by the cell's A1 notation, e.g. "Games!F12".
+func UpdateCells(ctx context.Context, spreadsheetID string, cells map[string]string, opts ...option.ClientOption) error {
+	srv, err := sheets.NewService(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve Google Sheets client: %w", err)
+	}
+
+	req := &sheets.BatchUpdateValuesRequest{ValueInputOption: "RAW"}
+	for cell, value := range cells {
+		req.Data = append(req.Data, &sheets.ValueRange{Range: cell, Values: [][]interface{}{{value}}})
+	}
+	_, err = srv.Spreadsheets.Values.BatchUpdate(spreadsheetID, req).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("unable to update cells in sheet: %w", err)
+	}
+	return nil
+}
+
+// FetchRanges fetches the raw rows of several ranges of the spreadsheet in a
+// single batch request. The rows are returned in the same order as the ranges.
+func FetchRanges(ctx context.Context, spreadsheetID string, ranges []string, opts ...option.ClientOption) ([][][]interface{}, error) {
+	srv, err := sheets.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve Google Sheets client: %w", err)
+	}
+
+	resp, err := srv.Spreadsheets.Values.BatchGet(spreadsheetID).Ranges(ranges...).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve data from sheet: %w", err)
+	}
+	if len(resp.ValueRanges) != len(ranges) {
+		return nil, fmt.Errorf("expected %d ranges from sheet but got %d", len(ranges), len(resp.ValueRanges))
+	}
+
+	values := make([][][]interface{}, len(ranges))
+	for i, vr := range resp.ValueRanges {
+		values[i] = vr.Values
+	}
+	return values, nil
+}
+
+// IsQuotaError reports whether err is Google rejecting a request for quota,
+// with a 429 or a rate limit error.
+func IsQuotaError(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.Code == http.StatusTooManyRequests {
+		return true
+	}
+	for _, item := range apiErr.Errors {
+		switch item.Reason {
+		case "rateLimitExceeded", "userRateLimitExceeded", "quotaExceeded":
+			return true
+		}
+	}
+	return apiErr.Code == http.StatusForbidden && strings.Contains(strings.ToLower(apiErr.Message), "quota")
+}
+
+// RangeStart returns the sheet prefix, e.g. "Games!", and the zero based
+// column and one based row of the top left cell of the range in A1 notation.
+func RangeStart(rng string) (string, int, int) {
+	sheet := ""
+	if idx := strings.LastIndex(rng, "!"); idx >= 0 {
+		sheet, rng = rng[:idx+1], rng[idx+1:]
+	}
+	if idx := strings.Index(rng, ":"); idx >= 0 {
+		rng = rng[:idx]
+	}
+	col, row := 0, 0
+	for _, r := range strings.ToUpper(rng) {
+		switch {
+		case unicode.IsLetter(r):
+			col = col*26 + int(r-'A'+1)
+		case unicode.IsDigit(r):
+			row = row*10 + int(r-'0')
+		}
+	}
+	if col == 0 {
+		col = 1
+	}
+	if row == 0 {
+		row = 1
+	}
+	return sheet, col - 1, row
+}
+
+// RangeEnd returns the column letters and one based row of the bottom right
+// cell of the range in A1 notation. The row is 0 for whole columns, like
+// "A:X", and the column is "" for a range without an end, like "Games!A1".
+func RangeEnd(rng string) (string, int) {
+	idx := strings.Index(rng, ":")
+	if idx < 0 {
+		return "", 0
+	}
+	col, row := "", 0
+	for _, r := range strings.ToUpper(rng[idx+1:]) {
+		switch {
+		case unicode.IsLetter(r):
+			col += string(r)
+		case unicode.IsDigit(r):
+			row = row*10 + int(r-'0')
+		}
+	}
+	return col, row
+}
+
+// ColumnLetter returns the A1 notation of the zero based column.
+func ColumnLetter(col int) string {
+	s := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		s = string(rune('A'+(col-1)%26)) + s
+	}
+	return s
+}
@@ -0,0 +1,106 @@
+package storage
+
+// migrations are applied in order to bring the schema up to date. Append new
+// migrations to the end; never edit one that has shipped.
+var migrations = []string{
+	`CREATE TABLE tenants (
+		slug TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		spreadsheet_id TEXT NOT NULL,
+		read_range TEXT NOT NULL,
+		api_key TEXT NOT NULL DEFAULT '',
+		quota_per_hour INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE TABLE predictions (
+		league TEXT NOT NULL,
+		game_id TEXT NOT NULL,
+		member TEXT NOT NULL,
+		pick TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (league, game_id, member)
+	)`,
+	`CREATE TABLE comments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		league TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		target TEXT NOT NULL,
+		author TEXT NOT NULL,
+		body TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		hidden INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX comments_target ON comments (league, kind, target)`,
+	`CREATE TABLE rating_snapshots (
+		league TEXT NOT NULL,
+		as_of TEXT NOT NULL,
+		version TEXT NOT NULL,
+		rankings TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (league, as_of)
+	)`,
+	`CREATE TABLE pending_games (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		league TEXT NOT NULL,
+		game TEXT NOT NULL,
+		submitted_by TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE TABLE polls (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		league TEXT NOT NULL,
+		title TEXT NOT NULL,
+		dates TEXT NOT NULL,
+		created_by TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE TABLE poll_availability (
+		poll_id INTEGER NOT NULL,
+		player TEXT NOT NULL,
+		date TEXT NOT NULL,
+		PRIMARY KEY (poll_id, player, date)
+	)`,
+	`CREATE TABLE standings_archive (
+		league TEXT NOT NULL,
+		week TEXT NOT NULL,
+		html BLOB NOT NULL,
+		png BLOB NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (league, week)
+	)`,
+	`CREATE TABLE game_reports (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		league TEXT NOT NULL,
+		game TEXT NOT NULL,
+		author TEXT NOT NULL,
+		writeup TEXT NOT NULL,
+		photo TEXT NOT NULL,
+		photo_type TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE INDEX game_reports_game ON game_reports (league, game)`,
+	`CREATE TABLE sheet_rows (
+		league TEXT NOT NULL,
+		tab TEXT NOT NULL,
+		row INTEGER NOT NULL,
+		cells TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		PRIMARY KEY (league, tab, row)
+	)`,
+	`CREATE TABLE sheet_imports (
+		league TEXT NOT NULL,
+		tab TEXT NOT NULL,
+		source TEXT NOT NULL,
+		rows INTEGER NOT NULL,
+		checksum TEXT NOT NULL,
+		imported_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (league, tab)
+	)`,
+	`CREATE TABLE player_id_migrations (
+		league TEXT PRIMARY KEY,
+		migrated_at TIMESTAMP NOT NULL
+	)`,
+	// hosted tenants' rating snapshots are keyed by slug now, and the ones
+	// keyed by spreadsheet are rebuilt on demand
+	`DELETE FROM rating_snapshots WHERE league IN (SELECT spreadsheet_id FROM tenants)`,
+}
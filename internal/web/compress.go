@@ -0,0 +1,140 @@
+// Package web holds the scoreboard's HTTP plumbing that doesn't depend on the
+// league: listening, compression, CORS, reverse proxies and JSON responses.
+package web
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	},
+}
+
+// Compress gzips the responses of next for clients that accept it. Responses
+// that are already encoded or are compressed formats like PNG are passed
+// through untouched. Brotli isn't offered, since the standard library has no
+// encoder for it, so clients that only accept br get uncompressed responses.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w, accept: AcceptsGzip(r.Header.Get("Accept-Encoding"))}
+		defer gw.Close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// AcceptsGzip reports whether the Accept-Encoding header allows gzip, either
+// by name or through *. Naming gzip takes precedence over *, so
+// "*, gzip;q=0" refuses it.
+func AcceptsGzip(header string) bool {
+	named, wildcard := -1.0, -1.0
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && kv[0] == "q" {
+				q, _ = strconv.ParseFloat(kv[1], 64)
+			}
+		}
+		if coding == "gzip" {
+			named = q
+		} else {
+			wildcard = q
+		}
+	}
+	if named >= 0 {
+		return named > 0
+	}
+	return wildcard > 0
+}
+
+// gzipResponseWriter decides whether to compress once the handler sets its
+// status, based on the headers it set.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	accept      bool // whether the client accepts gzip.
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	h := w.Header()
+	if !compressible(h.Get("Content-Type")) || h.Get("Content-Encoding") != "" {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if !strings.Contains(h.Get("Vary"), "Accept-Encoding") {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if w.accept && status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.gz.Write(b)
+}
+
+// Flush flushes the compressed data written so far, for streamed responses.
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the gzip stream and returns the writer to the pool.
+func (w *gzipResponseWriter) Close() {
+	if w.gz == nil {
+		return
+	}
+	w.gz.Close()
+	gzipWriters.Put(w.gz)
+	w.gz = nil
+}
+
+// compressible reports whether responses of the content type are worth
+// compressing.
+func compressible(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range []string{"text/", "application/json", "application/x-ndjson", "application/xml", "application/javascript", "image/svg+xml"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CORSPolicy is the cross-origin access allowed to the API, so dashboards on
+// other origins can call it from the browser.
+type CORSPolicy struct {
+	Origins []string // allowed origins, or "*" for any.
+	Methods string   // allowed methods, for preflight requests.
+	Headers string   // allowed request headers, for preflight requests.
+}
+
+// ParseCORSOrigins parses a comma separated list of origins, e.g.
+// "https://dash.example.com,http://localhost:3000", or "*" for any origin.
+func ParseCORSOrigins(s string) []string {
+	var origins []string
+	for _, origin := range strings.Split(s, ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// AllowedOrigin returns the value of the Access-Control-Allow-Origin header
+// for a request from origin, or "" if the origin isn't allowed.
+func (p CORSPolicy) AllowedOrigin(origin string) string {
+	for _, o := range p.Origins {
+		if o == "*" {
+			return "*"
+		}
+		if strings.EqualFold(o, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// WithCORS applies the policy to next. Preflight requests are answered
+// directly.
+func WithCORS(p CORSPolicy, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || len(p.Origins) == 0 {
+			next(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		allowed := p.AllowedOrigin(origin)
+		if allowed != "" {
+			h.Set("Access-Control-Allow-Origin", allowed)
+		}
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if allowed != "" {
+				h.Set("Access-Control-Allow-Methods", p.Methods)
+				h.Set("Access-Control-Allow-Headers", p.Headers)
+				h.Set("Access-Control-Max-Age", "600")
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,53 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// UnixPrefix marks a listen address as the path of a Unix domain socket.
+const UnixPrefix = "unix:"
+
+// ListenAddrs returns the addresses to listen on: the comma separated list in
+// SCOREBOARD_LISTEN, or else the port on SCOREBOARD_BIND's address.
+func ListenAddrs(port string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(os.Getenv("SCOREBOARD_LISTEN"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		addrs = []string{net.JoinHostPort(os.Getenv("SCOREBOARD_BIND"), port)}
+	}
+	return addrs
+}
+
+// Listen listens on the address, a TCP host and port like 127.0.0.1:8080 or
+// [::1]:8080, or a Unix domain socket like unix:/run/scoreboard.sock. A socket
+// file left behind by a previous run is replaced.
+func Listen(addr string) (net.Listener, error) {
+	if !strings.HasPrefix(addr, UnixPrefix) {
+		return net.Listen("tcp", addr)
+	}
+	path := strings.TrimPrefix(addr, UnixPrefix)
+	if path == "" {
+		return nil, fmt.Errorf("%q is missing the socket's path", addr)
+	}
+	if fi, err := os.Stat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and isn't a socket", path)
+		}
+		// a server still listening on it is caught by the dial
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another server", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+	return net.Listen("unix", path)
+}
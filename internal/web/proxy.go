@@ -0,0 +1,101 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TrustedProxies are the networks of the reverse proxies, like nginx or
+// Traefik, whose X-Forwarded-Proto and X-Forwarded-Host headers are believed
+// when generating absolute URLs. Configured with SCOREBOARD_TRUSTED_PROXIES,
+// and only proxies on the same host are trusted by default.
+var TrustedProxies, _ = ParseTrustedProxies("127.0.0.0/8, ::1")
+
+// ParseTrustedProxies parses a comma separated list of IP addresses and CIDR
+// ranges, e.g. "10.0.0.0/8, 192.168.1.10".
+func ParseTrustedProxies(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			ip := net.ParseIP(part)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid proxy address %q", part)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy range %q", part)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// FromTrustedProxy reports whether the request came through a trusted
+// reverse proxy. A proxy connecting over a Unix domain socket is always
+// trusted, since only processes on the host allowed to open the socket can.
+func FromTrustedProxy(r *http.Request) bool {
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && addr.Network() == "unix" {
+		return true
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range TrustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ForwardedHeader returns the first value of a forwarded header set by a
+// trusted proxy, or "" if there isn't one. Proxies in a chain append their
+// values, so the first is the one the client connected to.
+func ForwardedHeader(r *http.Request, name string) string {
+	if !FromTrustedProxy(r) {
+		return ""
+	}
+	v := r.Header.Get(name)
+	if idx := strings.Index(v, ","); idx >= 0 {
+		v = v[:idx]
+	}
+	return strings.TrimSpace(v)
+}
+
+// RequestScheme returns the scheme the client requested, "http" or "https".
+func RequestScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	if proto := strings.ToLower(ForwardedHeader(r, "X-Forwarded-Proto")); proto == "https" || proto == "http" {
+		return proto
+	}
+	return "http"
+}
+
+// RequestHost returns the host the client requested, which a trusted proxy
+// may have passed on in X-Forwarded-Host.
+func RequestHost(r *http.Request) string {
+	if host := ForwardedHeader(r, "X-Forwarded-Host"); host != "" {
+		return host
+	}
+	return r.Host
+}
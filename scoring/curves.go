@@ -0,0 +1,90 @@
+package scoring
+
+// MaxPlayers is the largest pod there are reward curves for.
+const MaxPlayers = 6
+
+// reward curves for different numbers of players in a game
+var (
+	twoPlayers   = []float64{1.0, 0}
+	threePlayers = []float64{1.0, 0.5, 0}
+	fourPlayers  = []float64{1.0, 0.5, 0.25, 0}
+	fivePlayers  = []float64{1.0, 0.5, 0.25, 0.12, 0}
+	sixPlayers   = []float64{1.0, 0.5, 0.25, 0.12, 0.05, 0}
+)
+
+// Curves are the accepted reward curve shapes: "default" for the league's
+// original curves, "linear" to split the rewards evenly between the places,
+// or "winner" to only reward the winner.
+var Curves = []string{"default", "linear", "winner"}
+
+// RewardCurve returns the score awarded for each place in a pod of the given
+// size, or nil if there's no curve for the size. The curve's shape is set by
+// the config's Curve.
+func (c Config) RewardCurve(numPlayers int) []float64 {
+	if numPlayers < 2 || numPlayers > MaxPlayers {
+		return nil
+	}
+	switch c.Curve {
+	case "linear":
+		curve := make([]float64, numPlayers)
+		for idx := range curve {
+			curve[idx] = float64(numPlayers-1-idx) / float64(numPlayers-1)
+		}
+		return curve
+	case "winner":
+		curve := make([]float64, numPlayers)
+		curve[0] = 1
+		return curve
+	}
+	switch numPlayers {
+	case 2:
+		return twoPlayers
+	case 3:
+		return threePlayers
+	case 4:
+		return fourPlayers
+	case 5:
+		return fivePlayers
+	case 6:
+		return sixPlayers
+	}
+	return nil
+}
+
+// UpsetStep multiplies the rating changes in an upset where the rating gap
+// between the players is at least Gap.
+type UpsetStep struct {
+	Gap        int
+	Multiplier float64
+}
+
+// UpsetMultiplier returns the multiplier for the rating change of the player
+// at idx in a game finished in the order of ratings, the players' ratings
+// before the game. An underdog's gain is amplified by the largest gap to a
+// player they finished above, and a favorite's loss by the largest gap to a
+// player who finished above them.
+func (c Config) UpsetMultiplier(ratings []int, idx, delta int) float64 {
+	gap := 0
+	switch {
+	case delta > 0:
+		for _, r := range ratings[idx+1:] {
+			if r-ratings[idx] > gap {
+				gap = r - ratings[idx]
+			}
+		}
+	case delta < 0:
+		for _, r := range ratings[:idx] {
+			if ratings[idx]-r > gap {
+				gap = ratings[idx] - r
+			}
+		}
+	}
+
+	m := 1.0
+	for _, step := range c.Upsets {
+		if gap >= step.Gap {
+			m = step.Multiplier
+		}
+	}
+	return m
+}
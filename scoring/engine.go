@@ -0,0 +1,383 @@
+// Package scoring rates the players of multiplayer games, like commander pods,
+// from the order they finished in. It's the rating logic behind the
+// scoreboard, so other programs can score games the same way.
+package scoring
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	elogo "github.com/kortemy/elo-go"
+)
+
+// RatingEngine rates players from the games they've played.
+type RatingEngine interface {
+	// Initialize resets the engine to its starting state with no players.
+	Initialize()
+	// ScoreGame updates the player ratings with the result of the game, which
+	// must be scored in the order it was played, and records each player's
+	// result on the game.
+	ScoreGame(game *Game) error
+	// Snapshot returns the current rating of every player the engine has seen.
+	Snapshot() map[string]int
+}
+
+// Adjuster is implemented by rating engines that support manual adjustments.
+type Adjuster interface {
+	// Adjust changes the player's rating by amount and returns their rating
+	// before and after the change.
+	Adjust(player string, amount int) (before, after int)
+}
+
+// Seeder is implemented by rating engines that can start players at a
+// seeded rating, like one carried over from a previous league.
+type Seeder interface {
+	// Seed sets the ratings the players start at when they first appear,
+	// keyed by player.
+	Seed(seeds map[string]int)
+}
+
+// Config are the parameters the elo engine scores games with.
+type Config struct {
+	K      int         // the most a rating can move in a game before upsets are amplified.
+	Start  int         // the rating players start at.
+	Curve  string      // the shape of the reward curves, one of Curves.
+	Upsets []UpsetStep // amplify upsets beyond the usual expectation scaling, ordered by gap.
+
+	// ArchenemyMultiplier scales the archenemy's rating change, since a
+	// single game against the whole pod decides it.
+	ArchenemyMultiplier float64
+
+	// ZeroSum renormalizes each game's rating changes to sum to exactly zero,
+	// so the reward curves and multipliers can't leak points into or out of
+	// the league.
+	ZeroSum bool
+
+	// HalfLife, when set, decays the K-factor of older games, so a game
+	// played HalfLife before Now moves ratings half as much and the ratings
+	// reflect current skill. Games without a date aren't decayed.
+	HalfLife time.Duration
+	Now      time.Time
+}
+
+// gameK returns the K-factor the game is scored with, decayed by its age when
+// the config has a half life.
+func (c Config) gameK(game *Game) int {
+	if c.HalfLife <= 0 || game.Timestamp.IsZero() {
+		return c.K
+	}
+	age := c.Now.Sub(game.Timestamp)
+	if age <= 0 {
+		return c.K
+	}
+	return int(math.Round(float64(c.K) * math.Pow(0.5, float64(age)/float64(c.HalfLife))))
+}
+
+// DefaultConfig returns the parameters the league's ratings were designed
+// with.
+func DefaultConfig() Config {
+	return Config{K: elogo.K, Start: 1500, Curve: "default", ArchenemyMultiplier: 2}
+}
+
+// Rate scores the game with multiplayer elo, implementing Rater. Players
+// without a rating start at the config's Start.
+func (c Config) Rate(ratings Ratings, game *Game) (Ratings, []Result, error) {
+	if len(game.Rankings) < 2 {
+		return ratings, nil, fmt.Errorf("invalid game: not enough players")
+	}
+	pod, total := c.podRatings(ratings, game)
+	elo := elogo.NewEloWithFactors(c.gameK(game), elogo.D)
+
+	var results []Result
+	if game.IsArchenemy() {
+		results = c.rateArchenemy(elo, pod, game)
+	} else {
+		results = c.rateFreeForAll(elo, pod, total/len(pod), game)
+	}
+	if c.ZeroSum {
+		renormalize(results)
+	}
+
+	after := make(map[string]int, len(results))
+	for _, res := range results {
+		after[res.Player] = res.After
+	}
+	return ratings.With(after), results, nil
+}
+
+// podRatings returns the ratings the game's players went into it with, in
+// finishing order, and their total.
+func (c Config) podRatings(ratings Ratings, game *Game) ([]int, int) {
+	pod := make([]int, len(game.Rankings))
+	total := 0
+	for idx, player := range game.Rankings {
+		rating, ok := ratings.Get(player)
+		if !ok {
+			rating = c.Start
+		}
+		pod[idx] = rating
+		total += rating
+	}
+	return pod, total
+}
+
+// rateFreeForAll rates each player against the pod's average rating with the
+// reward for their place.
+func (c Config) rateFreeForAll(elo *elogo.Elo, pod []int, average int, game *Game) []Result {
+	results := make([]Result, 0, len(game.Rankings))
+	curve := c.RewardCurve(len(game.Rankings))
+	for idx, player := range game.Rankings {
+		delta := 0
+		if curve != nil {
+			delta = elo.RatingDelta(pod[idx], average, curve[idx])
+		}
+		if len(c.Upsets) > 0 && !game.IsDraw() {
+			delta = int(float64(delta) * c.UpsetMultiplier(pod, idx, delta))
+		}
+		results = append(results, Result{
+			Player: player,
+			Place:  idx + 1,
+			Before: pod[idx],
+			After:  pod[idx] + delta,
+			Delta:  delta,
+		})
+	}
+	return results
+}
+
+// rateArchenemy rates an archenemy game as elo between the archenemy and the
+// team. The archenemy is rated against the team's average rating with their
+// change scaled by the config's ArchenemyMultiplier, and each of the team's
+// players against the archenemy.
+func (c Config) rateArchenemy(elo *elogo.Elo, pod []int, game *Game) []Result {
+	archenemy, teamTotal := 0, 0
+	for idx, player := range game.Rankings {
+		if player == game.Archenemy {
+			archenemy = pod[idx]
+		} else {
+			teamTotal += pod[idx]
+		}
+	}
+	teamAverage := teamTotal / (len(game.Rankings) - 1)
+
+	// the archenemy's score, 1 for a win
+	score := 0.0
+	switch {
+	case game.IsDraw():
+		score = 0.5
+	case game.Rankings[0] == game.Archenemy:
+		score = 1
+	}
+
+	results := make([]Result, 0, len(game.Rankings))
+	for idx, player := range game.Rankings {
+		var delta int
+		if player == game.Archenemy {
+			delta = int(c.ArchenemyMultiplier * float64(elo.RatingDelta(pod[idx], teamAverage, score)))
+		} else {
+			delta = elo.RatingDelta(pod[idx], archenemy, 1-score)
+		}
+		results = append(results, Result{
+			Player: player,
+			Place:  game.Place(idx),
+			Before: pod[idx],
+			After:  pod[idx] + delta,
+			Delta:  delta,
+		})
+	}
+	return results
+}
+
+// NetChange returns the sum of a game's rating changes, which is 0 when the
+// game neither created nor destroyed rating points.
+func NetChange(results []Result) int {
+	net := 0
+	for _, res := range results {
+		net += res.Delta
+	}
+	return net
+}
+
+// renormalize spreads the game's net change evenly over its players so the
+// changes sum to exactly zero. What doesn't divide evenly comes off the
+// players who finished last.
+func renormalize(results []Result) {
+	net := NetChange(results)
+	if net == 0 || len(results) == 0 {
+		return
+	}
+	share, rest := net/len(results), net%len(results)
+	for idx := len(results) - 1; idx >= 0; idx-- {
+		correction := share
+		switch {
+		case rest > 0:
+			correction++
+			rest--
+		case rest < 0:
+			correction--
+			rest++
+		}
+		results[idx].Delta -= correction
+		results[idx].After -= correction
+	}
+}
+
+// Elo is the league's original rating algorithm: multiplayer Elo against the
+// pod's average rating, with rewards taken from the reward curve for the pod
+// size. It keeps the ratings between games as a snapshot rated by its
+// Config.
+type Elo struct {
+	config  Config
+	ratings Ratings
+	seeds   map[string]int // the ratings seeded players start at.
+}
+
+// NewElo returns an initialized elo engine scoring with the config.
+func NewElo(config Config) *Elo {
+	e := &Elo{config: config}
+	e.Initialize()
+	return e
+}
+
+func (e *Elo) Initialize() {
+	e.ratings = Ratings{}
+}
+
+func (e *Elo) ScoreGame(game *Game) error {
+	ratings := e.seedNewcomers(game.Rankings)
+	next, results, err := e.config.Rate(ratings, game)
+	if err != nil {
+		return err
+	}
+	pod, total := e.config.podRatings(ratings, game)
+	game.RankTotal = total
+	game.RankAverage = total / len(pod)
+	game.Results = results
+	e.ratings = next
+	return nil
+}
+
+func (e *Elo) Snapshot() map[string]int {
+	return e.ratings.Map()
+}
+
+// Ratings returns the current ratings, which stay the same as more games are
+// scored.
+func (e *Elo) Ratings() Ratings {
+	return e.ratings
+}
+
+func (e *Elo) Adjust(player string, amount int) (int, int) {
+	e.ratings = e.seedNewcomers([]string{player})
+	before, ok := e.ratings.Get(player)
+	if !ok {
+		before = e.config.Start
+	}
+	e.ratings = e.ratings.With(map[string]int{player: before + amount})
+	return before, before + amount
+}
+
+func (e *Elo) Seed(seeds map[string]int) {
+	e.seeds = seeds
+}
+
+// seedNewcomers returns the ratings with the players appearing for the first
+// time starting at their seeded rating.
+func (e *Elo) seedNewcomers(players []string) Ratings {
+	seeded := map[string]int{}
+	for _, player := range players {
+		if _, ok := e.ratings.Get(player); ok {
+			continue
+		}
+		if seed, ok := e.seeds[player]; ok {
+			seeded[player] = seed
+		}
+	}
+	if len(seeded) == 0 {
+		return e.ratings
+	}
+	return e.ratings.With(seeded)
+}
+
+// PointsRater rates games as a points race, implementing Rater.
+type PointsRater struct{}
+
+// Rate awards every player a point for each opponent they outlasted. Players
+// without points start at 0.
+func (PointsRater) Rate(ratings Ratings, game *Game) (Ratings, []Result, error) {
+	numPlayers := len(game.Rankings)
+	if numPlayers < 2 {
+		return ratings, nil, fmt.Errorf("invalid game: not enough players")
+	}
+
+	results := make([]Result, 0, numPlayers)
+	after := make(map[string]int, numPlayers)
+	for idx, player := range game.Rankings {
+		before, _ := ratings.Get(player)
+		delta := len(game.Beat(idx))
+		if game.IsDraw() {
+			delta = 0
+		}
+		after[player] = before + delta
+		results = append(results, Result{
+			Player: player,
+			Place:  game.Place(idx),
+			Before: before,
+			After:  before + delta,
+			Delta:  delta,
+		})
+	}
+	return ratings.With(after), results, nil
+}
+
+// Points is a simple points race: every player earns a point for each
+// opponent they outlast, so a 4 player win is worth 3 points.
+type Points struct {
+	ratings Ratings
+}
+
+// NewPoints returns an initialized points engine.
+func NewPoints() *Points {
+	e := &Points{}
+	e.Initialize()
+	return e
+}
+
+func (e *Points) Initialize() {
+	e.ratings = Ratings{}
+}
+
+func (e *Points) ScoreGame(game *Game) error {
+	next, results, err := PointsRater{}.Rate(e.ratings, game)
+	if err != nil {
+		return err
+	}
+	game.Results = results
+	e.ratings = next
+	return nil
+}
+
+func (e *Points) Snapshot() map[string]int {
+	return e.ratings.Map()
+}
+
+// Ratings returns the current points, which stay the same as more games are
+// scored.
+func (e *Points) Ratings() Ratings {
+	return e.ratings
+}
+
+func (e *Points) Adjust(player string, amount int) (int, int) {
+	before, _ := e.ratings.Get(player)
+	e.ratings = e.ratings.With(map[string]int{player: before + amount})
+	return before, before + amount
+}
+
+func copyScores(scores map[string]int) map[string]int {
+	snapshot := make(map[string]int, len(scores))
+	for name, score := range scores {
+		snapshot[name] = score
+	}
+	return snapshot
+}
@@ -0,0 +1,75 @@
+package scoring_test
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/fly-apps/go-example/scoring"
+)
+
+func ExampleScore() {
+	games := []*scoring.Game{
+		{ID: "1", Rankings: []string{"alice", "bob", "carol", "dave"}},
+		{ID: "2", Rankings: []string{"carol", "alice", "dave", "bob"}},
+	}
+	scores, errs := scoring.Score(scoring.NewElo(scoring.DefaultConfig()), games)
+	if len(errs) > 0 {
+		fmt.Println(errs)
+	}
+	for _, res := range games[1].Results {
+		fmt.Printf("%d. %s %d (%+d)\n", res.Place, res.Player, res.After, res.Delta)
+	}
+	fmt.Println(scores["alice"] > scores["bob"])
+	// Output:
+	// 1. carol 1508 (+16)
+	// 2. alice 1516 (+0)
+	// 3. dave 1477 (-7)
+	// 4. bob 1484 (-16)
+	// true
+}
+
+func ExampleReplay() {
+	games := []*scoring.Game{
+		{ID: "1", Rankings: []string{"alice", "bob", "carol", "dave"}},
+		{ID: "2", Rankings: []string{"carol", "alice", "dave", "bob"}},
+	}
+
+	// replays share the games and starting ratings, so what-ifs under
+	// different parameters can run at once
+	ks := []int{16, 32, 64}
+	finals := make([]scoring.Ratings, len(ks))
+	var wg sync.WaitGroup
+	for idx, k := range ks {
+		wg.Add(1)
+		go func(idx, k int) {
+			defer wg.Done()
+			config := scoring.DefaultConfig()
+			config.K = k
+			finals[idx], _, _ = scoring.Replay(config, scoring.Ratings{}, games)
+		}(idx, k)
+	}
+	wg.Wait()
+
+	for idx, k := range ks {
+		carol, _ := finals[idx].Get("carol")
+		fmt.Printf("K = %d: carol %d\n", k, carol)
+	}
+	// Output:
+	// K = 16: carol 1504
+	// K = 32: carol 1508
+	// K = 64: carol 1517
+}
+
+func ExampleNetChange() {
+	game := &scoring.Game{ID: "1", Rankings: []string{"alice", "bob", "carol", "dave"}}
+	config := scoring.DefaultConfig()
+	_, results, _ := config.Rate(scoring.Ratings{}, game)
+	fmt.Println(scoring.NetChange(results))
+
+	config.ZeroSum = true
+	_, results, _ = config.Rate(scoring.Ratings{}, game)
+	fmt.Println(scoring.NetChange(results), results[0].Delta, results[3].Delta)
+	// Output:
+	// -8
+	// 0 18 -14
+}
@@ -0,0 +1,165 @@
+package scoring
+
+import (
+	"strings"
+	"time"
+)
+
+// Game is a modeled MTG Game with a set of rankings determined by order of player loss.
+type Game struct {
+	ID             string    // the ID of the game, which also correlates to its number in the game log.
+	Date           string    // the date of the game.
+	Timestamp      time.Time // the parsed and formatted timestamp of the game's date for comparison purposes.
+	Rankings       []string  // an ordered list of players with index 0 being the winner and each subsequent position the next rank.
+	TableZap       string    // marks if the game was ended in one resolution.
+	DrawGame       string    // if draw game is marked, the game ended in a draw for all players, so order doesn't matter but players still need to be recorded.
+	RankTotal      int       // the total elo scores of the game for determining the skill level of the game.
+	RankAverage    int       // the average elo score of the game determined by diviving the number of players from the above rank average.
+	TwoHeadedGiant bool      // if the game is a match of multiple players per team, colloquially referred to as a two-headed giant game.
+	Notes          string    // free-form notes recorded with the game.
+	Archenemy      string    // the player who faced the rest of the pod as a team in an archenemy game.
+	Results        []Result  // the per-player rating changes in order of placement, filled in when the game is scored.
+
+	// Eliminations are the times players were eliminated, keyed by player,
+	// when they were recorded.
+	Eliminations map[string]time.Time
+
+	// Decks are the archetypes and color identities of the decks played,
+	// keyed by player, when they were tagged.
+	Decks map[string][]string
+
+	// Teams are the players of each team in finishing order in a two-headed
+	// giant game, which isn't scored yet.
+	Teams [][]string
+
+	// Tags are free-form labels for the game, like "budget-night" or "cedh",
+	// lowercased.
+	Tags []string
+
+	// FirstBlood is the player who dealt the game's first damage, and
+	// FirstEliminated the player who was knocked out first, when they were
+	// recorded.
+	FirstBlood, FirstEliminated string
+
+	// Kingmaker is the player recorded as deciding the winner of the game
+	// once they were out of contention themselves.
+	Kingmaker string
+
+	// Row is the game's 1-indexed row in the game log range it was parsed
+	// from, where row 1 is the header, or 0 if it wasn't parsed from a sheet.
+	Row int
+}
+
+// Result records how a single game changed a player's rating.
+type Result struct {
+	Player string `json:"player"`
+	Place  int    `json:"place"`  // the player's 1-indexed placement in the game.
+	Before int    `json:"before"` // the player's rating going into the game.
+	After  int    `json:"after"`  // the player's rating after the game was scored.
+	Delta  int    `json:"delta"`
+}
+
+// IsDraw reports whether the game was marked as a draw in the sheet.
+func (g *Game) IsDraw() bool {
+	switch strings.ToLower(strings.TrimSpace(g.DrawGame)) {
+	case "", "false", "no", "n", "0":
+		return false
+	}
+	return true
+}
+
+// IsArchenemy reports whether the game was an archenemy game.
+func (g *Game) IsArchenemy() bool {
+	return g.Archenemy != ""
+}
+
+// Place returns the 1-indexed place of the player at idx in the rankings. In
+// archenemy games the team's players share a place.
+func (g *Game) Place(idx int) int {
+	if !g.IsArchenemy() {
+		return idx + 1
+	}
+	archenemyWon := g.Rankings[0] == g.Archenemy
+	if (g.Rankings[idx] == g.Archenemy) == archenemyWon {
+		return 1
+	}
+	return 2
+}
+
+// Won reports whether the player won the game, or was on the winning team.
+func (g *Game) Won(player string) bool {
+	for idx, name := range g.Rankings {
+		if name == player {
+			return g.Place(idx) == 1
+		}
+	}
+	return false
+}
+
+// Beat returns the players the player at idx finished above. In archenemy
+// games that's the other side when the player's side won.
+func (g *Game) Beat(idx int) []string {
+	if !g.IsArchenemy() {
+		return g.Rankings[idx+1:]
+	}
+	if g.Place(idx) != 1 {
+		return nil
+	}
+	var beat []string
+	for i, name := range g.Rankings {
+		if g.Place(i) != 1 {
+			beat = append(beat, name)
+		}
+	}
+	return beat
+}
+
+// Survival returns how many minutes the player survived in the game, and
+// whether it's known. The winner survives until the last elimination.
+func (g *Game) Survival(player string) (float64, bool) {
+	if g.Timestamp.IsZero() || len(g.Eliminations) == 0 {
+		return 0, false
+	}
+	at, ok := g.Eliminations[player]
+	if !ok {
+		if len(g.Rankings) == 0 || g.Rankings[0] != player {
+			return 0, false
+		}
+		for _, t := range g.Eliminations {
+			if t.After(at) {
+				at = t
+			}
+		}
+	}
+	return at.Sub(g.Timestamp).Minutes(), true
+}
+
+// FirstOut returns the first player eliminated from the game: the one
+// recorded as eliminated first, or otherwise the player who finished in last
+// place.
+func (g *Game) FirstOut() string {
+	if g.FirstEliminated != "" {
+		return g.FirstEliminated
+	}
+	if g.IsDraw() || g.IsArchenemy() || len(g.Rankings) < 2 {
+		return ""
+	}
+	return g.Rankings[len(g.Rankings)-1]
+}
+
+// Adjustment is a manual change to a player's rating, such as a penalty for
+// slow play or a bonus for hosting, recorded in the adjustments tab.
+type Adjustment struct {
+	Date      string    // the date of the adjustment as recorded in the sheet.
+	Timestamp time.Time // the parsed date, used to apply the adjustment in order with games.
+	Player    string
+	Amount    int
+	Reason    string
+	Before    int // the player's rating before the adjustment, filled in when scored.
+	After     int // the player's rating after the adjustment, filled in when scored.
+
+	// Seed marks a rating from the seeds tab, which the player starts at
+	// when they first appear rather than an adjustment on a date. Its Amount
+	// is the rating.
+	Seed bool
+}
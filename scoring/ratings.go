@@ -0,0 +1,70 @@
+package scoring
+
+// Ratings is an immutable snapshot of players' ratings. Rating a game returns
+// a new snapshot instead of changing the one it was given, so a snapshot can
+// be shared by simulations running in parallel.
+type Ratings struct {
+	scores map[string]int
+}
+
+// NewRatings returns a snapshot of the ratings, keyed by player.
+func NewRatings(scores map[string]int) Ratings {
+	return Ratings{scores: copyScores(scores)}
+}
+
+// Get returns the player's rating and whether they have one.
+func (r Ratings) Get(player string) (int, bool) {
+	rating, ok := r.scores[player]
+	return rating, ok
+}
+
+// Len returns the number of rated players.
+func (r Ratings) Len() int {
+	return len(r.scores)
+}
+
+// Map returns a copy of the ratings keyed by player.
+func (r Ratings) Map() map[string]int {
+	return copyScores(r.scores)
+}
+
+// With returns a snapshot with the given players' ratings replaced.
+func (r Ratings) With(changes map[string]int) Ratings {
+	next := make(map[string]int, len(r.scores)+len(changes))
+	for player, rating := range r.scores {
+		next[player] = rating
+	}
+	for player, rating := range changes {
+		next[player] = rating
+	}
+	return Ratings{scores: next}
+}
+
+// Rater rates a game without side effects, so the same rater can score many
+// simulations at once.
+type Rater interface {
+	// Rate scores the game against the ratings going into it and returns the
+	// ratings after it along with each player's result in finishing order.
+	// Neither the ratings nor the game are modified.
+	Rate(ratings Ratings, game *Game) (Ratings, []Result, error)
+}
+
+// Replay rates the games in the order they were played from the starting
+// ratings, returning the final ratings and each game's results in the order
+// of the games. Games that can't be rated get no results, with an error for
+// each returned. The games aren't modified, so the same games can be replayed
+// under different raters in parallel.
+func Replay(rater Rater, start Ratings, games []*Game) (Ratings, [][]Result, []error) {
+	var errs []error
+	ratings := start
+	results := make([][]Result, len(games))
+	for idx, game := range games {
+		next, res, err := rater.Rate(ratings, game)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ratings, results[idx] = next, res
+	}
+	return ratings, results, errs
+}
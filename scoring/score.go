@@ -0,0 +1,58 @@
+package scoring
+
+import "fmt"
+
+// Score scores the games, which must be in the order they were played, with
+// the engine and returns every player's final rating. Adjustments, which must
+// be in date order, are applied in between the games they were made between,
+// and seeds among them set the rating players start at instead.
+//
+// Games and adjustments that can't be scored are skipped, with an error for
+// each returned alongside the ratings.
+func Score(engine RatingEngine, games []*Game, adjustments ...*Adjustment) (map[string]int, []error) {
+	var errs []error
+
+	seeds := map[string]int{}
+	dated := make([]*Adjustment, 0, len(adjustments))
+	for _, adj := range adjustments {
+		if adj.Seed {
+			seeds[adj.Player] = adj.Amount
+		} else {
+			dated = append(dated, adj)
+		}
+	}
+	adjustments = dated
+	if len(seeds) > 0 {
+		if s, ok := engine.(Seeder); ok {
+			s.Seed(seeds)
+		} else {
+			errs = append(errs, fmt.Errorf("%T does not support seeded ratings, skipping %d seeds", engine, len(seeds)))
+		}
+	}
+
+	adjust := func(game *Game) {
+		for len(adjustments) > 0 && (game == nil || !adjustments[0].Timestamp.After(game.Timestamp)) {
+			adj := adjustments[0]
+			adjustments = adjustments[1:]
+			a, ok := engine.(Adjuster)
+			if !ok {
+				errs = append(errs, fmt.Errorf("%T does not support adjustments, skipping %+v", engine, adj))
+				continue
+			}
+			adj.Before, adj.After = a.Adjust(adj.Player, adj.Amount)
+		}
+	}
+
+	for _, game := range games {
+		if !game.Timestamp.IsZero() {
+			adjust(game)
+		}
+		if err := engine.ScoreGame(game); err != nil {
+			errs = append(errs, fmt.Errorf("failed to score game %s: %w", game.ID, err))
+		}
+	}
+	// apply any adjustments made after the last game
+	adjust(nil)
+
+	return engine.Snapshot(), errs
+}